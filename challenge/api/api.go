@@ -0,0 +1,9 @@
+// Package api holds the OpenAPI document of the tickets api.
+package api
+
+import _ "embed"
+
+// Spec is the OpenAPI 3.1 document of the api
+//
+//go:embed openapi.json
+var Spec []byte
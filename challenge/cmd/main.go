@@ -1,41 +1,72 @@
 package main
 
 import (
+	"app/api"
+	"app/internal/config"
 	"app/internal/handler"
 	"app/internal/loader"
 	"app/internal/repository"
 	"app/internal/service"
+	"app/platform/health"
+	"app/platform/web/docs"
+	"app/platform/web/lifecycle"
+	"app/platform/web/middleware"
+	"context"
 	"fmt"
-	"net/http"
+	"log/slog"
 	"os"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
 func main() {
 	// env
-	// ...
+	// - config: defaults < config file < environment < flags
+	loader := config.NewLoader(os.Args[1:], os.Getenv)
+	appConfig, err := loader.Load()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if loader.PrintConfig {
+		if err := appConfig.Print(os.Stdout); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	// - logger
+	slog.SetDefault(newLogger(appConfig.Log))
 
 	// application
 	// - config
 	cfg := &ConfigAppDefault{
-		ServerAddr: os.Getenv("SERVER_ADDR"),
-		DbFile:     os.Getenv("DB_FILE"),
+		ServerAddr:      appConfig.Server.Addr,
+		DbFile:          appConfig.Storage.Path,
+		ReadTimeout:     appConfig.Server.ReadTimeout,
+		WriteTimeout:    appConfig.Server.WriteTimeout,
+		IdleTimeout:     appConfig.Server.IdleTimeout,
+		ShutdownTimeout: appConfig.Server.ShutdownTimeout,
+		ShutdownDelay:   appConfig.Server.ShutdownDelay,
+		RequestTimeout:  appConfig.Server.RequestTimeout,
+		TLSCertFile:     appConfig.Server.TLS.CertFile,
+		TLSKeyFile:      appConfig.Server.TLS.KeyFile,
 	}
 	app := NewApplicationDefault(cfg)
 
 	// - setup
-	err := app.SetUp()
+	err = app.SetUp()
 	if err != nil {
 		fmt.Println(err)
-		return
+		os.Exit(1)
 	}
 
 	// - run
 	err = app.Run()
 	if err != nil {
 		fmt.Println(err)
-		return
+		os.Exit(1)
 	}
 }
 
@@ -45,6 +76,21 @@ type ConfigAppDefault struct {
 	ServerAddr string
 	// dbFile represents the path to the database file
 	DbFile string
+	// ReadTimeout represents the maximum duration for reading a request
+	ReadTimeout time.Duration
+	// WriteTimeout represents the maximum duration for writing a response
+	WriteTimeout time.Duration
+	// IdleTimeout represents the maximum duration of idle keep-alive connections
+	IdleTimeout time.Duration
+	// ShutdownTimeout represents the maximum duration to drain in-flight requests
+	ShutdownTimeout time.Duration
+	// ShutdownDelay represents the time the readiness fails before draining
+	ShutdownDelay time.Duration
+	// RequestTimeout represents the deadline of each request, 0 disables it
+	RequestTimeout time.Duration
+	// TLSCertFile and TLSKeyFile enable TLS when both are set
+	TLSCertFile string
+	TLSKeyFile  string
 }
 
 // NewApplicationDefault creates a new default application
@@ -52,8 +98,12 @@ func NewApplicationDefault(cfg *ConfigAppDefault) *ApplicationDefault {
 	// default values
 	defaultRouter := chi.NewRouter()
 	defaultConfig := &ConfigAppDefault{
-		ServerAddr: ":8080",
-		DbFile:     "docs/db/tickets.csv",
+		ServerAddr:      ":8080",
+		DbFile:          "docs/db/tickets.csv",
+		ReadTimeout:     5 * time.Second,
+		WriteTimeout:    10 * time.Second,
+		IdleTimeout:     60 * time.Second,
+		ShutdownTimeout: 15 * time.Second,
 	}
 	if cfg != nil {
 		if cfg.ServerAddr != "" {
@@ -62,12 +112,37 @@ func NewApplicationDefault(cfg *ConfigAppDefault) *ApplicationDefault {
 		if cfg.DbFile != "" {
 			defaultConfig.DbFile = cfg.DbFile
 		}
+		if cfg.ReadTimeout != 0 {
+			defaultConfig.ReadTimeout = cfg.ReadTimeout
+		}
+		if cfg.WriteTimeout != 0 {
+			defaultConfig.WriteTimeout = cfg.WriteTimeout
+		}
+		if cfg.IdleTimeout != 0 {
+			defaultConfig.IdleTimeout = cfg.IdleTimeout
+		}
+		if cfg.ShutdownTimeout != 0 {
+			defaultConfig.ShutdownTimeout = cfg.ShutdownTimeout
+		}
+		defaultConfig.ShutdownDelay = cfg.ShutdownDelay
+		defaultConfig.RequestTimeout = cfg.RequestTimeout
+		defaultConfig.TLSCertFile = cfg.TLSCertFile
+		defaultConfig.TLSKeyFile = cfg.TLSKeyFile
 	}
 
 	return &ApplicationDefault{
-		rt:         defaultRouter,
-		serverAddr: defaultConfig.ServerAddr,
-		dbFile:     defaultConfig.DbFile,
+		rt:              defaultRouter,
+		serverAddr:      defaultConfig.ServerAddr,
+		dbFile:          defaultConfig.DbFile,
+		readTimeout:     defaultConfig.ReadTimeout,
+		writeTimeout:    defaultConfig.WriteTimeout,
+		idleTimeout:     defaultConfig.IdleTimeout,
+		shutdownTimeout: defaultConfig.ShutdownTimeout,
+		shutdownDelay:   defaultConfig.ShutdownDelay,
+		requestTimeout:  defaultConfig.RequestTimeout,
+		health:          health.NewRegistry(0),
+		tlsCertFile:     defaultConfig.TLSCertFile,
+		tlsKeyFile:      defaultConfig.TLSKeyFile,
 	}
 }
 
@@ -79,24 +154,50 @@ type ApplicationDefault struct {
 	serverAddr string
 	// dbFile represents the path to the database file
 	dbFile string
+	// readTimeout represents the maximum duration for reading a request
+	readTimeout time.Duration
+	// writeTimeout represents the maximum duration for writing a response
+	writeTimeout time.Duration
+	// idleTimeout represents the maximum duration of idle keep-alive connections
+	idleTimeout time.Duration
+	// shutdownTimeout represents the maximum duration to drain in-flight requests
+	shutdownTimeout time.Duration
+	// shutdownDelay represents the time the readiness fails before draining
+	shutdownDelay time.Duration
+	// requestTimeout represents the deadline of each request, 0 disables it
+	requestTimeout time.Duration
+	// health represents the readiness checks of the application
+	health *health.Registry
+	// tlsCertFile and tlsKeyFile enable TLS when both are set
+	tlsCertFile string
+	tlsKeyFile  string
 }
 
 // SetUp sets up the application
 func (a *ApplicationDefault) SetUp() (err error) {
 	// dependencies
 	loader := loader.NewLoaderTicketCSV(a.dbFile)
+	(*a).health.Register("tickets_csv", true, loader.HealthCheck)
 	rp := repository.NewRepositoryTicketMap(loader)
 	// service ...
 	sv := service.NewServiceTicketDefault(rp)
 	// handler ...
 	h := handler.NewHandlerTicketDefault(sv)
 
+	// middlewares
+	(*a).rt.Use(middleware.NewDeadline(a.requestTimeout).Timeout)
+
 	// routes
-	(*a).rt.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Header().Set("Content-Type", "text/plain")
-		w.Write([]byte("OK"))
-	})
+	// - /health is kept as an alias of /readyz for the existing probes
+	(*a).rt.Get("/health", a.health.ReadinessHandler)
+	(*a).rt.Get("/livez", a.health.LivenessHandler)
+	(*a).rt.Get("/readyz", a.health.ReadinessHandler)
+
+	// - docs
+	(*a).rt.Get("/openapi.json", docs.SpecHandler(api.Spec))
+	swaggerUI := docs.UIHandler("/docs/", "/openapi.json")
+	(*a).rt.Handle("/docs", swaggerUI)
+	(*a).rt.Handle("/docs/*", swaggerUI)
 
 	(*a).rt.Route("/tickets", func(router chi.Router) {
 		router.Get("/total", h.GetTotalAmountTickets())
@@ -107,8 +208,35 @@ func (a *ApplicationDefault) SetUp() (err error) {
 	return
 }
 
-// Run runs the application
+// Run runs the application until a shutdown signal is received, draining in-flight requests
 func (a *ApplicationDefault) Run() (err error) {
-	err = http.ListenAndServe(a.serverAddr, a.rt)
+	lc := lifecycle.NewServer(lifecycle.Config{
+		Addr:            a.serverAddr,
+		ReadTimeout:     a.readTimeout,
+		WriteTimeout:    a.writeTimeout,
+		IdleTimeout:     a.idleTimeout,
+		ShutdownTimeout: a.shutdownTimeout,
+		ShutdownDelay:   a.shutdownDelay,
+		CertFile:        a.tlsCertFile,
+		KeyFile:         a.tlsKeyFile,
+	}, a.rt)
+	lc.BeforeShutdown(func(ctx context.Context) error {
+		a.health.SetShuttingDown()
+		return nil
+	})
+	err = lc.Run()
 	return
 }
+
+// newLogger creates the default logger from the log config
+func newLogger(cfg config.LogConfig) *slog.Logger {
+	var level slog.Level
+	// the level is validated by the config
+	_ = level.UnmarshalText([]byte(cfg.Level))
+
+	opts := &slog.HandlerOptions{Level: level}
+	if cfg.Format == config.LogFormatJSON {
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts))
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, opts))
+}
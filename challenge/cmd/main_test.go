@@ -0,0 +1,116 @@
+package main
+
+import (
+	"app/api"
+	"app/platform/health"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	t.Run("success - every registered route is in the spec", func(t *testing.T) {
+		// ARRANGE
+		// decode the spec
+		var spec struct {
+			OpenAPI string                                `json:"openapi"`
+			Paths   map[string]map[string]json.RawMessage `json:"paths"`
+		}
+		require.NoError(t, json.Unmarshal(api.Spec, &spec))
+		// set up the application
+		a := NewApplicationDefault(&ConfigAppDefault{DbFile: "../docs/db/tickets.csv"})
+		require.NoError(t, a.SetUp())
+
+		// ACT
+		var missing []string
+		err := chi.Walk(a.rt, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+			// the Swagger UI is not part of the api
+			if route == "/docs" || strings.HasPrefix(route, "/docs/") {
+				return nil
+			}
+			if _, ok := spec.Paths[route][strings.ToLower(method)]; !ok {
+				missing = append(missing, method+" "+route)
+			}
+			return nil
+		})
+
+		// ASSERT
+		require.NoError(t, err)
+		require.Equal(t, "3.1.0", spec.OpenAPI)
+		require.Empty(t, missing, "routes missing from api/openapi.json")
+	})
+
+	t.Run("success - spec and swagger ui served", func(t *testing.T) {
+		// ARRANGE
+		a := NewApplicationDefault(&ConfigAppDefault{DbFile: "../docs/db/tickets.csv"})
+		require.NoError(t, a.SetUp())
+
+		// ACT
+		get := func(path string) *httptest.ResponseRecorder {
+			rr := httptest.NewRecorder()
+			a.rt.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+			return rr
+		}
+		spec := get("/openapi.json")
+		index := get("/docs/")
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, spec.Code)
+		require.JSONEq(t, string(api.Spec), spec.Body.String())
+		require.Equal(t, http.StatusOK, index.Code)
+		require.Contains(t, index.Body.String(), "swagger-ui")
+	})
+}
+
+func TestHealth(t *testing.T) {
+	// get serves a GET of path by the application
+	get := func(a *ApplicationDefault, path string) (*httptest.ResponseRecorder, health.Report) {
+		rr := httptest.NewRecorder()
+		a.rt.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		var report health.Report
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+		return rr, report
+	}
+
+	t.Run("success - /health and /readyz report the check of the tickets", func(t *testing.T) {
+		// ARRANGE
+		a := NewApplicationDefault(&ConfigAppDefault{DbFile: "../docs/db/tickets.csv"})
+		require.NoError(t, a.SetUp())
+
+		// ACT
+		rrHealth, reportHealth := get(a, "/health")
+		rrReady, reportReady := get(a, "/readyz")
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, rrHealth.Code)
+		require.Equal(t, "application/json", rrHealth.Header().Get("Content-Type"))
+		require.Equal(t, health.StatusOK, reportHealth.Checks["tickets_csv"].Status)
+		require.Equal(t, http.StatusOK, rrReady.Code)
+		require.Equal(t, reportHealth.Status, reportReady.Status)
+	})
+
+	t.Run("failure - /health fails without the tickets file or while shutting down", func(t *testing.T) {
+		// ARRANGE
+		missing := NewApplicationDefault(&ConfigAppDefault{DbFile: "../docs/db/missing.csv"})
+		require.NoError(t, missing.SetUp())
+		shutdown := NewApplicationDefault(&ConfigAppDefault{DbFile: "../docs/db/tickets.csv"})
+		require.NoError(t, shutdown.SetUp())
+		shutdown.health.SetShuttingDown()
+
+		// ACT
+		rrMissing, reportMissing := get(missing, "/health")
+		rrShutdown, _ := get(shutdown, "/health")
+		rrLive, _ := get(shutdown, "/livez")
+
+		// ASSERT
+		require.Equal(t, http.StatusServiceUnavailable, rrMissing.Code)
+		require.Equal(t, health.StatusFailing, reportMissing.Checks["tickets_csv"].Status)
+		require.Equal(t, http.StatusServiceUnavailable, rrShutdown.Code)
+		require.Equal(t, http.StatusOK, rrLive.Code)
+	})
+}
@@ -2,4 +2,9 @@ module app
 
 go 1.21.2
 
-require github.com/go-chi/chi/v5 v5.0.10
+require (
+	github.com/BurntSushi/toml v1.3.2
+	github.com/go-chi/chi/v5 v5.0.10
+	github.com/swaggo/files/v2 v2.0.2
+	gopkg.in/yaml.v3 v3.0.1
+)
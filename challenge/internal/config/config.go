@@ -0,0 +1,163 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrInvalidConfig is returned when the merged configuration is not valid.
+	ErrInvalidConfig = errors.New("config: invalid configuration")
+	// ErrConfigFile is returned when the config file can not be read or decoded.
+	ErrConfigFile = errors.New("config: invalid config file")
+)
+
+const (
+	// StorageBackendCSV loads the tickets from a CSV file.
+	StorageBackendCSV = "csv"
+
+	// LogFormatText writes logs as key=value pairs.
+	LogFormatText = "text"
+	// LogFormatJSON writes logs as JSON objects.
+	LogFormatJSON = "json"
+)
+
+// Config is the configuration of the tickets application
+type Config struct {
+	// Server is the configuration of the http server
+	Server ServerConfig `yaml:"server" toml:"server"`
+	// Storage is the configuration of the tickets storage
+	Storage StorageConfig `yaml:"storage" toml:"storage"`
+	// Log is the configuration of the logger
+	Log LogConfig `yaml:"log" toml:"log"`
+}
+
+// ServerConfig is the configuration of the http server
+type ServerConfig struct {
+	Addr            string        `yaml:"addr" toml:"addr"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	ShutdownDelay   time.Duration `yaml:"shutdown_delay" toml:"shutdown_delay"`
+	RequestTimeout  time.Duration `yaml:"request_timeout" toml:"request_timeout"`
+	TLS             TLSConfig     `yaml:"tls" toml:"tls"`
+}
+
+// TLSConfig is the configuration of TLS. TLS is enabled when both files are set.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file" toml:"cert_file"`
+	KeyFile  string `yaml:"key_file" toml:"key_file"`
+}
+
+// StorageConfig is the configuration of the tickets storage
+type StorageConfig struct {
+	// Backend is StorageBackendCSV
+	Backend string `yaml:"backend" toml:"backend"`
+	// Path is the path to the CSV file of tickets
+	Path string `yaml:"path" toml:"path"`
+}
+
+// LogConfig is the configuration of the logger
+type LogConfig struct {
+	// Level is one of debug, info, warn or error
+	Level string `yaml:"level" toml:"level"`
+	// Format is one of LogFormatText or LogFormatJSON
+	Format string `yaml:"format" toml:"format"`
+}
+
+// Default returns the default configuration.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:            ":8080",
+			ReadTimeout:     5 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 15 * time.Second,
+			RequestTimeout:  8 * time.Second,
+		},
+		Storage: StorageConfig{
+			Backend: StorageBackendCSV,
+			Path:    "docs/db/tickets.csv",
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: LogFormatText,
+		},
+	}
+}
+
+// Validate checks that the configuration is consistent.
+func (c Config) Validate() error {
+	var errs []error
+
+	// server
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("server.addr %q is not a valid address", c.Server.Addr))
+	}
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", timeout.name))
+		}
+	}
+	if c.Server.ShutdownDelay < 0 {
+		errs = append(errs, errors.New("server.shutdown_delay must not be negative"))
+	}
+	if c.Server.RequestTimeout < 0 {
+		errs = append(errs, errors.New("server.request_timeout must not be negative"))
+	}
+	if c.Server.RequestTimeout > 0 && c.Server.RequestTimeout >= c.Server.WriteTimeout {
+		errs = append(errs, errors.New("server.request_timeout must be shorter than server.write_timeout"))
+	}
+	if (c.Server.TLS.CertFile == "") != (c.Server.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("server.tls.cert_file and server.tls.key_file must be set together"))
+	}
+
+	// storage
+	if c.Storage.Backend != StorageBackendCSV {
+		errs = append(errs, fmt.Errorf("storage.backend %q is not %s", c.Storage.Backend, StorageBackendCSV))
+	}
+	if c.Storage.Path == "" {
+		errs = append(errs, errors.New("storage.path is required"))
+	}
+
+	// log
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level %q is not one of debug, info, warn, error", c.Log.Level))
+	}
+	switch c.Log.Format {
+	case LogFormatText, LogFormatJSON:
+	default:
+		errs = append(errs, fmt.Errorf("log.format %q is not one of %s, %s", c.Log.Format, LogFormatText, LogFormatJSON))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, errors.Join(errs...))
+	}
+	return nil
+}
+
+// Print writes the effective configuration as YAML. It holds no secrets to redact.
+func (c Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	defer enc.Close()
+	return enc.Encode(c)
+}
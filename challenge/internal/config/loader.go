@@ -0,0 +1,156 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// knob binds a configuration value to its environment variable and command-line flag
+type knob struct {
+	// env is the name of the environment variable
+	env string
+	// flag is the name of the command-line flag
+	flag string
+	// usage is the description of the flag
+	usage string
+	// set parses the raw value into the configuration
+	set func(c *Config, v string) error
+}
+
+// knobs are all the values that can be set from the environment and the command line
+var knobs = []knob{
+	{"SERVER_ADDR", "addr", "address the server listens on", setString(func(c *Config) *string { return &c.Server.Addr })},
+	{"READ_TIMEOUT", "read-timeout", "maximum duration for reading a request", setDuration(func(c *Config) *time.Duration { return &c.Server.ReadTimeout })},
+	{"WRITE_TIMEOUT", "write-timeout", "maximum duration for writing a response", setDuration(func(c *Config) *time.Duration { return &c.Server.WriteTimeout })},
+	{"IDLE_TIMEOUT", "idle-timeout", "maximum duration of idle keep-alive connections", setDuration(func(c *Config) *time.Duration { return &c.Server.IdleTimeout })},
+	{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "maximum duration to drain in-flight requests", setDuration(func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout })},
+	{"SHUTDOWN_DELAY", "shutdown-delay", "time to fail the readiness before draining on shutdown", setDuration(func(c *Config) *time.Duration { return &c.Server.ShutdownDelay })},
+	{"REQUEST_TIMEOUT", "request-timeout", "deadline of each request, 0 disables it", setDuration(func(c *Config) *time.Duration { return &c.Server.RequestTimeout })},
+	{"TLS_CERT_FILE", "tls-cert-file", "path to the TLS certificate", setString(func(c *Config) *string { return &c.Server.TLS.CertFile })},
+	{"TLS_KEY_FILE", "tls-key-file", "path to the TLS private key", setString(func(c *Config) *string { return &c.Server.TLS.KeyFile })},
+	{"STORAGE_BACKEND", "storage-backend", "storage backend: csv", setString(func(c *Config) *string { return &c.Storage.Backend })},
+	{"DB_FILE", "db-file", "path to the CSV file of tickets", setString(func(c *Config) *string { return &c.Storage.Path })},
+	{"LOG_LEVEL", "log-level", "log level: debug, info, warn or error", setString(func(c *Config) *string { return &c.Log.Level })},
+	{"LOG_FORMAT", "log-format", "log format: text or json", setString(func(c *Config) *string { return &c.Log.Format })},
+}
+
+func setString(field func(c *Config) *string) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		*field(c) = v
+		return nil
+	}
+}
+
+func setDuration(field func(c *Config) *time.Duration) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+// NewLoader creates a Loader reading the given command-line arguments and environment
+func NewLoader(args []string, getenv func(string) string) *Loader {
+	return &Loader{
+		args:   args,
+		getenv: getenv,
+	}
+}
+
+// Loader merges the configuration, in increasing order of precedence, from:
+// the defaults, the config file (YAML or TOML), the environment and the command-line flags.
+type Loader struct {
+	// args are the command-line arguments, without the program name
+	args []string
+	// getenv reads an environment variable
+	getenv func(string) string
+
+	// PrintConfig is set by the -print-config flag once Load is called
+	PrintConfig bool
+}
+
+// Load merges and validates the configuration.
+func (l *Loader) Load() (cfg Config, err error) {
+	cfg = Default()
+
+	// flags are parsed first to find the config file, but applied last
+	fs := flag.NewFlagSet("tickets", flag.ContinueOnError)
+	var configFile string
+	fs.StringVar(&configFile, "config", l.getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+	fs.BoolVar(&l.PrintConfig, "print-config", false, "print the effective config and exit")
+	flagValues := make(map[string]string)
+	for _, k := range knobs {
+		k := k
+		fs.Func(k.flag, k.usage+" (env "+k.env+")", func(v string) error {
+			flagValues[k.flag] = v
+			return nil
+		})
+	}
+	if err = fs.Parse(l.args); err != nil {
+		return
+	}
+
+	// - file
+	if configFile != "" {
+		if err = loadFile(configFile, &cfg); err != nil {
+			return
+		}
+	}
+
+	// - environment
+	for _, k := range knobs {
+		v := l.getenv(k.env)
+		if v == "" {
+			continue
+		}
+		if err = k.set(&cfg, v); err != nil {
+			err = fmt.Errorf("%w: %s: %v", ErrInvalidConfig, k.env, err)
+			return
+		}
+	}
+
+	// - flags
+	for _, k := range knobs {
+		v, ok := flagValues[k.flag]
+		if !ok {
+			continue
+		}
+		if err = k.set(&cfg, v); err != nil {
+			err = fmt.Errorf("%w: -%s: %v", ErrInvalidConfig, k.flag, err)
+			return
+		}
+	}
+
+	err = cfg.Validate()
+	return
+}
+
+// loadFile decodes the config file over cfg, choosing the format by extension
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrConfigFile, err)
+	}
+
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	default:
+		err = fmt.Errorf("unsupported extension %q, use .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrConfigFile, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package config_test
+
+import (
+	"app/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoaderLoad tests the precedence and the validation of the Loader.
+func TestLoaderLoad(t *testing.T) {
+	t.Run("success - defaults without file, environment nor flags", func(t *testing.T) {
+		// ARRANGE
+		loader := config.NewLoader(nil, func(string) string { return "" })
+
+		// ACT
+		cfg, err := loader.Load()
+
+		// ASSERT
+		require.NoError(t, err)
+		require.Equal(t, config.Default(), cfg)
+		require.False(t, loader.PrintConfig)
+	})
+
+	t.Run("success - flags override environment, environment overrides file", func(t *testing.T) {
+		// ARRANGE
+		file := filepath.Join(t.TempDir(), "config.yaml")
+		err := os.WriteFile(file, []byte("server:\n  addr: \":9000\"\n  read_timeout: 2s\nlog:\n  level: debug\n  format: json\n"), 0644)
+		require.NoError(t, err)
+		env := map[string]string{
+			"SERVER_ADDR": ":9001",
+			"LOG_LEVEL":   "warn",
+			"DB_FILE":     "env.csv",
+		}
+		loader := config.NewLoader([]string{"-config", file, "-addr", ":9002"}, func(k string) string { return env[k] })
+
+		// ACT
+		cfg, err := loader.Load()
+
+		// ASSERT
+		require.NoError(t, err)
+		require.Equal(t, ":9002", cfg.Server.Addr)
+		require.Equal(t, 2*time.Second, cfg.Server.ReadTimeout)
+		require.Equal(t, "warn", cfg.Log.Level)
+		require.Equal(t, config.LogFormatJSON, cfg.Log.Format)
+		require.Equal(t, "env.csv", cfg.Storage.Path)
+		require.Equal(t, config.StorageBackendCSV, cfg.Storage.Backend)
+	})
+
+	t.Run("success - toml config file from the environment", func(t *testing.T) {
+		// ARRANGE
+		file := filepath.Join(t.TempDir(), "config.toml")
+		err := os.WriteFile(file, []byte("[server]\naddr = \":7000\"\n[storage]\npath = \"tickets.csv\"\n"), 0644)
+		require.NoError(t, err)
+		loader := config.NewLoader(nil, func(k string) string {
+			if k == "CONFIG_FILE" {
+				return file
+			}
+			return ""
+		})
+
+		// ACT
+		cfg, err := loader.Load()
+
+		// ASSERT
+		require.NoError(t, err)
+		require.Equal(t, ":7000", cfg.Server.Addr)
+		require.Equal(t, "tickets.csv", cfg.Storage.Path)
+	})
+
+	t.Run("success - print the effective configuration", func(t *testing.T) {
+		// ARRANGE
+		loader := config.NewLoader([]string{"-print-config", "-log-format", "json"}, func(string) string { return "" })
+		cfg, err := loader.Load()
+		require.NoError(t, err)
+		var sb strings.Builder
+
+		// ACT
+		err = cfg.Print(&sb)
+
+		// ASSERT
+		require.NoError(t, err)
+		require.True(t, loader.PrintConfig)
+		require.Contains(t, sb.String(), "format: json")
+	})
+
+	t.Run("error - invalid configuration", func(t *testing.T) {
+		// ARRANGE
+		loader := config.NewLoader([]string{"-storage-backend", "sql", "-log-level", "trace"}, func(string) string { return "" })
+
+		// ACT
+		_, err := loader.Load()
+
+		// ASSERT
+		require.ErrorIs(t, err, config.ErrInvalidConfig)
+		require.ErrorContains(t, err, `storage.backend "sql" is not csv`)
+		require.ErrorContains(t, err, `log.level "trace" is not one of debug, info, warn, error`)
+	})
+
+	t.Run("error - request timeout not shorter than write timeout", func(t *testing.T) {
+		// ARRANGE
+		env := map[string]string{
+			"REQUEST_TIMEOUT": "10s",
+			"WRITE_TIMEOUT":   "10s",
+		}
+		loader := config.NewLoader(nil, func(k string) string { return env[k] })
+
+		// ACT
+		_, err := loader.Load()
+
+		// ASSERT
+		require.ErrorIs(t, err, config.ErrInvalidConfig)
+		require.ErrorContains(t, err, "server.request_timeout must be shorter than server.write_timeout")
+	})
+
+	t.Run("error - tls files not set together", func(t *testing.T) {
+		// ARRANGE
+		loader := config.NewLoader([]string{"-tls-cert-file", "cert.pem"}, func(string) string { return "" })
+
+		// ACT
+		_, err := loader.Load()
+
+		// ASSERT
+		require.ErrorIs(t, err, config.ErrInvalidConfig)
+		require.ErrorContains(t, err, "server.tls.cert_file and server.tls.key_file must be set together")
+	})
+
+	t.Run("error - environment value not parsed", func(t *testing.T) {
+		// ARRANGE
+		loader := config.NewLoader(nil, func(k string) string {
+			if k == "READ_TIMEOUT" {
+				return "soon"
+			}
+			return ""
+		})
+
+		// ACT
+		_, err := loader.Load()
+
+		// ASSERT
+		require.ErrorIs(t, err, config.ErrInvalidConfig)
+		require.ErrorContains(t, err, "READ_TIMEOUT")
+	})
+
+	t.Run("error - config file with an unsupported extension", func(t *testing.T) {
+		// ARRANGE
+		file := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(file, []byte("{}"), 0644))
+		loader := config.NewLoader([]string{"-config", file}, func(string) string { return "" })
+
+		// ACT
+		_, err := loader.Load()
+
+		// ASSERT
+		require.ErrorIs(t, err, config.ErrConfigFile)
+	})
+}
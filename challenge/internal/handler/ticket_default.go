@@ -3,7 +3,10 @@ package handler
 import (
 	"app/internal"
 	"app/platform/web/response"
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -22,20 +25,29 @@ type HandlerTicketDefault struct {
 
 // TotalTicketsResponse represents the response of the total number of tickets
 type TotalTicketsResponse struct {
-	Total int `json:"total"`
+	Total int `json:"total" xml:"total"`
+}
+
+// MarshalCSV returns the total as a single column table
+func (t *TotalTicketsResponse) MarshalCSV() [][]string {
+	return [][]string{{"total"}, {strconv.Itoa(t.Total)}}
 }
 
 // Get returns the total amount of tickets
 func (h *HandlerTicketDefault) GetTotalAmountTickets() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// get the total amount of tickets
-		total, err := h.sv.GetTotalAmountTickets()
+		total, err := h.sv.GetTotalAmountTickets(r.Context())
 		if err != nil {
+			if isContextError(err) {
+				response.Error(w, http.StatusGatewayTimeout, "request timeout")
+				return
+			}
 			response.Error(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 		// write the response
-		response.JSON(w, http.StatusOK, "succesfully fetched total", &TotalTicketsResponse{
+		response.Negotiated(w, r, http.StatusOK, "succesfully fetched total", &TotalTicketsResponse{
 			Total: total,
 		})
 	}
@@ -47,14 +59,18 @@ func (h *HandlerTicketDefault) GetTotalAmountTicketsByDestinationCountry() http.
 		// get the destination country from the query params
 		country := chi.URLParam(r, "dest")
 		// get the total amount of tickets by destination country
-		total, err := h.sv.GetTicketsAmountByDestinationCountry(country)
+		total, err := h.sv.GetTicketsAmountByDestinationCountry(r.Context(), country)
 		if err != nil {
+			if isContextError(err) {
+				response.Error(w, http.StatusGatewayTimeout, "request timeout")
+				return
+			}
 			response.Error(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 
 		// write the response
-		response.JSON(w, http.StatusOK, "succesfully fetched total", &TotalTicketsResponse{
+		response.Negotiated(w, r, http.StatusOK, "succesfully fetched total", &TotalTicketsResponse{
 			Total: total,
 		})
 	}
@@ -62,7 +78,12 @@ func (h *HandlerTicketDefault) GetTotalAmountTicketsByDestinationCountry() http.
 
 // PercentageTicketsResponse represents the response of the percentage of tickets
 type PercentageTicketsResponse struct {
-	Percentage float64 `json:"percentage"`
+	Percentage float64 `json:"percentage" xml:"percentage"`
+}
+
+// MarshalCSV returns the percentage as a single column table
+func (p *PercentageTicketsResponse) MarshalCSV() [][]string {
+	return [][]string{{"percentage"}, {strconv.FormatFloat(p.Percentage, 'f', -1, 64)}}
 }
 
 // GetPercentageTicketsByDestinationCountry returns the percentage of tickets by destination country
@@ -71,15 +92,24 @@ func (h *HandlerTicketDefault) GetPercentageTicketsByDestinationCountry() http.H
 		// get the destination country from the query params
 		country := chi.URLParam(r, "dest")
 		// get the percentage of tickets by destination country
-		percentage, err := h.sv.GetPercentageTicketsByDestinationCountry(country)
+		percentage, err := h.sv.GetPercentageTicketsByDestinationCountry(r.Context(), country)
 		if err != nil {
+			if isContextError(err) {
+				response.Error(w, http.StatusGatewayTimeout, "request timeout")
+				return
+			}
 			response.Error(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 
 		// write the response
-		response.JSON(w, http.StatusOK, "succesfully fetched total", &PercentageTicketsResponse{
+		response.Negotiated(w, r, http.StatusOK, "succesfully fetched total", &PercentageTicketsResponse{
 			Percentage: percentage,
 		})
 	}
 }
+
+// isContextError returns true if err is due to the request being cancelled or exceeding its deadline
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
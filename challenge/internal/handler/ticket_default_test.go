@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -17,7 +18,7 @@ func TestGetTotalAmountTickets(t *testing.T) {
 		// ARRANGE
 		// create the mock of the service
 		service := new(service.ServiceTicketDefaultMock)
-		service.On("GetTotalAmountTickets").Return(10, nil)
+		service.On("GetTotalAmountTickets", mock.Anything).Return(10, nil)
 		expected := `{"message":"succesfully fetched total","data":{"total":10}}`
 		// create the handler
 		handler := NewHandlerTicketDefault(service)
@@ -32,7 +33,7 @@ func TestGetTotalAmountTickets(t *testing.T) {
 		handfunc.ServeHTTP(w, req)
 
 		// ASSERT
-		service.AssertCalled(t, "GetTotalAmountTickets")
+		service.AssertCalled(t, "GetTotalAmountTickets", mock.Anything)
 		require.Equal(t, http.StatusOK, w.Code)
 		require.JSONEq(t, expected, w.Body.String())
 	})
@@ -40,7 +41,7 @@ func TestGetTotalAmountTickets(t *testing.T) {
 		// ARRANGE
 		// create the mock of the service
 		service := new(service.ServiceTicketDefaultMock)
-		service.On("GetTotalAmountTickets").Return(0, errors.New("error getting the tickets"))
+		service.On("GetTotalAmountTickets", mock.Anything).Return(0, errors.New("error getting the tickets"))
 		expected := `{"message":"error getting the tickets", "status":"Internal Server Error"}`
 		// create the handler
 		handler := NewHandlerTicketDefault(service)
@@ -55,10 +56,32 @@ func TestGetTotalAmountTickets(t *testing.T) {
 		handfunc.ServeHTTP(w, req)
 
 		// ASSERT
-		service.AssertCalled(t, "GetTotalAmountTickets")
+		service.AssertCalled(t, "GetTotalAmountTickets", mock.Anything)
 		require.Equal(t, http.StatusInternalServerError, w.Code)
 		require.JSONEq(t, expected, w.Body.String())
 	})
+	t.Run("error - deadline exceeded", func(t *testing.T) {
+		// ARRANGE
+		// create the mock of the service
+		service := new(service.ServiceTicketDefaultMock)
+		service.On("GetTotalAmountTickets", mock.Anything).Return(0, context.DeadlineExceeded)
+		expected := `{"message":"request timeout", "status":"Gateway Timeout"}`
+		// create the handler
+		handler := NewHandlerTicketDefault(service)
+		// create the request
+		req := httptest.NewRequest("GET", "/tickets/total", nil)
+		// create the response
+		w := httptest.NewRecorder()
+		// create the function
+		handfunc := http.HandlerFunc(handler.GetTotalAmountTickets())
+
+		// ACT
+		handfunc.ServeHTTP(w, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusGatewayTimeout, w.Code)
+		require.JSONEq(t, expected, w.Body.String())
+	})
 }
 
 func TestGetTicketsAmountByDestinationCountry(t *testing.T) {
@@ -66,7 +89,7 @@ func TestGetTicketsAmountByDestinationCountry(t *testing.T) {
 		// ARRANGE
 		// create the mock of the service
 		service := new(service.ServiceTicketDefaultMock)
-		service.On("GetTicketsAmountByDestinationCountry", "argentina").Return(10, nil)
+		service.On("GetTicketsAmountByDestinationCountry", mock.Anything, "argentina").Return(10, nil)
 		expected := `{"message":"succesfully fetched total","data":{"total":10}}`
 		// create the handler
 		handler := NewHandlerTicketDefault(service)
@@ -85,7 +108,7 @@ func TestGetTicketsAmountByDestinationCountry(t *testing.T) {
 		handfunc.ServeHTTP(w, req)
 
 		// ASSERT
-		service.AssertCalled(t, "GetTicketsAmountByDestinationCountry", "argentina")
+		service.AssertCalled(t, "GetTicketsAmountByDestinationCountry", mock.Anything, "argentina")
 		require.Equal(t, http.StatusOK, w.Code)
 		require.JSONEq(t, expected, w.Body.String())
 	})
@@ -94,7 +117,7 @@ func TestGetTicketsAmountByDestinationCountry(t *testing.T) {
 		// ARRANGE
 		// create the mock of the service
 		service := new(service.ServiceTicketDefaultMock)
-		service.On("GetTicketsAmountByDestinationCountry", "argentina").Return(0, errors.New("error getting the tickets"))
+		service.On("GetTicketsAmountByDestinationCountry", mock.Anything, "argentina").Return(0, errors.New("error getting the tickets"))
 		expected := `{"message":"error getting the tickets", "status":"Internal Server Error"}`
 		// create the handler
 		handler := NewHandlerTicketDefault(service)
@@ -113,7 +136,7 @@ func TestGetTicketsAmountByDestinationCountry(t *testing.T) {
 		handfunc.ServeHTTP(w, req)
 
 		// ASSERT
-		service.AssertCalled(t, "GetTicketsAmountByDestinationCountry", "argentina")
+		service.AssertCalled(t, "GetTicketsAmountByDestinationCountry", mock.Anything, "argentina")
 		require.Equal(t, http.StatusInternalServerError, w.Code)
 		require.JSONEq(t, expected, w.Body.String())
 	})
@@ -124,7 +147,7 @@ func TestGetPercentageTicketsByDestinationCountry(t *testing.T) {
 		// ARRANGE
 		// create the mock of the service
 		service := new(service.ServiceTicketDefaultMock)
-		service.On("GetPercentageTicketsByDestinationCountry", "argentina").Return(0.5, nil)
+		service.On("GetPercentageTicketsByDestinationCountry", mock.Anything, "argentina").Return(0.5, nil)
 		expected := `{"message":"succesfully fetched total","data":{"percentage":0.5}}`
 		// create the handler
 		handler := NewHandlerTicketDefault(service)
@@ -143,7 +166,7 @@ func TestGetPercentageTicketsByDestinationCountry(t *testing.T) {
 		handfunc.ServeHTTP(w, req)
 
 		// ASSERT
-		service.AssertCalled(t, "GetPercentageTicketsByDestinationCountry", "argentina")
+		service.AssertCalled(t, "GetPercentageTicketsByDestinationCountry", mock.Anything, "argentina")
 		require.Equal(t, http.StatusOK, w.Code)
 		require.JSONEq(t, expected, w.Body.String())
 	})
@@ -151,7 +174,7 @@ func TestGetPercentageTicketsByDestinationCountry(t *testing.T) {
 		// ARRANGE
 		// create the mock of the service
 		service := new(service.ServiceTicketDefaultMock)
-		service.On("GetPercentageTicketsByDestinationCountry", "argentina").Return(0, errors.New("error getting the tickets"))
+		service.On("GetPercentageTicketsByDestinationCountry", mock.Anything, "argentina").Return(0, errors.New("error getting the tickets"))
 		expected := `{"message":"error getting the tickets", "status":"Internal Server Error"}`
 		// create the handler
 		handler := NewHandlerTicketDefault(service)
@@ -170,8 +193,65 @@ func TestGetPercentageTicketsByDestinationCountry(t *testing.T) {
 		handfunc.ServeHTTP(w, req)
 
 		// ASSERT
-		service.AssertCalled(t, "GetPercentageTicketsByDestinationCountry", "argentina")
+		service.AssertCalled(t, "GetPercentageTicketsByDestinationCountry", mock.Anything, "argentina")
 		require.Equal(t, http.StatusInternalServerError, w.Code)
 		require.JSONEq(t, expected, w.Body.String())
 	})
 }
+
+func TestGetTotalAmountTicketsNegotiation(t *testing.T) {
+	t.Run("success - csv", func(t *testing.T) {
+		// ARRANGE
+		service := new(service.ServiceTicketDefaultMock)
+		service.On("GetTotalAmountTickets", mock.Anything).Return(5, nil)
+		handler := NewHandlerTicketDefault(service)
+		req := httptest.NewRequest("GET", "/tickets/total", nil)
+		req.Header.Set("Accept", "text/csv")
+		w := httptest.NewRecorder()
+		handfunc := http.HandlerFunc(handler.GetTotalAmountTickets())
+
+		// ACT
+		handfunc.ServeHTTP(w, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "total\n5\n", w.Body.String())
+	})
+	t.Run("success - xml", func(t *testing.T) {
+		// ARRANGE
+		service := new(service.ServiceTicketDefaultMock)
+		service.On("GetTotalAmountTickets", mock.Anything).Return(5, nil)
+		expected := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+			`<response><message>succesfully fetched total</message><data><total>5</total></data></response>`
+		handler := NewHandlerTicketDefault(service)
+		req := httptest.NewRequest("GET", "/tickets/total", nil)
+		req.Header.Set("Accept", "application/xml;q=0.9, application/json;q=0.5")
+		w := httptest.NewRecorder()
+		handfunc := http.HandlerFunc(handler.GetTotalAmountTickets())
+
+		// ACT
+		handfunc.ServeHTTP(w, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, expected, w.Body.String())
+	})
+	t.Run("error - not acceptable", func(t *testing.T) {
+		// ARRANGE
+		service := new(service.ServiceTicketDefaultMock)
+		service.On("GetTotalAmountTickets", mock.Anything).Return(5, nil)
+		expected := `{"message":"not acceptable", "status":"Not Acceptable"}`
+		handler := NewHandlerTicketDefault(service)
+		req := httptest.NewRequest("GET", "/tickets/total", nil)
+		req.Header.Set("Accept", "image/png")
+		w := httptest.NewRecorder()
+		handfunc := http.HandlerFunc(handler.GetTotalAmountTickets())
+
+		// ACT
+		handfunc.ServeHTTP(w, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusNotAcceptable, w.Code)
+		require.JSONEq(t, expected, w.Body.String())
+	})
+}
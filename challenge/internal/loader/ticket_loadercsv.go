@@ -2,6 +2,7 @@ package loader
 
 import (
 	"app/internal"
+	"context"
 	"encoding/csv"
 	"fmt"
 	"io"
@@ -21,8 +22,8 @@ type LoaderTicketCSV struct {
 	filePath string
 }
 
-// Load loads the tickets from the CSV file
-func (t *LoaderTicketCSV) Load() (map[int]internal.TicketAttributes, error) {
+// Load loads the tickets from the CSV file, stopping early when ctx is done
+func (t *LoaderTicketCSV) Load(ctx context.Context) (map[int]internal.TicketAttributes, error) {
 	// open the file
 	f, err := os.Open(t.filePath)
 	if err != nil {
@@ -36,6 +37,11 @@ func (t *LoaderTicketCSV) Load() (map[int]internal.TicketAttributes, error) {
 	// read the records
 	ticketAttr := make(map[int]internal.TicketAttributes)
 	for {
+		// stop reading once the client is gone or the deadline is exceeded
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		record, err := r.Read()
 		if err != nil {
 			if err == io.EOF {
@@ -68,3 +74,9 @@ func (t *LoaderTicketCSV) Load() (map[int]internal.TicketAttributes, error) {
 
 	return ticketAttr, nil
 }
+
+// HealthCheck checks that the CSV file can be read and parsed
+func (t *LoaderTicketCSV) HealthCheck(ctx context.Context) error {
+	_, err := t.Load(ctx)
+	return err
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"app/internal"
+	"context"
 	"errors"
 )
 
@@ -29,10 +30,13 @@ type RepositoryTicketMap struct {
 }
 
 // Load loads the tickets from the loader
-func (r *RepositoryTicketMap) Load() error {
+func (r *RepositoryTicketMap) Load(ctx context.Context) error {
 	// load the tickets from the loader
-	t, err := r.loader.Load()
+	t, err := r.loader.Load(ctx)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 		return errors.New("error loading the tickets")
 	}
 
@@ -52,10 +56,13 @@ func (r *RepositoryTicketMap) Load() error {
 }
 
 // GetAll returns all the tickets
-func (r *RepositoryTicketMap) Get() (map[int]internal.TicketAttributes, error) {
+func (r *RepositoryTicketMap) Get(ctx context.Context) (map[int]internal.TicketAttributes, error) {
 	// load the tickets from the loader
-	err := r.Load()
+	err := r.Load(ctx)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return nil, errors.New("error loading the tickets")
 	}
 	// create a copy of the map
@@ -68,10 +75,13 @@ func (r *RepositoryTicketMap) Get() (map[int]internal.TicketAttributes, error) {
 }
 
 // GetTicketsByDestinationCountry returns the tickets filtered by destination country
-func (r *RepositoryTicketMap) GetTicketsByDestinationCountry(country string) (map[int]internal.TicketAttributes, error) {
+func (r *RepositoryTicketMap) GetTicketsByDestinationCountry(ctx context.Context, country string) (map[int]internal.TicketAttributes, error) {
 	// load the tickets from the loader
-	err := r.Load()
+	err := r.Load(ctx)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return nil, errors.New("error loading the tickets")
 	}
 	// create a copy of the map
@@ -2,6 +2,7 @@ package service
 
 import (
 	"app/internal"
+	"context"
 	"errors"
 )
 
@@ -19,10 +20,13 @@ func NewServiceTicketDefault(rp internal.RepositoryTicket) *ServiceTicketDefault
 }
 
 // GetTotalTickets returns the total number of tickets
-func (s *ServiceTicketDefault) GetTotalAmountTickets() (total int, err error) {
+func (s *ServiceTicketDefault) GetTotalAmountTickets(ctx context.Context) (total int, err error) {
 	// get all the tickets
-	t, err := s.rp.Get()
+	t, err := s.rp.Get(ctx)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return 0, ctxErr
+		}
 		return 0, errors.New("error getting the tickets")
 	}
 
@@ -31,10 +35,13 @@ func (s *ServiceTicketDefault) GetTotalAmountTickets() (total int, err error) {
 }
 
 // GetTotalAmountTicketsByDestinationCountry returns the total amount of tickets by destination country
-func (s *ServiceTicketDefault) GetTicketsAmountByDestinationCountry(country string) (total int, err error) {
+func (s *ServiceTicketDefault) GetTicketsAmountByDestinationCountry(ctx context.Context, country string) (total int, err error) {
 	// get all the tickets
-	t, err := s.rp.GetTicketsByDestinationCountry(country)
+	t, err := s.rp.GetTicketsByDestinationCountry(ctx, country)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return 0, ctxErr
+		}
 		return 0, errors.New("error getting the tickets")
 	}
 
@@ -42,16 +49,22 @@ func (s *ServiceTicketDefault) GetTicketsAmountByDestinationCountry(country stri
 	return len(t), nil
 }
 
-func (s *ServiceTicketDefault) GetPercentageTicketsByDestinationCountry(country string) (percentage float64, err error) {
+func (s *ServiceTicketDefault) GetPercentageTicketsByDestinationCountry(ctx context.Context, country string) (percentage float64, err error) {
 	// get all the tickets
-	t, err := s.rp.Get()
+	t, err := s.rp.Get(ctx)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return 0, ctxErr
+		}
 		return 0, errors.New("error getting the tickets")
 	}
 
 	// get tickets by destination country
-	tDest, err := s.rp.GetTicketsByDestinationCountry(country)
+	tDest, err := s.rp.GetTicketsByDestinationCountry(ctx, country)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return 0, ctxErr
+		}
 		return 0, errors.New("error getting the tickets")
 	}
 
@@ -1,23 +1,27 @@
 package service
 
-import "github.com/stretchr/testify/mock"
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
 
 type ServiceTicketDefaultMock struct {
 	mock.Mock
 }
 
-func (m *ServiceTicketDefaultMock) GetTotalAmountTickets() (total int, err error) {
-	args := m.Called()
+func (m *ServiceTicketDefaultMock) GetTotalAmountTickets(ctx context.Context) (total int, err error) {
+	args := m.Called(ctx)
 	return args.Int(0), args.Error(1)
 }
 
-func (m *ServiceTicketDefaultMock) GetTicketsAmountByDestinationCountry(country string) (total int, err error) {
-	args := m.Called(country)
+func (m *ServiceTicketDefaultMock) GetTicketsAmountByDestinationCountry(ctx context.Context, country string) (total int, err error) {
+	args := m.Called(ctx, country)
 	return args.Int(0), args.Error(1)
 }
 
-func (m *ServiceTicketDefaultMock) GetPercentageTicketsByDestinationCountry(country string) (percentage float64, err error) {
-	args := m.Called(country)
+func (m *ServiceTicketDefaultMock) GetPercentageTicketsByDestinationCountry(ctx context.Context, country string) (percentage float64, err error) {
+	args := m.Called(ctx, country)
 	if arg, ok := args.Get(0).(float64); ok {
 		percentage = arg
 	}
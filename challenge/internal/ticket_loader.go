@@ -1,5 +1,8 @@
 package internal
 
+import "context"
+
 type TicketLoader interface {
-	Load() (t map[int]TicketAttributes, err error)
+	// Load returns the tickets, stopping early when ctx is done
+	Load(ctx context.Context) (t map[int]TicketAttributes, err error)
 }
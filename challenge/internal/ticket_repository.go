@@ -1,10 +1,12 @@
 package internal
 
+import "context"
+
 // RepositoryTicket represents the repository interface for tickets
 type RepositoryTicket interface {
 	// GetAll returns all the tickets
-	Get() (t map[int]TicketAttributes, err error)
+	Get(ctx context.Context) (t map[int]TicketAttributes, err error)
 
 	// GetTicketByDestinationCountry returns the tickets filtered by destination country
-	GetTicketsByDestinationCountry(country string) (t map[int]TicketAttributes, err error)
+	GetTicketsByDestinationCountry(ctx context.Context, country string) (t map[int]TicketAttributes, err error)
 }
@@ -1,12 +1,14 @@
 package internal
 
+import "context"
+
 type ServiceTicket interface {
 	// GetTotalAmountTickets returns the total amount of tickets
-	GetTotalAmountTickets() (total int, err error)
+	GetTotalAmountTickets(ctx context.Context) (total int, err error)
 
 	// GetTicketsAmountByDestinationCountry returns the amount of tickets filtered by destination country
-	GetTicketsAmountByDestinationCountry(country string) (total int, err error)
+	GetTicketsAmountByDestinationCountry(ctx context.Context, country string) (total int, err error)
 
 	// GetPercentageTicketsByDestinationCountry returns the percentage of tickets filtered by destination country
-	GetPercentageTicketsByDestinationCountry(country string) (percentage float64, err error)
+	GetPercentageTicketsByDestinationCountry(ctx context.Context, country string) (percentage float64, err error)
 }
@@ -0,0 +1,90 @@
+package health_test
+
+import (
+	"app/platform/health"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ok is a check that passes
+func ok(ctx context.Context) error { return nil }
+
+// failing is a check that fails
+func failing(ctx context.Context) error { return errors.New("unreachable") }
+
+// TestRegistry tests the aggregation of the checks and the readiness handler.
+func TestRegistry(t *testing.T) {
+	t.Run("success - every check passed", func(t *testing.T) {
+		// ARRANGE
+		reg := health.NewRegistry(0)
+		reg.Register("db", true, ok)
+
+		// ACT
+		report := reg.Check(context.Background())
+
+		// ASSERT
+		require.Equal(t, health.StatusOK, report.Status)
+		require.Equal(t, health.StatusOK, report.Checks["db"].Status)
+	})
+
+	t.Run("success - a failing non-critical check degrades the service", func(t *testing.T) {
+		// ARRANGE
+		reg := health.NewRegistry(0)
+		reg.Register("db", true, ok)
+		reg.Register("cache", false, failing)
+
+		// ACT
+		report := reg.Check(context.Background())
+
+		// ASSERT
+		require.Equal(t, health.StatusDegraded, report.Status)
+		require.Equal(t, "unreachable", report.Checks["cache"].Error)
+	})
+
+	t.Run("failure - a failing critical check or the shutdown fails the readiness", func(t *testing.T) {
+		// ARRANGE
+		critical := health.NewRegistry(0)
+		critical.Register("db", true, failing)
+		shutdown := health.NewRegistry(0)
+		shutdown.Register("db", true, ok)
+		shutdown.SetShuttingDown()
+		get := func(reg *health.Registry) (*httptest.ResponseRecorder, health.Report) {
+			rr := httptest.NewRecorder()
+			reg.ReadinessHandler(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			var report health.Report
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+			return rr, report
+		}
+
+		// ACT
+		rrCritical, reportCritical := get(critical)
+		rrShutdown, reportShutdown := get(shutdown)
+
+		// ASSERT
+		require.Equal(t, http.StatusServiceUnavailable, rrCritical.Code)
+		require.Equal(t, "application/json", rrCritical.Header().Get("Content-Type"))
+		require.Equal(t, health.StatusFailing, reportCritical.Status)
+		require.Equal(t, http.StatusServiceUnavailable, rrShutdown.Code)
+		require.Equal(t, health.StatusFailing, reportShutdown.Checks["shutdown"].Status)
+	})
+
+	t.Run("success - the liveness runs no check", func(t *testing.T) {
+		// ARRANGE
+		reg := health.NewRegistry(0)
+		reg.Register("db", true, failing)
+		rr := httptest.NewRecorder()
+
+		// ACT
+		reg.LivenessHandler(rr, httptest.NewRequest(http.MethodGet, "/livez", nil))
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, `{"status":"ok"}`, rr.Body.String())
+	})
+}
@@ -0,0 +1,52 @@
+// Package docs serves an OpenAPI document and the Swagger UI rendering it.
+package docs
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// SpecHandler serves the OpenAPI document spec.
+func SpecHandler(spec []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(spec)
+	}
+}
+
+// UIHandler serves the bundled Swagger UI under prefix, rendering the document of specURL.
+// Requests to the prefix without its trailing slash are redirected to it.
+func UIHandler(prefix, specURL string) http.Handler {
+	prefix = strings.TrimSuffix(prefix, "/") + "/"
+	initializer := fmt.Sprintf(`window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: %q,
+    dom_id: '#swagger-ui',
+    deepLinking: true,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+    plugins: [SwaggerUIBundle.plugins.DownloadUrl],
+    layout: "StandaloneLayout"
+  });
+};
+`, specURL)
+	files := http.StripPrefix(prefix, http.FileServer(http.FS(swaggerFiles.FS)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == strings.TrimSuffix(prefix, "/") {
+			http.Redirect(w, r, prefix, http.StatusMovedPermanently)
+			return
+		}
+		switch strings.TrimPrefix(r.URL.Path, prefix) {
+		case "swagger-initializer.js":
+			// the bundled initializer renders the petstore example
+			w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+			w.Write([]byte(initializer))
+		default:
+			files.ServeHTTP(w, r)
+		}
+	})
+}
@@ -0,0 +1,215 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	// ErrServerStart is returned when the server can not start listening.
+	ErrServerStart = errors.New("lifecycle: server failed to start")
+	// ErrServerShutdown is returned when the server can not drain in-flight requests in time.
+	ErrServerShutdown = errors.New("lifecycle: server failed to shutdown gracefully")
+)
+
+// Config represents the configuration of the lifecycle of an http server
+type Config struct {
+	// Addr is the address the server listens on
+	Addr string
+	// ReadTimeout is the maximum duration for reading the entire request
+	ReadTimeout time.Duration
+	// ReadHeaderTimeout is the maximum duration for reading the request headers
+	ReadHeaderTimeout time.Duration
+	// WriteTimeout is the maximum duration before timing out writes of the response
+	WriteTimeout time.Duration
+	// IdleTimeout is the maximum duration to wait for the next request on keep-alive connections
+	IdleTimeout time.Duration
+	// ShutdownTimeout is the maximum duration to drain in-flight requests on shutdown
+	ShutdownTimeout time.Duration
+	// ShutdownDelay is the time waited, once the shutdown starts and before draining, for load balancers
+	// to notice the failing readiness and stop routing new requests
+	ShutdownDelay time.Duration
+	// Signals are the os signals that trigger a graceful shutdown
+	Signals []os.Signal
+	// CertFile and KeyFile enable TLS when both are set
+	CertFile string
+	KeyFile  string
+}
+
+// Hook is a function executed during the shutdown of the server
+type Hook func(ctx context.Context) error
+
+// NewServer creates a new Server wrapping an http.Server built from the config
+func NewServer(cfg Config, handler http.Handler) *Server {
+	// default values
+	if cfg.ReadTimeout == 0 {
+		cfg.ReadTimeout = 5 * time.Second
+	}
+	if cfg.ReadHeaderTimeout == 0 {
+		cfg.ReadHeaderTimeout = cfg.ReadTimeout
+	}
+	if cfg.WriteTimeout == 0 {
+		cfg.WriteTimeout = 10 * time.Second
+	}
+	if cfg.IdleTimeout == 0 {
+		cfg.IdleTimeout = 60 * time.Second
+	}
+	if cfg.ShutdownTimeout == 0 {
+		cfg.ShutdownTimeout = 15 * time.Second
+	}
+	if len(cfg.Signals) == 0 {
+		cfg.Signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	return &Server{
+		srv: &http.Server{
+			Addr:              cfg.Addr,
+			Handler:           handler,
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+		},
+		shutdownTimeout: cfg.ShutdownTimeout,
+		shutdownDelay:   cfg.ShutdownDelay,
+		signals:         cfg.Signals,
+		certFile:        cfg.CertFile,
+		keyFile:         cfg.KeyFile,
+	}
+}
+
+// Server handles the lifecycle of an http server: start, drain and shutdown hooks
+type Server struct {
+	// srv is the underlying http server
+	srv *http.Server
+	// shutdownTimeout is the maximum duration of the shutdown
+	shutdownTimeout time.Duration
+	// shutdownDelay is the time waited before draining
+	shutdownDelay time.Duration
+	// signals are the os signals that trigger the shutdown
+	signals []os.Signal
+	// certFile and keyFile enable TLS when both are set
+	certFile string
+	keyFile  string
+
+	// mu guards the hooks and addr
+	mu sync.Mutex
+	// beforeHooks are executed in order when the shutdown starts, before draining
+	beforeHooks []Hook
+	// hooks are executed in order once in-flight requests are drained
+	hooks []Hook
+	// addr is the address the server is listening on, once started
+	addr net.Addr
+}
+
+// BeforeShutdown registers a hook executed when the shutdown starts, before draining, e.g. to fail the readiness
+func (s *Server) BeforeShutdown(hook Hook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.beforeHooks = append(s.beforeHooks, hook)
+}
+
+// OnShutdown registers a hook executed after in-flight requests are drained, e.g. to flush storage
+func (s *Server) OnShutdown(hook Hook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, hook)
+}
+
+// Addr returns the address the server is listening on, or nil if it is not started
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addr
+}
+
+// Run starts the server and blocks until it fails or a shutdown signal is received.
+// Startup errors are returned immediately; on signal the server is drained and the hooks are run.
+func (s *Server) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), s.signals...)
+	defer stop()
+
+	return s.RunContext(ctx)
+}
+
+// RunContext starts the server and blocks until it fails or ctx is done, then shuts it down gracefully
+func (s *Server) RunContext(ctx context.Context) error {
+	// listen first so that errors like an address in use are reported to the caller
+	ln, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrServerStart, err)
+	}
+	s.mu.Lock()
+	s.addr = ln.Addr()
+	s.mu.Unlock()
+
+	// serve
+	errCh := make(chan error, 1)
+	go func() {
+		if s.certFile != "" && s.keyFile != "" {
+			errCh <- s.srv.ServeTLS(ln, s.certFile, s.keyFile)
+			return
+		}
+		errCh <- s.srv.Serve(ln)
+	}()
+
+	// wait for a failure or the shutdown signal
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("%w: %v", ErrServerStart, err)
+	case <-ctx.Done():
+	}
+
+	return s.Shutdown()
+}
+
+// Shutdown runs the before hooks, waits the shutdown delay, then drains in-flight requests
+// and runs the shutdown hooks within the shutdown timeout
+func (s *Server) Shutdown() error {
+	s.mu.Lock()
+	beforeHooks := make([]Hook, len(s.beforeHooks))
+	copy(beforeHooks, s.beforeHooks)
+	hooks := make([]Hook, len(s.hooks))
+	copy(hooks, s.hooks)
+	s.mu.Unlock()
+
+	var errs []error
+	// - before hooks
+	for _, hook := range beforeHooks {
+		if err := hook(context.Background()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	// - delay, the server keeps serving in the meantime
+	if s.shutdownDelay > 0 {
+		time.Sleep(s.shutdownDelay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	// - drain
+	if err := s.srv.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("%w: %v", ErrServerShutdown, err))
+	}
+
+	// - hooks
+	for _, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,90 @@
+package lifecycle_test
+
+import (
+	"app/platform/web/lifecycle"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestServer tests the start, the drain and the shutdown hooks of the Server.
+func TestServer(t *testing.T) {
+	t.Run("success - in-flight requests drained, then the hooks run in order", func(t *testing.T) {
+		// ARRANGE
+		started := make(chan struct{})
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			io.WriteString(w, "done")
+		})
+		srv := lifecycle.NewServer(lifecycle.Config{Addr: "127.0.0.1:0"}, handler)
+		var calls []string
+		srv.BeforeShutdown(func(ctx context.Context) error {
+			calls = append(calls, "before")
+			return nil
+		})
+		srv.OnShutdown(func(ctx context.Context) error {
+			calls = append(calls, "after")
+			return nil
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- srv.RunContext(ctx) }()
+		require.Eventually(t, func() bool { return srv.Addr() != nil }, time.Second, 10*time.Millisecond)
+		body := make(chan string)
+		go func() {
+			res, err := http.Get("http://" + srv.Addr().String())
+			if err != nil {
+				body <- err.Error()
+				return
+			}
+			defer res.Body.Close()
+			b, _ := io.ReadAll(res.Body)
+			body <- string(b)
+		}()
+		<-started
+
+		// ACT
+		cancel()
+
+		// ASSERT
+		require.Equal(t, "done", <-body)
+		require.NoError(t, <-done)
+		require.Equal(t, []string{"before", "after"}, calls)
+	})
+
+	t.Run("failure - hook errors joined", func(t *testing.T) {
+		// ARRANGE
+		srv := lifecycle.NewServer(lifecycle.Config{Addr: "127.0.0.1:0"}, http.NotFoundHandler())
+		errFlush := errors.New("flush failed")
+		srv.OnShutdown(func(ctx context.Context) error { return errFlush })
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// ACT
+		err := srv.RunContext(ctx)
+
+		// ASSERT
+		require.ErrorIs(t, err, errFlush)
+	})
+
+	t.Run("error - address in use", func(t *testing.T) {
+		// ARRANGE
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer ln.Close()
+		srv := lifecycle.NewServer(lifecycle.Config{Addr: ln.Addr().String()}, http.NotFoundHandler())
+
+		// ACT
+		err = srv.RunContext(context.Background())
+
+		// ASSERT
+		require.ErrorIs(t, err, lifecycle.ErrServerStart)
+	})
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// NewDeadline creates a new deadline middleware. A timeout of 0 leaves the requests without deadline.
+func NewDeadline(timeout time.Duration) *Deadline {
+	return &Deadline{
+		timeout: timeout,
+	}
+}
+
+// Deadline bounds the time spent handling a request.
+type Deadline struct {
+	// timeout is the maximum duration of a request
+	timeout time.Duration
+}
+
+// Timeout sets a deadline on the context of the request, so the service, repository and loader
+// stop working once the client is gone or the deadline is exceeded.
+func (d *Deadline) Timeout(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.timeout <= 0 {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), d.timeout)
+		defer cancel()
+
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
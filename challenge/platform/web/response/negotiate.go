@@ -0,0 +1,115 @@
+package response
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MediaTypeJSON is the default media type of the responses
+	MediaTypeJSON = "application/json"
+	// MediaTypeXML renders the response envelope as xml
+	MediaTypeXML = "application/xml"
+	// MediaTypeNDJSON renders the data as a json document on a single line
+	MediaTypeNDJSON = "application/x-ndjson"
+	// MediaTypeCSV renders the data as a table
+	MediaTypeCSV = "text/csv"
+)
+
+// CSVMarshaler is implemented by the data of the negotiated responses.
+type CSVMarshaler interface {
+	// MarshalCSV returns the records of the table, the first one being the header
+	MarshalCSV() [][]string
+}
+
+// Negotiated writes the response in the media type preferred by the Accept header of the client among json,
+// xml, ndjson and csv, json without Accept header. It responds 406 Not Acceptable if none is accepted.
+func Negotiated(w http.ResponseWriter, r *http.Request, statusCode int, message string, data CSVMarshaler) {
+	// responses depend on the Accept header
+	w.Header().Add("Vary", "Accept")
+
+	switch negotiate(r.Header.Get("Accept"), MediaTypeJSON, MediaTypeXML, MediaTypeNDJSON, MediaTypeCSV) {
+	case MediaTypeJSON:
+		JSON(w, statusCode, message, data)
+	case MediaTypeXML:
+		w.Header().Set("Content-Type", MediaTypeXML+"; charset=utf-8")
+		w.WriteHeader(statusCode)
+		w.Write([]byte(xml.Header))
+		xml.NewEncoder(w).Encode(struct {
+			XMLName xml.Name     `xml:"response"`
+			Message string       `xml:"message"`
+			Data    CSVMarshaler `xml:"data"`
+		}{Message: message, Data: data})
+	case MediaTypeNDJSON:
+		w.Header().Set("Content-Type", MediaTypeNDJSON)
+		w.WriteHeader(statusCode)
+		json.NewEncoder(w).Encode(data)
+	case MediaTypeCSV:
+		w.Header().Set("Content-Type", MediaTypeCSV+"; charset=utf-8; header=present")
+		w.WriteHeader(statusCode)
+		csv.NewWriter(w).WriteAll(data.MarshalCSV())
+	default:
+		Error(w, http.StatusNotAcceptable, "not acceptable")
+	}
+}
+
+// negotiate returns the offer with the highest quality in accept, the most specific range of an offer setting
+// its quality and the first offer winning ties. An empty accept accepts the first offer, and none accepted
+// returns "".
+func negotiate(accept string, offers ...string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, specificity := 0.0, -1
+		for _, part := range strings.Split(accept, ",") {
+			mediaRange, rangeQ := parseMediaRange(part)
+			if s := matchMediaRange(mediaRange, offer); s > specificity {
+				q, specificity = rangeQ, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// parseMediaRange splits an element of the Accept header into its media range and q parameter
+func parseMediaRange(part string) (value string, q float64) {
+	q = 1
+	params := strings.Split(part, ";")
+	value = strings.ToLower(strings.TrimSpace(params[0]))
+	for _, param := range params[1:] {
+		k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || strings.ToLower(strings.TrimSpace(k)) != "q" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			return value, 0
+		}
+		q = parsed
+	}
+	return value, q
+}
+
+// matchMediaRange returns how specifically mediaRange matches mediaType: 2 exactly, 1 by type/*, 0 by */*,
+// and -1 if it does not match
+func matchMediaRange(mediaRange, mediaType string) int {
+	switch {
+	case mediaRange == mediaType:
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+		return 1
+	}
+	return -1
+}
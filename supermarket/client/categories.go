@@ -0,0 +1,34 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Category is a category of the products, under the category of ParentID, nil for the root categories
+type Category struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	ParentID *int   `json:"parent_id"`
+}
+
+// GetProductCategories returns the categories of the product of id, sorted by id.
+func (c *Client) GetProductCategories(ctx context.Context, id int) (categories []Category, err error) {
+	err = c.do(ctx, call{method: http.MethodGet, path: productCategoriesPath(id), idempotent: true}, &categories)
+	return
+}
+
+// SetProductCategories replaces the categories of the product of id with the categories of categoryIDs,
+// returning them.
+func (c *Client) SetProductCategories(ctx context.Context, id int, categoryIDs ...int) (categories []Category, err error) {
+	body := struct {
+		Categories []int `json:"categories"`
+	}{append([]int{}, categoryIDs...)}
+	err = c.do(ctx, call{method: http.MethodPut, path: productCategoriesPath(id), body: body, idempotent: true}, &categories)
+	return
+}
+
+// productCategoriesPath returns the path of the categories of the product of id
+func productCategoriesPath(id int) string {
+	return productPath(id) + "/categories"
+}
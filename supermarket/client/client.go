@@ -0,0 +1,261 @@
+// Package client is a typed client of the supermarket api.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	mathrand "math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithToken authenticates the write requests with token, sent in the Token header.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithHTTPClient sends the requests with httpClient instead of http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient != nil {
+			c.httpClient = httpClient
+		}
+	}
+}
+
+// WithRetry retries the idempotent calls up to maxRetries times, waiting an exponential backoff starting at
+// minBackoff and capped at maxBackoff between attempts. 0 retries disables the retries.
+func WithRetry(maxRetries int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		if maxRetries >= 0 {
+			c.maxRetries = maxRetries
+		}
+		if minBackoff > 0 {
+			c.minBackoff = minBackoff
+		}
+		if maxBackoff >= c.minBackoff {
+			c.maxBackoff = maxBackoff
+		}
+	}
+}
+
+// New creates a Client of the api at baseURL, e.g. "http://localhost:8080".
+// By default the idempotent calls are retried 3 times, from 100ms up to 2s apart.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("client: invalid base url %q: scheme must be http or https", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		maxRetries: 3,
+		minBackoff: 100 * time.Millisecond,
+		maxBackoff: 2 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Client calls the supermarket api. It is safe for concurrent use.
+type Client struct {
+	// baseURL is the url the paths of the api are relative to
+	baseURL *url.URL
+	// httpClient sends the requests
+	httpClient *http.Client
+	// token is sent in the Token header, none if empty
+	token string
+	// maxRetries is the number of retries of the idempotent calls
+	maxRetries int
+	// minBackoff and maxBackoff bound the wait between attempts
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// call is a request to the api
+type call struct {
+	method string
+	path   string
+	query  url.Values
+	// body is encoded as json, none if nil
+	body any
+	// idempotent calls are retried
+	idempotent bool
+	// header is added to the request
+	header http.Header
+}
+
+// envelope is the body of the successful json responses
+type envelope struct {
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// do sends the call, retrying it if idempotent, and decodes the data of the response to out, if not nil.
+func (c *Client) do(ctx context.Context, cl call, out any) error {
+	var body []byte
+	if cl.body != nil {
+		var err error
+		if body, err = json.Marshal(cl.body); err != nil {
+			return fmt.Errorf("client: encode body: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		res, err := c.send(ctx, cl, body)
+		retry := cl.idempotent && attempt < c.maxRetries && retryable(ctx, res, err)
+		if !retry {
+			if err != nil {
+				return err
+			}
+			return decode(res, out)
+		}
+
+		wait := c.backoff(attempt)
+		if res != nil {
+			if after, ok := retryAfter(res); ok && after > wait {
+				wait = min(after, c.maxBackoff)
+			}
+			// drain so the connection is reused
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// send sends a single attempt of the call
+func (c *Client) send(ctx context.Context, cl call, body []byte) (*http.Response, error) {
+	u := c.baseURL.JoinPath(cl.path)
+	u.RawQuery = cl.query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, cl.method, u.String(), reader)
+	if err != nil {
+		return nil, fmt.Errorf("client: new request: %w", err)
+	}
+	for k, v := range cl.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Token", c.token)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("client: %s %s: %w", cl.method, cl.path, err)
+	}
+	return res, nil
+}
+
+// decode decodes the data of a successful response to out, or returns the error of the response
+func decode(res *http.Response, out any) error {
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("client: read response: %w", err)
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return newAPIError(res, data)
+	}
+	if out == nil {
+		return nil
+	}
+	if s, ok := out.(*string); ok && !strings.HasPrefix(res.Header.Get("Content-Type"), "application/json") {
+		*s = string(data)
+		return nil
+	}
+
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return fmt.Errorf("client: decode response: %w", err)
+	}
+	if err := json.Unmarshal(env.Data, out); err != nil {
+		return fmt.Errorf("client: decode response data: %w", err)
+	}
+	return nil
+}
+
+// retryable tells if an attempt failed in a way that a retry may succeed: a network error, a 429 Too Many
+// Requests or a 502, 503 or 504. Nothing is retried once ctx is done.
+func retryable(ctx context.Context, res *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns the wait before the retry of attempt: an exponential backoff with jitter
+func (c *Client) backoff(attempt int) time.Duration {
+	limit := float64(c.minBackoff) * math.Pow(2, float64(attempt))
+	if limit > float64(c.maxBackoff) {
+		limit = float64(c.maxBackoff)
+	}
+	return c.minBackoff + time.Duration(mathrand.Int63n(int64(limit-float64(c.minBackoff))+1))
+}
+
+// retryAfter returns the wait of the Retry-After header of res, in seconds
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	seconds, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// sleep waits d, or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// newIdempotencyKey returns a random key, so that the retries of a call are applied once
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,365 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"supermarket/client"
+	"supermarket/internal/application"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newServer starts the api on an empty in-memory catalogue, authenticating with token
+func newServer(t *testing.T, token string) *httptest.Server {
+	t.Helper()
+	server := application.NewServer(application.ServerConfig{
+		StorageBackend: "memory",
+		DbFile:         filepath.Join(t.TempDir(), "products.json"),
+		Token:          token,
+	})
+	require.NoError(t, server.SetUp())
+	ts := httptest.NewServer(server.Router())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// send sends body to the path of ts with the token "secret", for the routes the client does not call, and
+// decodes the data of the response, which must have status, to out
+func send(t *testing.T, ts *httptest.Server, method, path, body string, status int, out any) {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Token", "secret")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, status, res.StatusCode)
+	var envelope struct {
+		Data any `json:"data"`
+	}
+	envelope.Data = out
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&envelope))
+}
+
+// newClient creates a client of ts retrying quickly
+func newClient(t *testing.T, ts *httptest.Server, opts ...client.Option) *client.Client {
+	t.Helper()
+	opts = append([]client.Option{client.WithRetry(2, time.Millisecond, time.Millisecond)}, opts...)
+	c, err := client.New(ts.URL, opts...)
+	require.NoError(t, err)
+	return c
+}
+
+// TestClient_Products tests the product calls against the api.
+func TestClient_Products(t *testing.T) {
+	t.Run("success - create, read, update and delete", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		c := newClient(t, newServer(t, "secret"), client.WithToken("secret"))
+		request := client.ProductRequest{Name: "Milk", Quantity: 10, CodeValue: "M001", IsPublished: true, Expiration: "01/01/2030", Price: 1.5}
+
+		// act
+		pong, err := c.Ping(ctx)
+		require.NoError(t, err)
+		created, err := c.CreateProduct(ctx, request)
+		require.NoError(t, err)
+		got, err := c.GetProduct(ctx, created.Id)
+		require.NoError(t, err)
+		price := 3.0
+		patched, err := c.UpdateProduct(ctx, created.Id, client.ProductPatch{Price: &price})
+		require.NoError(t, err)
+		request.Name = "Bread"
+		request.CodeValue = "B001"
+		put, err := c.UpdateOrCreateProduct(ctx, 100, request)
+		require.NoError(t, err)
+		products, err := c.GetProducts(ctx)
+		require.NoError(t, err)
+		expensive, err := c.SearchProductsByPrice(ctx, 2)
+		require.NoError(t, err)
+		consumerPrice, err := c.GetConsumerPrice(ctx, []int{created.Id, created.Id})
+		require.NoError(t, err)
+		err = c.DeleteProduct(ctx, created.Id)
+		require.NoError(t, err)
+		_, errDeleted := c.GetProduct(ctx, created.Id)
+
+		// assert
+		require.Equal(t, "pong", pong)
+		require.NotZero(t, created.Id)
+		require.Equal(t, "Milk", created.Name)
+		require.Equal(t, created, got)
+		require.Equal(t, 3.0, patched.Price)
+		require.Equal(t, "Milk", patched.Name)
+		require.Equal(t, client.Product{Id: put.Id, Name: "Bread", Quantity: 10, CodeValue: "B001", IsPublished: true, Expiration: "01/01/2030", Price: 1.5}, put)
+		require.Len(t, products, 2)
+		require.Equal(t, []client.Product{patched}, expensive)
+		require.Len(t, consumerPrice.Products, 2)
+		require.NotZero(t, consumerPrice.TotalPrice)
+		require.ErrorIs(t, errDeleted, client.ErrProductNotFound)
+	})
+
+	t.Run("failure - domain errors", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		c := newClient(t, newServer(t, "secret"), client.WithToken("secret"))
+		request := client.ProductRequest{Name: "Milk", Quantity: 10, CodeValue: "M001", Expiration: "01/01/2030", Price: 1.5}
+		_, err := c.CreateProduct(ctx, request)
+		require.NoError(t, err)
+
+		// act
+		_, errNotFound := c.GetProduct(ctx, 42)
+		_, errDuplicate := c.CreateProduct(ctx, request)
+		_, errInvalid := c.CreateProduct(ctx, client.ProductRequest{Name: "Empty"})
+
+		// assert
+		require.ErrorIs(t, errNotFound, client.ErrProductNotFound)
+		require.ErrorIs(t, errDuplicate, client.ErrDuplicateCodeValue)
+		require.ErrorIs(t, errInvalid, client.ErrInvalidProduct)
+		var apiErr *client.APIError
+		require.ErrorAs(t, errDuplicate, &apiErr)
+		require.Equal(t, http.StatusConflict, apiErr.StatusCode)
+	})
+
+	t.Run("failure - unauthorized", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		c := newClient(t, newServer(t, "secret"), client.WithToken("wrong"))
+
+		// act
+		_, err := c.CreateProduct(ctx, client.ProductRequest{Name: "Milk", Quantity: 1, CodeValue: "M001", Expiration: "01/01/2030", Price: 1})
+
+		// assert
+		require.ErrorIs(t, err, client.ErrUnauthorized)
+	})
+}
+
+// TestClient_ConsumerPrice tests the consumer price against the api, at a store or not.
+func TestClient_ConsumerPrice(t *testing.T) {
+	t.Run("success - consumer price at a store", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		ts := newServer(t, "secret")
+		c := newClient(t, ts, client.WithToken("secret"))
+		milk, err := c.CreateProduct(ctx, client.ProductRequest{Name: "Milk", Quantity: 10, CodeValue: "M001", Expiration: "01/01/2030", Price: 1.5})
+		require.NoError(t, err)
+		var store struct {
+			ID int `json:"id"`
+		}
+		send(t, ts, http.MethodPost, "/stores", `{"name":"Downtown","address":"Main St 1"}`, http.StatusCreated, &store)
+		send(t, ts, http.MethodPut, fmt.Sprintf("/stores/%d/stock/%d", store.ID, milk.Id), `{"quantity":2}`, http.StatusOK, nil)
+
+		// act
+		atStore, err := c.GetConsumerPrice(ctx, []int{milk.Id, milk.Id}, client.AtStore(store.ID))
+		require.NoError(t, err)
+		_, errStock := c.GetConsumerPrice(ctx, []int{milk.Id, milk.Id, milk.Id}, client.AtStore(store.ID))
+		_, errStore := c.GetConsumerPrice(ctx, []int{milk.Id}, client.AtStore(42))
+
+		// assert
+		require.Len(t, atStore.Products, 2)
+		require.Equal(t, milk.Id, atStore.Products[0].Id)
+		require.NotZero(t, atStore.TotalPrice)
+		require.ErrorIs(t, errStock, client.ErrInsufficientQuantity)
+		require.ErrorIs(t, errStore, client.ErrStoreNotFound)
+	})
+}
+
+// TestClient_Categories tests the category calls against the api.
+func TestClient_Categories(t *testing.T) {
+	// newCategory creates a category named name under parentID, returning its id
+	newCategory := func(t *testing.T, ts *httptest.Server, name string, parentID int) int {
+		t.Helper()
+		body := fmt.Sprintf(`{"name":%q}`, name)
+		if parentID != 0 {
+			body = fmt.Sprintf(`{"name":%q,"parent_id":%d}`, name, parentID)
+		}
+		var created client.Category
+		send(t, ts, http.MethodPost, "/categories", body, http.StatusCreated, &created)
+		return created.ID
+	}
+
+	t.Run("success - assign the categories and filter the products", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		ts := newServer(t, "secret")
+		c := newClient(t, ts, client.WithToken("secret"))
+		food := newCategory(t, ts, "Food", 0)
+		dairy := newCategory(t, ts, "Dairy", food)
+		drinks := newCategory(t, ts, "Drinks", 0)
+		milk, err := c.CreateProduct(ctx, client.ProductRequest{Name: "Milk", Quantity: 10, CodeValue: "M001", Expiration: "01/01/2030", Price: 1.5})
+		require.NoError(t, err)
+		water, err := c.CreateProduct(ctx, client.ProductRequest{Name: "Water", Quantity: 10, CodeValue: "W001", Expiration: "01/01/2030", Price: 1})
+		require.NoError(t, err)
+		_, err = c.CreateProduct(ctx, client.ProductRequest{Name: "Soap", Quantity: 10, CodeValue: "S001", Expiration: "01/01/2030", Price: 2})
+		require.NoError(t, err)
+
+		// act
+		assigned, err := c.SetProductCategories(ctx, milk.Id, dairy, drinks)
+		require.NoError(t, err)
+		_, err = c.SetProductCategories(ctx, water.Id, drinks)
+		require.NoError(t, err)
+		got, err := c.GetProductCategories(ctx, milk.Id)
+		require.NoError(t, err)
+		ofFood, err := c.GetProducts(ctx, client.InCategories(food))
+		require.NoError(t, err)
+		ofEither, err := c.GetProducts(ctx, client.InCategories(dairy, drinks))
+		require.NoError(t, err)
+		all, err := c.GetProducts(ctx)
+		require.NoError(t, err)
+		cleared, err := c.SetProductCategories(ctx, milk.Id)
+		require.NoError(t, err)
+
+		// assert
+		require.Equal(t, []client.Category{{ID: dairy, Name: "Dairy", ParentID: &food}, {ID: drinks, Name: "Drinks"}}, assigned)
+		require.Equal(t, assigned, got)
+		require.Equal(t, []client.Product{milk}, ofFood)
+		require.ElementsMatch(t, []client.Product{milk, water}, ofEither)
+		require.Len(t, all, 3)
+		require.Empty(t, cleared)
+	})
+
+	t.Run("failure - category errors", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		c := newClient(t, newServer(t, "secret"), client.WithToken("secret"))
+		milk, err := c.CreateProduct(ctx, client.ProductRequest{Name: "Milk", Quantity: 10, CodeValue: "M001", Expiration: "01/01/2030", Price: 1.5})
+		require.NoError(t, err)
+
+		// act
+		_, errCategory := c.SetProductCategories(ctx, milk.Id, 42)
+		_, errProduct := c.GetProductCategories(ctx, 42)
+
+		// assert
+		require.ErrorIs(t, errCategory, client.ErrCategoryNotFound)
+		require.ErrorIs(t, errProduct, client.ErrProductNotFound)
+	})
+}
+
+// TestClient_Prices tests the price calls against the api.
+func TestClient_Prices(t *testing.T) {
+	t.Run("success - schedule, read and cancel prices", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		c := newClient(t, newServer(t, "secret"), client.WithToken("secret"))
+		created, err := c.CreateProduct(ctx, client.ProductRequest{Name: "Milk", Quantity: 10, CodeValue: "M001", Expiration: "01/01/2030", Price: 1.5})
+		require.NoError(t, err)
+		price := 2.0
+		_, err = c.UpdateProduct(ctx, created.Id, client.ProductPatch{Price: &price})
+		require.NoError(t, err)
+		effectiveAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+		// act
+		scheduled, err := c.SchedulePrice(ctx, created.Id, 3, effectiveAt)
+		require.NoError(t, err)
+		history, err := c.GetPrices(ctx, created.Id)
+		require.NoError(t, err)
+		err = c.CancelPrice(ctx, created.Id, scheduled.ID)
+		require.NoError(t, err)
+		cancelled, err := c.GetPrices(ctx, created.Id)
+		require.NoError(t, err)
+
+		// assert
+		require.NotEmpty(t, scheduled.ID)
+		require.Equal(t, created.Id, scheduled.ProductID)
+		require.Equal(t, 3.0, scheduled.Price)
+		require.True(t, effectiveAt.Equal(scheduled.EffectiveAt))
+		require.Nil(t, scheduled.AppliedAt)
+		require.Equal(t, 2.0, history.Price)
+		require.Len(t, history.Entries, 3)
+		require.Equal(t, 1.5, history.Entries[1].PreviousPrice)
+		require.Equal(t, scheduled.ID, history.Entries[2].ID)
+		require.Equal(t, 2.0, cancelled.Price)
+		require.Len(t, cancelled.Entries, 2)
+	})
+
+	t.Run("failure - price errors", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		c := newClient(t, newServer(t, "secret"), client.WithToken("secret"))
+		created, err := c.CreateProduct(ctx, client.ProductRequest{Name: "Milk", Quantity: 10, CodeValue: "M001", Expiration: "01/01/2030", Price: 1.5})
+		require.NoError(t, err)
+		history, err := c.GetPrices(ctx, created.Id)
+		require.NoError(t, err)
+
+		// act
+		_, errPrice := c.SchedulePrice(ctx, created.Id, -1, time.Now().Add(time.Hour))
+		_, errEffectiveAt := c.SchedulePrice(ctx, created.Id, 3, time.Now().Add(-time.Hour))
+		_, errProduct := c.SchedulePrice(ctx, 42, 3, time.Now().Add(time.Hour))
+		errNotFound := c.CancelPrice(ctx, created.Id, "unknown")
+		errEffective := c.CancelPrice(ctx, created.Id, history.Entries[0].ID)
+
+		// assert
+		require.ErrorIs(t, errPrice, client.ErrInvalidPrice)
+		require.ErrorIs(t, errEffectiveAt, client.ErrInvalidEffectiveAt)
+		require.ErrorIs(t, errProduct, client.ErrProductNotFound)
+		require.ErrorIs(t, errNotFound, client.ErrPriceNotFound)
+		require.ErrorIs(t, errEffective, client.ErrPriceEffective)
+	})
+}
+
+// TestClient_Retry tests the retries of the calls.
+func TestClient_Retry(t *testing.T) {
+	// flaky responds status to the first failures requests, then the products
+	flaky := func(failures int32, status int) (*httptest.Server, *atomic.Int32) {
+		var calls atomic.Int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) <= failures {
+				w.WriteHeader(status)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"message":"ok","data":[]}`))
+		}))
+		t.Cleanup(ts.Close)
+		return ts, &calls
+	}
+
+	t.Run("success - idempotent call retried", func(t *testing.T) {
+		// arrange
+		ts, calls := flaky(2, http.StatusServiceUnavailable)
+		c := newClient(t, ts)
+
+		// act
+		products, err := c.GetProducts(context.Background())
+
+		// assert
+		require.NoError(t, err)
+		require.Empty(t, products)
+		require.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("failure - retries exhausted", func(t *testing.T) {
+		// arrange
+		ts, calls := flaky(5, http.StatusTooManyRequests)
+		c := newClient(t, ts)
+
+		// act
+		_, err := c.GetProducts(context.Background())
+
+		// assert
+		require.ErrorIs(t, err, client.ErrRateLimited)
+		require.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("failure - patch not retried", func(t *testing.T) {
+		// arrange
+		ts, calls := flaky(1, http.StatusServiceUnavailable)
+		c := newClient(t, ts)
+
+		// act
+		_, err := c.UpdateProduct(context.Background(), 1, client.ProductPatch{})
+
+		// assert
+		var apiErr *client.APIError
+		require.ErrorAs(t, err, &apiErr)
+		require.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+		require.Equal(t, int32(1), calls.Load())
+	})
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"supermarket/internal/category"
+	internalProduct "supermarket/internal/product"
+	"supermarket/internal/product/pricing"
+)
+
+var (
+	// ErrInvalidID is returned when an id is not valid.
+	ErrInvalidID = internalProduct.ErrInvalidID
+	// ErrProductNotFound is returned when a product does not exist.
+	ErrProductNotFound = internalProduct.ErrProductNotFound
+	// ErrInvalidPriceGt is returned when the price of a search is not valid.
+	ErrInvalidPriceGt = internalProduct.ErrInvalidPriceGt
+	// ErrInvalidProduct is returned when the fields of a product are not valid.
+	ErrInvalidProduct = internalProduct.ErrInvalidProduct
+	// ErrDuplicateCodeValue is returned when the code value of a product is already used.
+	ErrDuplicateCodeValue = internalProduct.ErrDuplicateCodeValue
+	// ErrInsufficientQuantity is returned when a product has no quantity left.
+	ErrInsufficientQuantity = internalProduct.ErrInsufficientQuantity
+	// ErrStoreNotFound is returned when a store does not exist.
+	ErrStoreNotFound = internalProduct.ErrStoreNotFound
+	// ErrPriceNotFound is returned when a price entry does not exist.
+	ErrPriceNotFound = pricing.ErrEntryNotFound
+	// ErrPriceEffective is returned when cancelling a price which already took effect.
+	ErrPriceEffective = pricing.ErrEntryEffective
+	// ErrInvalidPrice is returned when scheduling a price which is not positive.
+	ErrInvalidPrice = pricing.ErrInvalidPrice
+	// ErrInvalidEffectiveAt is returned when scheduling a price which does not take effect in the future.
+	ErrInvalidEffectiveAt = pricing.ErrInvalidEffectiveAt
+	// ErrCategoryNotFound is returned when a category does not exist.
+	ErrCategoryNotFound = category.ErrCategoryNotFound
+	// ErrUnauthorized is returned when the token is missing or not valid.
+	ErrUnauthorized = errors.New("client: unauthorized")
+	// ErrRateLimited is returned when the requests exceed the rate limit, after the retries.
+	ErrRateLimited = errors.New("client: rate limited")
+)
+
+// domainErrors are the errors of the api, matched by the message of the response
+var domainErrors = map[int][]error{
+	http.StatusBadRequest:          {ErrInvalidID, ErrInvalidPriceGt, ErrInvalidProduct},
+	http.StatusNotFound:            {ErrProductNotFound, ErrStoreNotFound, ErrPriceNotFound, ErrCategoryNotFound},
+	http.StatusConflict:            {ErrDuplicateCodeValue, ErrInsufficientQuantity, ErrPriceEffective},
+	http.StatusUnprocessableEntity: {ErrInvalidPrice, ErrInvalidEffectiveAt},
+}
+
+// APIError is an error response of the api.
+// It matches with errors.Is the domain error it mirrors, e.g. ErrProductNotFound.
+type APIError struct {
+	// StatusCode is the status code of the response
+	StatusCode int
+	// Message is the message of the response
+	Message string
+	// err is the domain error of the response, nil if unknown
+	err error
+}
+
+// Error returns the status and message of the response.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("client: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Unwrap returns the domain error of the response
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// newAPIError returns the error of a response with the status of res and body data
+func newAPIError(res *http.Response, data []byte) *APIError {
+	e := &APIError{StatusCode: res.StatusCode}
+	var body struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(data, &body) == nil && body.Message != "" {
+		e.Message = body.Message
+	} else {
+		e.Message = strings.TrimSpace(string(data))
+	}
+
+	switch res.StatusCode {
+	case http.StatusUnauthorized:
+		e.err = ErrUnauthorized
+	case http.StatusTooManyRequests:
+		e.err = ErrRateLimited
+	}
+	for _, err := range domainErrors[res.StatusCode] {
+		if strings.HasPrefix(e.Message, err.Error()) {
+			e.err = err
+			break
+		}
+	}
+	return e
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// PriceEntry is a price of a product, from the time it takes effect until the next entry of the product
+type PriceEntry struct {
+	ID        string  `json:"id"`
+	ProductID int     `json:"product_id"`
+	Price     float64 `json:"price"`
+	// PreviousPrice is the price replaced by a change of the product, 0 for the scheduled prices
+	PreviousPrice float64   `json:"previous_price,omitempty"`
+	EffectiveAt   time.Time `json:"effective_at"`
+	RecordedAt    time.Time `json:"recorded_at"`
+	Actor         string    `json:"actor"`
+	// AppliedAt is when a scheduled price was written to the product, nil until then
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// PriceHistory is the price of a product and its entries
+type PriceHistory struct {
+	ProductID int `json:"product_id"`
+	// Price is the price effective at the time of the call
+	Price float64 `json:"price"`
+	// Entries are sorted by effective_at, the scheduled ones last
+	Entries []PriceEntry `json:"entries"`
+}
+
+// GetPrices returns the effective price of the product of id, its changes and its scheduled prices.
+func (c *Client) GetPrices(ctx context.Context, id int) (history PriceHistory, err error) {
+	err = c.do(ctx, call{method: http.MethodGet, path: pricesPath(id), idempotent: true}, &history)
+	return
+}
+
+// SchedulePrice schedules price as the price of the product of id from effectiveAt, which must be in the future.
+// The call is not retried, as a retry would schedule the price again.
+func (c *Client) SchedulePrice(ctx context.Context, id int, price float64, effectiveAt time.Time) (entry PriceEntry, err error) {
+	body := struct {
+		Price       float64   `json:"price"`
+		EffectiveAt time.Time `json:"effective_at"`
+	}{price, effectiveAt}
+	err = c.do(ctx, call{method: http.MethodPost, path: pricesPath(id), body: body}, &entry)
+	return
+}
+
+// CancelPrice cancels the scheduled price entryID of the product of id, before it takes effect.
+func (c *Client) CancelPrice(ctx context.Context, id int, entryID string) error {
+	path := pricesPath(id) + "/" + url.PathEscape(entryID)
+	return c.do(ctx, call{method: http.MethodDelete, path: path, idempotent: true}, nil)
+}
+
+// pricesPath returns the path of the prices of the product of id
+func pricesPath(id int) string {
+	return productPath(id) + "/prices"
+}
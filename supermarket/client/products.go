@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Product is a product of the catalogue
+type Product struct {
+	Id          int     `json:"id"`
+	Name        string  `json:"name"`
+	Quantity    int     `json:"quantity"`
+	CodeValue   string  `json:"code_value"`
+	IsPublished bool    `json:"is_published"`
+	Expiration  string  `json:"expiration"`
+	Price       float64 `json:"price"`
+}
+
+// ProductRequest are the fields of a product to create or replace. Expiration is a date mm/dd/yyyy.
+type ProductRequest struct {
+	Name        string  `json:"name"`
+	Quantity    int     `json:"quantity"`
+	CodeValue   string  `json:"code_value"`
+	IsPublished bool    `json:"is_published"`
+	Expiration  string  `json:"expiration"`
+	Price       float64 `json:"price"`
+}
+
+// ProductPatch are the fields of a product to update, the nil ones being kept
+type ProductPatch struct {
+	Name        *string  `json:"name,omitempty"`
+	Quantity    *int     `json:"quantity,omitempty"`
+	CodeValue   *string  `json:"code_value,omitempty"`
+	IsPublished *bool    `json:"is_published,omitempty"`
+	Expiration  *string  `json:"expiration,omitempty"`
+	Price       *float64 `json:"price,omitempty"`
+}
+
+// ConsumerPrice is a list of products and their total price for a consumer
+type ConsumerPrice struct {
+	Products   []Product `json:"products"`
+	TotalPrice float64   `json:"total_price"`
+}
+
+// ProductsOption filters the products returned by GetProducts
+type ProductsOption func(query url.Values)
+
+// InCategories returns the products of the categories of ids or their subcategories only.
+func InCategories(ids ...int) ProductsOption {
+	return func(query url.Values) {
+		for _, id := range ids {
+			query.Add("category", strconv.Itoa(id))
+		}
+	}
+}
+
+// ConsumerPriceOption sets the parameters of GetConsumerPrice
+type ConsumerPriceOption func(query url.Values)
+
+// AtStore checks the products against the stock of the store of id, rather than the quantity of the catalogue.
+func AtStore(id int) ConsumerPriceOption {
+	return func(query url.Values) {
+		query.Set("store", strconv.Itoa(id))
+	}
+}
+
+// Ping checks that the api is up, returning "pong".
+func (c *Client) Ping(ctx context.Context) (pong string, err error) {
+	err = c.do(ctx, call{method: http.MethodGet, path: "/ping", idempotent: true}, &pong)
+	return
+}
+
+// GetProducts returns the products of the catalogue, filtered by opts.
+func (c *Client) GetProducts(ctx context.Context, opts ...ProductsOption) (products []Product, err error) {
+	query := url.Values{}
+	for _, opt := range opts {
+		opt(query)
+	}
+	err = c.do(ctx, call{method: http.MethodGet, path: "/products/", query: query, idempotent: true}, &products)
+	return
+}
+
+// GetProduct returns the product of id.
+func (c *Client) GetProduct(ctx context.Context, id int) (product Product, err error) {
+	err = c.do(ctx, call{method: http.MethodGet, path: productPath(id), idempotent: true}, &product)
+	return
+}
+
+// SearchProductsByPrice returns the products with a price greater than priceGt.
+func (c *Client) SearchProductsByPrice(ctx context.Context, priceGt float64) (products []Product, err error) {
+	query := url.Values{"priceGt": {strconv.FormatFloat(priceGt, 'f', -1, 64)}}
+	err = c.do(ctx, call{method: http.MethodGet, path: "/products/search", query: query, idempotent: true}, &products)
+	return
+}
+
+// GetConsumerPrice returns the products of ids, an id repeated once per unit, and their total price.
+func (c *Client) GetConsumerPrice(ctx context.Context, ids []int, opts ...ConsumerPriceOption) (consumerPrice ConsumerPrice, err error) {
+	list := make([]string, len(ids))
+	for i, id := range ids {
+		list[i] = strconv.Itoa(id)
+	}
+	query := url.Values{"list": {"[" + strings.Join(list, ",") + "]"}}
+	for _, opt := range opts {
+		opt(query)
+	}
+	err = c.do(ctx, call{method: http.MethodGet, path: "/products/consumer_price", query: query, idempotent: true}, &consumerPrice)
+	return
+}
+
+// CreateProduct creates a product, returning it with its id.
+// The call is sent with an Idempotency-Key, so that it is retried without creating duplicates.
+func (c *Client) CreateProduct(ctx context.Context, product ProductRequest) (created Product, err error) {
+	header := http.Header{"Idempotency-Key": {newIdempotencyKey()}}
+	err = c.do(ctx, call{method: http.MethodPost, path: "/products/", body: product, idempotent: true, header: header}, &created)
+	return
+}
+
+// UpdateOrCreateProduct replaces the product of id, creating it with a new id if it does not exist.
+// The call is not retried, as a retry would create the product again.
+func (c *Client) UpdateOrCreateProduct(ctx context.Context, id int, product ProductRequest) (updated Product, err error) {
+	err = c.do(ctx, call{method: http.MethodPut, path: productPath(id), body: product}, &updated)
+	return
+}
+
+// UpdateProduct updates the fields of patch of the product of id.
+func (c *Client) UpdateProduct(ctx context.Context, id int, patch ProductPatch) (updated Product, err error) {
+	err = c.do(ctx, call{method: http.MethodPatch, path: productPath(id), body: patch}, &updated)
+	return
+}
+
+// DeleteProduct deletes the product of id.
+func (c *Client) DeleteProduct(ctx context.Context, id int) error {
+	return c.do(ctx, call{method: http.MethodDelete, path: productPath(id), idempotent: true}, nil)
+}
+
+// productPath returns the path of the product of id
+func productPath(id int) string {
+	return "/products/" + strconv.Itoa(id)
+}
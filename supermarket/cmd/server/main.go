@@ -2,20 +2,122 @@ package main
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"supermarket/internal/application"
+	"supermarket/internal/config"
+	"supermarket/internal/platform/logging"
+	"supermarket/internal/platform/ratelimit"
+	"supermarket/internal/platform/web/middleware"
+	"supermarket/internal/product/snapshot"
+	"supermarket/internal/webhook"
 )
 
 func main() {
+	// config: defaults < config file < environment < flags
+	loader := config.NewLoader(os.Args[1:], os.Getenv)
+	cfg, err := loader.Load()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if loader.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// logger
+	logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
 	// server config
-	config := application.ServerConfig{
-		Host:   os.Getenv("ENV_HOST"),
-		Port:   os.Getenv("ENV_PORT"),
-		DbFile: os.Getenv("ENV_PATH_DBFILE"),
-		Token:  os.Getenv("ENV_TOKEN"),
+	serverConfig := application.ServerConfig{
+		Host:            cfg.Server.Host,
+		Port:            cfg.Server.Port,
+		GRPCPort:        cfg.Server.GRPCPort,
+		DbFile:          cfg.Storage.Path,
+		Token:           cfg.Auth.Token,
+		StorageBackend:  cfg.Storage.Backend,
+		AuthMode:        cfg.Auth.Mode,
+		TLSCertFile:     cfg.Server.TLS.CertFile,
+		TLSKeyFile:      cfg.Server.TLS.KeyFile,
+		ReadTimeout:     cfg.Server.ReadTimeout,
+		WriteTimeout:    cfg.Server.WriteTimeout,
+		IdleTimeout:     cfg.Server.IdleTimeout,
+		ShutdownTimeout: cfg.Server.ShutdownTimeout,
+		ShutdownDelay:   cfg.Server.ShutdownDelay,
+		RequestTimeout:  cfg.Server.RequestTimeout,
+		MaxBodyBytes:    cfg.Server.MaxBodyBytes,
+		StrictJSON:      cfg.Server.StrictJSON,
+		LogSampleRate:   &cfg.Log.SampleRate,
+
+		TrustedProxies: cfg.RateLimit.TrustedProxies,
+
+		ListCacheControl: cfg.HTTPCache.ListCacheControl,
+		ItemCacheControl: cfg.HTTPCache.ItemCacheControl,
+
+		IdempotencyTTL:  cfg.Idempotency.TTL,
+		IdempotencyFile: cfg.Idempotency.File,
+
+		GraphQLMaxDepth:      cfg.GraphQL.MaxDepth,
+		GraphQLMaxComplexity: cfg.GraphQL.MaxComplexity,
+
+		EventsLogSize:   cfg.Events.LogSize,
+		EventsHeartbeat: cfg.Events.Heartbeat,
+
+		WebhooksFile: cfg.Webhooks.File,
+		Webhooks: webhook.Config{
+			MaxAttempts: cfg.Webhooks.MaxAttempts,
+			MinBackoff:  cfg.Webhooks.MinBackoff,
+			MaxBackoff:  cfg.Webhooks.MaxBackoff,
+			Timeout:     cfg.Webhooks.Timeout,
+		},
+		WebhookRetention: webhook.Retention{
+			MaxCount: cfg.Webhooks.RetentionMaxCount,
+			MaxAge:   cfg.Webhooks.RetentionMaxAge,
+		},
+
+		SnapshotsDir: cfg.Snapshots.Dir,
+		SnapshotRetention: snapshot.Retention{
+			MaxCount: cfg.Snapshots.MaxCount,
+			MaxAge:   cfg.Snapshots.MaxAge,
+		},
+
+		PricesFile: cfg.Prices.File,
+
+		CategoriesFile: cfg.Categories.File,
+
+		InventoryFile: cfg.Inventory.File,
+
+		CORS: middleware.CORSConfig{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowedMethods:   cfg.CORS.AllowedMethods,
+			AllowedHeaders:   cfg.CORS.AllowedHeaders,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           cfg.CORS.MaxAge,
+		},
+
+		TracingExporter:    cfg.Tracing.Exporter,
+		TracingEndpoint:    cfg.Tracing.Endpoint,
+		TracingServiceName: cfg.Tracing.ServiceName,
 	}
+	if cfg.RateLimit.Enabled {
+		serverConfig.RateLimitRead = ratelimit.Limit{Rate: cfg.RateLimit.Read.Rate, Burst: cfg.RateLimit.Read.Burst}
+		serverConfig.RateLimitWrite = ratelimit.Limit{Rate: cfg.RateLimit.Write.Rate, Burst: cfg.RateLimit.Write.Burst}
+	}
+	if cfg.HTTPCache.ResponseCache {
+		serverConfig.ResponseCacheEntries = cfg.HTTPCache.ResponseCacheEntries
+	}
+
 	// create and start server
-	server := application.NewServer(config)
+	server := application.NewServer(serverConfig)
 	if err := server.Start(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
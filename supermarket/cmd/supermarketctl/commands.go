@@ -0,0 +1,409 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"supermarket/internal/catalog"
+	internalProduct "supermarket/internal/product"
+	"supermarket/internal/product/service"
+	"supermarket/internal/product/storage"
+	"time"
+)
+
+type Product = internalProduct.Product
+
+// ctl runs the commands on the storage, through the product service for the catalogue operations and
+// through the catalogue for the ones replacing the products. The data is written to stdout, the reports to stderr.
+type ctl struct {
+	ctx context.Context
+	// name is the name of the command
+	name string
+	// backend is the configured storage backend, seeded from or written to path
+	backend string
+	// path is the path of the json file of the products
+	path    string
+	storage *storage.ProductStorage
+	catalog *catalog.Catalog
+	service *service.ProductService
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+// flags returns the flag set of the command
+func (c *ctl) flags() *flag.FlagSet {
+	fs := flag.NewFlagSet("supermarketctl "+c.name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+// parse parses the flags of the command, expecting between min and max arguments and a known output format
+func (c *ctl) parse(fs *flag.FlagSet, args []string, min, max int) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() < min || fs.NArg() > max {
+		return errUsage
+	}
+	// the format is checked before the command writes
+	if o := fs.Lookup("o"); o != nil && o.Value.String() != formatTable && o.Value.String() != formatJSON {
+		fmt.Fprintf(c.stderr, "unknown output format %q\n", o.Value.String())
+		return errUsage
+	}
+	return nil
+}
+
+// list lists the products
+func (c *ctl) list(args []string) error {
+	fs := c.flags()
+	format := formatFlag(fs)
+	priceGt := fs.String("price-gt", "", "only the products with a greater price")
+	if err := c.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	var products []Product
+	var err error
+	if *priceGt != "" {
+		products, err = c.service.SearchProductsByPrice(c.ctx, *priceGt)
+	} else {
+		products, err = c.service.GetProducts(c.ctx)
+	}
+	if err != nil {
+		return err
+	}
+	sortProducts(products)
+	return writeProducts(c.stdout, *format, products)
+}
+
+// get shows a product
+func (c *ctl) get(args []string) error {
+	fs := c.flags()
+	format := formatFlag(fs)
+	if err := c.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	product, err := c.service.GetProduct(c.ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return writeProduct(c.stdout, *format, product)
+}
+
+// productFlags binds the fields of product to flags of fs
+func productFlags(fs *flag.FlagSet, product *Product) {
+	fs.StringVar(&product.Name, "name", product.Name, "name of the product")
+	fs.IntVar(&product.Quantity, "quantity", product.Quantity, "quantity in stock")
+	fs.StringVar(&product.CodeValue, "code-value", product.CodeValue, "unique code of the product")
+	fs.BoolVar(&product.IsPublished, "published", product.IsPublished, "whether the product is published")
+	fs.StringVar(&product.Expiration, "expiration", product.Expiration, "expiration date, MM/DD/YYYY")
+	fs.Float64Var(&product.Price, "price", product.Price, "price of the product")
+}
+
+// create creates a product
+func (c *ctl) create(args []string) error {
+	fs := c.flags()
+	format := formatFlag(fs)
+	var product Product
+	productFlags(fs, &product)
+	if err := c.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	if err := c.ensureStorage(); err != nil {
+		return err
+	}
+	product, err := c.service.CreateProduct(c.ctx, product)
+	if err != nil {
+		return err
+	}
+	return writeProduct(c.stdout, *format, product)
+}
+
+// update updates the fields of a product set by flags
+func (c *ctl) update(args []string) error {
+	fs := c.flags()
+	format := formatFlag(fs)
+	var patch Product
+	productFlags(fs, &patch)
+	if err := c.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	product, err := c.service.GetProduct(c.ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	// only the fields of the flags set are updated
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "name":
+			product.Name = patch.Name
+		case "quantity":
+			product.Quantity = patch.Quantity
+		case "code-value":
+			product.CodeValue = patch.CodeValue
+		case "published":
+			product.IsPublished = patch.IsPublished
+		case "expiration":
+			product.Expiration = patch.Expiration
+		case "price":
+			product.Price = patch.Price
+		}
+	})
+	product, err = c.service.UpdateProduct(c.ctx, product)
+	if err != nil {
+		return err
+	}
+	return writeProduct(c.stdout, *format, product)
+}
+
+// delete deletes a product
+func (c *ctl) delete(args []string) error {
+	fs := c.flags()
+	if err := c.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	if err := c.service.DeleteProduct(c.ctx, fs.Arg(0)); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "deleted product %s\n", fs.Arg(0))
+	return nil
+}
+
+// importProducts creates the products of a json array, with new ids, reporting the ones failing
+func (c *ctl) importProducts(args []string) error {
+	fs := c.flags()
+	if err := c.parse(fs, args, 0, 1); err != nil {
+		return err
+	}
+
+	r := c.stdin
+	if name := fs.Arg(0); name != "" && name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	var products []Product
+	if err := json.NewDecoder(r).Decode(&products); err != nil {
+		return fmt.Errorf("decode products: %w", err)
+	}
+
+	if err := c.ensureStorage(); err != nil {
+		return err
+	}
+	failed := 0
+	for i, product := range products {
+		product.Id = 0
+		if _, err := c.service.CreateProduct(c.ctx, product); err != nil {
+			if ctxErr := c.ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			fmt.Fprintf(c.stderr, "product %d (%s): %v\n", i+1, product.Name, err)
+			failed++
+		}
+	}
+	fmt.Fprintf(c.stderr, "imported %d products\n", len(products)-failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d products not imported", failed, len(products))
+	}
+	return nil
+}
+
+// exportProducts writes the products as a json array, by id, for import
+func (c *ctl) exportProducts(args []string) error {
+	fs := c.flags()
+	if err := c.parse(fs, args, 0, 1); err != nil {
+		return err
+	}
+
+	products, err := c.service.GetProducts(c.ctx)
+	if err != nil {
+		return err
+	}
+	sortProducts(products)
+
+	name := fs.Arg(0)
+	if name == "" || name == "-" {
+		return writeJSON(c.stdout, products)
+	}
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(file, products); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "exported %d products to %s\n", len(products), name)
+	return nil
+}
+
+// invalidProduct is a product failing the validation
+type invalidProduct struct {
+	Id    int    `json:"id"`
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// validate runs the validation of the service over every stored product
+func (c *ctl) validate(args []string) error {
+	fs := c.flags()
+	format := formatFlag(fs)
+	if err := c.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	products, err := c.service.GetProducts(c.ctx)
+	if err != nil {
+		return err
+	}
+	sortProducts(products)
+
+	invalid := []invalidProduct{}
+	for _, product := range products {
+		// validated as an update, so the product is not a duplicate of itself
+		if err := c.service.ValidateProduct(c.ctx, product, true); err != nil {
+			if ctxErr := c.ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			invalid = append(invalid, invalidProduct{Id: product.Id, Name: product.Name, Error: err.Error()})
+		}
+	}
+
+	if err := writeTable(c.stdout, *format, invalid, []string{"ID", "NAME", "ERROR"}, func(p invalidProduct) []string {
+		return []string{strconv.Itoa(p.Id), p.Name, p.Error}
+	}); err != nil {
+		return err
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("%d of %d products invalid", len(invalid), len(products))
+	}
+	fmt.Fprintf(c.stderr, "%d products valid\n", len(products))
+	return nil
+}
+
+// renumbered is the change of id of a product
+type renumbered struct {
+	OldId int `json:"old_id"`
+	NewId int `json:"new_id"`
+}
+
+// renumber renumbers the ids from 1, keeping their order, along with their prices, categories and stock
+func (c *ctl) renumber(args []string) error {
+	fs := c.flags()
+	format := formatFlag(fs)
+	dryRun := fs.Bool("dry-run", false, "report the changes without saving them")
+	if err := c.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	products, err := c.storage.LoadProducts(c.ctx)
+	if err != nil {
+		return err
+	}
+	ids := make([]int, 0, len(products))
+	for id := range products {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	changes := []renumbered{}
+	newIds := make(map[int]int)
+	for i, id := range ids {
+		if id != i+1 {
+			changes = append(changes, renumbered{OldId: id, NewId: i + 1})
+			newIds[id] = i + 1
+		}
+	}
+
+	if err := writeTable(c.stdout, *format, changes, []string{"OLD ID", "NEW ID"}, func(r renumbered) []string {
+		return []string{strconv.Itoa(r.OldId), strconv.Itoa(r.NewId)}
+	}); err != nil {
+		return err
+	}
+	if *dryRun {
+		fmt.Fprintf(c.stderr, "%d of %d products would be renumbered\n", len(changes), len(products))
+		return nil
+	}
+	if len(changes) > 0 {
+		if err := c.catalog.Renumber(c.ctx, newIds); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(c.stderr, "renumbered %d of %d products\n", len(changes), len(products))
+	return nil
+}
+
+// backup copies the stored products to a file, in the format of the storage
+func (c *ctl) backup(args []string) error {
+	fs := c.flags()
+	if err := c.parse(fs, args, 0, 1); err != nil {
+		return err
+	}
+
+	name := fs.Arg(0)
+	if name == "" {
+		name = c.path + "." + time.Now().Format("20060102T150405") + ".bak"
+	}
+	products, err := c.storage.LoadProducts(c.ctx)
+	if err != nil {
+		return err
+	}
+	if err := storage.NewProductStorage(name).SaveProducts(c.ctx, products); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	fmt.Fprintf(c.stderr, "backed up %d products to %s\n", len(products), name)
+	return nil
+}
+
+// restore replaces the stored products with the ones of a backup, publishing the changes like the writes
+func (c *ctl) restore(args []string) error {
+	fs := c.flags()
+	if err := c.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	name := fs.Arg(0)
+	products, err := storage.NewProductStorage(name).LoadProducts(c.ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if err := c.catalog.Replace(c.ctx, products); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "restored %d products from %s\n", len(products), name)
+	return nil
+}
+
+// ensureStorage creates an empty json file of products if it does not exist, for the first writes
+func (c *ctl) ensureStorage() error {
+	_, err := c.storage.LoadProducts(c.ctx)
+	if errors.Is(err, internalProduct.ErrFileNotFound) {
+		return c.storage.SaveProducts(c.ctx, map[int]Product{})
+	}
+	return err
+}
+
+// sortProducts sorts products by id
+func sortProducts(products []Product) {
+	sort.Slice(products, func(i, j int) bool { return products[i].Id < products[j].Id })
+}
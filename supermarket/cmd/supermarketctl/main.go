@@ -0,0 +1,163 @@
+// Command supermarketctl administers the catalogue directly on the configured storage, with no server needed.
+//
+//	supermarketctl [config flags] <command> [command flags] [args]
+//
+// The config flags, environment and config file are the ones of the server. The writes go through the same
+// service as the server's, so the prices, webhooks, categories and stock of their configured files follow them.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"supermarket/internal/catalog"
+	"supermarket/internal/config"
+	"supermarket/internal/product/storage"
+	"supermarket/internal/webhook"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Getenv, os.Stdin, os.Stdout, os.Stderr))
+}
+
+// command is a subcommand of the cli
+type command struct {
+	// usage are the arguments of the command
+	usage string
+	// summary describes the command
+	summary string
+	// run runs the command with its arguments
+	run func(c *ctl, args []string) error
+}
+
+// commands are the subcommands, by name
+var commands = map[string]command{
+	"list":     {"[-o table|json] [-price-gt price]", "list the products, by id", (*ctl).list},
+	"get":      {"[-o table|json] <id>", "show a product", (*ctl).get},
+	"create":   {"[-o table|json] -name name -quantity n -code-value code -expiration MM/DD/YYYY -price price [-published]", "create a product", (*ctl).create},
+	"update":   {"[-o table|json] [-name name] [-quantity n] [-code-value code] [-expiration MM/DD/YYYY] [-price price] [-published=bool] <id>", "update the given fields of a product", (*ctl).update},
+	"delete":   {"<id>", "delete a product", (*ctl).delete},
+	"import":   {"[file]", "create the products of a json array, read from stdin without file", (*ctl).importProducts},
+	"export":   {"[file]", "write the products as a json array, to stdout without file", (*ctl).exportProducts},
+	"validate": {"[-o table|json]", "validate every stored product, reporting the invalid ones", (*ctl).validate},
+	"renumber": {"[-o table|json] [-dry-run]", "renumber the ids from 1, in their order", (*ctl).renumber},
+	"backup":   {"[file]", "copy the storage to file, <storage path>.<time>.bak by default", (*ctl).backup},
+	"restore":  {"<file>", "replace the stored products with the ones of a backup", (*ctl).restore},
+	"migrate":  {"[-o table|json] [-dry-run] [-prune] [source] <destination>", "copy the products of a storage, the configured one by default, to another, json:<path>, memory:[path] or a path", (*ctl).migrate},
+}
+
+// errUsage is returned for invalid arguments, the usage being printed
+var errUsage = errors.New("invalid arguments")
+
+// run runs the cli with args, returning the exit code: 0 on success, 1 on failure and 2 for invalid arguments
+func run(ctx context.Context, args []string, getenv func(string) string, stdin io.Reader, stdout, stderr io.Writer) int {
+	// config: defaults < config file < environment < flags, the command following the flags
+	loader := config.NewLoader(args, getenv)
+	cfg, err := loader.Merge()
+	if errors.Is(err, flag.ErrHelp) {
+		usage(stderr)
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "supermarketctl:", err)
+		return 2
+	}
+	if loader.PrintConfig {
+		if err := cfg.Print(stdout); err != nil {
+			fmt.Fprintln(stderr, "supermarketctl:", err)
+			return 1
+		}
+		return 0
+	}
+	if cfg.Storage.Path == "" {
+		fmt.Fprintln(stderr, "supermarketctl: storage.path is required")
+		return 2
+	}
+
+	args = loader.Args()
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "supermarketctl: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	// the json file is used by both backends, the memory one being seeded from it
+	st := storage.NewProductStorage(cfg.Storage.Path)
+	cat, err := catalog.New(ctx, st, catalog.Config{
+		PricesFile:     cfg.Prices.File,
+		WebhooksFile:   cfg.Webhooks.File,
+		CategoriesFile: cfg.Categories.File,
+		InventoryFile:  cfg.Inventory.File,
+		Webhooks: webhook.Config{
+			MaxAttempts: cfg.Webhooks.MaxAttempts,
+			MinBackoff:  cfg.Webhooks.MinBackoff,
+			MaxBackoff:  cfg.Webhooks.MaxBackoff,
+			Timeout:     cfg.Webhooks.Timeout,
+		},
+		WebhookRetention: webhook.Retention{
+			MaxCount: cfg.Webhooks.RetentionMaxCount,
+			MaxAge:   cfg.Webhooks.RetentionMaxAge,
+		},
+		EventsLogSize: cfg.Events.LogSize,
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, "supermarketctl:", err)
+		return 1
+	}
+	defer cat.Close()
+	c := &ctl{
+		ctx:     ctx,
+		name:    args[0],
+		backend: cfg.Storage.Backend,
+		path:    cfg.Storage.Path,
+		storage: st,
+		catalog: cat,
+		service: cat.Service,
+		stdin:   stdin,
+		stdout:  stdout,
+		stderr:  stderr,
+	}
+	err = cmd.run(c, args[1:])
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		if err != errUsage {
+			fmt.Fprintln(stderr, "supermarketctl:", err)
+		}
+		fmt.Fprintf(stderr, "usage: supermarketctl %s %s\n", args[0], cmd.usage)
+		return 2
+	default:
+		fmt.Fprintln(stderr, "supermarketctl:", err)
+		return 1
+	}
+}
+
+// usage prints the commands
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: supermarketctl [config flags] <command> [command flags] [args]")
+	fmt.Fprintln(w, "\nThe config flags are the ones of the server, -db-file being the path of the products.")
+	fmt.Fprintln(w, "\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-9s %s\n", name, commands[name].summary)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"supermarket/internal/category"
+	"supermarket/internal/inventory"
+	"supermarket/internal/product/migration"
+	"supermarket/internal/product/pricing"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// catalogue writes a json file of products to a temporary directory, returning its path
+func catalogue(t *testing.T, products string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "products.json")
+	require.NoError(t, os.WriteFile(path, []byte(products), 0644))
+	return path
+}
+
+// runCtl runs the cli on the products of path, the files of the prices, webhooks, categories and inventory
+// being next to it, returning the exit code, stdout and stderr
+func runCtl(t *testing.T, path, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	dir := filepath.Dir(path)
+	args = append([]string{
+		"-db-file", path,
+		"-prices-file", filepath.Join(dir, "prices.json"),
+		"-webhooks-file", filepath.Join(dir, "webhooks.json"),
+		"-categories-file", filepath.Join(dir, "categories.json"),
+		"-inventory-file", filepath.Join(dir, "inventory.json"),
+	}, args...)
+	code := run(context.Background(), args, func(string) string { return "" }, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// categories and stock assign product 7 to a category and hold 4 units of it at a store
+const (
+	categories = `{"categories":{"1":{"id":1,"name":"Dairy","parent_id":null}},"products":{"7":[1]}}`
+	stock      = `{"stores":{"1":{"id":1,"name":"Centre","address":""}},"stock":{"1":{"7":4}},"transfers":[]}`
+)
+
+// readJSON decodes the json file name of the directory of path into v
+func readJSON(t *testing.T, path, name string, v any) {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(filepath.Dir(path), name))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(content, v))
+}
+
+const products = `[
+	{"id":7,"name":"Milk","quantity":10,"code_value":"M1","is_published":true,"expiration":"01/02/2030","price":1.5},
+	{"id":3,"name":"Bread","quantity":5,"code_value":"B1","is_published":false,"expiration":"31/12/2030","price":2}
+]`
+
+// TestRun tests the commands of the cli.
+func TestRun(t *testing.T) {
+	t.Run("success - list as a table, by id", func(t *testing.T) {
+		// arrange
+		path := catalogue(t, products)
+
+		// act
+		code, stdout, _ := runCtl(t, path, "", "list")
+
+		// assert
+		require.Equal(t, 0, code)
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+		require.Len(t, lines, 3)
+		require.True(t, strings.HasPrefix(lines[0], "ID"))
+		require.True(t, strings.HasPrefix(lines[1], "3 "))
+		require.Contains(t, lines[2], "Milk")
+	})
+
+	t.Run("success - create then update as json", func(t *testing.T) {
+		// arrange
+		path := catalogue(t, products)
+		code, _, _ := runCtl(t, path, "", "create", "-name", "Eggs", "-quantity", "12", "-code-value", "E1", "-expiration", "03/04/2030", "-price", "3.2")
+		require.Equal(t, 0, code)
+
+		// act
+		code, stdout, _ := runCtl(t, path, "", "update", "-o", "json", "-price", "3.5", "8")
+
+		// assert
+		require.Equal(t, 0, code)
+		var product Product
+		require.NoError(t, json.Unmarshal([]byte(stdout), &product))
+		require.Equal(t, Product{Id: 8, Name: "Eggs", Quantity: 12, CodeValue: "E1", Expiration: "03/04/2030", Price: 3.5}, product)
+	})
+
+	t.Run("success - import into a new storage, export", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "products.json")
+
+		// act
+		code, _, stderr := runCtl(t, path, products, "import")
+		require.Equal(t, 1, code)
+		_, stdout, _ := runCtl(t, path, "", "export")
+
+		// assert
+		require.Contains(t, stderr, "product 2 (Bread): invalid product parameters")
+		require.Contains(t, stderr, "1 of 2 products not imported")
+		var exported []Product
+		require.NoError(t, json.Unmarshal([]byte(stdout), &exported))
+		require.Equal(t, []Product{{Id: 1, Name: "Milk", Quantity: 10, CodeValue: "M1", IsPublished: true, Expiration: "01/02/2030", Price: 1.5}}, exported)
+	})
+
+	t.Run("failure - validate reports the invalid products", func(t *testing.T) {
+		// arrange
+		path := catalogue(t, products)
+
+		// act
+		code, stdout, stderr := runCtl(t, path, "", "validate", "-o", "json")
+
+		// assert
+		require.Equal(t, 1, code)
+		var invalid []invalidProduct
+		require.NoError(t, json.Unmarshal([]byte(stdout), &invalid))
+		require.Equal(t, []invalidProduct{{Id: 3, Name: "Bread", Error: "invalid product parameters"}}, invalid)
+		require.Contains(t, stderr, "1 of 2 products invalid")
+	})
+
+	t.Run("success - renumber from 1 in the order of the ids", func(t *testing.T) {
+		// arrange
+		path := catalogue(t, products)
+
+		// act
+		code, stdout, _ := runCtl(t, path, "", "renumber", "-o", "json")
+
+		// assert
+		require.Equal(t, 0, code)
+		var changes []renumbered
+		require.NoError(t, json.Unmarshal([]byte(stdout), &changes))
+		require.Equal(t, []renumbered{{OldId: 3, NewId: 1}, {OldId: 7, NewId: 2}}, changes)
+		_, stdout, _ = runCtl(t, path, "", "get", "2")
+		require.Contains(t, stdout, "Milk")
+	})
+
+	t.Run("success - renumber moves the categories and the stock", func(t *testing.T) {
+		// arrange
+		path := catalogue(t, products)
+		require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(path), "categories.json"), []byte(categories), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(path), "inventory.json"), []byte(stock), 0644))
+
+		// act
+		code, _, _ := runCtl(t, path, "", "renumber")
+
+		// assert
+		require.Equal(t, 0, code)
+		var ct category.Data
+		readJSON(t, path, "categories.json", &ct)
+		require.Equal(t, map[int][]int{2: {1}}, ct.Products)
+		var inv inventory.Data
+		readJSON(t, path, "inventory.json", &inv)
+		require.Equal(t, map[int]map[int]int{1: {2: 4}}, inv.Stock)
+	})
+
+	t.Run("success - delete removes the categories and the stock", func(t *testing.T) {
+		// arrange
+		path := catalogue(t, products)
+		require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(path), "categories.json"), []byte(categories), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(path), "inventory.json"), []byte(stock), 0644))
+
+		// act
+		code, _, _ := runCtl(t, path, "", "delete", "7")
+
+		// assert
+		require.Equal(t, 0, code)
+		var ct category.Data
+		readJSON(t, path, "categories.json", &ct)
+		require.Empty(t, ct.Products)
+		var inv inventory.Data
+		readJSON(t, path, "inventory.json", &inv)
+		require.Empty(t, inv.Stock)
+	})
+
+	t.Run("success - restore a backup", func(t *testing.T) {
+		// arrange
+		path := catalogue(t, products)
+		backup := filepath.Join(t.TempDir(), "products.bak")
+		code, _, _ := runCtl(t, path, "", "backup", backup)
+		require.Equal(t, 0, code)
+		code, _, _ = runCtl(t, path, "", "delete", "7")
+		require.Equal(t, 0, code)
+
+		// act
+		code, _, stderr := runCtl(t, path, "", "restore", backup)
+
+		// assert
+		require.Equal(t, 0, code)
+		require.Contains(t, stderr, "restored 2 products")
+		code, _, _ = runCtl(t, path, "", "get", "7")
+		require.Equal(t, 0, code)
+		var prices map[int][]pricing.Entry
+		readJSON(t, path, "prices.json", &prices)
+		require.Len(t, prices[7], 1)
+		require.Equal(t, 1.5, prices[7][0].Price)
+	})
+
+	t.Run("success - migrate to a new json file, then nothing left to migrate", func(t *testing.T) {
+		// arrange
+		path := catalogue(t, products)
+		destination := filepath.Join(t.TempDir(), "migrated.json")
+
+		// act
+		code, _, stderr := runCtl(t, path, "", "migrate", "json:"+destination)
+		codeAgain, stdout, _ := runCtl(t, path, "", "migrate", "-dry-run", "-o", "json", destination)
+
+		// assert
+		require.Equal(t, 0, code)
+		require.Contains(t, stderr, "migrated 2 products to 2: 2 added")
+		require.Equal(t, 0, codeAgain)
+		var report migration.Report
+		require.NoError(t, json.Unmarshal([]byte(stdout), &report))
+		require.Equal(t, 2, report.Unchanged)
+		require.Empty(t, report.Added)
+	})
+
+	t.Run("success - migrate the configured memory backend to an empty memory one", func(t *testing.T) {
+		// arrange
+		path := catalogue(t, products)
+
+		// act
+		code, _, stderr := runCtl(t, path, "", "-storage-backend", "memory", "migrate", "memory:")
+
+		// assert
+		require.Equal(t, 0, code)
+		require.Contains(t, stderr, "migrated 2 products to 2: 2 added")
+	})
+
+	t.Run("failure - product not found", func(t *testing.T) {
+		// arrange
+		path := catalogue(t, products)
+
+		// act
+		code, _, stderr := runCtl(t, path, "", "get", "1")
+
+		// assert
+		require.Equal(t, 1, code)
+		require.Equal(t, "supermarketctl: product not found\n", stderr)
+	})
+
+	t.Run("error - invalid arguments", func(t *testing.T) {
+		// arrange
+		path := catalogue(t, products)
+
+		// act
+		codeCommand, _, _ := runCtl(t, path, "", "sell")
+		codeArgs, _, _ := runCtl(t, path, "", "get")
+		codeFormat, _, _ := runCtl(t, path, "", "list", "-o", "yaml")
+
+		// assert
+		require.Equal(t, 2, codeCommand)
+		require.Equal(t, 2, codeArgs)
+		require.Equal(t, 2, codeFormat)
+	})
+}
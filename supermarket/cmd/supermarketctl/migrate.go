@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"supermarket/internal/config"
+	internalProduct "supermarket/internal/product"
+	"supermarket/internal/product/migration"
+	"supermarket/internal/product/storage"
+)
+
+// migrate copies the products of a storage to another
+func (c *ctl) migrate(args []string) error {
+	fs := c.flags()
+	format := formatFlag(fs)
+	dryRun := fs.Bool("dry-run", false, "report the diff without writing the destination")
+	prune := fs.Bool("prune", false, "remove the products of the destination missing in the source")
+	if err := c.parse(fs, args, 1, 2); err != nil {
+		return err
+	}
+
+	// without source, the configured storage is migrated
+	sourceSpec, destinationSpec := c.backend+":"+c.path, fs.Arg(0)
+	if fs.NArg() == 2 {
+		sourceSpec, destinationSpec = fs.Arg(0), fs.Arg(1)
+	}
+	source, err := openStorage(c.ctx, sourceSpec)
+	if err != nil {
+		return err
+	}
+	destination, err := openStorage(c.ctx, destinationSpec)
+	if err != nil {
+		return err
+	}
+
+	report, err := migration.Migrate(c.ctx, source, destination, migration.Options{DryRun: *dryRun, Prune: *prune})
+	if err != nil && !errors.Is(err, migration.ErrVerification) {
+		return err
+	}
+	// the changes are reported even if the verification failed, to inspect the destination
+	if errWrite := c.writeReport(*format, report); errWrite != nil {
+		return errWrite
+	}
+	if err != nil {
+		return err
+	}
+
+	extra := "kept"
+	if *prune {
+		extra = "removed"
+	}
+	summary := fmt.Sprintf("%d added, %d updated, %d %s, %d unchanged", len(report.Added), len(report.Updated), len(report.Extra), extra, report.Unchanged)
+	if *dryRun {
+		fmt.Fprintf(c.stderr, "dry run: %s\n", summary)
+		return nil
+	}
+	fmt.Fprintf(c.stderr, "migrated %d products to %d: %s, checksum %s verified\n", report.Source, report.Destination, summary, report.Checksum)
+	return nil
+}
+
+// migrationRow is a change of a migration
+type migrationRow struct {
+	change  string
+	product internalProduct.Product
+}
+
+// writeReport writes the changes of report, the whole report as json
+func (c *ctl) writeReport(format string, report migration.Report) error {
+	if format == formatJSON {
+		return writeJSON(c.stdout, report)
+	}
+
+	var rows []migrationRow
+	for _, product := range report.Added {
+		rows = append(rows, migrationRow{"add", product})
+	}
+	for _, change := range report.Updated {
+		rows = append(rows, migrationRow{"update", change.After})
+	}
+	extra := "keep"
+	if report.Pruned {
+		extra = "remove"
+	}
+	for _, product := range report.Extra {
+		rows = append(rows, migrationRow{extra, product})
+	}
+	return writeTable(c.stdout, format, rows, []string{"CHANGE", "ID", "NAME"}, func(r migrationRow) []string {
+		return []string{r.change, strconv.Itoa(r.product.Id), r.product.Name}
+	})
+}
+
+// openStorage returns the storage of spec, "<backend>:<path>" or the path of a json file. As for the server,
+// the memory backend is seeded from the json file of its path if any, and its writes are lost with the process.
+func openStorage(ctx context.Context, spec string) (internalProduct.ProductStorageInterface, error) {
+	backend, path, ok := strings.Cut(spec, ":")
+	if !ok {
+		backend, path = config.StorageBackendJSON, spec
+	}
+	switch backend {
+	case config.StorageBackendJSON:
+		if path == "" {
+			return nil, fmt.Errorf("%w: storage %q without path", errUsage, spec)
+		}
+		return storage.NewProductStorage(path), nil
+	case config.StorageBackendMemory:
+		if path == "" {
+			return storage.NewProductStorageMemory(nil), nil
+		}
+		seed, err := storage.NewProductStorage(path).LoadProducts(ctx)
+		if err != nil && !errors.Is(err, internalProduct.ErrFileNotFound) {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return storage.NewProductStorageMemory(seed), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown storage backend %q", errUsage, backend)
+	}
+}
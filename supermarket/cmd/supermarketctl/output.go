@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	// formatTable writes aligned columns, for people
+	formatTable = "table"
+	// formatJSON writes indented json, for scripts
+	formatJSON = "json"
+)
+
+// formatFlag adds the -o flag of the output format to fs
+func formatFlag(fs *flag.FlagSet) *string {
+	return fs.String("o", formatTable, "output format: table or json")
+}
+
+// productColumns are the columns of the tables of products
+var productColumns = []string{"ID", "NAME", "QUANTITY", "CODE VALUE", "PUBLISHED", "EXPIRATION", "PRICE"}
+
+// productRow returns the columns of product
+func productRow(product Product) []string {
+	return []string{
+		strconv.Itoa(product.Id),
+		product.Name,
+		strconv.Itoa(product.Quantity),
+		product.CodeValue,
+		strconv.FormatBool(product.IsPublished),
+		product.Expiration,
+		strconv.FormatFloat(product.Price, 'f', 2, 64),
+	}
+}
+
+// writeProducts writes products in format
+func writeProducts(w io.Writer, format string, products []Product) error {
+	if products == nil {
+		products = []Product{}
+	}
+	return writeTable(w, format, products, productColumns, productRow)
+}
+
+// writeProduct writes product in format
+func writeProduct(w io.Writer, format string, product Product) error {
+	if format == formatJSON {
+		return writeJSON(w, product)
+	}
+	return writeTable(w, format, []Product{product}, productColumns, productRow)
+}
+
+// writeTable writes rows in format, as a json array or a table of columns, each row returning its columns
+func writeTable[T any](w io.Writer, format string, rows []T, columns []string, row func(T) []string) error {
+	switch format {
+	case formatJSON:
+		return writeJSON(w, rows)
+	case formatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(columns, "\t"))
+		for _, r := range rows {
+			fmt.Fprintln(tw, strings.Join(row(r), "\t"))
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("%w: unknown output format %q", errUsage, format)
+	}
+}
+
+// writeJSON writes v as indented json
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
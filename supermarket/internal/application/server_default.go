@@ -1,31 +1,231 @@
 package application
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"supermarket/api"
 	"supermarket/internal/auth"
 	"supermarket/internal/auth/middleware"
+	"supermarket/internal/catalog"
+	categoryHandler "supermarket/internal/category/handler"
+	appConfig "supermarket/internal/config"
+	inventoryHandler "supermarket/internal/inventory/handler"
+	"supermarket/internal/platform/health"
+	"supermarket/internal/platform/httpcache"
+	"supermarket/internal/platform/idempotency"
+	"supermarket/internal/platform/metrics"
+	"supermarket/internal/platform/ratelimit"
+	"supermarket/internal/platform/tracing"
+	"supermarket/internal/platform/web/docs"
+	"supermarket/internal/platform/web/lifecycle"
 	middlewareLog "supermarket/internal/platform/web/middleware"
+	internalProduct "supermarket/internal/product"
+	"supermarket/internal/product/events"
+	"supermarket/internal/product/graph"
 	"supermarket/internal/product/handler"
+	"supermarket/internal/product/pricing"
+	pricingHandler "supermarket/internal/product/pricing/handler"
 	"supermarket/internal/product/repository"
-	"supermarket/internal/product/service"
+	"supermarket/internal/product/rpc"
+	"supermarket/internal/product/snapshot"
+	snapshotHandler "supermarket/internal/product/snapshot/handler"
 	"supermarket/internal/product/storage"
+	"supermarket/internal/webhook"
+	webhookHandler "supermarket/internal/webhook/handler"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 type Server struct {
-	host   string
-	port   string
-	dbFile string
-	token  string
+	host           string
+	port           string
+	dbFile         string
+	token          string
+	storageBackend string
+	authMode       string
+	tlsCertFile    string
+	tlsKeyFile     string
+	// grpcPort is the port of the grpc api, disabled if empty
+	grpcPort string
+
+	// timeouts of the http server
+	readTimeout     time.Duration
+	writeTimeout    time.Duration
+	idleTimeout     time.Duration
+	shutdownTimeout time.Duration
+	shutdownDelay   time.Duration
+	requestTimeout  time.Duration
+	// maxBodyBytes is the maximum size of a request body
+	maxBodyBytes int64
+	// strictJSON rejects the json bodies with unknown fields or trailing data
+	strictJSON bool
+	// cors is the configuration of the cross-origin requests
+	cors middlewareLog.CORSConfig
+
+	// logSampleRate is the fraction of successful requests logged
+	logSampleRate float64
+
+	// rate limits of the read and write routes, and the proxies trusted to forward the client ip
+	rateLimitRead  ratelimit.Limit
+	rateLimitWrite ratelimit.Limit
+	trustedProxies []string
+
+	// caching of the catalogue reads
+	listCacheControl     string
+	itemCacheControl     string
+	responseCacheEntries int
+
+	// ttl and file of the idempotency keys
+	idempotencyTTL  time.Duration
+	idempotencyFile string
+
+	// limits of the graphql queries
+	graphQLLimits graph.Limits
+
+	// size of the log and heartbeat of the events of the catalogue
+	eventsLogSize   int
+	eventsHeartbeat time.Duration
+
+	// file, configuration and retention of the webhooks
+	webhooksFile     string
+	webhooks         webhook.Config
+	webhookRetention webhook.Retention
+
+	// directory and retention of the snapshots of the catalogue
+	snapshotsDir      string
+	snapshotRetention snapshot.Retention
+
+	// file of the price history and the scheduled prices
+	pricesFile string
+
+	// file of the categories and the categories of the products
+	categoriesFile string
+
+	// file of the stores, their stock and the transfers
+	inventoryFile string
+
+	// tracing exporter, endpoint and service name
+	tracingExporter    string
+	tracingEndpoint    string
+	tracingServiceName string
+
+	// router and grpcServer are built by SetUp
+	router     *chi.Mux
+	grpcServer *grpc.Server
+	// events is the bus of the catalogue events, closed on shutdown to end the streams
+	events *events.Bus
+	// dispatcher delivers the webhooks, run by Start
+	dispatcher *webhook.Dispatcher
+	// flush waits for pending storage writes on shutdown
+	flush lifecycle.Hook
+	// health holds the readiness checks, failing once the shutdown starts
+	health *health.Registry
+	// flushSpans exports the pending spans on shutdown
+	flushSpans lifecycle.Hook
+	// prices schedules the prices, its timer stopped on shutdown
+	prices *pricing.Prices
 }
 
 type ServerConfig struct {
 	Host   string
 	Port   string
 	DbFile string
-	Token  string
+	// GRPCPort is the port of the grpc api, disabled if empty
+	GRPCPort string
+	Token    string
+
+	// StorageBackend is config.StorageBackendJSON (default) or config.StorageBackendMemory
+	StorageBackend string
+	// AuthMode is config.AuthModeToken (default) or config.AuthModeNone
+	AuthMode string
+	// TLSCertFile and TLSKeyFile enable TLS when both are set
+	TLSCertFile string
+	TLSKeyFile  string
+
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+	// ShutdownDelay is the time the readiness fails before draining on shutdown
+	ShutdownDelay time.Duration
+	// RequestTimeout is the deadline of each request, 0 disables it
+	RequestTimeout time.Duration
+	// MaxBodyBytes is the maximum size of a request body, 1 MiB by default
+	MaxBodyBytes int64
+	// StrictJSON rejects the json bodies with unknown fields or trailing data
+	StrictJSON bool
+
+	// CORS is the configuration of the cross-origin requests, disabled without allowed origins
+	CORS middlewareLog.CORSConfig
+
+	// LogSampleRate is the fraction, between 0 and 1, of successful requests logged, 0 logging none of them.
+	// nil defaults to 1.
+	LogSampleRate *float64
+
+	// RateLimitRead limits the GET routes of products per client ip, and RateLimitWrite the write routes
+	// per api token or client ip. A zero limit disables the rate limiting of its routes.
+	RateLimitRead  ratelimit.Limit
+	RateLimitWrite ratelimit.Limit
+	// TrustedProxies are the ips or CIDRs of the proxies whose forwarding headers are trusted
+	TrustedProxies []string
+
+	// ListCacheControl and ItemCacheControl are the Cache-Control headers of GET /products and
+	// GET /products/{id}, none if empty
+	ListCacheControl string
+	ItemCacheControl string
+	// ResponseCacheEntries is the size of the server-side cache of the catalogue reads, 0 disables it
+	ResponseCacheEntries int
+
+	// IdempotencyTTL is how long the response of an Idempotency-Key is replayed, 24 hours by default
+	IdempotencyTTL time.Duration
+	// IdempotencyFile persists the idempotency keys, kept in memory if empty
+	IdempotencyFile string
+
+	// GraphQLMaxDepth and GraphQLMaxComplexity limit the graphql queries, 8 and 1000 by default
+	GraphQLMaxDepth      int
+	GraphQLMaxComplexity int
+
+	// EventsLogSize is the number of catalogue events kept for the streams to resume from, 1024 by default
+	EventsLogSize int
+	// EventsHeartbeat is the interval of the heartbeats of the idle event streams, 15 seconds by default
+	EventsHeartbeat time.Duration
+
+	// WebhooksFile persists the webhooks and their outbox, kept in memory if empty
+	WebhooksFile string
+	// Webhooks configures the deliveries of the webhooks
+	Webhooks webhook.Config
+	// WebhookRetention bounds the finished deliveries kept per webhook, unbounded if zero
+	WebhookRetention webhook.Retention
+
+	// SnapshotsDir keeps the snapshots of the catalogue, kept in memory if empty
+	SnapshotsDir string
+	// SnapshotRetention bounds the snapshots kept, unbounded if zero
+	SnapshotRetention snapshot.Retention
+
+	// PricesFile persists the price history and the scheduled prices, kept in memory if empty
+	PricesFile string
+
+	// CategoriesFile persists the categories and the categories of the products, kept in memory if empty
+	CategoriesFile string
+
+	// InventoryFile persists the stores, their stock and the transfers, kept in memory if empty
+	InventoryFile string
+
+	// TracingExporter is config.TracingExporterNone (default), config.TracingExporterStdout or
+	// config.TracingExporterOTLP
+	TracingExporter string
+	// TracingEndpoint is the OTLP/HTTP traces endpoint, used by the config.TracingExporterOTLP exporter
+	TracingEndpoint string
+	// TracingServiceName identifies the server in the traces, "supermarket" by default
+	TracingServiceName string
 }
 
 func NewServer(config ServerConfig) *Server {
@@ -39,59 +239,499 @@ func NewServer(config ServerConfig) *Server {
 	if config.DbFile == "" {
 		config.DbFile = "docs/db/products.json"
 	}
+	if config.StorageBackend == "" {
+		config.StorageBackend = appConfig.StorageBackendJSON
+	}
+	if config.AuthMode == "" {
+		config.AuthMode = appConfig.AuthModeToken
+	}
+	if config.ReadTimeout == 0 {
+		config.ReadTimeout = 5 * time.Second
+	}
+	if config.WriteTimeout == 0 {
+		config.WriteTimeout = 10 * time.Second
+	}
+	if config.IdleTimeout == 0 {
+		config.IdleTimeout = 60 * time.Second
+	}
+	if config.ShutdownTimeout == 0 {
+		config.ShutdownTimeout = 15 * time.Second
+	}
+	if config.MaxBodyBytes == 0 {
+		config.MaxBodyBytes = 1 << 20
+	}
+	if len(config.CORS.ExposedHeaders) == 0 {
+		config.CORS.ExposedHeaders = []string{middlewareLog.RequestIDHeader, "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", "ETag", middlewareLog.IdempotentReplayedHeader}
+	}
+	if config.IdempotencyTTL == 0 {
+		config.IdempotencyTTL = 24 * time.Hour
+	}
+	logSampleRate := 1.0
+	if config.LogSampleRate != nil {
+		logSampleRate = *config.LogSampleRate
+	}
+	if config.TracingExporter == "" {
+		config.TracingExporter = appConfig.TracingExporterNone
+	}
+	if config.TracingServiceName == "" {
+		config.TracingServiceName = "supermarket"
+	}
 
 	return &Server{
-		host:   config.Host,
-		port:   config.Port,
-		dbFile: config.DbFile,
-		token:  config.Token,
+		host:            config.Host,
+		port:            config.Port,
+		grpcPort:        config.GRPCPort,
+		dbFile:          config.DbFile,
+		token:           config.Token,
+		storageBackend:  config.StorageBackend,
+		authMode:        config.AuthMode,
+		tlsCertFile:     config.TLSCertFile,
+		tlsKeyFile:      config.TLSKeyFile,
+		readTimeout:     config.ReadTimeout,
+		writeTimeout:    config.WriteTimeout,
+		idleTimeout:     config.IdleTimeout,
+		shutdownTimeout: config.ShutdownTimeout,
+		shutdownDelay:   config.ShutdownDelay,
+		requestTimeout:  config.RequestTimeout,
+		maxBodyBytes:    config.MaxBodyBytes,
+		strictJSON:      config.StrictJSON,
+		cors:            config.CORS,
+		logSampleRate:   logSampleRate,
+
+		rateLimitRead:  config.RateLimitRead,
+		rateLimitWrite: config.RateLimitWrite,
+		trustedProxies: config.TrustedProxies,
+
+		listCacheControl:     config.ListCacheControl,
+		itemCacheControl:     config.ItemCacheControl,
+		responseCacheEntries: config.ResponseCacheEntries,
+
+		idempotencyTTL:  config.IdempotencyTTL,
+		idempotencyFile: config.IdempotencyFile,
+
+		graphQLLimits: graph.Limits{MaxDepth: config.GraphQLMaxDepth, MaxComplexity: config.GraphQLMaxComplexity},
+
+		eventsLogSize:   config.EventsLogSize,
+		eventsHeartbeat: config.EventsHeartbeat,
+
+		webhooksFile:     config.WebhooksFile,
+		webhooks:         config.Webhooks,
+		webhookRetention: config.WebhookRetention,
+
+		snapshotsDir:      config.SnapshotsDir,
+		snapshotRetention: config.SnapshotRetention,
+
+		pricesFile: config.PricesFile,
+
+		categoriesFile: config.CategoriesFile,
+
+		inventoryFile: config.InventoryFile,
+
+		tracingExporter:    config.TracingExporter,
+		tracingEndpoint:    config.TracingEndpoint,
+		tracingServiceName: config.TracingServiceName,
 	}
 }
 
-func (s *Server) Start() error {
+// SetUp builds the dependencies and the routes of the server.
+func (s *Server) SetUp() error {
 	// - dependencies
+	// -- metrics
+	reg := metrics.NewRegistry()
+	mtMd := middlewareLog.NewMetrics(reg)
+
+	// -- health checks
+	s.health = health.NewRegistry(0)
+
 	// -- authenticator
-	au := auth.NewAuthTokenBasic(s.token)
+	var au auth.AuthToken
+	switch s.authMode {
+	case appConfig.AuthModeToken:
+		au = auth.NewAuthTokenBasic(s.token)
+	case appConfig.AuthModeNone:
+		au = auth.NewAuthNone()
+	default:
+		return fmt.Errorf("unknown auth mode %q", s.authMode)
+	}
+	au = auth.NewAuthTokenInstrumented(au, reg)
 	auMiddleware := middleware.NewAuthenticator(au)
 
+	// -- rate limiters, sharing a store
+	clientIP, err := middlewareLog.NewClientIP(s.trustedProxies)
+	if err != nil {
+		return err
+	}
+	rlStore := ratelimit.NewMemoryStore()
+	readLimit := s.rateLimit(rlStore, "read", s.rateLimitRead, middlewareLog.KeyByIP(clientIP))
+	writeLimit := s.rateLimit(rlStore, "write", s.rateLimitWrite, middlewareLog.KeyByToken(clientIP, func(token string) bool {
+		return au.Auth(token) == nil
+	}))
+
+	// -- idempotency keys
+	var idStore idempotency.Store = idempotency.NewMemoryStore()
+	if s.idempotencyFile != "" {
+		if idStore, err = idempotency.NewFileStore(s.idempotencyFile); err != nil {
+			return err
+		}
+	}
+	idMd := middlewareLog.NewIdempotency(idStore, s.idempotencyTTL)
+
 	// -- logger
-	lgMd := middlewareLog.NewLogger()
+	lgMd := middlewareLog.NewLogger(slog.Default(), s.logSampleRate)
+
+	// -- tracer
+	var exporter tracing.Exporter
+	switch s.tracingExporter {
+	case appConfig.TracingExporterNone:
+	case appConfig.TracingExporterStdout:
+		exporter = tracing.NewStdoutExporter(os.Stdout)
+	case appConfig.TracingExporterOTLP:
+		exporter = tracing.NewOTLPExporter(s.tracingEndpoint, s.tracingServiceName, nil)
+	default:
+		return fmt.Errorf("unknown tracing exporter %q", s.tracingExporter)
+	}
+	if exporter != nil {
+		processor := tracing.NewBatchProcessor(exporter, 0, 0)
+		tracing.SetDefault(tracing.NewTracer(processor))
+		s.flushSpans = processor.Shutdown
+	}
 
 	// create Repository
-	storage := storage.NewProductStorage(s.dbFile)
-	repository := repository.NewProductRepository(storage)
+	var st internalProduct.ProductStorageInterface
+	switch s.storageBackend {
+	case appConfig.StorageBackendJSON:
+		jsonStorage := storage.NewProductStorage(s.dbFile)
+		s.flush = jsonStorage.Flush
+		s.health.Register("storage", true, jsonStorage.HealthCheck)
+		s.health.Register("storage_writable", false, jsonStorage.WritableCheck)
+		st = jsonStorage
+	case appConfig.StorageBackendMemory:
+		// seed from the JSON file when it exists
+		seed, err := storage.NewProductStorage(s.dbFile).LoadProducts(context.Background())
+		if err != nil && !errors.Is(err, internalProduct.ErrFileNotFound) {
+			return err
+		}
+		st = storage.NewProductStorageMemory(seed)
+	default:
+		return fmt.Errorf("unknown storage backend %q", s.storageBackend)
+	}
+	st = storage.NewProductStorageInstrumented(st, reg)
+
+	// -- the catalogue: the service and the stores following its writes, as built by the cli. The version of
+	// the catalogue is bumped by every write, the scheduled prices included, and change of the categories,
+	// invalidating the cached reads
+	catalogVersion := httpcache.NewVersion()
+	cat, err := catalog.New(context.Background(), st, catalog.Config{
+		PricesFile:       s.pricesFile,
+		WebhooksFile:     s.webhooksFile,
+		CategoriesFile:   s.categoriesFile,
+		InventoryFile:    s.inventoryFile,
+		Webhooks:         s.webhooks,
+		WebhookRetention: s.webhookRetention,
+		EventsLogSize:    s.eventsLogSize,
+		Repository: func(repo internalProduct.ProductRepositoryInterface) internalProduct.ProductRepositoryInterface {
+			return repository.NewProductRepositoryVersioned(repository.NewProductRepositoryInstrumented(repo, reg), catalogVersion)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	s.prices, s.events, s.dispatcher = cat.Prices, cat.Events, cat.Dispatcher
+	service, categories, stores, whStore := cat.Service, cat.Categories, cat.Inventory, cat.Webhooks
+	categories.OnChange(catalogVersion.Bump)
+	listCache := middlewareLog.NewConditional(catalogVersion, s.listCacheControl).Handle
+	itemCache := middlewareLog.NewConditional(catalogVersion, s.itemCacheControl).Handle
+	responseCache := func(handler http.Handler) http.Handler { return handler }
+	if s.responseCacheEntries > 0 {
+		cache := httpcache.NewCache(s.responseCacheEntries)
+		catalogVersion.OnChange(cache.Clear)
+		responseCache = middlewareLog.NewResponseCache(cache, catalogVersion).Handle
+	}
 
-	// create service and handler
-	service := service.NewProductService(repository)
+	// -- snapshots of the storage, a restore bumping the version of the catalogue and writing the stock back
+	var snStore snapshot.Store = snapshot.NewMemoryStore()
+	if s.snapshotsDir != "" {
+		if snStore, err = snapshot.NewDirStore(s.snapshotsDir); err != nil {
+			return err
+		}
+	}
+	snapshots := snapshot.NewManager(st, snStore, s.snapshotRetention)
+	snapshots.WriteLock = cat.WriteLock
+	snapshots.OnRestore(catalogVersion.Bump)
+	snapshots.OnRestore(func() {
+		// the quantity of the restored products kept by the stores is written back to their stock
+		if err := stores.WriteQuantities(context.Background()); err != nil {
+			slog.Error("write quantities", slog.Any("error", err))
+		}
+	})
+	snHandler := snapshotHandler.NewSnapshotHandler(snapshots)
+	snHandler.StrictJSON = s.strictJSON
+
+	ctHandler := categoryHandler.NewCategoryHandler(categories, service)
+	ctHandler.StrictJSON = s.strictJSON
+	invHandler := inventoryHandler.NewInventoryHandler(stores)
+	invHandler.StrictJSON = s.strictJSON
+	whHandler := webhookHandler.NewWebhookHandler(whStore, s.dispatcher)
+	whHandler.StrictJSON = s.strictJSON
 	handler := handler.NewProductHandler(service)
+	handler.Categories = categories
+	handler.Stores = stores
+	handler.StrictJSON = s.strictJSON
+	prHandler := pricingHandler.NewPriceHandler(s.prices, service)
+	prHandler.StrictJSON = s.strictJSON
+
+	// grpc server, over the same service
+	var grpcOpts []grpc.ServerOption
+	if s.tlsCertFile != "" && s.tlsKeyFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(s.tlsCertFile, s.tlsKeyFile)
+		if err != nil {
+			return err
+		}
+		grpcOpts = append(grpcOpts, grpc.Creds(creds))
+	}
+	s.grpcServer = rpc.NewServer(service, au, s.requestTimeout, grpcOpts...)
+
+	// graphql handler, over the same service
+	graphHandler, err := graph.NewHandler(service, au, s.graphQLLimits)
+	if err != nil {
+		return err
+	}
+	graphHandler.MutationLimit = writeLimit
 
 	// router
 	router := chi.NewRouter()
 
 	// - middlewares
+	// -- tracing, first so the logs carry the trace id
+	router.Use(middlewareLog.Trace)
 	// -- logger
 	router.Use(lgMd.Log)
+	// -- metrics
+	router.Use(mtMd.Instrument)
+	// -- security headers and cross-origin requests, preflights are answered before routing
+	router.Use(middlewareLog.SecurityHeaders)
+	router.Use(middlewareLog.NewCORS(s.cors).Handler)
+	// -- compression, negotiated from the Accept-Encoding header
+	router.Use(middlewareLog.NewCompressor(0).Compress)
+	// -- deadline
+	router.Use(middlewareLog.NewDeadline(s.requestTimeout, "/products/events").Timeout)
+	// -- body size
+	router.Use(middlewareLog.NewBodyLimit(s.maxBodyBytes).Limit)
 
 	// - routes
 	router.Get("/ping", handler.GetPingHandler)
+	router.Get("/livez", s.health.LivenessHandler)
+	router.Get("/readyz", s.health.ReadinessHandler)
+	router.Method(http.MethodGet, "/metrics", reg.Handler())
+	router.Get("/openapi.json", docs.SpecHandler(api.Spec))
+	swaggerUI := docs.UIHandler("/docs/", "/openapi.json")
+	router.Handle("/docs", swaggerUI)
+	router.Handle("/docs/*", swaggerUI)
+
+	// graphql, queries by GET or POST and mutations by POST authenticated by the handler, which applies the
+	// write limit to the mutations
+	router.With(readLimit).Method(http.MethodGet, "/graphql", graphHandler)
+	router.With(readLimit).Method(http.MethodPost, "/graphql", graphHandler)
 
 	router.Route("/products", func(router chi.Router) {
-		router.Get("/", handler.GetProductsHandler)
-		router.Get("/{id}", handler.GetProductHandler)
-		router.Get("/search", handler.SearchProductsByPriceHandler)
-		router.Get("/consumer_price", handler.GetConsumerPriceHandler)
-
-		// subrouter with auth middleware
-		router.With(auMiddleware.Auth).Group(func(router chi.Router) {
-			router.Post("/", handler.CreateProductHandler)
+		// subrouter with the read rate limit
+		router.With(readLimit).Group(func(router chi.Router) {
+			router.With(listCache, responseCache).Get("/", handler.GetProductsHandler)
+			router.With(itemCache, responseCache).Get("/{id}", handler.GetProductHandler)
+			router.Get("/search", handler.SearchProductsByPriceHandler)
+			router.Get("/consumer_price", handler.GetConsumerPriceHandler)
+			router.Get("/{id}/prices", prHandler.GetPricesHandler)
+			router.Get("/{id}/categories", ctHandler.GetProductCategoriesHandler)
+			router.Method(http.MethodGet, "/events", events.NewStreamHandler(s.events, s.eventsHeartbeat))
+		})
+
+		// subrouter with the write rate limit and auth middleware
+		router.With(writeLimit, auMiddleware.Auth).Group(func(router chi.Router) {
+			router.With(idMd.Handle).Post("/", handler.CreateProductHandler)
 			router.Patch("/{id}", handler.UpdateProductHandler)
 			router.Delete("/{id}", handler.DeleteProductHandler)
 			router.Put("/{id}", handler.UpdateOrCreateProductHandler)
+			router.Post("/{id}/prices", prHandler.SchedulePriceHandler)
+			router.Delete("/{id}/prices/{entryId}", prHandler.CancelPriceHandler)
+			router.Put("/{id}/categories", ctHandler.AssignProductCategoriesHandler)
+		})
+	})
+
+	// categories, read as the products and written with the api token
+	router.Route("/categories", func(router chi.Router) {
+		router.With(readLimit).Group(func(router chi.Router) {
+			router.Get("/", ctHandler.GetCategoriesHandler)
+			router.Get("/{id}", ctHandler.GetCategoryHandler)
+			router.Get("/{id}/products", ctHandler.GetCategoryProductsHandler)
 		})
+		router.With(writeLimit, auMiddleware.Auth).Group(func(router chi.Router) {
+			router.Post("/", ctHandler.CreateCategoryHandler)
+			router.Put("/{id}", ctHandler.UpdateCategoryHandler)
+			router.Delete("/{id}", ctHandler.DeleteCategoryHandler)
+		})
+	})
+
+	// stores and their stock, read as the products and written with the api token
+	router.Route("/stores", func(router chi.Router) {
+		router.With(readLimit).Group(func(router chi.Router) {
+			router.Get("/", invHandler.GetStoresHandler)
+			router.Get("/{id}", invHandler.GetStoreHandler)
+			router.Get("/{id}/stock", invHandler.GetStockHandler)
+		})
+		router.With(writeLimit, auMiddleware.Auth).Group(func(router chi.Router) {
+			router.Post("/", invHandler.CreateStoreHandler)
+			router.Put("/{id}", invHandler.UpdateStoreHandler)
+			router.Delete("/{id}", invHandler.DeleteStoreHandler)
+			router.Put("/{id}/stock/{productId}", invHandler.SetStockHandler)
+		})
+	})
+	router.With(readLimit).Get("/inventory", invHandler.GetInventoryHandler)
+
+	// transfers of stock between stores, audited with the actor of the api token
+	router.Route("/transfers", func(router chi.Router) {
+		router.Use(writeLimit, auMiddleware.Auth)
+		router.Post("/", invHandler.CreateTransferHandler)
+		router.Get("/", invHandler.GetTransfersHandler)
+	})
+
+	// webhooks, administered with the api token
+	router.Route("/webhooks", func(router chi.Router) {
+		router.Use(writeLimit, auMiddleware.Auth)
+		router.Post("/", whHandler.CreateSubscriptionHandler)
+		router.Get("/", whHandler.GetSubscriptionsHandler)
+		router.Get("/{id}", whHandler.GetSubscriptionHandler)
+		router.Delete("/{id}", whHandler.DeleteSubscriptionHandler)
+		router.Get("/{id}/deliveries", whHandler.GetDeliveriesHandler)
+		router.Post("/{id}/deliveries/{deliveryId}/redeliver", whHandler.RedeliverHandler)
+	})
+
+	// snapshots of the catalogue, administered with the api token
+	router.Route("/admin/snapshots", func(router chi.Router) {
+		router.Use(writeLimit, auMiddleware.Auth)
+		router.Post("/", snHandler.CreateSnapshotHandler)
+		router.Get("/", snHandler.GetSnapshotsHandler)
+		router.Get("/{name}", snHandler.DownloadSnapshotHandler)
+		router.Post("/{name}/restore", snHandler.RestoreSnapshotHandler)
+	})
+
+	s.router = router
+	return nil
+}
+
+// stopGRPC drains the in-flight calls of the grpc server, cancelling them once ctx is done
+func (s *Server) stopGRPC(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+		return fmt.Errorf("%w: grpc: %v", lifecycle.ErrServerShutdown, ctx.Err())
+	}
+}
+
+// runDispatcher runs the dispatcher of the webhooks in the background, and returns the hook stopping it
+func (s *Server) runDispatcher() lifecycle.Hook {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		s.dispatcher.Run(ctx)
+		close(stopped)
+	}()
+	return func(shutdownCtx context.Context) error {
+		cancel()
+		select {
+		case <-stopped:
+			return nil
+		case <-shutdownCtx.Done():
+			return fmt.Errorf("%w: webhooks: %v", lifecycle.ErrServerShutdown, shutdownCtx.Err())
+		}
+	}
+}
+
+// rateLimit returns the middleware limiting requests to limit per key, or a no-op middleware for a zero limit.
+func (s *Server) rateLimit(store ratelimit.Store, group string, limit ratelimit.Limit, key middlewareLog.KeyFunc) func(http.Handler) http.Handler {
+	if limit == (ratelimit.Limit{}) {
+		return func(handler http.Handler) http.Handler { return handler }
+	}
+	return middlewareLog.NewRateLimiter(store, group, limit, key).Limit
+}
+
+// Router returns the router of the server, nil until SetUp is called.
+func (s *Server) Router() http.Handler {
+	return s.router
+}
+
+// GRPCServer returns the grpc server of the ProductService, nil until SetUp is called.
+func (s *Server) GRPCServer() *grpc.Server {
+	return s.grpcServer
+}
+
+// Start sets up the server and serves until a shutdown signal is received.
+// In-flight requests are drained and pending storage writes are flushed before returning.
+func (s *Server) Start() error {
+	if s.router == nil {
+		if err := s.SetUp(); err != nil {
+			return err
+		}
+	}
+
+	lc := lifecycle.NewServer(lifecycle.Config{
+		Addr:            s.host + ":" + s.port,
+		ReadTimeout:     s.readTimeout,
+		WriteTimeout:    s.writeTimeout,
+		IdleTimeout:     s.idleTimeout,
+		ShutdownTimeout: s.shutdownTimeout,
+		ShutdownDelay:   s.shutdownDelay,
+		CertFile:        s.tlsCertFile,
+		KeyFile:         s.tlsKeyFile,
+	}, s.router)
+	lc.BeforeShutdown(func(ctx context.Context) error {
+		s.health.SetShuttingDown()
+		return nil
+	})
+	// the event streams never go idle, they end before draining
+	lc.BeforeShutdown(s.events.Close)
+	// the webhooks are delivered until the requests are drained, the pending ones on the next start
+	lc.OnShutdown(s.runDispatcher())
+	if s.grpcPort != "" {
+		// listen first so that errors like an address in use are reported to the caller
+		ln, err := net.Listen("tcp", s.host+":"+s.grpcPort)
+		if err != nil {
+			return fmt.Errorf("%w: %v", lifecycle.ErrServerStart, err)
+		}
+		go func() {
+			if err := s.grpcServer.Serve(ln); err != nil {
+				slog.Error("grpc server", slog.Any("error", err))
+			}
+		}()
+		lc.OnShutdown(s.stopGRPC)
+		slog.Info("grpc server started", slog.String("addr", ln.Addr().String()))
+	}
+	if s.flush != nil {
+		lc.OnShutdown(s.flush)
+	}
+	if s.flushSpans != nil {
+		lc.OnShutdown(s.flushSpans)
+	}
+	lc.OnShutdown(func(ctx context.Context) error {
+		s.prices.Close()
+		return nil
 	})
 
 	// start server
-	fmt.Printf("Server started on %s:%s\n", s.host, s.port)
-	http.ListenAndServe(s.host+":"+s.port, router)
+	scheme := "http"
+	if s.tlsCertFile != "" && s.tlsKeyFile != "" {
+		scheme = "https"
+	}
+	slog.Info("server started", slog.String("addr", fmt.Sprintf("%s://%s:%s", scheme, s.host, s.port)))
+	if err := lc.Run(); err != nil {
+		return err
+	}
+	slog.Info("server stopped")
 	return nil
 }
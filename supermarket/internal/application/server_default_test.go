@@ -0,0 +1,508 @@
+package application_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"supermarket/api"
+	"supermarket/internal/application"
+	"supermarket/internal/config"
+	"supermarket/internal/platform/ratelimit"
+	"supermarket/internal/product/rpc"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newServer sets up a server on an empty in-memory catalogue
+func newServer(t *testing.T) *application.Server {
+	t.Helper()
+	server := application.NewServer(application.ServerConfig{
+		StorageBackend: config.StorageBackendMemory,
+		DbFile:         filepath.Join(t.TempDir(), "products.json"),
+		AuthMode:       config.AuthModeNone,
+	})
+	require.NoError(t, server.SetUp())
+	return server
+}
+
+// newRouter sets up a server on an empty in-memory catalogue and returns its router
+func newRouter(t *testing.T) *chi.Mux {
+	t.Helper()
+	router, ok := newServer(t).Router().(*chi.Mux)
+	require.True(t, ok)
+	return router
+}
+
+// TestOpenAPISpec tests that the OpenAPI document describes the routes of the server.
+func TestOpenAPISpec(t *testing.T) {
+	t.Run("success - every registered route is in the spec", func(t *testing.T) {
+		// arrange
+		var spec struct {
+			OpenAPI string                                `json:"openapi"`
+			Paths   map[string]map[string]json.RawMessage `json:"paths"`
+		}
+		require.NoError(t, json.Unmarshal(api.Spec, &spec))
+		router := newRouter(t)
+
+		// act
+		var missing []string
+		err := chi.Walk(router, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+			// the Swagger UI is not part of the api
+			if route == "/docs" || strings.HasPrefix(route, "/docs/") {
+				return nil
+			}
+			if route != "/" {
+				route = strings.TrimSuffix(route, "/")
+			}
+			if _, ok := spec.Paths[route][strings.ToLower(method)]; !ok {
+				missing = append(missing, method+" "+route)
+			}
+			return nil
+		})
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, "3.1.0", spec.OpenAPI)
+		require.Empty(t, missing, "routes missing from api/openapi.json")
+	})
+
+	t.Run("success - spec and swagger ui served", func(t *testing.T) {
+		// arrange
+		router := newRouter(t)
+
+		// act
+		get := func(path string) *httptest.ResponseRecorder {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+			return rr
+		}
+		spec := get("/openapi.json")
+		redirect := get("/docs")
+		index := get("/docs/")
+		initializer := get("/docs/swagger-initializer.js")
+
+		// assert
+		require.Equal(t, http.StatusOK, spec.Code)
+		require.JSONEq(t, string(api.Spec), spec.Body.String())
+		require.Equal(t, http.StatusMovedPermanently, redirect.Code)
+		require.Equal(t, "/docs/", redirect.Header().Get("Location"))
+		require.Equal(t, http.StatusOK, index.Code)
+		require.Contains(t, index.Body.String(), "swagger-ui")
+		require.Contains(t, index.Header().Get("Content-Security-Policy"), "default-src 'self'")
+		require.Contains(t, initializer.Body.String(), `url: "/openapi.json"`)
+	})
+}
+
+// TestGRPCServer tests that the grpc api serves the catalogue of the http api.
+func TestGRPCServer(t *testing.T) {
+	t.Run("success - product created over grpc is served over http", func(t *testing.T) {
+		// arrange
+		server := newServer(t)
+		ln := bufconn.Listen(1 << 20)
+		go server.GRPCServer().Serve(ln)
+		t.Cleanup(server.GRPCServer().Stop)
+		conn, err := grpc.NewClient("passthrough:///bufconn",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		require.NoError(t, err)
+		defer conn.Close()
+		client := rpc.NewProductServiceClient(conn)
+
+		// act
+		created, err := client.CreateProduct(context.Background(), &rpc.CreateProductRequest{Product: &rpc.ProductFields{
+			Name: "Milk", Quantity: 10, CodeValue: "M001", Expiration: "01/01/2030", Price: 1.5,
+		}})
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/products/"+strconv.FormatInt(created.Id, 10), nil))
+
+		// assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Contains(t, rr.Body.String(), `"code_value":"M001"`)
+	})
+}
+
+// TestGraphQL tests that the graphql api serves the catalogue of the http api.
+func TestGraphQL(t *testing.T) {
+	t.Run("success - product created over graphql is served over http", func(t *testing.T) {
+		// arrange
+		router := newRouter(t)
+		body := `{"query": "mutation { createProduct(input: {name: \"Milk\", quantity: 10, codeValue: \"M001\", expiration: \"01/01/2030\", price: 1.5}) { id } }"}`
+
+		// act
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var res struct {
+			Data struct {
+				CreateProduct struct {
+					ID int `json:"id"`
+				} `json:"createProduct"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		get := httptest.NewRecorder()
+		router.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/products/"+strconv.Itoa(res.Data.CreateProduct.ID), nil))
+
+		// assert
+		require.Equal(t, http.StatusOK, get.Code)
+		require.Contains(t, get.Body.String(), `"code_value":"M001"`)
+	})
+
+	t.Run("failure - mutations count against the write rate limit", func(t *testing.T) {
+		// arrange
+		server := application.NewServer(application.ServerConfig{
+			StorageBackend: config.StorageBackendMemory,
+			DbFile:         filepath.Join(t.TempDir(), "products.json"),
+			AuthMode:       config.AuthModeNone,
+			RateLimitRead:  ratelimit.Limit{Rate: 100, Burst: 100},
+			RateLimitWrite: ratelimit.Limit{Rate: 0.001, Burst: 1},
+		})
+		require.NoError(t, server.SetUp())
+		send := func(query string) int {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query": "`+query+`"}`))
+			req.Header.Set("Content-Type", "application/json")
+			server.Router().ServeHTTP(rr, req)
+			return rr.Code
+		}
+
+		// act
+		first := send(`mutation { deleteProduct(id: 1) }`)
+		second := send(`mutation { deleteProduct(id: 1) }`)
+		query := send(`{ products { totalCount } }`)
+
+		// assert
+		require.Equal(t, http.StatusOK, first)
+		require.Equal(t, http.StatusTooManyRequests, second)
+		require.Equal(t, http.StatusOK, query)
+	})
+}
+
+// TestProductEvents tests that the writes of the catalogue are streamed as server-sent events.
+func TestProductEvents(t *testing.T) {
+	t.Run("success - create and stock change streamed", func(t *testing.T) {
+		// arrange
+		server := httptest.NewServer(newRouter(t))
+		defer server.Close()
+		stream, err := http.Get(server.URL + "/products/events")
+		require.NoError(t, err)
+		defer stream.Body.Close()
+		send := func(method, path, body string) {
+			req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			res, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			res.Body.Close()
+			require.Less(t, res.StatusCode, 300)
+		}
+
+		// act
+		send(http.MethodPost, "/products", `{"name":"Milk","quantity":10,"code_value":"M001","expiration":"01/01/2030","price":1.5}`)
+		send(http.MethodPatch, "/products/1", `{"quantity":4}`)
+		var types []string
+		scanner := bufio.NewScanner(stream.Body)
+		for len(types) < 3 && scanner.Scan() {
+			if eventType, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+				types = append(types, eventType)
+			}
+		}
+
+		// assert
+		require.Equal(t, []string{"product.created", "product.updated", "product.stock_changed"}, types)
+	})
+}
+
+// TestSnapshots tests that a restored snapshot is served, the cached reads being invalidated.
+func TestSnapshots(t *testing.T) {
+	t.Run("success - restore invalidates the cached catalogue", func(t *testing.T) {
+		// arrange
+		server := application.NewServer(application.ServerConfig{
+			StorageBackend:       config.StorageBackendMemory,
+			DbFile:               filepath.Join(t.TempDir(), "products.json"),
+			AuthMode:             config.AuthModeNone,
+			ResponseCacheEntries: 16,
+		})
+		require.NoError(t, server.SetUp())
+		router := server.Router()
+		send := func(method, path, body string) *httptest.ResponseRecorder {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(method, path, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(rr, req)
+			return rr
+		}
+		require.Equal(t, http.StatusCreated, send(http.MethodPost, "/products", `{"name":"Milk","quantity":10,"code_value":"M001","expiration":"01/01/2030","price":1.5}`).Code)
+		require.Equal(t, http.StatusCreated, send(http.MethodPost, "/admin/snapshots", `{"name":"before-delete"}`).Code)
+		require.Equal(t, http.StatusOK, send(http.MethodDelete, "/products/1", "").Code)
+		require.NotContains(t, send(http.MethodGet, "/products", "").Body.String(), "M001")
+
+		// act
+		restore := send(http.MethodPost, "/admin/snapshots/before-delete/restore", "")
+		list := send(http.MethodGet, "/products", "")
+
+		// assert
+		require.Equal(t, http.StatusOK, restore.Code, restore.Body.String())
+		require.Contains(t, list.Body.String(), "M001")
+		download := send(http.MethodGet, "/admin/snapshots/before-delete", "")
+		require.Equal(t, http.StatusOK, download.Code)
+		require.Contains(t, download.Header().Get("Content-Disposition"), "before-delete.json")
+	})
+}
+
+// TestPrices tests that the price changes are recorded, and the scheduled prices served once effective.
+func TestPrices(t *testing.T) {
+	t.Run("success - scheduled price takes effect for the cached reads and the search", func(t *testing.T) {
+		// arrange
+		server := application.NewServer(application.ServerConfig{
+			StorageBackend:       config.StorageBackendMemory,
+			DbFile:               filepath.Join(t.TempDir(), "products.json"),
+			AuthMode:             config.AuthModeNone,
+			ResponseCacheEntries: 16,
+		})
+		require.NoError(t, server.SetUp())
+		router := server.Router()
+		send := func(method, path, body string) *httptest.ResponseRecorder {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(method, path, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Actor", "marketing")
+			router.ServeHTTP(rr, req)
+			return rr
+		}
+		require.Equal(t, http.StatusCreated, send(http.MethodPost, "/products", `{"name":"Milk","quantity":10,"code_value":"M001","expiration":"01/01/2030","price":1.5}`).Code)
+		require.Equal(t, http.StatusOK, send(http.MethodPatch, "/products/1", `{"price":2}`).Code)
+		require.Contains(t, send(http.MethodGet, "/products", "").Body.String(), `"price":2`)
+
+		// act
+		effectiveAt := time.Now().Add(200 * time.Millisecond).UTC().Format(time.RFC3339Nano)
+		schedule := send(http.MethodPost, "/products/1/prices", `{"price":3,"effective_at":"`+effectiveAt+`"}`)
+
+		// assert
+		require.Equal(t, http.StatusCreated, schedule.Code, schedule.Body.String())
+		require.NotContains(t, send(http.MethodGet, "/products/search?priceGt=2.5", "").Body.String(), "M001")
+		require.Eventually(t, func() bool {
+			return strings.Contains(send(http.MethodGet, "/products", "").Body.String(), `"price":3`)
+		}, 5*time.Second, 20*time.Millisecond)
+		require.Contains(t, send(http.MethodGet, "/products/search?priceGt=2.5", "").Body.String(), "M001")
+
+		var history struct {
+			Data struct {
+				Price   float64 `json:"price"`
+				Entries []struct {
+					Price         float64 `json:"price"`
+					PreviousPrice float64 `json:"previous_price"`
+					Actor         string  `json:"actor"`
+				} `json:"entries"`
+			} `json:"data"`
+		}
+		prices := send(http.MethodGet, "/products/1/prices", "")
+		require.Equal(t, http.StatusOK, prices.Code)
+		require.NoError(t, json.Unmarshal(prices.Body.Bytes(), &history))
+		require.Equal(t, 3.0, history.Data.Price)
+		// the scheduled price, then its write to the product
+		require.Len(t, history.Data.Entries, 4)
+		require.Equal(t, 1.5, history.Data.Entries[1].PreviousPrice)
+		require.Equal(t, 2.0, history.Data.Entries[1].Price)
+		require.Equal(t, "marketing", history.Data.Entries[2].Actor)
+		require.Equal(t, 2.0, history.Data.Entries[3].PreviousPrice)
+		require.Equal(t, 3.0, history.Data.Entries[3].Price)
+	})
+
+	t.Run("success - a restore after a change of the price keeps the restored price", func(t *testing.T) {
+		// arrange
+		router := newRouter(t)
+		send := func(method, path, body string) *httptest.ResponseRecorder {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(method, path, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(rr, req)
+			return rr
+		}
+		require.Equal(t, http.StatusCreated, send(http.MethodPost, "/products", `{"name":"Milk","quantity":10,"code_value":"M001","expiration":"01/01/2030","price":1.5}`).Code)
+		require.Equal(t, http.StatusCreated, send(http.MethodPost, "/admin/snapshots", `{"name":"before-sale"}`).Code)
+		require.Equal(t, http.StatusOK, send(http.MethodPatch, "/products/1", `{"price":2}`).Code)
+
+		// act
+		restore := send(http.MethodPost, "/admin/snapshots/before-sale/restore", "")
+
+		// assert
+		require.Equal(t, http.StatusOK, restore.Code, restore.Body.String())
+		require.Contains(t, send(http.MethodGet, "/products/1", "").Body.String(), `"price":1.5`)
+	})
+}
+
+// TestCategories tests the browsing of the products by category and the deletion of the categories in use.
+func TestCategories(t *testing.T) {
+	t.Run("success - products filtered by category and its descendants", func(t *testing.T) {
+		// arrange
+		server := application.NewServer(application.ServerConfig{
+			StorageBackend:       config.StorageBackendMemory,
+			DbFile:               filepath.Join(t.TempDir(), "products.json"),
+			AuthMode:             config.AuthModeNone,
+			ResponseCacheEntries: 16,
+		})
+		require.NoError(t, server.SetUp())
+		router := server.Router()
+		send := func(method, path, body string) *httptest.ResponseRecorder {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(method, path, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(rr, req)
+			return rr
+		}
+		require.Equal(t, http.StatusCreated, send(http.MethodPost, "/products", `{"name":"Milk","quantity":10,"code_value":"M001","expiration":"01/01/2030","price":1.5}`).Code)
+		require.Equal(t, http.StatusCreated, send(http.MethodPost, "/products", `{"name":"Water","quantity":10,"code_value":"W001","expiration":"01/01/2030","price":1}`).Code)
+		require.Equal(t, http.StatusCreated, send(http.MethodPost, "/categories", `{"name":"Food"}`).Code)
+		require.Equal(t, http.StatusCreated, send(http.MethodPost, "/categories", `{"name":"Dairy","parent_id":1}`).Code)
+		require.Contains(t, send(http.MethodGet, "/products?category=1", "").Body.String(), `"data":[]`)
+
+		// act
+		assign := send(http.MethodPut, "/products/1/categories", `{"categories":[2]}`)
+		filtered := send(http.MethodGet, "/products?category=1", "")
+		ofFood := send(http.MethodGet, "/categories/1/products", "")
+
+		// assert
+		require.Equal(t, http.StatusOK, assign.Code, assign.Body.String())
+		require.Contains(t, filtered.Body.String(), "M001")
+		require.NotContains(t, filtered.Body.String(), "W001")
+		require.Equal(t, http.StatusOK, ofFood.Code)
+		require.Contains(t, ofFood.Body.String(), "M001")
+		require.NotContains(t, ofFood.Body.String(), "W001")
+		require.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/products?category=dairy", "").Code)
+	})
+
+	t.Run("failure - delete a category with products assigned", func(t *testing.T) {
+		// arrange
+		router := newRouter(t)
+		send := func(method, path, body string) *httptest.ResponseRecorder {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(method, path, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(rr, req)
+			return rr
+		}
+		require.Equal(t, http.StatusCreated, send(http.MethodPost, "/products", `{"name":"Milk","quantity":10,"code_value":"M001","expiration":"01/01/2030","price":1.5}`).Code)
+		require.Equal(t, http.StatusCreated, send(http.MethodPost, "/categories", `{"name":"Dairy"}`).Code)
+		require.Equal(t, http.StatusOK, send(http.MethodPut, "/products/1/categories", `{"categories":[1]}`).Code)
+
+		// act
+		inUse := send(http.MethodDelete, "/categories/1", "")
+		require.Equal(t, http.StatusOK, send(http.MethodDelete, "/products/1", "").Code)
+		unused := send(http.MethodDelete, "/categories/1", "")
+
+		// assert
+		require.Equal(t, http.StatusConflict, inUse.Code)
+		require.Contains(t, inUse.Body.String(), "products assigned")
+		require.Equal(t, http.StatusOK, unused.Code)
+	})
+}
+
+func TestInventory(t *testing.T) {
+	t.Run("success - stock transferred between stores and priced at a store", func(t *testing.T) {
+		// arrange
+		router := newRouter(t)
+		send := func(method, path, body string) *httptest.ResponseRecorder {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(method, path, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Actor", "warehouse")
+			router.ServeHTTP(rr, req)
+			return rr
+		}
+		require.Equal(t, http.StatusCreated, send(http.MethodPost, "/products", `{"name":"Milk","quantity":10,"code_value":"M001","expiration":"01/01/2030","price":1.5}`).Code)
+		require.Equal(t, http.StatusCreated, send(http.MethodPost, "/stores", `{"name":"Center","address":"Main St 1"}`).Code)
+		require.Equal(t, http.StatusCreated, send(http.MethodPost, "/stores", `{"name":"North"}`).Code)
+		require.Equal(t, http.StatusOK, send(http.MethodPut, "/stores/1/stock/1", `{"quantity":5}`).Code)
+
+		// act
+		transfer := send(http.MethodPost, "/transfers", `{"product_id":1,"from_store_id":1,"to_store_id":2,"quantity":2}`)
+		exceeded := send(http.MethodPost, "/transfers", `{"product_id":1,"from_store_id":1,"to_store_id":2,"quantity":4}`)
+		totals := send(http.MethodGet, "/inventory", "")
+		atNorth := send(http.MethodGet, "/products/consumer_price?list=[1,1]&store=2", "")
+		overNorth := send(http.MethodGet, "/products/consumer_price?list=[1,1,1]&store=2", "")
+
+		// assert
+		require.Equal(t, http.StatusCreated, transfer.Code, transfer.Body.String())
+		require.Contains(t, transfer.Body.String(), `"actor":"warehouse"`)
+		require.Equal(t, http.StatusConflict, exceeded.Code)
+		require.Equal(t, http.StatusOK, totals.Code)
+		require.Contains(t, totals.Body.String(), `{"product_id":1,"quantity":5,"stores":{"1":3,"2":2}}`)
+		require.Equal(t, http.StatusOK, atNorth.Code, atNorth.Body.String())
+		require.Equal(t, http.StatusConflict, overNorth.Code)
+		require.Contains(t, send(http.MethodGet, "/transfers?store=2", "").Body.String(), `"quantity":2`)
+		require.Equal(t, http.StatusNotFound, send(http.MethodGet, "/products/consumer_price?list=[1]&store=9", "").Code)
+		require.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/products/consumer_price?list=[1]&store=north", "").Code)
+	})
+
+	t.Run("failure - delete a store with stock", func(t *testing.T) {
+		// arrange
+		router := newRouter(t)
+		send := func(method, path, body string) *httptest.ResponseRecorder {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(method, path, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(rr, req)
+			return rr
+		}
+		require.Equal(t, http.StatusCreated, send(http.MethodPost, "/products", `{"name":"Milk","quantity":10,"code_value":"M001","expiration":"01/01/2030","price":1.5}`).Code)
+		require.Equal(t, http.StatusCreated, send(http.MethodPost, "/stores", `{"name":"Center"}`).Code)
+		require.Equal(t, http.StatusOK, send(http.MethodPut, "/stores/1/stock/1", `{"quantity":5}`).Code)
+
+		// act
+		inUse := send(http.MethodDelete, "/stores/1", "")
+		require.Equal(t, http.StatusOK, send(http.MethodDelete, "/products/1", "").Code)
+		empty := send(http.MethodDelete, "/stores/1", "")
+
+		// assert
+		require.Equal(t, http.StatusConflict, inUse.Code)
+		require.Contains(t, inUse.Body.String(), "store has stock")
+		require.Equal(t, http.StatusOK, empty.Code)
+	})
+}
+
+func TestLogSampleRate(t *testing.T) {
+	t.Run("success - a sample rate of 0 logs none of the successful requests", func(t *testing.T) {
+		// arrange
+		var buf bytes.Buffer
+		defer slog.SetDefault(slog.Default())
+		slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+		rate := 0.0
+		server := application.NewServer(application.ServerConfig{
+			StorageBackend: config.StorageBackendMemory,
+			DbFile:         filepath.Join(t.TempDir(), "products.json"),
+			AuthMode:       config.AuthModeNone,
+			LogSampleRate:  &rate,
+		})
+		require.NoError(t, server.SetUp())
+
+		// act
+		ok := httptest.NewRecorder()
+		server.Router().ServeHTTP(ok, httptest.NewRequest(http.MethodGet, "/ping", nil))
+		notFound := httptest.NewRecorder()
+		server.Router().ServeHTTP(notFound, httptest.NewRequest(http.MethodGet, "/products/99", nil))
+
+		// assert
+		require.Equal(t, http.StatusOK, ok.Code)
+		require.NotContains(t, buf.String(), "path=/ping")
+		require.Contains(t, buf.String(), "path=/products/99")
+	})
+}
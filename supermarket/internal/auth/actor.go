@@ -0,0 +1,23 @@
+package auth
+
+import "context"
+
+// ActorHeader is the header naming who makes an authenticated request, recorded with its changes
+const ActorHeader = "X-Actor"
+
+// DefaultActor is the actor of the authenticated requests without ActorHeader
+const DefaultActor = "api"
+
+// actorKey is the key of the actor in a context
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor carried by ctx, empty if none.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"errors"
+	"supermarket/internal/platform/metrics"
+)
+
+// NewAuthTokenInstrumented wraps an authenticator to count its failures by reason
+func NewAuthTokenInstrumented(au AuthToken, reg *metrics.Registry) *AuthInstrumented {
+	return &AuthInstrumented{
+		au: au,
+		failures: reg.NewCounter("supermarket_auth_failures_total",
+			"Number of failed authentications.", "reason"),
+	}
+}
+
+// AuthInstrumented is an authenticator that records metrics
+type AuthInstrumented struct {
+	// au is the wrapped authenticator
+	au AuthToken
+	// failures counts the failed authentications
+	failures *metrics.Counter
+}
+
+// Auth is a method that authenticates with the wrapped authenticator
+func (a *AuthInstrumented) Auth(token string) (err error) {
+	err = a.au.Auth(token)
+	if err == nil {
+		return nil
+	}
+
+	reason := "internal"
+	switch {
+	case errors.Is(err, ErrAuthTokenInvalid):
+		reason = "invalid"
+	case errors.Is(err, ErrAuthTokenNotFound):
+		reason = "not_found"
+	case errors.Is(err, ErrAuthTokenExpired):
+		reason = "expired"
+	}
+	a.failures.Inc(reason)
+	return err
+}
@@ -0,0 +1,42 @@
+package auth_test
+
+import (
+	"strings"
+	"supermarket/internal/auth"
+	"supermarket/internal/platform/metrics"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// expiring is an authenticator failing with an expired token
+type expiring struct{}
+
+func (expiring) Auth(token string) error { return auth.ErrAuthTokenExpired }
+
+// TestAuthInstrumented tests the count of the failed authentications.
+func TestAuthInstrumented(t *testing.T) {
+	t.Run("success - failures counted by reason", func(t *testing.T) {
+		// arrange
+		reg := metrics.NewRegistry()
+		basic := auth.NewAuthTokenInstrumented(auth.NewAuthTokenBasic("secret"), reg)
+		expired := auth.NewAuthTokenInstrumented(expiring{}, reg)
+
+		// act
+		errValid := basic.Auth("secret")
+		errInvalid := basic.Auth("other")
+		basic.Auth("")
+		errExpired := expired.Auth("secret")
+
+		// assert
+		require.NoError(t, errValid)
+		require.ErrorIs(t, errInvalid, auth.ErrAuthTokenInvalid)
+		require.ErrorIs(t, errExpired, auth.ErrAuthTokenExpired)
+		var sb strings.Builder
+		_, err := reg.WriteTo(&sb)
+		require.NoError(t, err)
+		require.Contains(t, sb.String(), `supermarket_auth_failures_total{reason="invalid"} 2`)
+		require.Contains(t, sb.String(), `supermarket_auth_failures_total{reason="expired"} 1`)
+		require.NotContains(t, sb.String(), `reason="internal"`)
+	})
+}
@@ -30,7 +30,13 @@ func (a *Authenticator) Auth(handler http.Handler) http.Handler {
 			return
 		}
 
+		// the actor is declared by the client, the token is shared by all of them
+		actor := r.Header.Get(auth.ActorHeader)
+		if actor == "" {
+			actor = auth.DefaultActor
+		}
+
 		// call
-		handler.ServeHTTP(w, r)
+		handler.ServeHTTP(w, r.WithContext(auth.WithActor(r.Context(), actor)))
 	})
 }
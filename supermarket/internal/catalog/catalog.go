@@ -0,0 +1,244 @@
+// Package catalog wires the product service to the stores following the writes of the catalogue: the
+// history of the prices, the outbox of the webhooks, the assignments of the categories and the stock of the
+// stores. The server and the admin cli build their service with it, so the writes of both keep them consistent.
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"supermarket/internal/category"
+	"supermarket/internal/inventory"
+	"supermarket/internal/platform/logging"
+	internalProduct "supermarket/internal/product"
+	"supermarket/internal/product/events"
+	"supermarket/internal/product/pricing"
+	"supermarket/internal/product/repository"
+	"supermarket/internal/product/service"
+	"supermarket/internal/webhook"
+	"sync"
+)
+
+type Product = internalProduct.Product
+
+// Config are the files of the stores of a catalogue, the stores without file being kept in memory.
+type Config struct {
+	PricesFile     string
+	WebhooksFile   string
+	CategoriesFile string
+	InventoryFile  string
+	// Webhooks configures the delivery of the webhooks
+	Webhooks webhook.Config
+	// WebhookRetention bounds the finished deliveries kept in the outbox
+	WebhookRetention webhook.Retention
+	// EventsLogSize is the number of events kept by the bus
+	EventsLogSize int
+	// Repository wraps the repository of the products, e.g. to instrument it, if not nil
+	Repository func(internalProduct.ProductRepositoryInterface) internalProduct.ProductRepositoryInterface
+}
+
+// New returns the catalogue of the products of storage, with its stores opened as configured.
+func New(ctx context.Context, storage internalProduct.ProductStorageInterface, config Config) (*Catalog, error) {
+	c := &Catalog{storage: storage}
+
+	// prices, the scheduled ones being written to the products once they take effect
+	var prStore pricing.Store = pricing.NewMemoryStore()
+	if config.PricesFile != "" {
+		fileStore, err := pricing.NewFileStore(config.PricesFile)
+		if err != nil {
+			return nil, err
+		}
+		prStore = fileStore
+	}
+	prices, err := pricing.NewPrices(ctx, prStore)
+	if err != nil {
+		return nil, err
+	}
+	c.Prices = prices
+
+	base := repository.NewProductRepository(storage)
+	c.WriteLock = base.Mu
+	var repo internalProduct.ProductRepositoryInterface = base
+	if config.Repository != nil {
+		repo = config.Repository(repo)
+	}
+
+	// the service publishes the events of the catalogue and records the price changes
+	c.Events = events.NewBus(config.EventsLogSize)
+	c.Service = service.NewProductService(repo)
+	c.Service.Events = c.Events
+	c.Service.Prices = prices
+
+	// webhooks, the events being saved to their outbox before the writes return
+	var whStore webhook.Store = webhook.NewMemoryStore(config.WebhookRetention)
+	if config.WebhooksFile != "" {
+		fileStore, err := webhook.NewFileStore(config.WebhooksFile, config.WebhookRetention)
+		if err != nil {
+			prices.Close()
+			return nil, err
+		}
+		whStore = fileStore
+	}
+	c.Webhooks = whStore
+	c.Dispatcher = webhook.NewDispatcher(whStore, config.Webhooks)
+	c.Events.Handle(c.Dispatcher.Publish)
+
+	// categories, their assignments being removed with the products
+	var ctStore category.Store = category.NewMemoryStore()
+	if config.CategoriesFile != "" {
+		fileStore, err := category.NewFileStore(config.CategoriesFile)
+		if err != nil {
+			prices.Close()
+			return nil, err
+		}
+		ctStore = fileStore
+	}
+	c.Categories = category.NewCategories(ctStore, c.Service)
+	c.Events.Handle(c.Categories.Publish)
+
+	// stores and their stock, the stock being removed with the products
+	var invStorage inventory.Storage = inventory.NewMemoryStorage()
+	if config.InventoryFile != "" {
+		fileStorage, err := inventory.NewFileStorage(config.InventoryFile)
+		if err != nil {
+			prices.Close()
+			return nil, err
+		}
+		invStorage = fileStorage
+	}
+	c.Inventory = inventory.NewInventory(invStorage, c.Service)
+	c.Events.Handle(c.Inventory.Publish)
+
+	// the quantity of the products kept by the stores is their stock, written once it changes
+	c.Service.Stock = c.Inventory
+	if err := c.Inventory.WriteThrough(ctx, c.writeQuantity); err != nil {
+		logging.FromContext(ctx).Error("write quantities", slog.Any("error", err))
+	}
+
+	// the scheduled prices are written through the service, as the changes of the api, once the handlers are set
+	if err := prices.WriteThrough(ctx, c.writePrice); err != nil {
+		logging.FromContext(ctx).Error("write scheduled prices", slog.Any("error", err))
+	}
+	return c, nil
+}
+
+// Catalog is the product service with the stores following its writes.
+type Catalog struct {
+	// storage is the storage of the products, written directly by Replace and Renumber
+	storage internalProduct.ProductStorageInterface
+	// WriteLock is the lock of the repository of the service, held by Replace and Renumber and to be held by
+	// the other writers of the storage, e.g. the restores of the snapshots
+	WriteLock sync.Locker
+
+	Service    *service.ProductService
+	Events     *events.Bus
+	Prices     *pricing.Prices
+	Webhooks   webhook.Store
+	Dispatcher *webhook.Dispatcher
+	Categories *category.Categories
+	Inventory  *inventory.Inventory
+}
+
+// writePrice sets the price of the product id through the service, its other fields left as they are
+func (c *Catalog) writePrice(ctx context.Context, id int, price float64) error {
+	_, err := c.Service.SetPrice(ctx, id, price)
+	return err
+}
+
+// writeQuantity sets the quantity of the product id through the service
+func (c *Catalog) writeQuantity(ctx context.Context, id, quantity int) error {
+	_, err := c.Service.SetQuantity(ctx, id, quantity)
+	return err
+}
+
+// Replace replaces the stored products with products, e.g. from a backup, as the writes of the service
+// would: the deleted, created and updated products are published and their price changes recorded. The
+// quantity of the products kept by the stores is then written back to their stock.
+func (c *Catalog) Replace(ctx context.Context, products map[int]Product) error {
+	previous, err := c.replace(ctx, products)
+	if err != nil {
+		return err
+	}
+
+	for id, product := range previous {
+		if _, ok := products[id]; !ok {
+			c.Events.Publish(ctx, internalProduct.Event{Type: internalProduct.EventProductDeleted, Product: product})
+		}
+	}
+	for id, product := range products {
+		old, ok := previous[id]
+		switch {
+		case !ok:
+			c.Events.Publish(ctx, internalProduct.Event{Type: internalProduct.EventProductCreated, Product: product})
+		case old != product:
+			c.Events.Publish(ctx, internalProduct.Event{Type: internalProduct.EventProductUpdated, Product: product})
+			if old.Quantity != product.Quantity {
+				c.Events.Publish(ctx, internalProduct.Event{Type: internalProduct.EventStockChanged, Product: product, PreviousQuantity: &old.Quantity})
+			}
+		}
+		if old.Price != product.Price {
+			if err := c.Prices.RecordPrice(ctx, id, old.Price, product.Price); err != nil {
+				return fmt.Errorf("catalog: record the price of product %d: %w", id, err)
+			}
+		}
+	}
+	return c.Inventory.WriteQuantities(ctx)
+}
+
+// replace implements Replace for the storage, returning the previous products
+func (c *Catalog) replace(ctx context.Context, products map[int]Product) (map[int]Product, error) {
+	c.WriteLock.Lock()
+	defer c.WriteLock.Unlock()
+	previous, err := c.storage.LoadProducts(ctx)
+	if err != nil && !errors.Is(err, internalProduct.ErrFileNotFound) {
+		return nil, err
+	}
+	if err := c.storage.SaveProducts(ctx, products); err != nil {
+		return nil, err
+	}
+	return previous, nil
+}
+
+// Renumber changes the ids of the stored products, ids mapping the old ids to the new ones, and moves
+// their prices, categories and stock along. The new ids must not be taken by the products keeping theirs.
+// No events are published, the products being the same.
+func (c *Catalog) Renumber(ctx context.Context, ids map[int]int) error {
+	if err := c.renumber(ctx, ids); err != nil {
+		return err
+	}
+
+	if err := c.Prices.Renumber(ctx, ids); err != nil {
+		return err
+	}
+	if err := c.Categories.Renumber(ctx, ids); err != nil {
+		return err
+	}
+	return c.Inventory.Renumber(ctx, ids)
+}
+
+// renumber implements Renumber for the storage
+func (c *Catalog) renumber(ctx context.Context, ids map[int]int) error {
+	c.WriteLock.Lock()
+	defer c.WriteLock.Unlock()
+	products, err := c.storage.LoadProducts(ctx)
+	if err != nil {
+		return err
+	}
+	renumbered := make(map[int]Product, len(products))
+	for id, product := range products {
+		if newID, ok := ids[id]; ok {
+			product.Id = newID
+		}
+		if _, ok := renumbered[product.Id]; ok {
+			return fmt.Errorf("catalog: renumber: id %d taken by two products", product.Id)
+		}
+		renumbered[product.Id] = product
+	}
+	return c.storage.SaveProducts(ctx, renumbered)
+}
+
+// Close stops the timer of the scheduled prices.
+func (c *Catalog) Close() {
+	c.Prices.Close()
+}
@@ -0,0 +1,244 @@
+package catalog_test
+
+import (
+	"context"
+	"supermarket/internal/catalog"
+	"supermarket/internal/category"
+	"supermarket/internal/inventory"
+	internalProduct "supermarket/internal/product"
+	"supermarket/internal/product/storage"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newCatalog returns a catalogue in memory with products
+func newCatalog(t *testing.T, products ...catalog.Product) *catalog.Catalog {
+	t.Helper()
+	seed := make(map[int]catalog.Product, len(products))
+	for _, product := range products {
+		seed[product.Id] = product
+	}
+	c, err := catalog.New(context.Background(), storage.NewProductStorageMemory(seed), catalog.Config{})
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+	return c
+}
+
+var (
+	milk  = catalog.Product{Id: 7, Name: "Milk", Quantity: 10, CodeValue: "M1", Expiration: "01/02/2030", Price: 1.5}
+	bread = catalog.Product{Id: 3, Name: "Bread", Quantity: 5, CodeValue: "B1", Expiration: "12/31/2030", Price: 2}
+)
+
+// TestCatalog tests the writes replacing the products.
+func TestCatalog(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success - the writes of the service remove the categories and the stock", func(t *testing.T) {
+		// arrange
+		c := newCatalog(t, milk)
+		dairy, err := c.Categories.Create(ctx, category.Category{Name: "Dairy"})
+		require.NoError(t, err)
+		_, err = c.Categories.Assign(ctx, milk.Id, []int{dairy.ID})
+		require.NoError(t, err)
+		store, err := c.Inventory.CreateStore(ctx, inventory.Store{Name: "Centre"})
+		require.NoError(t, err)
+		_, err = c.Inventory.SetStock(ctx, store.ID, milk.Id, 4)
+		require.NoError(t, err)
+
+		// act
+		err = c.Service.DeleteProduct(ctx, "7")
+
+		// assert
+		require.NoError(t, err)
+		ids, err := c.Categories.ProductIDs(ctx, []int{dairy.ID})
+		require.NoError(t, err)
+		require.Empty(t, ids)
+		totals, err := c.Inventory.Totals(ctx)
+		require.NoError(t, err)
+		require.Empty(t, totals)
+	})
+
+	t.Run("success - the quantity of a product kept by the stores is their stock", func(t *testing.T) {
+		// arrange
+		c := newCatalog(t, milk)
+		center, err := c.Inventory.CreateStore(ctx, inventory.Store{Name: "Centre"})
+		require.NoError(t, err)
+		north, err := c.Inventory.CreateStore(ctx, inventory.Store{Name: "North"})
+		require.NoError(t, err)
+		_, err = c.Inventory.SetStock(ctx, center.ID, milk.Id, 4)
+		require.NoError(t, err)
+		_, err = c.Inventory.SetStock(ctx, north.ID, milk.Id, 3)
+		require.NoError(t, err)
+		updated := milk
+		updated.Quantity = 50
+		updated.Price = 1.6
+
+		// act
+		product, errUpdate := c.Service.UpdateProduct(ctx, updated)
+		_, errStock := c.Inventory.SetStock(ctx, center.ID, milk.Id, 0)
+		_, errEmpty := c.Inventory.SetStock(ctx, north.ID, milk.Id, 0)
+
+		// assert
+		require.NoError(t, errUpdate)
+		require.Equal(t, 7, product.Quantity)
+		require.Equal(t, 1.6, product.Price)
+		require.NoError(t, errStock)
+		require.NoError(t, errEmpty)
+		product, err = c.Service.GetProduct(ctx, "7")
+		require.NoError(t, err)
+		require.Equal(t, 0, product.Quantity)
+	})
+
+	t.Run("success - replace writes the stock back to the quantity of the products", func(t *testing.T) {
+		// arrange
+		c := newCatalog(t, milk)
+		store, err := c.Inventory.CreateStore(ctx, inventory.Store{Name: "Centre"})
+		require.NoError(t, err)
+		_, err = c.Inventory.SetStock(ctx, store.ID, milk.Id, 4)
+		require.NoError(t, err)
+
+		// act
+		err = c.Replace(ctx, map[int]catalog.Product{milk.Id: milk})
+
+		// assert
+		require.NoError(t, err)
+		product, err := c.Service.GetProduct(ctx, "7")
+		require.NoError(t, err)
+		require.Equal(t, 4, product.Quantity)
+	})
+
+	t.Run("success - a scheduled price is written to a product invalid for the updates", func(t *testing.T) {
+		// arrange
+		legacy := catalog.Product{Id: 4, Name: "Desserts", Quantity: 0, CodeValue: "T2262", Expiration: "14/10/2021", Price: 10}
+		c := newCatalog(t, legacy)
+		scheduled, err := c.Prices.Schedule(ctx, legacy.Id, 12, time.Now().Add(50*time.Millisecond))
+		require.NoError(t, err)
+
+		// act
+		applied := func() bool {
+			history, err := c.Prices.History(ctx, legacy.Id)
+			require.NoError(t, err)
+			for _, entry := range history {
+				if entry.ID == scheduled.ID {
+					return entry.AppliedAt != nil
+				}
+			}
+			return false
+		}
+
+		// assert
+		require.Eventually(t, applied, 5*time.Second, 10*time.Millisecond)
+		product, err := c.Service.GetProduct(ctx, "4")
+		require.NoError(t, err)
+		legacy.Price = 12
+		require.Equal(t, legacy, product)
+	})
+
+	t.Run("success - concurrent updates record the price each replaced", func(t *testing.T) {
+		// arrange
+		c := newCatalog(t, milk)
+		var wg sync.WaitGroup
+
+		// act
+		for n := range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				updated := milk
+				updated.Price = float64(n + 2)
+				_, err := c.Service.UpdateProduct(ctx, updated)
+				require.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		// assert
+		history, err := c.Prices.History(ctx, milk.Id)
+		require.NoError(t, err)
+		require.Len(t, history, 20)
+		replaced := make(map[float64]int)
+		for _, entry := range history {
+			replaced[entry.PreviousPrice]++
+		}
+		for price, count := range replaced {
+			require.Equal(t, 1, count, "price %v replaced %d times", price, count)
+		}
+	})
+
+	t.Run("success - replace publishes the changes and records the prices", func(t *testing.T) {
+		// arrange
+		c := newCatalog(t, milk, bread)
+		sub, err := c.Events.Subscribe("")
+		require.NoError(t, err)
+		defer sub.Close()
+		cheaper := bread
+		cheaper.Price = 1.8
+
+		// act
+		err = c.Replace(ctx, map[int]catalog.Product{cheaper.Id: cheaper})
+
+		// assert
+		require.NoError(t, err)
+		var types []string
+		for range 2 {
+			event := <-sub.Events()
+			types = append(types, event.Type)
+		}
+		require.Equal(t, []string{internalProduct.EventProductDeleted, internalProduct.EventProductUpdated}, types)
+		history, err := c.Prices.History(ctx, bread.Id)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		require.Equal(t, 2.0, history[0].PreviousPrice)
+		require.Equal(t, 1.8, history[0].Price)
+	})
+
+	t.Run("success - renumber moves the prices, categories and stock", func(t *testing.T) {
+		// arrange
+		c := newCatalog(t, milk, bread)
+		require.NoError(t, c.Prices.RecordPrice(ctx, milk.Id, 0, milk.Price))
+		dairy, err := c.Categories.Create(ctx, category.Category{Name: "Dairy"})
+		require.NoError(t, err)
+		_, err = c.Categories.Assign(ctx, milk.Id, []int{dairy.ID})
+		require.NoError(t, err)
+		store, err := c.Inventory.CreateStore(ctx, inventory.Store{Name: "Centre"})
+		require.NoError(t, err)
+		_, err = c.Inventory.SetStock(ctx, store.ID, milk.Id, 4)
+		require.NoError(t, err)
+
+		// act
+		err = c.Renumber(ctx, map[int]int{bread.Id: 1, milk.Id: 2})
+
+		// assert
+		require.NoError(t, err)
+		product, err := c.Service.GetProduct(ctx, "2")
+		require.NoError(t, err)
+		require.Equal(t, "Milk", product.Name)
+		history, err := c.Prices.History(ctx, 2)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		require.Equal(t, 2, history[0].ProductID)
+		ids, err := c.Categories.ProductIDs(ctx, []int{dairy.ID})
+		require.NoError(t, err)
+		require.Equal(t, map[int]bool{2: true}, ids)
+		stock, err := c.Inventory.StoreStock(ctx, store.ID)
+		require.NoError(t, err)
+		require.Equal(t, map[int]int{2: 4}, stock)
+	})
+
+	t.Run("failure - renumber to an id taken by another product", func(t *testing.T) {
+		// arrange
+		c := newCatalog(t, milk, bread)
+
+		// act
+		err := c.Renumber(ctx, map[int]int{milk.Id: bread.Id})
+
+		// assert
+		require.Error(t, err)
+		product, err := c.Service.GetProduct(ctx, "7")
+		require.NoError(t, err)
+		require.Equal(t, "Milk", product.Name)
+	})
+}
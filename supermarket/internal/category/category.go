@@ -0,0 +1,375 @@
+// Package category keeps the tree of the categories of the products, and the categories each product is
+// assigned to.
+package category
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	internalProduct "supermarket/internal/product"
+	"sync"
+)
+
+var (
+	// ErrCategoryNotFound is returned when a category does not exist.
+	ErrCategoryNotFound = errors.New("category: not found")
+	// ErrInvalidCategory is returned for a category without name.
+	ErrInvalidCategory = errors.New("category: invalid category")
+	// ErrDuplicateName is returned for a category with the name of a sibling.
+	ErrDuplicateName = errors.New("category: duplicated name")
+	// ErrCycle is returned when moving a category under itself or one of its descendants.
+	ErrCycle = errors.New("category: parent is the category or one of its descendants")
+	// ErrCategoryInUse is returned when deleting a category with products assigned.
+	ErrCategoryInUse = errors.New("category: products assigned")
+	// ErrCategoryHasChildren is returned when deleting a category with subcategories.
+	ErrCategoryHasChildren = errors.New("category: has subcategories")
+
+	// errUnchanged is returned by the functions of change leaving the data unchanged
+	errUnchanged = errors.New("category: unchanged")
+)
+
+// Category is a node of the tree of categories.
+type Category struct {
+	// ID identifies the category
+	ID int `json:"id"`
+	// Name is unique among the siblings
+	Name string `json:"name"`
+	// ParentID is the id of the parent, nil for the root categories
+	ParentID *int `json:"parent_id"`
+}
+
+// Data is the content of a store.
+type Data struct {
+	// Categories are the categories by id
+	Categories map[int]Category `json:"categories"`
+	// Products are the ids of the categories of the products, by product id
+	Products map[int][]int `json:"products"`
+}
+
+// Store loads and saves the categories. Implementations must be safe for concurrent use.
+type Store interface {
+	// Load returns the data of the store, empty if never saved
+	Load(ctx context.Context) (Data, error)
+	// Save replaces the data of the store
+	Save(ctx context.Context, data Data) error
+}
+
+// NewCategories returns the Categories kept in store, checking the products assigned with productService.
+func NewCategories(store Store, productService internalProduct.ProductServiceInterface) *Categories {
+	return &Categories{
+		store:          store,
+		productService: productService,
+	}
+}
+
+// Categories manages the tree of categories and the assignments of the products.
+type Categories struct {
+	store          Store
+	productService internalProduct.ProductServiceInterface
+	// mu serializes the changes, so the tree is checked and saved as a whole
+	mu sync.Mutex
+	// onChange are called after every change
+	onChange []func()
+}
+
+// OnChange registers fn to be called after every change, e.g. to invalidate the caches of the catalogue
+// filtered by category.
+func (c *Categories) OnChange(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onChange = append(c.onChange, fn)
+}
+
+// List returns the categories, sorted by id.
+func (c *Categories) List(ctx context.Context) ([]Category, error) {
+	data, err := c.store.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	categories := make([]Category, 0, len(data.Categories))
+	for _, category := range data.Categories {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].ID < categories[j].ID })
+	return categories, nil
+}
+
+// Get returns a category by id.
+func (c *Categories) Get(ctx context.Context, id int) (Category, error) {
+	data, err := c.store.Load(ctx)
+	if err != nil {
+		return Category{}, err
+	}
+	category, ok := data.Categories[id]
+	if !ok {
+		return Category{}, ErrCategoryNotFound
+	}
+	return category, nil
+}
+
+// Create adds a category, with the next id.
+func (c *Categories) Create(ctx context.Context, category Category) (Category, error) {
+	category.ID = 0
+	category.Name = strings.TrimSpace(category.Name)
+	return c.change(ctx, func(data Data) (Category, error) {
+		for id := range data.Categories {
+			category.ID = max(category.ID, id)
+		}
+		category.ID++
+		if err := validate(data, category); err != nil {
+			return Category{}, err
+		}
+		data.Categories[category.ID] = category
+		return category, nil
+	})
+}
+
+// Update renames or moves a category.
+func (c *Categories) Update(ctx context.Context, category Category) (Category, error) {
+	category.Name = strings.TrimSpace(category.Name)
+	return c.change(ctx, func(data Data) (Category, error) {
+		if _, ok := data.Categories[category.ID]; !ok {
+			return Category{}, ErrCategoryNotFound
+		}
+		if err := validate(data, category); err != nil {
+			return Category{}, err
+		}
+		data.Categories[category.ID] = category
+		return category, nil
+	})
+}
+
+// Delete removes a category without subcategories nor products assigned. The assignments of the products
+// no longer in the catalogue, e.g. left by a deletion whose cleanup failed, are removed rather than counted.
+func (c *Categories) Delete(ctx context.Context, id int) error {
+	_, err := c.change(ctx, func(data Data) (Category, error) {
+		category, ok := data.Categories[id]
+		if !ok {
+			return Category{}, ErrCategoryNotFound
+		}
+		for _, other := range data.Categories {
+			if other.ParentID != nil && *other.ParentID == id {
+				return Category{}, fmt.Errorf("%w: %q", ErrCategoryHasChildren, category.Name)
+			}
+		}
+		assigned := 0
+		for productID, categoryIDs := range data.Products {
+			if !slices.Contains(categoryIDs, id) {
+				continue
+			}
+			_, err := c.productService.GetProduct(ctx, strconv.Itoa(productID))
+			switch {
+			case errors.Is(err, internalProduct.ErrProductNotFound):
+				delete(data.Products, productID)
+			case err != nil:
+				return Category{}, err
+			default:
+				assigned++
+			}
+		}
+		if assigned > 0 {
+			return Category{}, fmt.Errorf("%w: %d products assigned to %q", ErrCategoryInUse, assigned, category.Name)
+		}
+		delete(data.Categories, id)
+		return category, nil
+	})
+	return err
+}
+
+// ProductCategories returns the categories of the product id, sorted by id.
+func (c *Categories) ProductCategories(ctx context.Context, productID int) ([]Category, error) {
+	data, err := c.store.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	categories := []Category{}
+	for _, id := range data.Products[productID] {
+		categories = append(categories, data.Categories[id])
+	}
+	return categories, nil
+}
+
+// Assign replaces the categories of the product id, none removing its assignments.
+func (c *Categories) Assign(ctx context.Context, productID int, categoryIDs []int) ([]Category, error) {
+	var categories []Category
+	_, err := c.change(ctx, func(data Data) (Category, error) {
+		ids := make(map[int]bool, len(categoryIDs))
+		categories = []Category{}
+		for _, id := range categoryIDs {
+			category, ok := data.Categories[id]
+			if !ok {
+				return Category{}, fmt.Errorf("%w: %d", ErrCategoryNotFound, id)
+			}
+			if !ids[id] {
+				ids[id] = true
+				categories = append(categories, category)
+			}
+		}
+		sort.Slice(categories, func(i, j int) bool { return categories[i].ID < categories[j].ID })
+
+		if len(categories) == 0 {
+			delete(data.Products, productID)
+			return Category{}, nil
+		}
+		assigned := make([]int, 0, len(categories))
+		for _, category := range categories {
+			assigned = append(assigned, category.ID)
+		}
+		data.Products[productID] = assigned
+		return Category{}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// ProductIDs returns the ids of the products assigned to the categories or their descendants. Unknown
+// categories have no products.
+func (c *Categories) ProductIDs(ctx context.Context, categoryIDs []int) (map[int]bool, error) {
+	data, err := c.store.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	descendants := make(map[int]bool)
+	for _, id := range categoryIDs {
+		if _, ok := data.Categories[id]; ok {
+			for _, descendant := range subtree(data, id) {
+				descendants[descendant] = true
+			}
+		}
+	}
+
+	productIDs := make(map[int]bool)
+	for productID, assigned := range data.Products {
+		for _, id := range assigned {
+			if descendants[id] {
+				productIDs[productID] = true
+				break
+			}
+		}
+	}
+	return productIDs, nil
+}
+
+// Publish removes the assignments of the deleted products, as a handler of the catalogue events.
+func (c *Categories) Publish(ctx context.Context, event internalProduct.Event) error {
+	if event.Type != internalProduct.EventProductDeleted {
+		return nil
+	}
+	// the product is deleted already, so a failure leaves assignments that only block deleting the categories
+	_, err := c.change(ctx, func(data Data) (Category, error) {
+		if _, ok := data.Products[event.Product.Id]; !ok {
+			return Category{}, errUnchanged
+		}
+		delete(data.Products, event.Product.Id)
+		return Category{}, nil
+	})
+	if err != nil {
+		return fmt.Errorf("category: remove the assignments of product %d: %w", event.Product.Id, err)
+	}
+	return nil
+}
+
+// Renumber moves the assignments of the products to their new ids, ids mapping the old ids to the new ones.
+// The products missing from ids keep their id.
+func (c *Categories) Renumber(ctx context.Context, ids map[int]int) error {
+	_, err := c.change(ctx, func(data Data) (Category, error) {
+		products := make(map[int][]int, len(data.Products))
+		changed := false
+		for productID, categoryIDs := range data.Products {
+			if id, ok := ids[productID]; ok && id != productID {
+				productID = id
+				changed = true
+			}
+			products[productID] = categoryIDs
+		}
+		if !changed {
+			return Category{}, errUnchanged
+		}
+		clear(data.Products)
+		maps.Copy(data.Products, products)
+		return Category{}, nil
+	})
+	if err != nil {
+		return fmt.Errorf("category: renumber the products: %w", err)
+	}
+	return nil
+}
+
+// change applies fn to the data of the store, saving it and calling the OnChange functions unless it fails
+// or leaves the data unchanged
+func (c *Categories) change(ctx context.Context, fn func(data Data) (Category, error)) (Category, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, err := c.store.Load(ctx)
+	if err != nil {
+		return Category{}, err
+	}
+	category, err := fn(data)
+	if errors.Is(err, errUnchanged) {
+		return category, nil
+	}
+	if err != nil {
+		return Category{}, err
+	}
+	if err := c.store.Save(ctx, data); err != nil {
+		return Category{}, err
+	}
+	for _, fn := range c.onChange {
+		fn()
+	}
+	return category, nil
+}
+
+// validate checks category against the tree of data: a name unique among its siblings and an existing
+// parent which is not the category or one of its descendants
+func validate(data Data, category Category) error {
+	if category.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCategory)
+	}
+	if category.ParentID != nil {
+		if _, ok := data.Categories[*category.ParentID]; !ok {
+			return fmt.Errorf("%w: parent %d", ErrCategoryNotFound, *category.ParentID)
+		}
+		for _, id := range subtree(data, category.ID) {
+			if id == *category.ParentID {
+				return ErrCycle
+			}
+		}
+	}
+	for _, other := range data.Categories {
+		if other.ID != category.ID && strings.EqualFold(other.Name, category.Name) && sameParent(other, category) {
+			return fmt.Errorf("%w: %q", ErrDuplicateName, category.Name)
+		}
+	}
+	return nil
+}
+
+// subtree returns the id and the ids of the descendants of the category id
+func subtree(data Data, id int) []int {
+	children := make(map[int][]int)
+	for _, category := range data.Categories {
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], category.ID)
+		}
+	}
+	ids := []int{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids
+}
+
+// sameParent returns true if a and b are siblings
+func sameParent(a, b Category) bool {
+	if a.ParentID == nil || b.ParentID == nil {
+		return a.ParentID == nil && b.ParentID == nil
+	}
+	return *a.ParentID == *b.ParentID
+}
@@ -0,0 +1,175 @@
+package category_test
+
+import (
+	"context"
+	"path/filepath"
+	"supermarket/internal/category"
+	internalProduct "supermarket/internal/product"
+	"supermarket/internal/product/repository"
+	"supermarket/internal/product/service"
+	"supermarket/internal/product/storage"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newCategories returns the Categories kept in store over a catalogue of milk, water and bread
+func newCategories(store category.Store) *category.Categories {
+	products := storage.NewProductStorageMemory(map[int]internalProduct.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 100, CodeValue: "M1", Expiration: "01/02/2030", Price: 1.5},
+		2: {Id: 2, Name: "Water", Quantity: 100, CodeValue: "W1", Expiration: "01/02/2030", Price: 1},
+		3: {Id: 3, Name: "Bread", Quantity: 100, CodeValue: "B1", Expiration: "01/02/2030", Price: 2},
+	})
+	return category.NewCategories(store, service.NewProductService(repository.NewProductRepository(products)))
+}
+
+// create creates a category named name under parentID, nil for a root category
+func create(t *testing.T, categories *category.Categories, name string, parentID *int) category.Category {
+	t.Helper()
+	c, err := categories.Create(context.Background(), category.Category{Name: name, ParentID: parentID})
+	require.NoError(t, err)
+	return c
+}
+
+// TestCategories tests the tree of categories and the assignments of the products.
+func TestCategories(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success - products of a category include its descendants", func(t *testing.T) {
+		// arrange
+		categories := newCategories(category.NewMemoryStore())
+		food := create(t, categories, "Food", nil)
+		dairy := create(t, categories, "Dairy", &food.ID)
+		cheese := create(t, categories, "Cheese", &dairy.ID)
+		drinks := create(t, categories, "Drinks", nil)
+		_, err := categories.Assign(ctx, 1, []int{cheese.ID})
+		require.NoError(t, err)
+		_, err = categories.Assign(ctx, 2, []int{drinks.ID, dairy.ID, drinks.ID})
+		require.NoError(t, err)
+		_, err = categories.Assign(ctx, 3, []int{drinks.ID})
+		require.NoError(t, err)
+
+		// act
+		ofFood, errFood := categories.ProductIDs(ctx, []int{food.ID})
+		ofCheese, errCheese := categories.ProductIDs(ctx, []int{cheese.ID, 99})
+
+		// assert
+		require.NoError(t, errFood)
+		require.Equal(t, map[int]bool{1: true, 2: true}, ofFood)
+		require.NoError(t, errCheese)
+		require.Equal(t, map[int]bool{1: true}, ofCheese)
+		assigned, err := categories.ProductCategories(ctx, 2)
+		require.NoError(t, err)
+		require.Equal(t, []category.Category{dairy, drinks}, assigned)
+	})
+
+	t.Run("success - the deletion of a product removes its assignments", func(t *testing.T) {
+		// arrange
+		categories := newCategories(category.NewMemoryStore())
+		food := create(t, categories, "Food", nil)
+		_, err := categories.Assign(ctx, 1, []int{food.ID})
+		require.NoError(t, err)
+		require.ErrorIs(t, categories.Delete(ctx, food.ID), category.ErrCategoryInUse)
+
+		// act
+		errPublish := categories.Publish(ctx, internalProduct.Event{Type: internalProduct.EventProductDeleted, Product: internalProduct.Product{Id: 1}})
+		err = categories.Delete(ctx, food.ID)
+
+		// assert
+		require.NoError(t, errPublish)
+		require.NoError(t, err)
+		list, err := categories.List(ctx)
+		require.NoError(t, err)
+		require.Empty(t, list)
+	})
+
+	t.Run("success - assignments of products missing from the catalogue do not keep a category", func(t *testing.T) {
+		// arrange
+		categories := newCategories(category.NewMemoryStore())
+		food := create(t, categories, "Food", nil)
+		_, err := categories.Assign(ctx, 99, []int{food.ID})
+		require.NoError(t, err)
+
+		// act
+		err = categories.Delete(ctx, food.ID)
+
+		// assert
+		require.NoError(t, err)
+		assigned, err := categories.ProductCategories(ctx, 99)
+		require.NoError(t, err)
+		require.Empty(t, assigned)
+	})
+
+	t.Run("success - the file store keeps the tree across restarts", func(t *testing.T) {
+		// arrange
+		filename := filepath.Join(t.TempDir(), "categories.json")
+		store, err := category.NewFileStore(filename)
+		require.NoError(t, err)
+		categories := newCategories(store)
+		food := create(t, categories, "Food", nil)
+		dairy := create(t, categories, "Dairy", &food.ID)
+		_, err = categories.Assign(ctx, 1, []int{dairy.ID})
+		require.NoError(t, err)
+
+		// act
+		reopened, err := category.NewFileStore(filename)
+		require.NoError(t, err)
+		restarted := newCategories(reopened)
+
+		// assert
+		list, err := restarted.List(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []category.Category{food, dairy}, list)
+		productIDs, err := restarted.ProductIDs(ctx, []int{food.ID})
+		require.NoError(t, err)
+		require.Equal(t, map[int]bool{1: true}, productIDs)
+	})
+
+	t.Run("failure - delete a category with subcategories", func(t *testing.T) {
+		// arrange
+		categories := newCategories(category.NewMemoryStore())
+		food := create(t, categories, "Food", nil)
+		create(t, categories, "Dairy", &food.ID)
+
+		// act
+		err := categories.Delete(ctx, food.ID)
+
+		// assert
+		require.ErrorIs(t, err, category.ErrCategoryHasChildren)
+	})
+
+	t.Run("failure - move a category under its descendant", func(t *testing.T) {
+		// arrange
+		categories := newCategories(category.NewMemoryStore())
+		food := create(t, categories, "Food", nil)
+		dairy := create(t, categories, "Dairy", &food.ID)
+		cheese := create(t, categories, "Cheese", &dairy.ID)
+
+		// act
+		_, errDescendant := categories.Update(ctx, category.Category{ID: food.ID, Name: "Food", ParentID: &cheese.ID})
+		_, errSelf := categories.Update(ctx, category.Category{ID: food.ID, Name: "Food", ParentID: &food.ID})
+
+		// assert
+		require.ErrorIs(t, errDescendant, category.ErrCycle)
+		require.ErrorIs(t, errSelf, category.ErrCycle)
+	})
+
+	t.Run("error - invalid categories", func(t *testing.T) {
+		// arrange
+		categories := newCategories(category.NewMemoryStore())
+		food := create(t, categories, "Food", nil)
+		missing := 99
+
+		// act
+		_, errName := categories.Create(ctx, category.Category{Name: " "})
+		_, errDuplicate := categories.Create(ctx, category.Category{Name: "food"})
+		_, errParent := categories.Create(ctx, category.Category{Name: "Dairy", ParentID: &missing})
+		_, errAssign := categories.Assign(ctx, 1, []int{food.ID, missing})
+
+		// assert
+		require.ErrorIs(t, errName, category.ErrInvalidCategory)
+		require.ErrorIs(t, errDuplicate, category.ErrDuplicateName)
+		require.ErrorIs(t, errParent, category.ErrCategoryNotFound)
+		require.ErrorIs(t, errAssign, category.ErrCategoryNotFound)
+	})
+}
@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"supermarket/internal/category"
+	"supermarket/internal/platform/logging"
+	"supermarket/internal/platform/web/request"
+	"supermarket/internal/platform/web/response"
+	"supermarket/internal/platform/web/serialization"
+	internalProduct "supermarket/internal/product"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type CategoryHandler struct {
+	Categories     *category.Categories
+	ProductService internalProduct.ProductServiceInterface
+	// StrictJSON rejects the json bodies with unknown fields or trailing data
+	StrictJSON bool
+}
+
+// NewCategoryHandler returns a new CategoryHandler.
+func NewCategoryHandler(categories *category.Categories, productService internalProduct.ProductServiceInterface) *CategoryHandler {
+	return &CategoryHandler{
+		Categories:     categories,
+		ProductService: productService,
+	}
+}
+
+// CategoryRequestJSON is the body of a request creating or replacing a category
+type CategoryRequestJSON struct {
+	Name     string `json:"name"`
+	ParentID *int   `json:"parent_id"`
+}
+
+// ProductCategoriesRequestJSON is the body of a request assigning a product to categories
+type ProductCategoriesRequestJSON struct {
+	Categories []int `json:"categories"`
+}
+
+// CreateCategoryHandler creates a category, under its parent if any.
+func (h *CategoryHandler) CreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var categoryRequest CategoryRequestJSON
+	if err := request.JSON(r, &categoryRequest, request.Options(h.StrictJSON)...); err != nil {
+		response.RequestError(w, err)
+		return
+	}
+
+	c, err := h.Categories.Create(r.Context(), category.Category{Name: categoryRequest.Name, ParentID: categoryRequest.ParentID})
+	if err != nil {
+		categoryError(w, r, "create category", err)
+		return
+	}
+	response.JSON(w, http.StatusCreated, "category created successfully", c)
+}
+
+// GetCategoriesHandler returns the categories, sorted by id.
+func (h *CategoryHandler) GetCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.Categories.List(r.Context())
+	if err != nil {
+		categoryError(w, r, "get categories", err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "categories fetched successfully", categories)
+}
+
+// GetCategoryHandler returns a category by id.
+func (h *CategoryHandler) GetCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := urlID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	c, err := h.Categories.Get(r.Context(), id)
+	if err != nil {
+		categoryError(w, r, "get category", err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "category fetched successfully", c)
+}
+
+// UpdateCategoryHandler renames or moves a category.
+func (h *CategoryHandler) UpdateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := urlID(w, r, "id")
+	if !ok {
+		return
+	}
+	var categoryRequest CategoryRequestJSON
+	if err := request.JSON(r, &categoryRequest, request.Options(h.StrictJSON)...); err != nil {
+		response.RequestError(w, err)
+		return
+	}
+
+	c, err := h.Categories.Update(r.Context(), category.Category{ID: id, Name: categoryRequest.Name, ParentID: categoryRequest.ParentID})
+	if err != nil {
+		categoryError(w, r, "update category", err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "category updated successfully", c)
+}
+
+// DeleteCategoryHandler deletes a category without subcategories nor products.
+func (h *CategoryHandler) DeleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := urlID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.Categories.Delete(r.Context(), id); err != nil {
+		categoryError(w, r, "delete category", err)
+		return
+	}
+	response.Text(w, http.StatusOK, "category deleted successfully")
+}
+
+// GetCategoryProductsHandler returns the products of a category and its descendants, sorted by id.
+func (h *CategoryHandler) GetCategoryProductsHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := urlID(w, r, "id")
+	if !ok {
+		return
+	}
+	if _, err := h.Categories.Get(r.Context(), id); err != nil {
+		categoryError(w, r, "get category products", err)
+		return
+	}
+
+	productIDs, err := h.Categories.ProductIDs(r.Context(), []int{id})
+	if err != nil {
+		categoryError(w, r, "get category products", err)
+		return
+	}
+	products, err := h.ProductService.GetProducts(r.Context())
+	if err != nil {
+		categoryError(w, r, "get category products", err)
+		return
+	}
+	filtered := []internalProduct.Product{}
+	for _, product := range products {
+		if productIDs[product.Id] {
+			filtered = append(filtered, product)
+		}
+	}
+	sort.Slice(filtered, func(i, j int) bool { return filtered[i].Id < filtered[j].Id })
+
+	productsResponse := serialization.ProductsResponse(serialization.ProductsToProductsResponse(filtered))
+	response.Negotiated(w, r, http.StatusOK, "products fetched successfully", productsResponse)
+}
+
+// GetProductCategoriesHandler returns the categories of a product.
+func (h *CategoryHandler) GetProductCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductService.GetProduct(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		categoryError(w, r, "get product categories", err)
+		return
+	}
+
+	categories, err := h.Categories.ProductCategories(r.Context(), product.Id)
+	if err != nil {
+		categoryError(w, r, "get product categories", err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "product categories fetched successfully", categories)
+}
+
+// AssignProductCategoriesHandler replaces the categories of a product.
+func (h *CategoryHandler) AssignProductCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	var categoriesRequest ProductCategoriesRequestJSON
+	if err := request.JSON(r, &categoriesRequest, request.Options(h.StrictJSON)...); err != nil {
+		response.RequestError(w, err)
+		return
+	}
+	product, err := h.ProductService.GetProduct(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		categoryError(w, r, "assign product categories", err)
+		return
+	}
+
+	categories, err := h.Categories.Assign(r.Context(), product.Id, categoriesRequest.Categories)
+	if err != nil {
+		categoryError(w, r, "assign product categories", err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "product categories updated successfully", categories)
+}
+
+// urlID returns the id of the url parameter name, responding a bad request if it is not a number
+func urlID(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, name))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid id")
+		return 0, false
+	}
+	return id, true
+}
+
+// categoryError responds the error of the categories or the products, logging the unexpected ones
+func categoryError(w http.ResponseWriter, r *http.Request, op string, err error) {
+	switch {
+	case errors.Is(err, internalProduct.ErrInvalidID):
+		response.Errorw(w, http.StatusBadRequest, err)
+	case errors.Is(err, internalProduct.ErrProductNotFound), errors.Is(err, category.ErrCategoryNotFound):
+		response.Errorw(w, http.StatusNotFound, err)
+	case errors.Is(err, category.ErrDuplicateName), errors.Is(err, category.ErrCategoryInUse), errors.Is(err, category.ErrCategoryHasChildren):
+		response.Errorw(w, http.StatusConflict, err)
+	case errors.Is(err, category.ErrInvalidCategory), errors.Is(err, category.ErrCycle):
+		response.Errorw(w, http.StatusUnprocessableEntity, err)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		response.Error(w, http.StatusGatewayTimeout, "request timeout")
+	default:
+		logging.FromContext(r.Context()).Error(op, slog.Any("error", err))
+		response.Error(w, http.StatusInternalServerError, "internal server error")
+	}
+}
@@ -0,0 +1,132 @@
+package category
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// NewMemoryStore creates a Store keeping the categories in memory.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: newData()}
+}
+
+// MemoryStore is a Store local to the process
+type MemoryStore struct {
+	mu   sync.RWMutex
+	data Data
+}
+
+// Load returns a copy of the data.
+func (s *MemoryStore) Load(ctx context.Context) (Data, error) {
+	if err := ctx.Err(); err != nil {
+		return Data{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyData(s.data), nil
+}
+
+// Save replaces the data with a copy of data.
+func (s *MemoryStore) Save(ctx context.Context, data Data) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = copyData(data)
+	return nil
+}
+
+// NewFileStore creates a Store persisting the categories to a json file. The content of the file is loaded
+// if it exists.
+func NewFileStore(filename string) (*FileStore, error) {
+	s := &FileStore{MemoryStore: NewMemoryStore(), filename: filename}
+
+	content, err := os.ReadFile(filename)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return s, nil
+	case err != nil:
+		return nil, fmt.Errorf("category: read %s: %w", filename, err)
+	}
+	var data Data
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("category: decode %s: %w", filename, err)
+	}
+	s.data = copyData(data)
+	return s, nil
+}
+
+// FileStore is a MemoryStore saved to a json file on every change
+type FileStore struct {
+	*MemoryStore
+	filename string
+}
+
+// Save replaces the data, unless it can not be written to the file.
+func (s *FileStore) Save(ctx context.Context, data Data) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.write(data); err != nil {
+		return err
+	}
+	s.data = copyData(data)
+	return nil
+}
+
+// write writes data to a temporary file renamed over the file, so a crash never leaves it truncated
+func (s *FileStore) write(data Data) error {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("category: encode: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(s.filename), filepath.Base(s.filename)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("category: save: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	if _, err := file.Write(content); err != nil {
+		return fmt.Errorf("category: save: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("category: save: %w", err)
+	}
+	if err := os.Rename(file.Name(), s.filename); err != nil {
+		return fmt.Errorf("category: save: %w", err)
+	}
+	return nil
+}
+
+// newData returns empty data
+func newData() Data {
+	return Data{
+		Categories: make(map[int]Category),
+		Products:   make(map[int][]int),
+	}
+}
+
+// copyData returns a deep copy of data, with its maps allocated
+func copyData(data Data) Data {
+	copied := newData()
+	for id, category := range data.Categories {
+		if category.ParentID != nil {
+			parentID := *category.ParentID
+			category.ParentID = &parentID
+		}
+		copied.Categories[id] = category
+	}
+	for productID, categoryIDs := range data.Products {
+		copied.Products[productID] = append([]int{}, categoryIDs...)
+	}
+	return copied
+}
@@ -0,0 +1,539 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrInvalidConfig is returned when the merged configuration is not valid.
+	ErrInvalidConfig = errors.New("config: invalid configuration")
+	// ErrConfigFile is returned when the config file can not be read or decoded.
+	ErrConfigFile = errors.New("config: invalid config file")
+)
+
+const (
+	// StorageBackendJSON stores the products in a JSON file.
+	StorageBackendJSON = "json"
+	// StorageBackendMemory stores the products in memory, seeded from the JSON file if it exists.
+	StorageBackendMemory = "memory"
+
+	// AuthModeToken requires the configured token in the Token header of write requests.
+	AuthModeToken = "token"
+	// AuthModeNone disables the authentication of write requests.
+	AuthModeNone = "none"
+
+	// LogFormatText writes logs as key=value pairs.
+	LogFormatText = "text"
+	// LogFormatJSON writes logs as JSON objects.
+	LogFormatJSON = "json"
+
+	// TracingExporterNone disables tracing.
+	TracingExporterNone = "none"
+	// TracingExporterStdout writes the spans as JSON lines to stdout.
+	TracingExporterStdout = "stdout"
+	// TracingExporterOTLP posts the spans to an OTLP/HTTP collector.
+	TracingExporterOTLP = "otlp"
+
+	// redacted replaces secrets when printing the configuration.
+	redacted = "[REDACTED]"
+)
+
+// Config is the configuration of the supermarket server
+type Config struct {
+	// Server is the configuration of the http server
+	Server ServerConfig `yaml:"server" toml:"server"`
+	// Storage is the configuration of the products storage
+	Storage StorageConfig `yaml:"storage" toml:"storage"`
+	// Auth is the configuration of the authentication of write requests
+	Auth AuthConfig `yaml:"auth" toml:"auth"`
+	// Log is the configuration of the logger
+	Log LogConfig `yaml:"log" toml:"log"`
+	// Tracing is the configuration of the tracing of requests
+	Tracing TracingConfig `yaml:"tracing" toml:"tracing"`
+	// RateLimit is the configuration of the rate limiting of requests
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	// CORS is the configuration of the cross-origin requests of browsers
+	CORS CORSConfig `yaml:"cors" toml:"cors"`
+	// HTTPCache is the configuration of the caching of the catalogue reads
+	HTTPCache HTTPCacheConfig `yaml:"http_cache" toml:"http_cache"`
+	// Idempotency is the configuration of the Idempotency-Key of the write requests
+	Idempotency IdempotencyConfig `yaml:"idempotency" toml:"idempotency"`
+	// GraphQL is the configuration of the limits of the GraphQL queries
+	GraphQL GraphQLConfig `yaml:"graphql" toml:"graphql"`
+	// Events is the configuration of the stream of the catalogue changes
+	Events EventsConfig `yaml:"events" toml:"events"`
+	// Webhooks is the configuration of the deliveries of the catalogue changes to the webhooks
+	Webhooks WebhooksConfig `yaml:"webhooks" toml:"webhooks"`
+	// Snapshots is the configuration of the snapshots of the catalogue
+	Snapshots SnapshotsConfig `yaml:"snapshots" toml:"snapshots"`
+	// Prices is the configuration of the history and the schedule of the prices
+	Prices PricesConfig `yaml:"prices" toml:"prices"`
+	// Categories is the configuration of the tree of categories
+	Categories CategoriesConfig `yaml:"categories" toml:"categories"`
+	// Inventory is the configuration of the stores and their stock
+	Inventory InventoryConfig `yaml:"inventory" toml:"inventory"`
+}
+
+// ServerConfig is the configuration of the http server
+type ServerConfig struct {
+	Host string `yaml:"host" toml:"host"`
+	Port string `yaml:"port" toml:"port"`
+	// GRPCPort is the port of the grpc api, disabled if empty
+	GRPCPort        string        `yaml:"grpc_port" toml:"grpc_port"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	ShutdownDelay   time.Duration `yaml:"shutdown_delay" toml:"shutdown_delay"`
+	RequestTimeout  time.Duration `yaml:"request_timeout" toml:"request_timeout"`
+	MaxBodyBytes    int64         `yaml:"max_body_bytes" toml:"max_body_bytes"`
+	StrictJSON      bool          `yaml:"strict_json" toml:"strict_json"`
+	TLS             TLSConfig     `yaml:"tls" toml:"tls"`
+}
+
+// TLSConfig is the configuration of TLS. TLS is enabled when both files are set.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file" toml:"cert_file"`
+	KeyFile  string `yaml:"key_file" toml:"key_file"`
+}
+
+// Enabled returns true if TLS is configured.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// StorageConfig is the configuration of the products storage
+type StorageConfig struct {
+	// Backend is one of StorageBackendJSON or StorageBackendMemory
+	Backend string `yaml:"backend" toml:"backend"`
+	// Path is the path to the JSON file of products
+	Path string `yaml:"path" toml:"path"`
+}
+
+// AuthConfig is the configuration of the authentication
+type AuthConfig struct {
+	// Mode is one of AuthModeToken or AuthModeNone
+	Mode string `yaml:"mode" toml:"mode"`
+	// Token is the secret token required when Mode is AuthModeToken
+	Token string `yaml:"token" toml:"token"`
+}
+
+// LogConfig is the configuration of the logger
+type LogConfig struct {
+	// Level is one of debug, info, warn or error
+	Level string `yaml:"level" toml:"level"`
+	// Format is one of LogFormatText or LogFormatJSON
+	Format string `yaml:"format" toml:"format"`
+	// SampleRate is the fraction, between 0 and 1, of successful requests logged
+	SampleRate float64 `yaml:"sample_rate" toml:"sample_rate"`
+}
+
+// TracingConfig is the configuration of the tracing
+type TracingConfig struct {
+	// Exporter is one of TracingExporterNone, TracingExporterStdout or TracingExporterOTLP
+	Exporter string `yaml:"exporter" toml:"exporter"`
+	// Endpoint is the OTLP/HTTP traces endpoint, e.g. http://localhost:4318/v1/traces
+	Endpoint string `yaml:"endpoint" toml:"endpoint"`
+	// ServiceName identifies the server in the traces
+	ServiceName string `yaml:"service_name" toml:"service_name"`
+}
+
+// RateLimitConfig is the configuration of the rate limiting
+type RateLimitConfig struct {
+	// Enabled turns the rate limiting on
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// TrustedProxies are the ips or CIDRs of the proxies whose X-Forwarded-For and X-Real-IP headers are trusted
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
+	// Read limits the GET routes of products, per client ip
+	Read LimitConfig `yaml:"read" toml:"read"`
+	// Write limits the write routes of products, per api token or client ip
+	Write LimitConfig `yaml:"write" toml:"write"`
+}
+
+// LimitConfig is the configuration of a token bucket
+type LimitConfig struct {
+	// Rate is the number of requests allowed per second
+	Rate float64 `yaml:"rate" toml:"rate"`
+	// Burst is the number of requests allowed at once
+	Burst int `yaml:"burst" toml:"burst"`
+}
+
+// HTTPCacheConfig is the configuration of the caching of the catalogue reads
+type HTTPCacheConfig struct {
+	// ListCacheControl is the Cache-Control header of GET /products
+	ListCacheControl string `yaml:"list_cache_control" toml:"list_cache_control"`
+	// ItemCacheControl is the Cache-Control header of GET /products/{id}
+	ItemCacheControl string `yaml:"item_cache_control" toml:"item_cache_control"`
+	// ResponseCache enables the server-side cache of the responses
+	ResponseCache bool `yaml:"response_cache" toml:"response_cache"`
+	// ResponseCacheEntries is the maximum number of responses cached
+	ResponseCacheEntries int `yaml:"response_cache_entries" toml:"response_cache_entries"`
+}
+
+// IdempotencyConfig is the configuration of the idempotency keys
+type IdempotencyConfig struct {
+	// TTL is how long the response of a key is replayed
+	TTL time.Duration `yaml:"ttl" toml:"ttl"`
+	// File persists the responses, they are kept in memory if empty
+	File string `yaml:"file" toml:"file"`
+}
+
+// GraphQLConfig is the configuration of the limits of the GraphQL queries
+type GraphQLConfig struct {
+	// MaxDepth is the maximum nesting of the fields of a query
+	MaxDepth int `yaml:"max_depth" toml:"max_depth"`
+	// MaxComplexity is the maximum cost of a query, the fields of lists counted once per item
+	MaxComplexity int `yaml:"max_complexity" toml:"max_complexity"`
+}
+
+// EventsConfig is the configuration of the stream of the catalogue changes
+type EventsConfig struct {
+	// LogSize is the number of events kept for the clients to resume from
+	LogSize int `yaml:"log_size" toml:"log_size"`
+	// Heartbeat is the interval of the heartbeats of the idle streams
+	Heartbeat time.Duration `yaml:"heartbeat" toml:"heartbeat"`
+}
+
+// WebhooksConfig is the configuration of the deliveries of the webhooks
+type WebhooksConfig struct {
+	// File persists the subscriptions and the outbox of the deliveries, they are kept in memory if empty
+	File string `yaml:"file" toml:"file"`
+	// MaxAttempts is the number of attempts of a delivery before it is dead-lettered
+	MaxAttempts int `yaml:"max_attempts" toml:"max_attempts"`
+	// MinBackoff is the delay before the second attempt, doubled for every attempt up to MaxBackoff
+	MinBackoff time.Duration `yaml:"min_backoff" toml:"min_backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff" toml:"max_backoff"`
+	// Timeout bounds an attempt
+	Timeout time.Duration `yaml:"timeout" toml:"timeout"`
+	// RetentionMaxCount is the number of delivered and dead deliveries kept per webhook, unbounded if 0
+	RetentionMaxCount int `yaml:"retention_max_count" toml:"retention_max_count"`
+	// RetentionMaxAge is the age of the delivered and dead deliveries kept, unbounded if 0
+	RetentionMaxAge time.Duration `yaml:"retention_max_age" toml:"retention_max_age"`
+}
+
+// SnapshotsConfig is the configuration of the snapshots of the catalogue
+type SnapshotsConfig struct {
+	// Dir keeps a json file per snapshot, they are kept in memory if empty
+	Dir string `yaml:"dir" toml:"dir"`
+	// MaxCount is the number of snapshots kept, unbounded if 0
+	MaxCount int `yaml:"max_count" toml:"max_count"`
+	// MaxAge is the age of the snapshots kept, unbounded if 0
+	MaxAge time.Duration `yaml:"max_age" toml:"max_age"`
+}
+
+// PricesConfig is the configuration of the history and the schedule of the prices
+type PricesConfig struct {
+	// File persists the price changes and the scheduled prices, they are kept in memory if empty
+	File string `yaml:"file" toml:"file"`
+}
+
+// CategoriesConfig is the configuration of the tree of categories
+type CategoriesConfig struct {
+	// File persists the categories and the assignments of the products, they are kept in memory if empty
+	File string `yaml:"file" toml:"file"`
+}
+
+// InventoryConfig is the configuration of the stores and their stock
+type InventoryConfig struct {
+	// File persists the stores, their stock and the transfers, they are kept in memory if empty
+	File string `yaml:"file" toml:"file"`
+}
+
+// CORSConfig is the configuration of CORS. CORS is enabled when an origin is allowed.
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to call the api, "*" allows any origin
+	AllowedOrigins []string `yaml:"allowed_origins" toml:"allowed_origins"`
+	// AllowedMethods are the methods allowed in cross-origin requests
+	AllowedMethods []string `yaml:"allowed_methods" toml:"allowed_methods"`
+	// AllowedHeaders are the request headers allowed in cross-origin requests
+	AllowedHeaders []string `yaml:"allowed_headers" toml:"allowed_headers"`
+	// AllowCredentials allows cookies and authorization headers in cross-origin requests
+	AllowCredentials bool `yaml:"allow_credentials" toml:"allow_credentials"`
+	// MaxAge is how long browsers cache a preflight response
+	MaxAge time.Duration `yaml:"max_age" toml:"max_age"`
+}
+
+// Default returns the default configuration.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Host:            "localhost",
+			Port:            "8080",
+			ReadTimeout:     5 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 15 * time.Second,
+			RequestTimeout:  8 * time.Second,
+			MaxBodyBytes:    1 << 20,
+		},
+		Storage: StorageConfig{
+			Backend: StorageBackendJSON,
+			Path:    "docs/db/products.json",
+		},
+		Auth: AuthConfig{
+			Mode: AuthModeToken,
+		},
+		Log: LogConfig{
+			Level:      "info",
+			Format:     LogFormatText,
+			SampleRate: 1,
+		},
+		Tracing: TracingConfig{
+			Exporter:    TracingExporterNone,
+			Endpoint:    "http://localhost:4318/v1/traces",
+			ServiceName: "supermarket",
+		},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders: []string{"Content-Type", "Token", "X-Request-ID", "Idempotency-Key", "X-Actor"},
+			MaxAge:         10 * time.Minute,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Read:    LimitConfig{Rate: 20, Burst: 40},
+			Write:   LimitConfig{Rate: 5, Burst: 10},
+		},
+		HTTPCache: HTTPCacheConfig{
+			ListCacheControl:     "no-cache",
+			ItemCacheControl:     "no-cache",
+			ResponseCacheEntries: 1024,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL: 24 * time.Hour,
+		},
+		GraphQL: GraphQLConfig{
+			MaxDepth:      8,
+			MaxComplexity: 1000,
+		},
+		Events: EventsConfig{
+			LogSize:   1024,
+			Heartbeat: 15 * time.Second,
+		},
+		Webhooks: WebhooksConfig{
+			File:        "docs/db/webhooks.json",
+			MaxAttempts: 8,
+			MinBackoff:  10 * time.Second,
+			MaxBackoff:  time.Hour,
+			Timeout:     10 * time.Second,
+
+			RetentionMaxCount: 100,
+			RetentionMaxAge:   7 * 24 * time.Hour,
+		},
+		Snapshots: SnapshotsConfig{
+			Dir:      "docs/db/snapshots",
+			MaxCount: 10,
+		},
+		Prices: PricesConfig{
+			File: "docs/db/prices.json",
+		},
+		Categories: CategoriesConfig{
+			File: "docs/db/categories.json",
+		},
+		Inventory: InventoryConfig{
+			File: "docs/db/inventory.json",
+		},
+	}
+}
+
+// Validate checks that the configuration is consistent.
+func (c Config) Validate() error {
+	var errs []error
+
+	// server
+	if c.Server.Host == "" {
+		errs = append(errs, errors.New("server.host is required"))
+	}
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 0 || port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port %q is not a valid port", c.Server.Port))
+	}
+	if c.Server.GRPCPort != "" {
+		if port, err := strconv.Atoi(c.Server.GRPCPort); err != nil || port < 0 || port > 65535 {
+			errs = append(errs, fmt.Errorf("server.grpc_port %q is not a valid port", c.Server.GRPCPort))
+		} else if c.Server.GRPCPort == c.Server.Port && port != 0 {
+			errs = append(errs, errors.New("server.grpc_port must differ from server.port"))
+		}
+	}
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", timeout.name))
+		}
+	}
+	if c.Server.ShutdownDelay < 0 {
+		errs = append(errs, errors.New("server.shutdown_delay must not be negative"))
+	}
+	if c.Server.RequestTimeout < 0 {
+		errs = append(errs, errors.New("server.request_timeout must not be negative"))
+	}
+	if c.Server.RequestTimeout > 0 && c.Server.RequestTimeout >= c.Server.WriteTimeout {
+		errs = append(errs, errors.New("server.request_timeout must be shorter than server.write_timeout"))
+	}
+	if c.Server.MaxBodyBytes < 0 {
+		errs = append(errs, errors.New("server.max_body_bytes must not be negative"))
+	}
+	if (c.Server.TLS.CertFile == "") != (c.Server.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("server.tls.cert_file and server.tls.key_file must be set together"))
+	}
+
+	// storage
+	switch c.Storage.Backend {
+	case StorageBackendJSON:
+		if c.Storage.Path == "" {
+			errs = append(errs, errors.New("storage.path is required by the json backend"))
+		}
+	case StorageBackendMemory:
+	default:
+		errs = append(errs, fmt.Errorf("storage.backend %q is not one of %s, %s", c.Storage.Backend, StorageBackendJSON, StorageBackendMemory))
+	}
+
+	// auth
+	switch c.Auth.Mode {
+	case AuthModeToken:
+		if c.Auth.Token == "" {
+			errs = append(errs, errors.New("auth.token is required by the token auth mode"))
+		}
+	case AuthModeNone:
+	default:
+		errs = append(errs, fmt.Errorf("auth.mode %q is not one of %s, %s", c.Auth.Mode, AuthModeToken, AuthModeNone))
+	}
+
+	// log
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level %q is not one of debug, info, warn, error", c.Log.Level))
+	}
+	switch c.Log.Format {
+	case LogFormatText, LogFormatJSON:
+	default:
+		errs = append(errs, fmt.Errorf("log.format %q is not one of %s, %s", c.Log.Format, LogFormatText, LogFormatJSON))
+	}
+	if c.Log.SampleRate < 0 || c.Log.SampleRate > 1 {
+		errs = append(errs, fmt.Errorf("log.sample_rate %v is not between 0 and 1", c.Log.SampleRate))
+	}
+
+	// graphql
+	if c.GraphQL.MaxDepth <= 0 {
+		errs = append(errs, errors.New("graphql.max_depth must be positive"))
+	}
+	if c.GraphQL.MaxComplexity <= 0 {
+		errs = append(errs, errors.New("graphql.max_complexity must be positive"))
+	}
+
+	// events
+	if c.Events.LogSize <= 0 {
+		errs = append(errs, errors.New("events.log_size must be positive"))
+	}
+	if c.Events.Heartbeat <= 0 {
+		errs = append(errs, errors.New("events.heartbeat must be positive"))
+	}
+
+	// webhooks
+	if c.Webhooks.MaxAttempts <= 0 {
+		errs = append(errs, errors.New("webhooks.max_attempts must be positive"))
+	}
+	if c.Webhooks.MinBackoff <= 0 || c.Webhooks.Timeout <= 0 {
+		errs = append(errs, errors.New("webhooks.min_backoff and webhooks.timeout must be positive"))
+	}
+	if c.Webhooks.MaxBackoff < c.Webhooks.MinBackoff {
+		errs = append(errs, errors.New("webhooks.max_backoff must not be shorter than webhooks.min_backoff"))
+	}
+	if c.Webhooks.RetentionMaxCount < 0 || c.Webhooks.RetentionMaxAge < 0 {
+		errs = append(errs, errors.New("webhooks.retention_max_count and webhooks.retention_max_age must not be negative"))
+	}
+
+	// snapshots
+	if c.Snapshots.MaxCount < 0 || c.Snapshots.MaxAge < 0 {
+		errs = append(errs, errors.New("snapshots.max_count and snapshots.max_age must not be negative"))
+	}
+
+	// tracing
+	switch c.Tracing.Exporter {
+	case TracingExporterNone, TracingExporterStdout:
+	case TracingExporterOTLP:
+		if c.Tracing.Endpoint == "" {
+			errs = append(errs, errors.New("tracing.endpoint is required by the otlp exporter"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter %q is not one of %s, %s, %s", c.Tracing.Exporter, TracingExporterNone, TracingExporterStdout, TracingExporterOTLP))
+	}
+
+	// rate limit
+	if c.RateLimit.Enabled {
+		limits := []struct {
+			name  string
+			value LimitConfig
+		}{
+			{"rate_limit.read", c.RateLimit.Read},
+			{"rate_limit.write", c.RateLimit.Write},
+		}
+		for _, limit := range limits {
+			if limit.value.Rate <= 0 || limit.value.Burst <= 0 {
+				errs = append(errs, fmt.Errorf("%s.rate and %s.burst must be positive", limit.name, limit.name))
+			}
+		}
+	}
+	for _, proxy := range c.RateLimit.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs = append(errs, fmt.Errorf("rate_limit.trusted_proxies %q is not an ip or a CIDR", proxy))
+			}
+		}
+	}
+
+	// cors
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" && c.CORS.AllowCredentials {
+			errs = append(errs, errors.New("cors.allow_credentials can not be used with the origin *"))
+		}
+	}
+	if c.CORS.MaxAge < 0 {
+		errs = append(errs, errors.New("cors.max_age must not be negative"))
+	}
+
+	// http cache
+	if c.HTTPCache.ResponseCache && c.HTTPCache.ResponseCacheEntries <= 0 {
+		errs = append(errs, errors.New("http_cache.response_cache_entries must be positive"))
+	}
+
+	// idempotency
+	if c.Idempotency.TTL <= 0 {
+		errs = append(errs, errors.New("idempotency.ttl must be positive"))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, errors.Join(errs...))
+	}
+	return nil
+}
+
+// Redacted returns a copy of the configuration with the secrets replaced.
+func (c Config) Redacted() Config {
+	if c.Auth.Token != "" {
+		c.Auth.Token = redacted
+	}
+	return c
+}
+
+// Print writes the effective configuration as YAML, with the secrets redacted.
+func (c Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	defer enc.Close()
+	return enc.Encode(c.Redacted())
+}
@@ -0,0 +1,274 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// knob binds a configuration value to its environment variable and command-line flag
+type knob struct {
+	// env is the name of the environment variable
+	env string
+	// flag is the name of the command-line flag
+	flag string
+	// usage is the description of the flag
+	usage string
+	// set parses the raw value into the configuration
+	set func(c *Config, v string) error
+}
+
+// knobs are all the values that can be set from the environment and the command line
+var knobs = []knob{
+	{"ENV_HOST", "host", "host the server listens on", setString(func(c *Config) *string { return &c.Server.Host })},
+	{"ENV_PORT", "port", "port the server listens on", setString(func(c *Config) *string { return &c.Server.Port })},
+	{"ENV_GRPC_PORT", "grpc-port", "port of the grpc api, disabled if empty", setString(func(c *Config) *string { return &c.Server.GRPCPort })},
+	{"ENV_READ_TIMEOUT", "read-timeout", "maximum duration for reading a request", setDuration(func(c *Config) *time.Duration { return &c.Server.ReadTimeout })},
+	{"ENV_WRITE_TIMEOUT", "write-timeout", "maximum duration for writing a response", setDuration(func(c *Config) *time.Duration { return &c.Server.WriteTimeout })},
+	{"ENV_IDLE_TIMEOUT", "idle-timeout", "maximum duration of idle keep-alive connections", setDuration(func(c *Config) *time.Duration { return &c.Server.IdleTimeout })},
+	{"ENV_SHUTDOWN_TIMEOUT", "shutdown-timeout", "maximum duration to drain in-flight requests", setDuration(func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout })},
+	{"ENV_SHUTDOWN_DELAY", "shutdown-delay", "time to fail the readiness before draining on shutdown", setDuration(func(c *Config) *time.Duration { return &c.Server.ShutdownDelay })},
+	{"ENV_REQUEST_TIMEOUT", "request-timeout", "deadline of each request, 0 disables it", setDuration(func(c *Config) *time.Duration { return &c.Server.RequestTimeout })},
+	{"ENV_MAX_BODY_BYTES", "max-body-bytes", "maximum size of a request body, 0 disables it", setInt64(func(c *Config) *int64 { return &c.Server.MaxBodyBytes })},
+	{"ENV_STRICT_JSON", "strict-json", "reject json bodies with unknown fields or trailing data", setBool(func(c *Config) *bool { return &c.Server.StrictJSON })},
+	{"ENV_TLS_CERT_FILE", "tls-cert-file", "path to the TLS certificate", setString(func(c *Config) *string { return &c.Server.TLS.CertFile })},
+	{"ENV_TLS_KEY_FILE", "tls-key-file", "path to the TLS private key", setString(func(c *Config) *string { return &c.Server.TLS.KeyFile })},
+	{"ENV_STORAGE_BACKEND", "storage-backend", "storage backend: json or memory", setString(func(c *Config) *string { return &c.Storage.Backend })},
+	{"ENV_PATH_DBFILE", "db-file", "path to the JSON file of products", setString(func(c *Config) *string { return &c.Storage.Path })},
+	{"ENV_AUTH_MODE", "auth-mode", "auth mode: token or none", setString(func(c *Config) *string { return &c.Auth.Mode })},
+	{"ENV_TOKEN", "token", "token required by write requests", setString(func(c *Config) *string { return &c.Auth.Token })},
+	{"ENV_LOG_LEVEL", "log-level", "log level: debug, info, warn or error", setString(func(c *Config) *string { return &c.Log.Level })},
+	{"ENV_LOG_FORMAT", "log-format", "log format: text or json", setString(func(c *Config) *string { return &c.Log.Format })},
+	{"ENV_LOG_SAMPLE_RATE", "log-sample-rate", "fraction of successful requests logged", setFloat(func(c *Config) *float64 { return &c.Log.SampleRate })},
+	{"ENV_RATE_LIMIT_ENABLED", "rate-limit-enabled", "enable the rate limiting of requests", setBool(func(c *Config) *bool { return &c.RateLimit.Enabled })},
+	{"ENV_RATE_LIMIT_READ_RATE", "rate-limit-read-rate", "requests per second allowed per client ip on the GET routes", setFloat(func(c *Config) *float64 { return &c.RateLimit.Read.Rate })},
+	{"ENV_RATE_LIMIT_READ_BURST", "rate-limit-read-burst", "requests allowed at once per client ip on the GET routes", setInt(func(c *Config) *int { return &c.RateLimit.Read.Burst })},
+	{"ENV_RATE_LIMIT_WRITE_RATE", "rate-limit-write-rate", "requests per second allowed per token or client ip on the write routes", setFloat(func(c *Config) *float64 { return &c.RateLimit.Write.Rate })},
+	{"ENV_RATE_LIMIT_WRITE_BURST", "rate-limit-write-burst", "requests allowed at once per token or client ip on the write routes", setInt(func(c *Config) *int { return &c.RateLimit.Write.Burst })},
+	{"ENV_TRUSTED_PROXIES", "trusted-proxies", "comma-separated ips or CIDRs of the trusted proxies", setList(func(c *Config) *[]string { return &c.RateLimit.TrustedProxies })},
+	{"ENV_CORS_ALLOWED_ORIGINS", "cors-allowed-origins", "comma-separated origins allowed to call the api, * for any", setList(func(c *Config) *[]string { return &c.CORS.AllowedOrigins })},
+	{"ENV_CORS_ALLOWED_METHODS", "cors-allowed-methods", "comma-separated methods allowed in cross-origin requests", setList(func(c *Config) *[]string { return &c.CORS.AllowedMethods })},
+	{"ENV_CORS_ALLOWED_HEADERS", "cors-allowed-headers", "comma-separated headers allowed in cross-origin requests", setList(func(c *Config) *[]string { return &c.CORS.AllowedHeaders })},
+	{"ENV_CORS_ALLOW_CREDENTIALS", "cors-allow-credentials", "allow credentials in cross-origin requests", setBool(func(c *Config) *bool { return &c.CORS.AllowCredentials })},
+	{"ENV_CORS_MAX_AGE", "cors-max-age", "duration browsers cache a preflight response", setDuration(func(c *Config) *time.Duration { return &c.CORS.MaxAge })},
+	{"ENV_TRACING_EXPORTER", "tracing-exporter", "tracing exporter: none, stdout or otlp", setString(func(c *Config) *string { return &c.Tracing.Exporter })},
+	{"ENV_TRACING_ENDPOINT", "tracing-endpoint", "OTLP/HTTP traces endpoint", setString(func(c *Config) *string { return &c.Tracing.Endpoint })},
+	{"ENV_CACHE_CONTROL_LIST", "cache-control-list", "Cache-Control header of GET /products", setString(func(c *Config) *string { return &c.HTTPCache.ListCacheControl })},
+	{"ENV_CACHE_CONTROL_ITEM", "cache-control-item", "Cache-Control header of GET /products/{id}", setString(func(c *Config) *string { return &c.HTTPCache.ItemCacheControl })},
+	{"ENV_RESPONSE_CACHE_ENABLED", "response-cache-enabled", "cache the catalogue reads in memory", setBool(func(c *Config) *bool { return &c.HTTPCache.ResponseCache })},
+	{"ENV_RESPONSE_CACHE_ENTRIES", "response-cache-entries", "maximum number of responses cached", setInt(func(c *Config) *int { return &c.HTTPCache.ResponseCacheEntries })},
+	{"ENV_IDEMPOTENCY_TTL", "idempotency-ttl", "duration the response of an Idempotency-Key is replayed", setDuration(func(c *Config) *time.Duration { return &c.Idempotency.TTL })},
+	{"ENV_IDEMPOTENCY_FILE", "idempotency-file", "json file persisting the idempotency keys, in memory if empty", setString(func(c *Config) *string { return &c.Idempotency.File })},
+	{"ENV_GRAPHQL_MAX_DEPTH", "graphql-max-depth", "maximum nesting of the fields of a graphql query", setInt(func(c *Config) *int { return &c.GraphQL.MaxDepth })},
+	{"ENV_GRAPHQL_MAX_COMPLEXITY", "graphql-max-complexity", "maximum cost of a graphql query, the fields of lists counted once per item", setInt(func(c *Config) *int { return &c.GraphQL.MaxComplexity })},
+	{"ENV_EVENTS_LOG_SIZE", "events-log-size", "number of catalogue events kept for the streams to resume from", setInt(func(c *Config) *int { return &c.Events.LogSize })},
+	{"ENV_EVENTS_HEARTBEAT", "events-heartbeat", "interval of the heartbeats of the idle event streams", setDuration(func(c *Config) *time.Duration { return &c.Events.Heartbeat })},
+	{"ENV_WEBHOOKS_FILE", "webhooks-file", "json file persisting the webhooks and their outbox, in memory if empty", setString(func(c *Config) *string { return &c.Webhooks.File })},
+	{"ENV_WEBHOOKS_MAX_ATTEMPTS", "webhooks-max-attempts", "attempts of a webhook delivery before it is dead-lettered", setInt(func(c *Config) *int { return &c.Webhooks.MaxAttempts })},
+	{"ENV_WEBHOOKS_MIN_BACKOFF", "webhooks-min-backoff", "delay before the second attempt of a webhook delivery, doubled for every attempt", setDuration(func(c *Config) *time.Duration { return &c.Webhooks.MinBackoff })},
+	{"ENV_WEBHOOKS_MAX_BACKOFF", "webhooks-max-backoff", "maximum delay between the attempts of a webhook delivery", setDuration(func(c *Config) *time.Duration { return &c.Webhooks.MaxBackoff })},
+	{"ENV_WEBHOOKS_TIMEOUT", "webhooks-timeout", "timeout of an attempt of a webhook delivery", setDuration(func(c *Config) *time.Duration { return &c.Webhooks.Timeout })},
+	{"ENV_WEBHOOKS_RETENTION_MAX_COUNT", "webhooks-retention-max-count", "delivered and dead deliveries kept per webhook, unbounded if 0", setInt(func(c *Config) *int { return &c.Webhooks.RetentionMaxCount })},
+	{"ENV_WEBHOOKS_RETENTION_MAX_AGE", "webhooks-retention-max-age", "age of the delivered and dead deliveries kept, unbounded if 0", setDuration(func(c *Config) *time.Duration { return &c.Webhooks.RetentionMaxAge })},
+	{"ENV_SNAPSHOTS_DIR", "snapshots-dir", "directory of the snapshots of the catalogue, in memory if empty", setString(func(c *Config) *string { return &c.Snapshots.Dir })},
+	{"ENV_SNAPSHOTS_MAX_COUNT", "snapshots-max-count", "number of snapshots kept, unbounded if 0", setInt(func(c *Config) *int { return &c.Snapshots.MaxCount })},
+	{"ENV_SNAPSHOTS_MAX_AGE", "snapshots-max-age", "age of the snapshots kept, unbounded if 0", setDuration(func(c *Config) *time.Duration { return &c.Snapshots.MaxAge })},
+	{"ENV_PRICES_FILE", "prices-file", "json file persisting the price history and the scheduled prices, in memory if empty", setString(func(c *Config) *string { return &c.Prices.File })},
+	{"ENV_CATEGORIES_FILE", "categories-file", "json file persisting the categories and the categories of the products, in memory if empty", setString(func(c *Config) *string { return &c.Categories.File })},
+	{"ENV_INVENTORY_FILE", "inventory-file", "json file persisting the stores, their stock and the transfers, in memory if empty", setString(func(c *Config) *string { return &c.Inventory.File })},
+	{"ENV_TRACING_SERVICE_NAME", "tracing-service-name", "service name reported in the traces", setString(func(c *Config) *string { return &c.Tracing.ServiceName })},
+}
+
+func setString(field func(c *Config) *string) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		*field(c) = v
+		return nil
+	}
+}
+
+func setFloat(field func(c *Config) *float64) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		*field(c) = f
+		return nil
+	}
+}
+
+func setInt(field func(c *Config) *int) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = i
+		return nil
+	}
+}
+
+func setInt64(field func(c *Config) *int64) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return err
+		}
+		*field(c) = i
+		return nil
+	}
+}
+
+func setBool(field func(c *Config) *bool) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func setList(field func(c *Config) *[]string) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		var list []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*field(c) = list
+		return nil
+	}
+}
+
+func setDuration(field func(c *Config) *time.Duration) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+// NewLoader creates a Loader reading the given command-line arguments and environment
+func NewLoader(args []string, getenv func(string) string) *Loader {
+	return &Loader{
+		args:   args,
+		getenv: getenv,
+	}
+}
+
+// Loader merges the configuration, in increasing order of precedence, from:
+// the defaults, the config file (YAML or TOML), the environment and the command-line flags.
+type Loader struct {
+	// args are the command-line arguments, without the program name
+	args []string
+	// getenv reads an environment variable
+	getenv func(string) string
+
+	// PrintConfig is set by the -print-config flag once Load is called
+	PrintConfig bool
+	// rest are the arguments following the flags, set once Load is called
+	rest []string
+}
+
+// Args returns the arguments following the flags, e.g. the subcommand of a cli, once Load is called.
+func (l *Loader) Args() []string {
+	return l.rest
+}
+
+// Load merges and validates the configuration.
+func (l *Loader) Load() (Config, error) {
+	cfg, err := l.Merge()
+	if err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// Merge merges the configuration without validating it, for the tools using only a part of it.
+func (l *Loader) Merge() (cfg Config, err error) {
+	cfg = Default()
+
+	// flags are parsed first to find the config file, but applied last
+	fs := flag.NewFlagSet("supermarket", flag.ContinueOnError)
+	var configFile string
+	fs.StringVar(&configFile, "config", l.getenv("ENV_CONFIG_FILE"), "path to a YAML or TOML config file")
+	fs.BoolVar(&l.PrintConfig, "print-config", false, "print the effective config, with secrets redacted, and exit")
+	flagValues := make(map[string]string)
+	for _, k := range knobs {
+		k := k
+		fs.Func(k.flag, k.usage+" (env "+k.env+")", func(v string) error {
+			flagValues[k.flag] = v
+			return nil
+		})
+	}
+	if err = fs.Parse(l.args); err != nil {
+		return
+	}
+	l.rest = fs.Args()
+
+	// - file
+	if configFile != "" {
+		if err = loadFile(configFile, &cfg); err != nil {
+			return
+		}
+	}
+
+	// - environment
+	for _, k := range knobs {
+		v := l.getenv(k.env)
+		if v == "" {
+			continue
+		}
+		if err = k.set(&cfg, v); err != nil {
+			err = fmt.Errorf("%w: %s: %v", ErrInvalidConfig, k.env, err)
+			return
+		}
+	}
+
+	// - flags
+	for _, k := range knobs {
+		v, ok := flagValues[k.flag]
+		if !ok {
+			continue
+		}
+		if err = k.set(&cfg, v); err != nil {
+			err = fmt.Errorf("%w: -%s: %v", ErrInvalidConfig, k.flag, err)
+			return
+		}
+	}
+	return
+}
+
+// loadFile decodes the config file over cfg, choosing the format by extension
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrConfigFile, err)
+	}
+
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	default:
+		err = fmt.Errorf("unsupported extension %q, use .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrConfigFile, path, err)
+	}
+	return nil
+}
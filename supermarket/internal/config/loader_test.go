@@ -0,0 +1,101 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"supermarket/internal/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoaderLoad tests the precedence of the Loader.
+func TestLoaderLoad(t *testing.T) {
+	t.Run("success - flags override environment, environment overrides file", func(t *testing.T) {
+		// arrange
+		file := filepath.Join(t.TempDir(), "config.yaml")
+		err := os.WriteFile(file, []byte("server:\n  port: \"9000\"\n  read_timeout: 2s\n  host: filehost\nauth:\n  token: filetoken\n"), 0644)
+		require.NoError(t, err)
+		env := map[string]string{
+			"ENV_PORT":  "9001",
+			"ENV_TOKEN": "envtoken",
+		}
+		loader := config.NewLoader([]string{"-config", file, "-port", "9002"}, func(k string) string { return env[k] })
+
+		// act
+		cfg, err := loader.Load()
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, "filehost", cfg.Server.Host)
+		require.Equal(t, "9002", cfg.Server.Port)
+		require.Equal(t, 2*time.Second, cfg.Server.ReadTimeout)
+		require.Equal(t, "envtoken", cfg.Auth.Token)
+		require.Equal(t, config.StorageBackendJSON, cfg.Storage.Backend)
+	})
+
+	t.Run("success - print redacts secrets", func(t *testing.T) {
+		// arrange
+		loader := config.NewLoader([]string{"-token", "secret", "-print-config"}, func(string) string { return "" })
+		cfg, err := loader.Load()
+		require.NoError(t, err)
+		var sb strings.Builder
+
+		// act
+		err = cfg.Print(&sb)
+
+		// assert
+		require.NoError(t, err)
+		require.True(t, loader.PrintConfig)
+		require.NotContains(t, sb.String(), "secret")
+	})
+
+	t.Run("error - invalid configuration", func(t *testing.T) {
+		// arrange
+		loader := config.NewLoader([]string{"-auth-mode", "none", "-storage-backend", "sql"}, func(string) string { return "" })
+
+		// act
+		_, err := loader.Load()
+
+		// assert
+		require.ErrorIs(t, err, config.ErrInvalidConfig)
+	})
+
+	t.Run("error - grpc port same as port", func(t *testing.T) {
+		// arrange
+		loader := config.NewLoader([]string{"-port", "9000", "-grpc-port", "9000"}, func(string) string { return "" })
+
+		// act
+		_, err := loader.Load()
+
+		// assert
+		require.ErrorIs(t, err, config.ErrInvalidConfig)
+		require.ErrorContains(t, err, "server.grpc_port must differ from server.port")
+	})
+
+	t.Run("error - graphql max depth not positive", func(t *testing.T) {
+		// arrange
+		loader := config.NewLoader([]string{"-graphql-max-depth", "0"}, func(string) string { return "" })
+
+		// act
+		_, err := loader.Load()
+
+		// assert
+		require.ErrorIs(t, err, config.ErrInvalidConfig)
+		require.ErrorContains(t, err, "graphql.max_depth must be positive")
+	})
+
+	t.Run("error - snapshots max count negative", func(t *testing.T) {
+		// arrange
+		loader := config.NewLoader([]string{"-snapshots-max-count", "-1"}, func(string) string { return "" })
+
+		// act
+		_, err := loader.Load()
+
+		// assert
+		require.ErrorIs(t, err, config.ErrInvalidConfig)
+		require.ErrorContains(t, err, "snapshots.max_count and snapshots.max_age must not be negative")
+	})
+}
@@ -0,0 +1,257 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"supermarket/internal/inventory"
+	"supermarket/internal/platform/logging"
+	"supermarket/internal/platform/web/request"
+	"supermarket/internal/platform/web/response"
+	internalProduct "supermarket/internal/product"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type InventoryHandler struct {
+	Inventory *inventory.Inventory
+	// StrictJSON rejects the json bodies with unknown fields or trailing data
+	StrictJSON bool
+}
+
+// NewInventoryHandler returns a new InventoryHandler.
+func NewInventoryHandler(inv *inventory.Inventory) *InventoryHandler {
+	return &InventoryHandler{
+		Inventory: inv,
+	}
+}
+
+// StoreRequestJSON is the body of a request creating or replacing a store
+type StoreRequestJSON struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+// StockRequestJSON is the body of a request setting the stock of a product at a store
+type StockRequestJSON struct {
+	Quantity int `json:"quantity"`
+}
+
+// TransferRequestJSON is the body of a request transferring stock between stores
+type TransferRequestJSON struct {
+	ProductID   int `json:"product_id"`
+	FromStoreID int `json:"from_store_id"`
+	ToStoreID   int `json:"to_store_id"`
+	Quantity    int `json:"quantity"`
+}
+
+// CreateStoreHandler creates a store.
+func (h *InventoryHandler) CreateStoreHandler(w http.ResponseWriter, r *http.Request) {
+	var storeRequest StoreRequestJSON
+	if err := request.JSON(r, &storeRequest, request.Options(h.StrictJSON)...); err != nil {
+		response.RequestError(w, err)
+		return
+	}
+
+	store, err := h.Inventory.CreateStore(r.Context(), inventory.Store{Name: storeRequest.Name, Address: storeRequest.Address})
+	if err != nil {
+		inventoryError(w, r, "create store", err)
+		return
+	}
+	response.JSON(w, http.StatusCreated, "store created successfully", store)
+}
+
+// GetStoresHandler returns the stores, sorted by id.
+func (h *InventoryHandler) GetStoresHandler(w http.ResponseWriter, r *http.Request) {
+	stores, err := h.Inventory.ListStores(r.Context())
+	if err != nil {
+		inventoryError(w, r, "get stores", err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "stores fetched successfully", stores)
+}
+
+// GetStoreHandler returns a store by id.
+func (h *InventoryHandler) GetStoreHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := urlID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	store, err := h.Inventory.GetStore(r.Context(), id)
+	if err != nil {
+		inventoryError(w, r, "get store", err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "store fetched successfully", store)
+}
+
+// UpdateStoreHandler renames a store or changes its address.
+func (h *InventoryHandler) UpdateStoreHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := urlID(w, r, "id")
+	if !ok {
+		return
+	}
+	var storeRequest StoreRequestJSON
+	if err := request.JSON(r, &storeRequest, request.Options(h.StrictJSON)...); err != nil {
+		response.RequestError(w, err)
+		return
+	}
+
+	store, err := h.Inventory.UpdateStore(r.Context(), inventory.Store{ID: id, Name: storeRequest.Name, Address: storeRequest.Address})
+	if err != nil {
+		inventoryError(w, r, "update store", err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "store updated successfully", store)
+}
+
+// DeleteStoreHandler deletes a store without stock.
+func (h *InventoryHandler) DeleteStoreHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := urlID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.Inventory.DeleteStore(r.Context(), id); err != nil {
+		inventoryError(w, r, "delete store", err)
+		return
+	}
+	response.Text(w, http.StatusOK, "store deleted successfully")
+}
+
+// GetStockHandler returns the products in stock at a store, sorted by product id.
+func (h *InventoryHandler) GetStockHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := urlID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	stock, err := h.Inventory.Stock(r.Context(), id)
+	if err != nil {
+		inventoryError(w, r, "get stock", err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "stock fetched successfully", stock)
+}
+
+// SetStockHandler sets the quantity of a product at a store.
+func (h *InventoryHandler) SetStockHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := urlID(w, r, "id")
+	if !ok {
+		return
+	}
+	productID, ok := urlID(w, r, "productId")
+	if !ok {
+		return
+	}
+	var stockRequest StockRequestJSON
+	if err := request.JSON(r, &stockRequest, request.Options(h.StrictJSON)...); err != nil {
+		response.RequestError(w, err)
+		return
+	}
+
+	level, err := h.Inventory.SetStock(r.Context(), id, productID, stockRequest.Quantity)
+	if err != nil {
+		inventoryError(w, r, "set stock", err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "stock updated successfully", level)
+}
+
+// CreateTransferHandler moves stock of a product from a store to another.
+func (h *InventoryHandler) CreateTransferHandler(w http.ResponseWriter, r *http.Request) {
+	var transferRequest TransferRequestJSON
+	if err := request.JSON(r, &transferRequest, request.Options(h.StrictJSON)...); err != nil {
+		response.RequestError(w, err)
+		return
+	}
+
+	transfer, err := h.Inventory.Transfer(r.Context(), inventory.Transfer{
+		ProductID:   transferRequest.ProductID,
+		FromStoreID: transferRequest.FromStoreID,
+		ToStoreID:   transferRequest.ToStoreID,
+		Quantity:    transferRequest.Quantity,
+	})
+	if err != nil {
+		inventoryError(w, r, "create transfer", err)
+		return
+	}
+	response.JSON(w, http.StatusCreated, "stock transferred successfully", transfer)
+}
+
+// GetTransfersHandler returns the transfers, the newest first, of the product and the store of the product
+// and store query parameters if any.
+func (h *InventoryHandler) GetTransfersHandler(w http.ResponseWriter, r *http.Request) {
+	productID, ok := queryID(w, r, "product")
+	if !ok {
+		return
+	}
+	storeID, ok := queryID(w, r, "store")
+	if !ok {
+		return
+	}
+
+	transfers, err := h.Inventory.Transfers(r.Context(), productID, storeID)
+	if err != nil {
+		inventoryError(w, r, "get transfers", err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "transfers fetched successfully", transfers)
+}
+
+// GetInventoryHandler returns the stock of the products aggregated over the stores.
+func (h *InventoryHandler) GetInventoryHandler(w http.ResponseWriter, r *http.Request) {
+	totals, err := h.Inventory.Totals(r.Context())
+	if err != nil {
+		inventoryError(w, r, "get inventory", err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "inventory fetched successfully", totals)
+}
+
+// urlID returns the id of the url parameter name, responding a bad request if it is not a number
+func urlID(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, name))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid id")
+		return 0, false
+	}
+	return id, true
+}
+
+// queryID returns the id of the query parameter name, 0 if absent, responding a bad request if it is not a
+// number
+func queryID(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	param := r.URL.Query().Get(name)
+	if param == "" {
+		return 0, true
+	}
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid "+name)
+		return 0, false
+	}
+	return id, true
+}
+
+// inventoryError responds the error of the inventory or the products, logging the unexpected ones
+func inventoryError(w http.ResponseWriter, r *http.Request, op string, err error) {
+	switch {
+	case errors.Is(err, internalProduct.ErrInvalidID):
+		response.Errorw(w, http.StatusBadRequest, err)
+	case errors.Is(err, internalProduct.ErrProductNotFound), errors.Is(err, inventory.ErrStoreNotFound):
+		response.Errorw(w, http.StatusNotFound, err)
+	case errors.Is(err, inventory.ErrDuplicateName), errors.Is(err, inventory.ErrStoreInUse), errors.Is(err, inventory.ErrInsufficientStock):
+		response.Errorw(w, http.StatusConflict, err)
+	case errors.Is(err, inventory.ErrInvalidStore), errors.Is(err, inventory.ErrInvalidQuantity), errors.Is(err, inventory.ErrSameStore):
+		response.Errorw(w, http.StatusUnprocessableEntity, err)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		response.Error(w, http.StatusGatewayTimeout, "request timeout")
+	default:
+		logging.FromContext(r.Context()).Error(op, slog.Any("error", err))
+		response.Error(w, http.StatusInternalServerError, "internal server error")
+	}
+}
@@ -0,0 +1,556 @@
+// Package inventory keeps the stores of the supermarket, the stock of the products at each store, and the
+// audit trail of the transfers of stock between stores.
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"supermarket/internal/auth"
+	"supermarket/internal/platform/random"
+	internalProduct "supermarket/internal/product"
+	"sync"
+	"time"
+)
+
+type Product = internalProduct.Product
+
+var (
+	// ErrStoreNotFound is returned when a store does not exist.
+	ErrStoreNotFound = internalProduct.ErrStoreNotFound
+	// ErrInvalidStore is returned for a store without name.
+	ErrInvalidStore = errors.New("inventory: invalid store")
+	// ErrDuplicateName is returned for a store with the name of another.
+	ErrDuplicateName = errors.New("inventory: duplicated store name")
+	// ErrStoreInUse is returned when deleting a store with stock.
+	ErrStoreInUse = errors.New("inventory: store has stock")
+	// ErrInvalidQuantity is returned for a negative stock or a transfer of no units.
+	ErrInvalidQuantity = errors.New("inventory: invalid quantity")
+	// ErrSameStore is returned for a transfer from a store to itself.
+	ErrSameStore = errors.New("inventory: transfer to the same store")
+	// ErrInsufficientStock is returned for a transfer of more units than the stock of the source store.
+	ErrInsufficientStock = errors.New("inventory: insufficient stock")
+
+	// errUnchanged is returned by the functions of change leaving the data unchanged
+	errUnchanged = errors.New("inventory: unchanged")
+)
+
+// Store is a supermarket, holding stock of the products.
+type Store struct {
+	// ID identifies the store
+	ID int `json:"id"`
+	// Name is unique among the stores
+	Name string `json:"name"`
+	// Address is where the store is, optional
+	Address string `json:"address"`
+}
+
+// StockLevel is the quantity of a product at a store.
+type StockLevel struct {
+	ProductID int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+}
+
+// Total is the stock of a product aggregated over the stores.
+type Total struct {
+	ProductID int `json:"product_id"`
+	// Quantity is the sum of the quantities of the stores
+	Quantity int `json:"quantity"`
+	// Stores are the quantities by store id, the stores without stock omitted
+	Stores map[int]int `json:"stores"`
+}
+
+// Transfer is a move of stock of a product from a store to another, kept as audit trail.
+type Transfer struct {
+	// ID identifies the transfer
+	ID          string `json:"id"`
+	ProductID   int    `json:"product_id"`
+	FromStoreID int    `json:"from_store_id"`
+	ToStoreID   int    `json:"to_store_id"`
+	// Quantity is the number of units moved
+	Quantity int `json:"quantity"`
+	// Actor is who made the transfer, empty if unknown
+	Actor string `json:"actor"`
+	// CreatedAt is when the transfer was made
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Data is the content of a storage.
+type Data struct {
+	// Stores are the stores by id
+	Stores map[int]Store `json:"stores"`
+	// Stock are the quantities by store id then product id, the products without stock omitted
+	Stock map[int]map[int]int `json:"stock"`
+	// Transfers are the transfers, the oldest first
+	Transfers []Transfer `json:"transfers"`
+}
+
+// Storage loads and saves the inventory. Implementations must be safe for concurrent use.
+type Storage interface {
+	// Load returns the data of the storage, empty if never saved
+	Load(ctx context.Context) (Data, error)
+	// Save replaces the data of the storage
+	Save(ctx context.Context, data Data) error
+}
+
+// NewInventory returns the Inventory kept in storage, reading the products from productService.
+func NewInventory(storage Storage, productService internalProduct.ProductServiceInterface) *Inventory {
+	return &Inventory{
+		storage:        storage,
+		productService: productService,
+	}
+}
+
+// Inventory manages the stores, their stock and the transfers between them.
+type Inventory struct {
+	storage        Storage
+	productService internalProduct.ProductServiceInterface
+	// mu serializes the changes, so a transfer is checked and saved as a whole
+	mu sync.Mutex
+	// write writes the stock of a product over the stores to its quantity, if not nil
+	write func(ctx context.Context, id, quantity int) error
+}
+
+// ListStores returns the stores, sorted by id.
+func (i *Inventory) ListStores(ctx context.Context) ([]Store, error) {
+	data, err := i.storage.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stores := make([]Store, 0, len(data.Stores))
+	for _, store := range data.Stores {
+		stores = append(stores, store)
+	}
+	sort.Slice(stores, func(a, b int) bool { return stores[a].ID < stores[b].ID })
+	return stores, nil
+}
+
+// GetStore returns a store by id.
+func (i *Inventory) GetStore(ctx context.Context, id int) (Store, error) {
+	data, err := i.storage.Load(ctx)
+	if err != nil {
+		return Store{}, err
+	}
+	store, ok := data.Stores[id]
+	if !ok {
+		return Store{}, ErrStoreNotFound
+	}
+	return store, nil
+}
+
+// CreateStore adds a store, with the next id.
+func (i *Inventory) CreateStore(ctx context.Context, store Store) (Store, error) {
+	store.ID = 0
+	store.Name = strings.TrimSpace(store.Name)
+	err := i.change(ctx, func(data *Data) error {
+		for id := range data.Stores {
+			store.ID = max(store.ID, id)
+		}
+		store.ID++
+		if err := validate(data, store); err != nil {
+			return err
+		}
+		data.Stores[store.ID] = store
+		return nil
+	})
+	if err != nil {
+		return Store{}, err
+	}
+	return store, nil
+}
+
+// UpdateStore renames a store or changes its address.
+func (i *Inventory) UpdateStore(ctx context.Context, store Store) (Store, error) {
+	store.Name = strings.TrimSpace(store.Name)
+	err := i.change(ctx, func(data *Data) error {
+		if _, ok := data.Stores[store.ID]; !ok {
+			return ErrStoreNotFound
+		}
+		if err := validate(data, store); err != nil {
+			return err
+		}
+		data.Stores[store.ID] = store
+		return nil
+	})
+	if err != nil {
+		return Store{}, err
+	}
+	return store, nil
+}
+
+// DeleteStore removes a store without stock. Its transfers are kept.
+func (i *Inventory) DeleteStore(ctx context.Context, id int) error {
+	return i.change(ctx, func(data *Data) error {
+		store, ok := data.Stores[id]
+		if !ok {
+			return ErrStoreNotFound
+		}
+		if len(data.Stock[id]) > 0 {
+			return fmt.Errorf("%w: %d products in stock at %q", ErrStoreInUse, len(data.Stock[id]), store.Name)
+		}
+		delete(data.Stores, id)
+		delete(data.Stock, id)
+		return nil
+	})
+}
+
+// Stock returns the products in stock at the store id, sorted by product id.
+func (i *Inventory) Stock(ctx context.Context, storeID int) ([]StockLevel, error) {
+	stock, err := i.StoreStock(ctx, storeID)
+	if err != nil {
+		return nil, err
+	}
+	levels := make([]StockLevel, 0, len(stock))
+	for productID, quantity := range stock {
+		levels = append(levels, StockLevel{ProductID: productID, Quantity: quantity})
+	}
+	sort.Slice(levels, func(a, b int) bool { return levels[a].ProductID < levels[b].ProductID })
+	return levels, nil
+}
+
+// StoreStock returns the quantities in stock at the store id, by product id.
+func (i *Inventory) StoreStock(ctx context.Context, storeID int) (map[int]int, error) {
+	data, err := i.storage.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := data.Stores[storeID]; !ok {
+		return nil, ErrStoreNotFound
+	}
+	stock := make(map[int]int, len(data.Stock[storeID]))
+	for productID, quantity := range data.Stock[storeID] {
+		stock[productID] = quantity
+	}
+	return stock, nil
+}
+
+// SetStock sets the quantity of the product id at the store id, e.g. after a delivery or a count, and writes
+// the stock of the product over the stores to its quantity. The stock is saved first, so a failed write is
+// repaired by setting it again.
+func (i *Inventory) SetStock(ctx context.Context, storeID, productID, quantity int) (StockLevel, error) {
+	if quantity < 0 {
+		return StockLevel{}, fmt.Errorf("%w: %d must not be negative", ErrInvalidQuantity, quantity)
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	total := 0
+	err := i.apply(ctx, func(data *Data) error {
+		// the product is checked with the lock held, so its deletion removes the stock after this change
+		if _, err := i.productService.GetProduct(ctx, strconv.Itoa(productID)); err != nil {
+			return err
+		}
+		if _, ok := data.Stores[storeID]; !ok {
+			return ErrStoreNotFound
+		}
+		setStock(data, storeID, productID, quantity)
+		total, _ = countStock(data, productID)
+		return nil
+	})
+	if err != nil {
+		return StockLevel{}, err
+	}
+	if err := i.writeQuantity(ctx, productID, total); err != nil {
+		return StockLevel{}, err
+	}
+	return StockLevel{ProductID: productID, Quantity: quantity}, nil
+}
+
+// Transfer moves stock of a product between two stores, recording the transfer with the actor of ctx in
+// the same save, so the stock and the audit trail never disagree.
+func (i *Inventory) Transfer(ctx context.Context, transfer Transfer) (Transfer, error) {
+	if transfer.Quantity <= 0 {
+		return Transfer{}, fmt.Errorf("%w: %d must be positive", ErrInvalidQuantity, transfer.Quantity)
+	}
+	if transfer.FromStoreID == transfer.ToStoreID {
+		return Transfer{}, ErrSameStore
+	}
+	transfer.ID = random.ID()
+	transfer.Actor = auth.ActorFromContext(ctx)
+	transfer.CreatedAt = time.Now().UTC()
+
+	err := i.change(ctx, func(data *Data) error {
+		for _, storeID := range []int{transfer.FromStoreID, transfer.ToStoreID} {
+			if _, ok := data.Stores[storeID]; !ok {
+				return fmt.Errorf("%w: %d", ErrStoreNotFound, storeID)
+			}
+		}
+		available := data.Stock[transfer.FromStoreID][transfer.ProductID]
+		if available < transfer.Quantity {
+			return fmt.Errorf("%w: %d of %d units in stock", ErrInsufficientStock, available, transfer.Quantity)
+		}
+		setStock(data, transfer.FromStoreID, transfer.ProductID, available-transfer.Quantity)
+		setStock(data, transfer.ToStoreID, transfer.ProductID, data.Stock[transfer.ToStoreID][transfer.ProductID]+transfer.Quantity)
+		data.Transfers = append(data.Transfers, transfer)
+		return nil
+	})
+	if err != nil {
+		return Transfer{}, err
+	}
+	return transfer, nil
+}
+
+// Transfers returns the transfers of the product productID from or to the store storeID, the newest first.
+// A zero id does not filter.
+func (i *Inventory) Transfers(ctx context.Context, productID, storeID int) ([]Transfer, error) {
+	data, err := i.storage.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	transfers := []Transfer{}
+	for j := len(data.Transfers) - 1; j >= 0; j-- {
+		transfer := data.Transfers[j]
+		if productID != 0 && transfer.ProductID != productID {
+			continue
+		}
+		if storeID != 0 && transfer.FromStoreID != storeID && transfer.ToStoreID != storeID {
+			continue
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, nil
+}
+
+// Totals returns the stock of the products aggregated over the stores, sorted by product id.
+func (i *Inventory) Totals(ctx context.Context) ([]Total, error) {
+	data, err := i.storage.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byProduct := make(map[int]*Total)
+	for storeID, stock := range data.Stock {
+		for productID, quantity := range stock {
+			total, ok := byProduct[productID]
+			if !ok {
+				total = &Total{ProductID: productID, Stores: make(map[int]int)}
+				byProduct[productID] = total
+			}
+			total.Quantity += quantity
+			total.Stores[storeID] = quantity
+		}
+	}
+	totals := make([]Total, 0, len(byProduct))
+	for _, total := range byProduct {
+		totals = append(totals, *total)
+	}
+	sort.Slice(totals, func(a, b int) bool { return totals[a].ProductID < totals[b].ProductID })
+	return totals, nil
+}
+
+// CountStock returns the stock of the product id summed over the stores, ok false if no store keeps it.
+func (i *Inventory) CountStock(ctx context.Context, id int) (int, bool, error) {
+	data, err := i.storage.Load(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	quantity, ok := countStock(&data, id)
+	return quantity, ok, nil
+}
+
+// WriteThrough sets write as the writer of the quantities of the products, called with the stock of a product
+// over the stores once it changes, and writes the stock of the products kept by the stores.
+func (i *Inventory) WriteThrough(ctx context.Context, write func(ctx context.Context, id, quantity int) error) error {
+	i.mu.Lock()
+	i.write = write
+	i.mu.Unlock()
+	return i.WriteQuantities(ctx)
+}
+
+// WriteQuantities writes the stock of the products kept by the stores to their quantity, e.g. after the
+// products are replaced by a restore. The stock of the products no longer in the catalogue is skipped.
+func (i *Inventory) WriteQuantities(ctx context.Context) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	data, err := i.storage.Load(ctx)
+	if err != nil {
+		return err
+	}
+	totals := make(map[int]int)
+	for _, stock := range data.Stock {
+		for productID, quantity := range stock {
+			totals[productID] += quantity
+		}
+	}
+	for productID, total := range totals {
+		err := i.writeQuantity(ctx, productID, total)
+		if err != nil && !errors.Is(err, internalProduct.ErrProductNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeQuantity writes quantity to the product id if the inventory has a writer, with mu held so the writes
+// follow the order of the changes
+func (i *Inventory) writeQuantity(ctx context.Context, id, quantity int) error {
+	if i.write == nil {
+		return nil
+	}
+	if err := i.write(ctx, id, quantity); err != nil {
+		return fmt.Errorf("inventory: write the quantity of product %d: %w", id, err)
+	}
+	return nil
+}
+
+// GetConsumerPriceProducts returns the products of ids and their total price as sold by the store id, the
+// quantities being checked against its stock. Without ids, every product in stock at the store is priced once.
+func (i *Inventory) GetConsumerPriceProducts(ctx context.Context, storeID int, ids []string) (internalProduct.ConsumerPriceProducts, error) {
+	stock, err := i.StoreStock(ctx, storeID)
+	if err != nil {
+		return internalProduct.ConsumerPriceProducts{}, err
+	}
+	if len(ids) == 0 || ids[0] == "" {
+		ids = nil
+		for productID := range stock {
+			ids = append(ids, strconv.Itoa(productID))
+		}
+		sort.Slice(ids, func(a, b int) bool {
+			idA, _ := strconv.Atoi(ids[a])
+			idB, _ := strconv.Atoi(ids[b])
+			return idA < idB
+		})
+	}
+
+	products := []Product{}
+	sold := make(map[int]int)
+	for _, id := range ids {
+		if _, err := strconv.Atoi(id); err != nil {
+			return internalProduct.ConsumerPriceProducts{}, internalProduct.ErrInvalidID
+		}
+		// the product is read at its effective price
+		product, err := i.productService.GetProduct(ctx, id)
+		if err != nil {
+			return internalProduct.ConsumerPriceProducts{}, err
+		}
+		sold[product.Id]++
+		if sold[product.Id] > stock[product.Id] {
+			return internalProduct.ConsumerPriceProducts{}, internalProduct.ErrInsufficientQuantity
+		}
+		products = append(products, product)
+	}
+	return internalProduct.NewConsumerPriceProducts(products), nil
+}
+
+// Publish removes the stock of the deleted products, as a handler of the catalogue events. Their transfers
+// are kept.
+func (i *Inventory) Publish(ctx context.Context, event internalProduct.Event) error {
+	if event.Type != internalProduct.EventProductDeleted {
+		return nil
+	}
+	// the product is deleted already, so a failure leaves stock that only blocks deleting the stores
+	err := i.change(ctx, func(data *Data) error {
+		changed := false
+		for storeID, stock := range data.Stock {
+			if _, ok := stock[event.Product.Id]; ok {
+				setStock(data, storeID, event.Product.Id, 0)
+				changed = true
+			}
+		}
+		if !changed {
+			return errUnchanged
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("inventory: remove the stock of product %d: %w", event.Product.Id, err)
+	}
+	return nil
+}
+
+// Renumber moves the stock and the transfers of the products to their new ids, ids mapping the old ids to
+// the new ones. The products missing from ids keep their id.
+func (i *Inventory) Renumber(ctx context.Context, ids map[int]int) error {
+	err := i.change(ctx, func(data *Data) error {
+		changed := false
+		for storeID, stock := range data.Stock {
+			renumbered := make(map[int]int, len(stock))
+			for productID, quantity := range stock {
+				if id, ok := ids[productID]; ok && id != productID {
+					productID = id
+					changed = true
+				}
+				renumbered[productID] = quantity
+			}
+			data.Stock[storeID] = renumbered
+		}
+		for n, transfer := range data.Transfers {
+			if id, ok := ids[transfer.ProductID]; ok && id != transfer.ProductID {
+				data.Transfers[n].ProductID = id
+				changed = true
+			}
+		}
+		if !changed {
+			return errUnchanged
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("inventory: renumber the products: %w", err)
+	}
+	return nil
+}
+
+// change applies fn to the data of the storage, saving it unless it fails or leaves the data unchanged
+func (i *Inventory) change(ctx context.Context, fn func(data *Data) error) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.apply(ctx, fn)
+}
+
+// apply implements change, with mu held
+func (i *Inventory) apply(ctx context.Context, fn func(data *Data) error) error {
+	data, err := i.storage.Load(ctx)
+	if err != nil {
+		return err
+	}
+	err = fn(&data)
+	if errors.Is(err, errUnchanged) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return i.storage.Save(ctx, data)
+}
+
+// setStock sets the quantity of a product at a store in data, removing it at 0
+func setStock(data *Data, storeID, productID, quantity int) {
+	if quantity == 0 {
+		delete(data.Stock[storeID], productID)
+		if len(data.Stock[storeID]) == 0 {
+			delete(data.Stock, storeID)
+		}
+		return
+	}
+	if data.Stock[storeID] == nil {
+		data.Stock[storeID] = make(map[int]int)
+	}
+	data.Stock[storeID][productID] = quantity
+}
+
+// countStock returns the stock of the product id in data summed over the stores, ok false if no store keeps it
+func countStock(data *Data, id int) (int, bool) {
+	quantity, ok := 0, false
+	for _, stock := range data.Stock {
+		if q, kept := stock[id]; kept {
+			quantity += q
+			ok = true
+		}
+	}
+	return quantity, ok
+}
+
+// validate checks that store has a name unique among the stores of data
+func validate(data *Data, store Store) error {
+	if store.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidStore)
+	}
+	for _, other := range data.Stores {
+		if other.ID != store.ID && strings.EqualFold(other.Name, store.Name) {
+			return fmt.Errorf("%w: %q", ErrDuplicateName, store.Name)
+		}
+	}
+	return nil
+}
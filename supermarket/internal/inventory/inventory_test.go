@@ -0,0 +1,220 @@
+package inventory_test
+
+import (
+	"context"
+	"path/filepath"
+	"supermarket/internal/auth"
+	"supermarket/internal/inventory"
+	internalProduct "supermarket/internal/product"
+	"supermarket/internal/product/repository"
+	"supermarket/internal/product/service"
+	"supermarket/internal/product/storage"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newInventory returns an Inventory kept in storage over a catalogue of milk and water
+func newInventory(st inventory.Storage) *inventory.Inventory {
+	products := storage.NewProductStorageMemory(map[int]internalProduct.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 100, CodeValue: "M1", Expiration: "01/02/2030", Price: 1.5},
+		2: {Id: 2, Name: "Water", Quantity: 100, CodeValue: "W1", Expiration: "01/02/2030", Price: 1},
+	})
+	return inventory.NewInventory(st, service.NewProductService(repository.NewProductRepository(products)))
+}
+
+// createStore creates a store named name
+func createStore(t *testing.T, inv *inventory.Inventory, name string) inventory.Store {
+	t.Helper()
+	store, err := inv.CreateStore(context.Background(), inventory.Store{Name: name})
+	require.NoError(t, err)
+	return store
+}
+
+// TestInventory tests the stores, their stock and the transfers between them.
+func TestInventory(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success - a transfer moves the stock and is audited", func(t *testing.T) {
+		// arrange
+		inv := newInventory(inventory.NewMemoryStorage())
+		center := createStore(t, inv, "Center")
+		north := createStore(t, inv, "North")
+		_, err := inv.SetStock(ctx, center.ID, 1, 10)
+		require.NoError(t, err)
+
+		// act
+		transfer, err := inv.Transfer(auth.WithActor(ctx, "warehouse"), inventory.Transfer{ProductID: 1, FromStoreID: center.ID, ToStoreID: north.ID, Quantity: 4})
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, "warehouse", transfer.Actor)
+		stock, err := inv.Stock(ctx, north.ID)
+		require.NoError(t, err)
+		require.Equal(t, []inventory.StockLevel{{ProductID: 1, Quantity: 4}}, stock)
+		totals, err := inv.Totals(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []inventory.Total{{ProductID: 1, Quantity: 10, Stores: map[int]int{center.ID: 6, north.ID: 4}}}, totals)
+		transfers, err := inv.Transfers(ctx, 0, north.ID)
+		require.NoError(t, err)
+		require.Equal(t, []inventory.Transfer{transfer}, transfers)
+	})
+
+	t.Run("success - the consumer price at a store is checked against its stock", func(t *testing.T) {
+		// arrange
+		inv := newInventory(inventory.NewMemoryStorage())
+		center := createStore(t, inv, "Center")
+		_, err := inv.SetStock(ctx, center.ID, 1, 2)
+		require.NoError(t, err)
+
+		// act
+		priced, errPriced := inv.GetConsumerPriceProducts(ctx, center.ID, []string{"1", "1"})
+		inStock, errInStock := inv.GetConsumerPriceProducts(ctx, center.ID, []string{""})
+		_, errExceeded := inv.GetConsumerPriceProducts(ctx, center.ID, []string{"1", "1", "1"})
+		_, errMissing := inv.GetConsumerPriceProducts(ctx, center.ID, []string{"2"})
+		_, errStore := inv.GetConsumerPriceProducts(ctx, 99, []string{"1"})
+
+		// assert
+		require.NoError(t, errPriced)
+		require.InDelta(t, 3*1.21, priced.TotalPrice, 1e-9)
+		require.NoError(t, errInStock)
+		require.Len(t, inStock.Products, 1)
+		require.ErrorIs(t, errExceeded, internalProduct.ErrInsufficientQuantity)
+		require.ErrorIs(t, errMissing, internalProduct.ErrInsufficientQuantity)
+		require.ErrorIs(t, errStore, internalProduct.ErrStoreNotFound)
+	})
+
+	t.Run("success - the deletion of a product removes its stock", func(t *testing.T) {
+		// arrange
+		inv := newInventory(inventory.NewMemoryStorage())
+		center := createStore(t, inv, "Center")
+		_, err := inv.SetStock(ctx, center.ID, 1, 5)
+		require.NoError(t, err)
+		require.ErrorIs(t, inv.DeleteStore(ctx, center.ID), inventory.ErrStoreInUse)
+
+		// act
+		errPublish := inv.Publish(ctx, internalProduct.Event{Type: internalProduct.EventProductDeleted, Product: internalProduct.Product{Id: 1}})
+		err = inv.DeleteStore(ctx, center.ID)
+
+		// assert
+		require.NoError(t, errPublish)
+		require.NoError(t, err)
+		stores, err := inv.ListStores(ctx)
+		require.NoError(t, err)
+		require.Empty(t, stores)
+	})
+
+	t.Run("success - the stock of a product over the stores is written once it changes", func(t *testing.T) {
+		// arrange
+		inv := newInventory(inventory.NewMemoryStorage())
+		center := createStore(t, inv, "Center")
+		north := createStore(t, inv, "North")
+		written := make(map[int][]int)
+		require.NoError(t, inv.WriteThrough(ctx, func(_ context.Context, id, quantity int) error {
+			written[id] = append(written[id], quantity)
+			return nil
+		}))
+
+		// act
+		_, err := inv.SetStock(ctx, center.ID, 1, 6)
+		require.NoError(t, err)
+		_, err = inv.SetStock(ctx, north.ID, 1, 4)
+		require.NoError(t, err)
+		_, err = inv.Transfer(ctx, inventory.Transfer{ProductID: 1, FromStoreID: center.ID, ToStoreID: north.ID, Quantity: 2})
+		require.NoError(t, err)
+		_, err = inv.SetStock(ctx, center.ID, 1, 0)
+		require.NoError(t, err)
+
+		// assert
+		require.Equal(t, map[int][]int{1: {6, 10, 6}}, written)
+		quantity, ok, err := inv.CountStock(ctx, 1)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, 6, quantity)
+	})
+
+	t.Run("success - the file storage keeps the inventory across restarts", func(t *testing.T) {
+		// arrange
+		filename := filepath.Join(t.TempDir(), "inventory.json")
+		st, err := inventory.NewFileStorage(filename)
+		require.NoError(t, err)
+		inv := newInventory(st)
+		center := createStore(t, inv, "Center")
+		north := createStore(t, inv, "North")
+		_, err = inv.SetStock(ctx, center.ID, 2, 3)
+		require.NoError(t, err)
+		transfer, err := inv.Transfer(ctx, inventory.Transfer{ProductID: 2, FromStoreID: center.ID, ToStoreID: north.ID, Quantity: 3})
+		require.NoError(t, err)
+
+		// act
+		reopened, err := inventory.NewFileStorage(filename)
+		require.NoError(t, err)
+		restarted := newInventory(reopened)
+
+		// assert
+		stores, err := restarted.ListStores(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []inventory.Store{center, north}, stores)
+		stock, err := restarted.Stock(ctx, north.ID)
+		require.NoError(t, err)
+		require.Equal(t, []inventory.StockLevel{{ProductID: 2, Quantity: 3}}, stock)
+		transfers, err := restarted.Transfers(ctx, 2, 0)
+		require.NoError(t, err)
+		require.Len(t, transfers, 1)
+		require.Equal(t, transfer.ID, transfers[0].ID)
+	})
+
+	t.Run("failure - transfer more than the stock leaves the stores unchanged", func(t *testing.T) {
+		// arrange
+		inv := newInventory(inventory.NewMemoryStorage())
+		center := createStore(t, inv, "Center")
+		north := createStore(t, inv, "North")
+		_, err := inv.SetStock(ctx, center.ID, 1, 3)
+		require.NoError(t, err)
+
+		// act
+		_, errStock := inv.Transfer(ctx, inventory.Transfer{ProductID: 1, FromStoreID: center.ID, ToStoreID: north.ID, Quantity: 4})
+		_, errSame := inv.Transfer(ctx, inventory.Transfer{ProductID: 1, FromStoreID: center.ID, ToStoreID: center.ID, Quantity: 1})
+		_, errStore := inv.Transfer(ctx, inventory.Transfer{ProductID: 1, FromStoreID: center.ID, ToStoreID: 99, Quantity: 1})
+
+		// assert
+		require.ErrorIs(t, errStock, inventory.ErrInsufficientStock)
+		require.ErrorIs(t, errSame, inventory.ErrSameStore)
+		require.ErrorIs(t, errStore, inventory.ErrStoreNotFound)
+		stock, err := inv.Stock(ctx, center.ID)
+		require.NoError(t, err)
+		require.Equal(t, []inventory.StockLevel{{ProductID: 1, Quantity: 3}}, stock)
+		transfers, err := inv.Transfers(ctx, 0, 0)
+		require.NoError(t, err)
+		require.Empty(t, transfers)
+	})
+
+	t.Run("failure - stock of a product missing from the catalogue", func(t *testing.T) {
+		// arrange
+		inv := newInventory(inventory.NewMemoryStorage())
+		center := createStore(t, inv, "Center")
+
+		// act
+		_, err := inv.SetStock(ctx, center.ID, 9, 5)
+
+		// assert
+		require.ErrorIs(t, err, internalProduct.ErrProductNotFound)
+		stock, err := inv.Stock(ctx, center.ID)
+		require.NoError(t, err)
+		require.Empty(t, stock)
+	})
+
+	t.Run("failure - create a store with the name of another", func(t *testing.T) {
+		// arrange
+		inv := newInventory(inventory.NewMemoryStorage())
+		createStore(t, inv, "Center")
+
+		// act
+		_, errDuplicate := inv.CreateStore(ctx, inventory.Store{Name: " center "})
+		_, errEmpty := inv.CreateStore(ctx, inventory.Store{Name: " "})
+
+		// assert
+		require.ErrorIs(t, errDuplicate, inventory.ErrDuplicateName)
+		require.ErrorIs(t, errEmpty, inventory.ErrInvalidStore)
+	})
+}
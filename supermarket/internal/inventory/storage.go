@@ -0,0 +1,133 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// NewMemoryStorage creates a Storage keeping the inventory in memory.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{data: newData()}
+}
+
+// MemoryStorage is a Storage local to the process
+type MemoryStorage struct {
+	mu   sync.RWMutex
+	data Data
+}
+
+// Load returns a copy of the data.
+func (s *MemoryStorage) Load(ctx context.Context) (Data, error) {
+	if err := ctx.Err(); err != nil {
+		return Data{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyData(s.data), nil
+}
+
+// Save replaces the data with a copy of data.
+func (s *MemoryStorage) Save(ctx context.Context, data Data) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = copyData(data)
+	return nil
+}
+
+// NewFileStorage creates a Storage persisting the inventory to a json file. The content of the file is loaded
+// if it exists.
+func NewFileStorage(filename string) (*FileStorage, error) {
+	s := &FileStorage{MemoryStorage: NewMemoryStorage(), filename: filename}
+
+	content, err := os.ReadFile(filename)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return s, nil
+	case err != nil:
+		return nil, fmt.Errorf("inventory: read %s: %w", filename, err)
+	}
+	var data Data
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("inventory: decode %s: %w", filename, err)
+	}
+	s.data = copyData(data)
+	return s, nil
+}
+
+// FileStorage is a MemoryStorage saved to a json file on every change
+type FileStorage struct {
+	*MemoryStorage
+	filename string
+}
+
+// Save replaces the data, unless it can not be written to the file.
+func (s *FileStorage) Save(ctx context.Context, data Data) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.write(data); err != nil {
+		return err
+	}
+	s.data = copyData(data)
+	return nil
+}
+
+// write writes data to a temporary file renamed over the file, so a crash never leaves it truncated
+func (s *FileStorage) write(data Data) error {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("inventory: encode: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(s.filename), filepath.Base(s.filename)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("inventory: save: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	if _, err := file.Write(content); err != nil {
+		return fmt.Errorf("inventory: save: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("inventory: save: %w", err)
+	}
+	if err := os.Rename(file.Name(), s.filename); err != nil {
+		return fmt.Errorf("inventory: save: %w", err)
+	}
+	return nil
+}
+
+// newData returns empty data
+func newData() Data {
+	return Data{
+		Stores:    make(map[int]Store),
+		Stock:     make(map[int]map[int]int),
+		Transfers: []Transfer{},
+	}
+}
+
+// copyData returns a deep copy of data, with its maps allocated
+func copyData(data Data) Data {
+	copied := newData()
+	for id, store := range data.Stores {
+		copied.Stores[id] = store
+	}
+	for storeID, stock := range data.Stock {
+		copied.Stock[storeID] = make(map[int]int, len(stock))
+		for productID, quantity := range stock {
+			copied.Stock[storeID][productID] = quantity
+		}
+	}
+	copied.Transfers = append(copied.Transfers, data.Transfers...)
+	return copied
+}
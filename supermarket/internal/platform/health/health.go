@@ -0,0 +1,151 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Status is the status of a check or of the whole service
+type Status string
+
+const (
+	// StatusOK means every check passed
+	StatusOK Status = "ok"
+	// StatusDegraded means a non-critical check failed, the service still serves requests
+	StatusDegraded Status = "degraded"
+	// StatusFailing means a critical check failed or the service is shutting down
+	StatusFailing Status = "failing"
+)
+
+// CheckFunc checks a dependency, returning an error if it is not healthy
+type CheckFunc func(ctx context.Context) error
+
+// check is a registered CheckFunc
+type check struct {
+	name     string
+	critical bool
+	fn       CheckFunc
+}
+
+// CheckResult is the result of a check in the readiness response
+type CheckResult struct {
+	Status   Status `json:"status"`
+	Critical bool   `json:"critical"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the body of the liveness and readiness responses
+type Report struct {
+	Status Status                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// NewRegistry creates a Registry running each check with the given timeout
+func NewRegistry(timeout time.Duration) *Registry {
+	if timeout == 0 {
+		timeout = 2 * time.Second
+	}
+	return &Registry{
+		timeout: timeout,
+	}
+}
+
+// Registry holds the checks of the dependencies of the service
+type Registry struct {
+	// timeout is the maximum duration of each check
+	timeout time.Duration
+	// shuttingDown makes the readiness fail
+	shuttingDown atomic.Bool
+
+	mu     sync.Mutex
+	checks []check
+}
+
+// Register adds a readiness check. A failing critical check makes the service failing,
+// a failing non-critical check makes it degraded.
+func (r *Registry) Register(name string, critical bool, fn CheckFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, check{name: name, critical: critical, fn: fn})
+}
+
+// SetShuttingDown makes the readiness fail from now on, so no new traffic is routed to the service
+func (r *Registry) SetShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+// Check runs every check concurrently and aggregates their results
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.Lock()
+	checks := make([]check, len(r.checks))
+	copy(checks, r.checks)
+	r.mu.Unlock()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(checks)+1)}
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, r.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := c.fn(ctx)
+			results[i] = CheckResult{Status: StatusOK, Critical: c.critical, Duration: time.Since(start).String()}
+			if err != nil {
+				results[i].Status = StatusFailing
+				results[i].Error = err.Error()
+			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status == StatusOK {
+			continue
+		}
+		if c.critical {
+			report.Status = StatusFailing
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+
+	if r.shuttingDown.Load() {
+		report.Status = StatusFailing
+		report.Checks["shutdown"] = CheckResult{Status: StatusFailing, Critical: true, Error: "service is shutting down", Duration: "0s"}
+	}
+
+	return report
+}
+
+// LivenessHandler reports that the process is alive and serving requests. It runs no dependency checks.
+func (r *Registry) LivenessHandler(w http.ResponseWriter, req *http.Request) {
+	writeReport(w, http.StatusOK, Report{Status: StatusOK})
+}
+
+// ReadinessHandler reports the result of every check, with 503 when the service is failing
+func (r *Registry) ReadinessHandler(w http.ResponseWriter, req *http.Request) {
+	report := r.Check(req.Context())
+	statusCode := http.StatusOK
+	if report.Status == StatusFailing {
+		statusCode = http.StatusServiceUnavailable
+	}
+	writeReport(w, statusCode, report)
+}
+
+// writeReport writes the report as JSON
+func writeReport(w http.ResponseWriter, statusCode int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,53 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"supermarket/internal/platform/health"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRegistryReadinessHandler tests the ReadinessHandler method.
+func TestRegistryReadinessHandler(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	fail := func(ctx context.Context) error { return errors.New("boom") }
+
+	cases := []struct {
+		name         string
+		critical     health.CheckFunc
+		optional     health.CheckFunc
+		shuttingDown bool
+		expectedCode int
+		expected     health.Status
+	}{
+		{"success - every check passes", ok, ok, false, http.StatusOK, health.StatusOK},
+		{"success - non-critical check fails", ok, fail, false, http.StatusOK, health.StatusDegraded},
+		{"error - critical check fails", fail, ok, false, http.StatusServiceUnavailable, health.StatusFailing},
+		{"error - shutting down", ok, ok, true, http.StatusServiceUnavailable, health.StatusFailing},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// arrange
+			reg := health.NewRegistry(0)
+			reg.Register("critical", true, c.critical)
+			reg.Register("optional", false, c.optional)
+			if c.shuttingDown {
+				reg.SetShuttingDown()
+			}
+			req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			rr := httptest.NewRecorder()
+
+			// act
+			reg.ReadinessHandler(rr, req)
+
+			// assert
+			require.Equal(t, c.expectedCode, rr.Code)
+			require.Equal(t, c.expected, reg.Check(context.Background()).Status)
+		})
+	}
+}
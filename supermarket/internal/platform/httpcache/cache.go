@@ -0,0 +1,85 @@
+package httpcache
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+)
+
+// Response is a cached response
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// entry is a response of the cache with its key
+type entry struct {
+	key      string
+	response Response
+}
+
+// NewCache creates a cache of at most maxEntries responses, evicting the least recently used.
+func NewCache(maxEntries int) *Cache {
+	if maxEntries <= 0 {
+		maxEntries = 1024
+	}
+	return &Cache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Cache is an in-memory LRU cache of responses, safe for concurrent use.
+type Cache struct {
+	mu         sync.Mutex
+	maxEntries int
+	// order lists the entries from the most to the least recently used
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// Get returns the response of key.
+func (c *Cache) Get(key string) (Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return Response{}, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*entry).response, true
+}
+
+// Set caches the response of key.
+func (c *Cache) Set(key string, response Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*entry).response = response
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&entry{key: key, response: response})
+	if c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+	}
+}
+
+// Clear removes every response.
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+}
+
+// Len returns the number of responses cached.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,79 @@
+package httpcache_test
+
+import (
+	"net/http"
+	"supermarket/internal/platform/httpcache"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestVersion tests the version counter.
+func TestVersion(t *testing.T) {
+	t.Run("success - bump changes the etag and the modification time and notifies", func(t *testing.T) {
+		// arrange
+		version := httpcache.NewVersion()
+		notified := 0
+		version.OnChange(func() { notified++ })
+		etag1, modified1 := version.Current()
+
+		// act
+		version.Bump()
+		etag2, modified2 := version.Current()
+		version.Bump()
+		_, modified3 := version.Current()
+
+		// assert
+		require.Regexp(t, `^W/"[0-9a-f]{8}-0"$`, etag1)
+		require.NotEqual(t, etag1, etag2)
+		require.True(t, modified2.After(modified1))
+		require.True(t, modified3.After(modified2))
+		require.Equal(t, 2, notified)
+	})
+
+	t.Run("success - etags differ between processes", func(t *testing.T) {
+		// act
+		etag1, _ := httpcache.NewVersion().Current()
+		etag2, _ := httpcache.NewVersion().Current()
+
+		// assert
+		require.NotEqual(t, etag1, etag2)
+	})
+}
+
+// TestCache tests the LRU cache of responses.
+func TestCache(t *testing.T) {
+	t.Run("success - evicts the least recently used", func(t *testing.T) {
+		// arrange
+		cache := httpcache.NewCache(2)
+		cache.Set("a", httpcache.Response{Status: http.StatusOK, Body: []byte("a")})
+		cache.Set("b", httpcache.Response{Status: http.StatusOK, Body: []byte("b")})
+
+		// act
+		_, okA := cache.Get("a")
+		cache.Set("c", httpcache.Response{Status: http.StatusOK, Body: []byte("c")})
+		_, okB := cache.Get("b")
+		c, okC := cache.Get("c")
+
+		// assert
+		require.True(t, okA)
+		require.False(t, okB)
+		require.True(t, okC)
+		require.Equal(t, []byte("c"), c.Body)
+		require.Equal(t, 2, cache.Len())
+	})
+
+	t.Run("success - clear", func(t *testing.T) {
+		// arrange
+		cache := httpcache.NewCache(2)
+		cache.Set("a", httpcache.Response{Status: http.StatusOK})
+
+		// act
+		cache.Clear()
+		_, ok := cache.Get("a")
+
+		// assert
+		require.False(t, ok)
+		require.Equal(t, 0, cache.Len())
+	})
+}
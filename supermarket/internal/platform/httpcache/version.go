@@ -0,0 +1,67 @@
+package httpcache
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// NewVersion creates a version counter of a resource, e.g. the catalogue.
+// The counter starts at 0 on every start, so an epoch random per process is part of the ETag,
+// which never matches the ETags of a previous process.
+func NewVersion() *Version {
+	epoch := make([]byte, 4)
+	rand.Read(epoch)
+	return &Version{
+		epoch:    hex.EncodeToString(epoch),
+		modified: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// Version counts the changes of a resource.
+type Version struct {
+	mu sync.Mutex
+	// epoch identifies the process
+	epoch string
+	// counter is the number of changes since the start
+	counter uint64
+	// modified is the time of the last change, to the second as in the Last-Modified header
+	modified time.Time
+	// onChange are called after every change
+	onChange []func()
+}
+
+// Current returns the weak ETag and the time of the last change of the current version.
+// The ETag is weak as the representations of a version vary with the content negotiation.
+func (v *Version) Current() (etag string, modified time.Time) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return fmt.Sprintf(`W/"%s-%d"`, v.epoch, v.counter), v.modified
+}
+
+// Bump records a change of the resource and calls the OnChange functions.
+func (v *Version) Bump() {
+	v.mu.Lock()
+	v.counter++
+	// the modification time strictly increases, so two changes in a second do not share it
+	now := time.Now().UTC().Truncate(time.Second)
+	if !now.After(v.modified) {
+		now = v.modified.Add(time.Second)
+	}
+	v.modified = now
+	onChange := v.onChange
+	v.mu.Unlock()
+
+	for _, fn := range onChange {
+		fn()
+	}
+}
+
+// OnChange registers fn to be called after every change, e.g. to invalidate a cache.
+func (v *Version) OnChange(fn func()) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.onChange = append(v.onChange, fn)
+}
@@ -0,0 +1,93 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// NewFileStore creates a Store persisting the completed records to a json file, so that they survive restarts.
+// The records of the file are loaded if it exists. A reservation is kept in memory only: the request of a key
+// in progress when the process stops can be retried after the restart.
+func NewFileStore(filename string) (*FileStore, error) {
+	s := &FileStore{MemoryStore: NewMemoryStore(), filename: filename}
+
+	data, err := os.ReadFile(filename)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return s, nil
+	case err != nil:
+		return nil, fmt.Errorf("idempotency: read %s: %w", filename, err)
+	}
+	if err := json.Unmarshal(data, &s.records); err != nil {
+		return nil, fmt.Errorf("idempotency: decode %s: %w", filename, err)
+	}
+	if s.records == nil {
+		s.records = make(map[string]Record)
+	}
+	// files written before reservations stopped being saved may hold keys left in progress by a crash
+	for key, record := range s.records {
+		if !record.Completed {
+			delete(s.records, key)
+		}
+	}
+	return s, nil
+}
+
+// FileStore is a MemoryStore saving its completed records to a json file on every completion or release.
+// Reserve is inherited from MemoryStore, as reservations are not saved.
+type FileStore struct {
+	*MemoryStore
+	filename string
+}
+
+// Complete stores the response of key.
+func (s *FileStore) Complete(ctx context.Context, key string, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.complete(key, record)
+	return s.save()
+}
+
+// Release forgets key.
+func (s *FileStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return s.save()
+}
+
+// save writes the completed records to a temporary file renamed over the file, so a crash never leaves it
+// truncated
+func (s *FileStore) save() error {
+	completed := make(map[string]Record, len(s.records))
+	for key, record := range s.records {
+		if record.Completed {
+			completed[key] = record
+		}
+	}
+	data, err := json.Marshal(completed)
+	if err != nil {
+		return fmt.Errorf("idempotency: encode: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(s.filename), filepath.Base(s.filename)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("idempotency: save: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("idempotency: save: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("idempotency: save: %w", err)
+	}
+	if err := os.Rename(file.Name(), s.filename); err != nil {
+		return fmt.Errorf("idempotency: save: %w", err)
+	}
+	return nil
+}
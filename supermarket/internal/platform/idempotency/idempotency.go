@@ -0,0 +1,122 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInProgress is returned when the request of a key is still being processed.
+	ErrInProgress = errors.New("idempotency: request in progress")
+	// ErrFingerprintMismatch is returned when a key is reused with a different request.
+	ErrFingerprintMismatch = errors.New("idempotency: key reused with a different request")
+)
+
+// Record is the first response to a request with an idempotency key
+type Record struct {
+	// Fingerprint identifies the request, a retry must have the same fingerprint
+	Fingerprint string `json:"fingerprint"`
+	// Completed is false while the request is in progress
+	Completed bool `json:"completed"`
+	// Status, Header and Body are the response, once completed
+	Status int         `json:"status,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+	// ExpiresAt is the time the record is forgotten
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Store keeps the records by key. Implementations must be safe for concurrent use.
+type Store interface {
+	// Reserve records key as in progress for the request of fingerprint, and returns nil if the key is new.
+	// For a known key it returns its record, with ErrFingerprintMismatch if the fingerprints differ
+	// or ErrInProgress if it is not completed.
+	Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, error)
+	// Complete stores the response of key
+	Complete(ctx context.Context, key string, record Record) error
+	// Release forgets key, so that the request can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// NewMemoryStore creates a Store keeping the records in memory.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: make(map[string]Record),
+		now:     time.Now,
+	}
+}
+
+// MemoryStore is a Store local to the process
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+	// now returns the current time
+	now func() time.Time
+}
+
+// Reserve records key as in progress if it is new or expired.
+func (s *MemoryStore) Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reserve(key, fingerprint, ttl)
+}
+
+// reserve implements Reserve, with the lock held
+func (s *MemoryStore) reserve(key, fingerprint string, ttl time.Duration) (*Record, error) {
+	now := s.now()
+	s.sweep(now)
+
+	if record, ok := s.records[key]; ok {
+		switch {
+		case record.Fingerprint != fingerprint:
+			return &record, ErrFingerprintMismatch
+		case !record.Completed:
+			return &record, ErrInProgress
+		}
+		return &record, nil
+	}
+
+	s.records[key] = Record{Fingerprint: fingerprint, ExpiresAt: now.Add(ttl)}
+	return nil, nil
+}
+
+// Complete stores the response of key, keeping the expiry of its reservation.
+func (s *MemoryStore) Complete(ctx context.Context, key string, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.complete(key, record)
+	return nil
+}
+
+// complete implements Complete, with the lock held
+func (s *MemoryStore) complete(key string, record Record) {
+	if reserved, ok := s.records[key]; ok {
+		record.ExpiresAt = reserved.ExpiresAt
+	}
+	record.Completed = true
+	s.records[key] = record
+}
+
+// Release forgets key.
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// sweep removes the expired records
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, record := range s.records {
+		if !now.Before(record.ExpiresAt) {
+			delete(s.records, key)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package idempotency_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"supermarket/internal/platform/idempotency"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestMemoryStore tests the reservation and completion of keys of the MemoryStore.
+func TestMemoryStore(t *testing.T) {
+	t.Run("success - reserve, complete and replay", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		store := idempotency.NewMemoryStore()
+
+		// act
+		first, err1 := store.Reserve(ctx, "k", "fp", time.Hour)
+		inProgress, err2 := store.Reserve(ctx, "k", "fp", time.Hour)
+		err3 := store.Complete(ctx, "k", idempotency.Record{Fingerprint: "fp", Status: http.StatusCreated, Body: []byte("{}")})
+		replayed, err4 := store.Reserve(ctx, "k", "fp", time.Hour)
+		mismatch, err5 := store.Reserve(ctx, "k", "other", time.Hour)
+
+		// assert
+		require.NoError(t, err1)
+		require.Nil(t, first)
+		require.ErrorIs(t, err2, idempotency.ErrInProgress)
+		require.NotNil(t, inProgress)
+		require.NoError(t, err3)
+		require.NoError(t, err4)
+		require.True(t, replayed.Completed)
+		require.Equal(t, http.StatusCreated, replayed.Status)
+		require.Equal(t, []byte("{}"), replayed.Body)
+		require.ErrorIs(t, err5, idempotency.ErrFingerprintMismatch)
+		require.NotNil(t, mismatch)
+	})
+
+	t.Run("success - released and expired keys can be reserved again", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		store := idempotency.NewMemoryStore()
+
+		// act
+		_, err1 := store.Reserve(ctx, "released", "fp", time.Hour)
+		err2 := store.Release(ctx, "released")
+		again, err3 := store.Reserve(ctx, "released", "fp", time.Hour)
+		_, err4 := store.Reserve(ctx, "expired", "fp", time.Nanosecond)
+		time.Sleep(time.Millisecond)
+		expired, err5 := store.Reserve(ctx, "expired", "other", time.Hour)
+
+		// assert
+		require.NoError(t, err1)
+		require.NoError(t, err2)
+		require.NoError(t, err3)
+		require.Nil(t, again)
+		require.NoError(t, err4)
+		require.NoError(t, err5)
+		require.Nil(t, expired)
+	})
+}
+
+// TestFileStore tests that the FileStore persists the records.
+func TestFileStore(t *testing.T) {
+	t.Run("success - records survive a restart", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		filename := filepath.Join(t.TempDir(), "idempotency.json")
+		store, err := idempotency.NewFileStore(filename)
+		require.NoError(t, err)
+		_, err = store.Reserve(ctx, "k", "fp", time.Hour)
+		require.NoError(t, err)
+		err = store.Complete(ctx, "k", idempotency.Record{
+			Fingerprint: "fp",
+			Status:      http.StatusCreated,
+			Header:      http.Header{"Content-Type": {"application/json"}},
+			Body:        []byte(`{"id":1}`),
+		})
+		require.NoError(t, err)
+
+		// act
+		restarted, err := idempotency.NewFileStore(filename)
+		require.NoError(t, err)
+		record, err := restarted.Reserve(ctx, "k", "fp", time.Hour)
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, record.Status)
+		require.Equal(t, "application/json", record.Header.Get("Content-Type"))
+		require.Equal(t, `{"id":1}`, string(record.Body))
+	})
+	t.Run("success - a key in progress when the process stops can be retried after a restart", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		filename := filepath.Join(t.TempDir(), "idempotency.json")
+		stale := `{"k":{"fingerprint":"fp","completed":false,"expires_at":"2999-01-01T00:00:00Z"}}`
+		require.NoError(t, os.WriteFile(filename, []byte(stale), 0644))
+		store, err := idempotency.NewFileStore(filename)
+		require.NoError(t, err)
+		_, err = store.Reserve(ctx, "other", "fp", time.Hour)
+		require.NoError(t, err)
+
+		// act
+		restarted, err := idempotency.NewFileStore(filename)
+		require.NoError(t, err)
+		retried, errRetried := restarted.Reserve(ctx, "k", "fp", time.Hour)
+		other, errOther := restarted.Reserve(ctx, "other", "fp", time.Hour)
+
+		// assert
+		require.NoError(t, errRetried)
+		require.Nil(t, retried)
+		require.NoError(t, errOther)
+		require.Nil(t, other)
+	})
+}
@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+)
+
+const (
+	// FormatText writes logs as key=value pairs.
+	FormatText = "text"
+	// FormatJSON writes logs as JSON objects.
+	FormatJSON = "json"
+)
+
+// ErrInvalidLogger is returned when the logger can not be built from its configuration.
+var ErrInvalidLogger = errors.New("logging: invalid logger configuration")
+
+// New creates a logger writing to w with the given level (debug, info, warn, error) and format (text, json)
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lv slog.Level
+	if err := lv.UnmarshalText([]byte(level)); err != nil {
+		return nil, errors.Join(ErrInvalidLogger, err)
+	}
+
+	opts := &slog.HandlerOptions{Level: lv}
+	switch format {
+	case FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, errors.Join(ErrInvalidLogger, errors.New("unknown format "+format))
+	}
+}
+
+// ctxKey is the key of the logger in a context
+type ctxKey struct{}
+
+// WithLogger returns a copy of ctx holding the logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+// FromContext returns the request-scoped logger held by ctx, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,47 @@
+package metrics_test
+
+import (
+	"strings"
+	"supermarket/internal/platform/metrics"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRegistryWriteTo tests the text exposition format of the Registry.
+func TestRegistryWriteTo(t *testing.T) {
+	t.Run("success - counters, gauges and histograms", func(t *testing.T) {
+		// arrange
+		reg := metrics.NewRegistry()
+		counter := reg.NewCounter("requests_total", "Number of requests.", "route")
+		gauge := reg.NewGauge("products", "Number of products.")
+		histogram := reg.NewHistogram("duration_seconds", "Duration.", []float64{0.1, 1}, "route")
+		counter.Inc(`/a"b`)
+		counter.Add(2, "/c")
+		gauge.Set(3)
+		histogram.Observe(0.5, "/c")
+		expected := `# HELP requests_total Number of requests.
+# TYPE requests_total counter
+requests_total{route="/a\"b"} 1
+requests_total{route="/c"} 2
+# HELP products Number of products.
+# TYPE products gauge
+products 3
+# HELP duration_seconds Duration.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{route="/c",le="0.1"} 0
+duration_seconds_bucket{route="/c",le="1"} 1
+duration_seconds_bucket{route="/c",le="+Inf"} 1
+duration_seconds_sum{route="/c"} 0.5
+duration_seconds_count{route="/c"} 1
+`
+		var sb strings.Builder
+
+		// act
+		_, err := reg.WriteTo(&sb)
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, expected, sb.String())
+	})
+}
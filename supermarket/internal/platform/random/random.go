@@ -0,0 +1,15 @@
+// Package random generates the random ids of the records kept by the stores, e.g. the webhook deliveries, the
+// price entries and the transfers of stock.
+package random
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// ID returns a random id of 32 hex digits.
+func ID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,20 @@
+package random_test
+
+import (
+	"supermarket/internal/platform/random"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestID tests the random ids.
+func TestID(t *testing.T) {
+	t.Run("success - 32 hex digits, different every time", func(t *testing.T) {
+		// act
+		a, b := random.ID(), random.ID()
+
+		// assert
+		require.Regexp(t, "^[0-9a-f]{32}$", a)
+		require.NotEqual(t, a, b)
+	})
+}
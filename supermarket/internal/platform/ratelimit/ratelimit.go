@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrInvalidLimit is returned when a limit has no rate or no burst.
+var ErrInvalidLimit = errors.New("ratelimit: rate and burst must be positive")
+
+// Limit is the configuration of a token bucket
+type Limit struct {
+	// Rate is the number of tokens added to the bucket per second
+	Rate float64
+	// Burst is the capacity of the bucket, the maximum number of requests allowed at once
+	Burst int
+}
+
+// Validate checks that the limit can fill a bucket.
+func (l Limit) Validate() error {
+	if l.Rate <= 0 || l.Burst <= 0 {
+		return ErrInvalidLimit
+	}
+	return nil
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	// Allowed is true if a token was taken
+	Allowed bool
+	// Limit is the capacity of the bucket
+	Limit int
+	// Remaining is the number of whole tokens left in the bucket
+	Remaining int
+	// RetryAfter is the time until the next token is available, 0 if Allowed
+	RetryAfter time.Duration
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+}
+
+// Store keeps the token buckets. Implementations must be safe for concurrent use,
+// so that the state can be moved to a backend shared by several instances.
+type Store interface {
+	// Take takes a token from the bucket of key, created full with limit if it does not exist
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// bucket is a token bucket
+type bucket struct {
+	// tokens is the number of tokens in the bucket at last
+	tokens float64
+	// last is the time the tokens were last computed
+	last time.Time
+	// full is the time the bucket is full again, after which it can be forgotten
+	full time.Time
+}
+
+// sweepEvery is the number of takes between the removal of the full buckets
+const sweepEvery = 1024
+
+// NewMemoryStore creates a Store keeping the buckets in memory.
+// Buckets are forgotten once they are full again, which is the state of a new bucket.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// MemoryStore is a Store local to the process
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	// takes counts the takes since the last sweep
+	takes int
+	// now returns the current time
+	now func() time.Time
+}
+
+// Take takes a token from the bucket of key.
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	if err := limit.Validate(); err != nil {
+		return Result{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	// - refill
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	// - take
+	result := Result{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / limit.Rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((float64(limit.Burst) - b.tokens) / limit.Rate)
+	b.full = now.Add(result.Reset)
+
+	return result, nil
+}
+
+// sweep removes the full buckets every sweepEvery takes, to bound the memory used by idle keys
+func (s *MemoryStore) sweep(now time.Time) {
+	s.takes++
+	if s.takes < sweepEvery {
+		return
+	}
+	s.takes = 0
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// seconds converts a number of seconds to a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
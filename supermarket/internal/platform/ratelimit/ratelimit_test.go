@@ -0,0 +1,51 @@
+package ratelimit_test
+
+import (
+	"context"
+	"supermarket/internal/platform/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestMemoryStoreTake tests the token buckets of the MemoryStore.
+func TestMemoryStoreTake(t *testing.T) {
+	t.Run("success - allows the burst then rejects until a token is added", func(t *testing.T) {
+		// arrange
+		store := ratelimit.NewMemoryStore()
+		limit := ratelimit.Limit{Rate: 1, Burst: 2}
+
+		// act
+		first, err1 := store.Take(context.Background(), "a", limit)
+		second, err2 := store.Take(context.Background(), "a", limit)
+		third, err3 := store.Take(context.Background(), "a", limit)
+		other, err4 := store.Take(context.Background(), "b", limit)
+
+		// assert
+		require.NoError(t, err1)
+		require.NoError(t, err2)
+		require.NoError(t, err3)
+		require.NoError(t, err4)
+		require.True(t, first.Allowed)
+		require.Equal(t, 1, first.Remaining)
+		require.True(t, second.Allowed)
+		require.Equal(t, 0, second.Remaining)
+		require.False(t, third.Allowed)
+		require.Equal(t, 2, third.Limit)
+		require.InDelta(t, time.Second, third.RetryAfter, float64(50*time.Millisecond))
+		require.InDelta(t, 2*time.Second, third.Reset, float64(50*time.Millisecond))
+		require.True(t, other.Allowed)
+	})
+
+	t.Run("error - invalid limit", func(t *testing.T) {
+		// arrange
+		store := ratelimit.NewMemoryStore()
+
+		// act
+		_, err := store.Take(context.Background(), "a", ratelimit.Limit{Rate: 1})
+
+		// assert
+		require.ErrorIs(t, err, ratelimit.ErrInvalidLimit)
+	})
+}
@@ -0,0 +1,318 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrExport is returned when spans can not be exported.
+var ErrExport = errors.New("tracing: export failed")
+
+// Processor receives the spans when they end
+type Processor interface {
+	// OnEnd receives a finished span
+	OnEnd(span SpanData)
+	// Shutdown exports the pending spans and stops the processor
+	Shutdown(ctx context.Context) error
+}
+
+// Exporter sends spans to a backend
+type Exporter interface {
+	// Export sends a batch of spans
+	Export(ctx context.Context, spans []SpanData) error
+}
+
+// NewBatchProcessor creates a processor exporting the spans in batches of up to batchSize,
+// at least every interval
+func NewBatchProcessor(exporter Exporter, batchSize int, interval time.Duration) *BatchProcessor {
+	if batchSize <= 0 {
+		batchSize = 512
+	}
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	bp := &BatchProcessor{
+		exporter:  exporter,
+		batchSize: batchSize,
+		maxQueue:  batchSize * 4,
+		flushCh:   make(chan struct{}, 1),
+		stopCh:    make(chan struct{}),
+		doneCh:    make(chan struct{}),
+	}
+	go bp.run(interval)
+	return bp
+}
+
+// BatchProcessor is a Processor that buffers spans and exports them in the background
+type BatchProcessor struct {
+	exporter  Exporter
+	batchSize int
+	// maxQueue is the maximum number of buffered spans, newer spans are dropped when full
+	maxQueue int
+
+	mu    sync.Mutex
+	queue []SpanData
+
+	flushCh  chan struct{}
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// OnEnd buffers the span
+func (bp *BatchProcessor) OnEnd(span SpanData) {
+	bp.mu.Lock()
+	if len(bp.queue) >= bp.maxQueue {
+		bp.mu.Unlock()
+		return
+	}
+	bp.queue = append(bp.queue, span)
+	full := len(bp.queue) >= bp.batchSize
+	bp.mu.Unlock()
+
+	if full {
+		select {
+		case bp.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run exports the buffered spans periodically or when a batch is full
+func (bp *BatchProcessor) run(interval time.Duration) {
+	defer close(bp.doneCh)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-bp.flushCh:
+		case <-bp.stopCh:
+			return
+		}
+		bp.export(context.Background())
+	}
+}
+
+// export sends every buffered span
+func (bp *BatchProcessor) export(ctx context.Context) error {
+	var errs []error
+	for {
+		bp.mu.Lock()
+		n := len(bp.queue)
+		if n > bp.batchSize {
+			n = bp.batchSize
+		}
+		batch := bp.queue[:n:n]
+		bp.queue = bp.queue[n:]
+		bp.mu.Unlock()
+
+		if len(batch) == 0 {
+			return errors.Join(errs...)
+		}
+		if err := bp.exporter.Export(ctx, batch); err != nil {
+			errs = append(errs, err)
+		}
+	}
+}
+
+// Shutdown stops the background export and exports the pending spans
+func (bp *BatchProcessor) Shutdown(ctx context.Context) error {
+	bp.stopOnce.Do(func() { close(bp.stopCh) })
+	select {
+	case <-bp.doneCh:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return bp.export(ctx)
+}
+
+// NewStdoutExporter creates an exporter writing a JSON object per span to w
+func NewStdoutExporter(w io.Writer) *StdoutExporter {
+	return &StdoutExporter{w: w}
+}
+
+// StdoutExporter writes spans as JSON lines, to inspect traces locally
+type StdoutExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// stdoutSpan is the JSON representation of a span written by the StdoutExporter
+type stdoutSpan struct {
+	TraceID      string         `json:"trace_id"`
+	SpanID       string         `json:"span_id"`
+	ParentSpanID string         `json:"parent_span_id,omitempty"`
+	Name         string         `json:"name"`
+	Kind         SpanKind       `json:"kind"`
+	Start        time.Time      `json:"start"`
+	Duration     string         `json:"duration"`
+	Attributes   map[string]any `json:"attributes,omitempty"`
+	Status       StatusCode     `json:"status"`
+	Message      string         `json:"status_message,omitempty"`
+}
+
+// Export writes the spans
+func (e *StdoutExporter) Export(ctx context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	enc := json.NewEncoder(e.w)
+	for _, span := range spans {
+		out := stdoutSpan{
+			TraceID:  span.SpanContext.TraceID.String(),
+			SpanID:   span.SpanContext.SpanID.String(),
+			Name:     span.Name,
+			Kind:     span.Kind,
+			Start:    span.Start,
+			Duration: span.End.Sub(span.Start).String(),
+			Status:   span.StatusCode,
+			Message:  span.StatusMessage,
+		}
+		if span.Parent.SpanID.IsValid() {
+			out.ParentSpanID = span.Parent.SpanID.String()
+		}
+		if len(span.Attributes) > 0 {
+			out.Attributes = make(map[string]any, len(span.Attributes))
+			for _, attr := range span.Attributes {
+				out.Attributes[attr.Key] = attr.Value
+			}
+		}
+		if err := enc.Encode(out); err != nil {
+			return fmt.Errorf("%w: %v", ErrExport, err)
+		}
+	}
+	return nil
+}
+
+// NewOTLPExporter creates an exporter posting spans to an OTLP/HTTP endpoint using the JSON encoding,
+// e.g. http://localhost:4318/v1/traces
+func NewOTLPExporter(endpoint, serviceName string, client *http.Client) *OTLPExporter {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &OTLPExporter{
+		endpoint:    endpoint,
+		serviceName: serviceName,
+		client:      client,
+	}
+}
+
+// OTLPExporter sends spans to an OpenTelemetry collector
+type OTLPExporter struct {
+	endpoint    string
+	serviceName string
+	client      *http.Client
+}
+
+// OTLP/JSON request, see opentelemetry-proto collector/trace/v1
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              SpanKind       `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpStatus struct {
+		Code    StatusCode `json:"code"`
+		Message string     `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	}
+)
+
+// otlpValue encodes an attribute value as an OTLP AnyValue
+func otlpValue(v any) map[string]any {
+	switch v := v.(type) {
+	case string:
+		return map[string]any{"stringValue": v}
+	case int64:
+		return map[string]any{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		return map[string]any{"doubleValue": v}
+	case bool:
+		return map[string]any{"boolValue": v}
+	default:
+		return map[string]any{"stringValue": fmt.Sprint(v)}
+	}
+}
+
+// Export posts the spans to the collector
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	scope := otlpScopeSpans{Scope: otlpScope{Name: e.serviceName}}
+	for _, span := range spans {
+		out := otlpSpan{
+			TraceID:           span.SpanContext.TraceID.String(),
+			SpanID:            span.SpanContext.SpanID.String(),
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+			Status:            otlpStatus{Code: span.StatusCode, Message: span.StatusMessage},
+		}
+		if span.Parent.SpanID.IsValid() {
+			out.ParentSpanID = span.Parent.SpanID.String()
+		}
+		for _, attr := range span.Attributes {
+			out.Attributes = append(out.Attributes, otlpKeyValue{Key: attr.Key, Value: otlpValue(attr.Value)})
+		}
+		scope.Spans = append(scope.Spans, out)
+	}
+	body, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpKeyValue{
+			{Key: "service.name", Value: otlpValue(e.serviceName)},
+		}},
+		ScopeSpans: []otlpScopeSpans{scope},
+	}}})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrExport, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrExport, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrExport, err)
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%w: collector responded %s", ErrExport, res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// String returns the hex encoding of the id
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+// IsValid returns true if the id is not all zeros
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+// SpanID identifies a span in a trace
+type SpanID [8]byte
+
+// String returns the hex encoding of the id
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// IsValid returns true if the id is not all zeros
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+// SpanContext is the part of a span propagated across process boundaries
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+	// Remote is true if the span context was extracted from an incoming request
+	Remote bool
+}
+
+// IsValid returns true if both ids are valid
+func (sc SpanContext) IsValid() bool { return sc.TraceID.IsValid() && sc.SpanID.IsValid() }
+
+// SpanKind is the role of a span in a trace
+type SpanKind int
+
+const (
+	// SpanKindInternal is an operation inside the process
+	SpanKindInternal SpanKind = 1
+	// SpanKindServer is the handling of an incoming request
+	SpanKindServer SpanKind = 2
+	// SpanKindClient is an outgoing request
+	SpanKindClient SpanKind = 3
+)
+
+// StatusCode is the status of a span
+type StatusCode int
+
+const (
+	// StatusUnset is the default status
+	StatusUnset StatusCode = 0
+	// StatusOK marks the operation as successful
+	StatusOK StatusCode = 1
+	// StatusError marks the operation as failed
+	StatusError StatusCode = 2
+)
+
+// Attribute is a key-value pair describing a span
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String creates a string attribute
+func String(key, value string) Attribute { return Attribute{Key: key, Value: value} }
+
+// Int creates an integer attribute
+func Int(key string, value int) Attribute { return Attribute{Key: key, Value: int64(value)} }
+
+// Float64 creates a float attribute
+func Float64(key string, value float64) Attribute { return Attribute{Key: key, Value: value} }
+
+// Bool creates a boolean attribute
+func Bool(key string, value bool) Attribute { return Attribute{Key: key, Value: value} }
+
+// SpanData is a finished span, as handed to the exporters
+type SpanData struct {
+	Name          string
+	Kind          SpanKind
+	SpanContext   SpanContext
+	Parent        SpanContext
+	Start         time.Time
+	End           time.Time
+	Attributes    []Attribute
+	StatusCode    StatusCode
+	StatusMessage string
+}
+
+// Span is an operation being traced. A nil *Span is a valid no-op span.
+type Span struct {
+	tracer *Tracer
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SpanContext returns the span context of the span
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.SpanContext
+}
+
+// SetName replaces the name of the span, e.g. once the route of a request is known
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Name = name
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+}
+
+// SetStatus sets the status of the span
+func (s *Span) SetStatus(code StatusCode, message string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.StatusCode = code
+	s.data.StatusMessage = message
+}
+
+// RecordError marks the span as failed with err, if it is not nil
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.SetStatus(StatusError, err.Error())
+}
+
+// End finishes the span and hands it to the exporter if it is sampled
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if data.SpanContext.Sampled && s.tracer.processor != nil {
+		s.tracer.processor.OnEnd(data)
+	}
+}
+
+// spanKey is the key of the current span in a context
+type spanKey struct{}
+
+// remoteKey is the key of a remote parent span context in a context
+type remoteKey struct{}
+
+// ContextWithSpan returns a copy of ctx holding span as the current span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the current span of ctx, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemoteSpanContext returns a copy of ctx holding a span context extracted from a request,
+// used as the parent of the next span started
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context of the current span, or of the remote parent
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+// NewTracer creates a Tracer handing its sampled spans to processor. A nil processor disables the tracer.
+func NewTracer(processor Processor) *Tracer {
+	return &Tracer{processor: processor}
+}
+
+// Tracer creates spans
+type Tracer struct {
+	processor Processor
+}
+
+// SpanOption configures a span when it starts
+type SpanOption func(*SpanData)
+
+// WithSpanKind sets the kind of the span, SpanKindInternal by default
+func WithSpanKind(kind SpanKind) SpanOption {
+	return func(d *SpanData) { d.Kind = kind }
+}
+
+// WithAttributes sets the initial attributes of the span
+func WithAttributes(attrs ...Attribute) SpanOption {
+	return func(d *SpanData) { d.Attributes = append(d.Attributes, attrs...) }
+}
+
+// Start starts a span child of the current span of ctx, and returns a copy of ctx holding it.
+// A disabled tracer returns a nil span, which is a no-op.
+func (t *Tracer) Start(ctx context.Context, name string, opts ...SpanOption) (context.Context, *Span) {
+	if t == nil || t.processor == nil {
+		return ctx, nil
+	}
+
+	parent := SpanContextFromContext(ctx)
+	data := SpanData{
+		Name:   name,
+		Kind:   SpanKindInternal,
+		Parent: parent,
+		Start:  time.Now(),
+		SpanContext: SpanContext{
+			TraceID: parent.TraceID,
+			SpanID:  newSpanID(),
+			Sampled: true,
+		},
+	}
+	if parent.IsValid() {
+		data.SpanContext.Sampled = parent.Sampled
+	} else {
+		data.SpanContext.TraceID = newTraceID()
+	}
+	for _, opt := range opts {
+		opt(&data)
+	}
+
+	span := &Span{tracer: t, data: data}
+	return ContextWithSpan(ctx, span), span
+}
+
+// defaultTracer is the tracer used by the package level Start, disabled until SetDefault is called
+var defaultTracer atomic.Pointer[Tracer]
+
+// SetDefault sets the tracer used by Start
+func SetDefault(t *Tracer) {
+	defaultTracer.Store(t)
+}
+
+// Start starts a span with the default tracer, see Tracer.Start
+func Start(ctx context.Context, name string, opts ...SpanOption) (context.Context, *Span) {
+	return defaultTracer.Load().Start(ctx, name, opts...)
+}
+
+func newTraceID() (id TraceID) {
+	rand.Read(id[:])
+	return
+}
+
+func newSpanID() (id SpanID) {
+	rand.Read(id[:])
+	return
+}
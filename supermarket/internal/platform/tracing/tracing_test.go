@@ -0,0 +1,83 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"supermarket/internal/platform/tracing"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestTracerStart tests the propagation of spans through the context and the traceparent header.
+func TestTracerStart(t *testing.T) {
+	t.Run("success - children of a remote parent share its trace and are exported", func(t *testing.T) {
+		// arrange
+		var buf bytes.Buffer
+		processor := tracing.NewBatchProcessor(tracing.NewStdoutExporter(&buf), 0, 0)
+		tracer := tracing.NewTracer(processor)
+		header := http.Header{}
+		header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		ctx := tracing.Extract(context.Background(), header)
+
+		// act
+		ctx, parent := tracer.Start(ctx, "parent", tracing.WithSpanKind(tracing.SpanKindServer))
+		_, child := tracer.Start(ctx, "child", tracing.WithAttributes(tracing.Int("product.id", 1)))
+		child.RecordError(errors.New("not found"))
+		child.End()
+		parent.End()
+		out := http.Header{}
+		tracing.Inject(ctx, out)
+		err := processor.Shutdown(context.Background())
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+parent.SpanContext().SpanID.String()+"-01", out.Get(tracing.TraceparentHeader))
+		lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+		require.Len(t, lines, 2)
+		var childOut, parentOut map[string]any
+		require.NoError(t, json.Unmarshal(lines[0], &childOut))
+		require.NoError(t, json.Unmarshal(lines[1], &parentOut))
+		require.Equal(t, "child", childOut["name"])
+		require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", childOut["trace_id"])
+		require.Equal(t, parentOut["span_id"], childOut["parent_span_id"])
+		require.Equal(t, "not found", childOut["status_message"])
+		require.Equal(t, "00f067aa0ba902b7", parentOut["parent_span_id"])
+	})
+
+	t.Run("success - a parent not sampled is not exported", func(t *testing.T) {
+		// arrange
+		var buf bytes.Buffer
+		processor := tracing.NewBatchProcessor(tracing.NewStdoutExporter(&buf), 0, 0)
+		tracer := tracing.NewTracer(processor)
+		header := http.Header{}
+		header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+		ctx := tracing.Extract(context.Background(), header)
+
+		// act
+		_, span := tracer.Start(ctx, "span")
+		span.End()
+		err := processor.Shutdown(context.Background())
+
+		// assert
+		require.NoError(t, err)
+		require.Empty(t, buf.String())
+	})
+
+	t.Run("success - a disabled tracer returns a no-op span", func(t *testing.T) {
+		// arrange
+		tracer := tracing.NewTracer(nil)
+
+		// act
+		ctx, span := tracer.Start(context.Background(), "span")
+		span.SetAttributes(tracing.String("key", "value"))
+		span.End()
+
+		// assert
+		require.Nil(t, span)
+		require.False(t, tracing.SpanContextFromContext(ctx).IsValid())
+	})
+}
@@ -0,0 +1,68 @@
+package lifecycle_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"supermarket/internal/platform/web/lifecycle"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestServerRun tests the lifecycle of the Server.
+func TestServerRun(t *testing.T) {
+	t.Run("success - drains in-flight requests and runs hooks", func(t *testing.T) {
+		// arrange
+		started := make(chan struct{})
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+		})
+		server := lifecycle.NewServer(lifecycle.Config{Addr: "127.0.0.1:0"}, handler)
+		hookCalled := false
+		server.OnShutdown(func(ctx context.Context) error {
+			hookCalled = true
+			return nil
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+		go func() { errCh <- server.RunContext(ctx) }()
+		require.Eventually(t, func() bool { return server.Addr() != nil }, time.Second, 10*time.Millisecond)
+
+		// act
+		resCh := make(chan int, 1)
+		go func() {
+			res, err := http.Get("http://" + server.Addr().String())
+			if err != nil {
+				resCh <- 0
+				return
+			}
+			res.Body.Close()
+			resCh <- res.StatusCode
+		}()
+		<-started
+		cancel()
+
+		// assert
+		require.Equal(t, http.StatusOK, <-resCh)
+		require.NoError(t, <-errCh)
+		require.True(t, hookCalled)
+	})
+
+	t.Run("error - address already in use", func(t *testing.T) {
+		// arrange
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer ln.Close()
+		server := lifecycle.NewServer(lifecycle.Config{Addr: ln.Addr().String()}, http.NotFoundHandler())
+
+		// act
+		err = server.RunContext(context.Background())
+
+		// assert
+		require.ErrorIs(t, err, lifecycle.ErrServerStart)
+	})
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"supermarket/internal/platform/web/response"
+)
+
+// NewBodyLimit creates a new body limit middleware. A maxBytes of 0 leaves the bodies unlimited.
+func NewBodyLimit(maxBytes int64) *BodyLimit {
+	return &BodyLimit{
+		maxBytes: maxBytes,
+	}
+}
+
+// BodyLimit bounds the size of the request bodies.
+type BodyLimit struct {
+	// maxBytes is the maximum size of a body
+	maxBytes int64
+}
+
+// Limit responds 413 Request Entity Too Large when the declared Content-Length exceeds the limit, and
+// otherwise caps the body so that reading past the limit fails instead of exhausting the memory.
+func (b *BodyLimit) Limit(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if b.maxBytes <= 0 {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		if r.ContentLength > b.maxBytes {
+			response.Error(w, http.StatusRequestEntityTooLarge, "request body too large")
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, b.maxBytes)
+
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,47 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"supermarket/internal/platform/web/middleware"
+	"supermarket/internal/platform/web/request"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestBodyLimitLimit tests the BodyLimit middleware.
+func TestBodyLimitLimit(t *testing.T) {
+	t.Run("error - declared content length too large", func(t *testing.T) {
+		// arrange
+		handler := middleware.NewBodyLimit(4).Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Fatal("handler must not be called")
+		}))
+		req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader("too large"))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+
+	t.Run("error - reading past the limit fails", func(t *testing.T) {
+		// arrange
+		var err error
+		handler := middleware.NewBodyLimit(4).Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err = request.ReadAll(r)
+		}))
+		req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader("too large"))
+		req.ContentLength = -1
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.ErrorIs(t, err, request.ErrRequestBodyTooLarge)
+	})
+}
@@ -0,0 +1,272 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// EncodingZstd is the zstd content coding
+	EncodingZstd = "zstd"
+	// EncodingGzip is the gzip content coding
+	EncodingGzip = "gzip"
+)
+
+// NewCompressor creates a new compression middleware.
+// Responses smaller than minSize bytes are not worth compressing and are sent as is.
+func NewCompressor(minSize int) *Compressor {
+	if minSize <= 0 {
+		minSize = 1024
+	}
+	return &Compressor{
+		minSize: minSize,
+		gzipPool: sync.Pool{New: func() any {
+			gz, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+			return gz
+		}},
+		zstdPool: sync.Pool{New: func() any {
+			// the options are valid, so the encoder can not fail to be created
+			zw, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+			return zw
+		}},
+	}
+}
+
+// Compressor compresses the responses with the coding preferred by the client.
+type Compressor struct {
+	// minSize is the minimum size of a compressed response
+	minSize int
+	// gzipPool and zstdPool reuse the encoders, which are expensive to allocate
+	gzipPool sync.Pool
+	zstdPool sync.Pool
+}
+
+// Compress negotiates the content coding from the Accept-Encoding header, preferring zstd over gzip,
+// and compresses the response body. Responses already encoded, without body, or of media types
+// already compressed are sent as is.
+func (c *Compressor) Compress(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// responses depend on the Accept-Encoding header
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, compressor: c, encoding: encoding, status: http.StatusOK}
+		defer cw.Close()
+
+		handler.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding returns the supported coding with the highest quality in the Accept-Encoding header,
+// or "" for the identity. "*" stands for the codings not listed explicitly.
+func negotiateEncoding(header string) string {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		coding, q := parseQuality(part)
+		if coding != "" {
+			qualities[coding] = q
+		}
+	}
+
+	// on equal quality zstd wins, it is faster and compresses better
+	best, bestQ := "", 0.0
+	for _, coding := range []string{EncodingZstd, EncodingGzip} {
+		q, ok := qualities[coding]
+		if !ok {
+			q = qualities["*"]
+		}
+		if q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+// parseQuality splits an element of an Accept or Accept-Encoding header into its value and q parameter
+func parseQuality(part string) (value string, q float64) {
+	q = 1
+	params := strings.Split(part, ";")
+	value = strings.ToLower(strings.TrimSpace(params[0]))
+	for _, param := range params[1:] {
+		k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || strings.ToLower(strings.TrimSpace(k)) != "q" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			return value, 0
+		}
+		q = parsed
+	}
+	return value, q
+}
+
+// compressWriter buffers the beginning of the body until it knows whether compressing it is worth it
+type compressWriter struct {
+	http.ResponseWriter
+	compressor *Compressor
+	encoding   string
+
+	// status is the status code written by the handler, sent once the compression is decided
+	status int
+	// wroteHeader is true once the status code is set, explicitly or by the first write
+	wroteHeader bool
+	// buf holds the beginning of the body while undecided
+	buf []byte
+	// decided is true once the headers are sent
+	decided bool
+	// encoder compresses the body, nil if the body is sent as is
+	encoder io.WriteCloser
+}
+
+// WriteHeader records the status code, the headers are sent once the compression is decided
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.decided || cw.wroteHeader {
+		return
+	}
+	// informational responses are sent right away
+	if code >= 100 && code < 200 {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.status = code
+	cw.wroteHeader = true
+}
+
+// Write buffers the body until minSize bytes are written, then compresses it
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.decided {
+		return cw.write(p)
+	}
+	cw.wroteHeader = true
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) < cw.compressor.minSize {
+		return len(p), nil
+	}
+	if err := cw.decide(true); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// write writes p to the encoder or to the underlying writer
+func (cw *compressWriter) write(p []byte) (int, error) {
+	if cw.encoder != nil {
+		return cw.encoder.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// decide sends the headers, compressing the body if large is true and the response is eligible,
+// and writes the buffered body
+func (cw *compressWriter) decide(large bool) error {
+	cw.decided = true
+	h := cw.Header()
+	if large && cw.compressible() {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", cw.encoding)
+		switch cw.encoding {
+		case EncodingZstd:
+			zw := cw.compressor.zstdPool.Get().(*zstd.Encoder)
+			zw.Reset(cw.ResponseWriter)
+			cw.encoder = zw
+		case EncodingGzip:
+			gz := cw.compressor.gzipPool.Get().(*gzip.Writer)
+			gz.Reset(cw.ResponseWriter)
+			cw.encoder = gz
+		}
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := cw.write(buf)
+	return err
+}
+
+// compressible returns true if the response has a body that is not already compressed
+func (cw *compressWriter) compressible() bool {
+	if cw.status == http.StatusNoContent || cw.status == http.StatusNotModified || cw.status < http.StatusOK {
+		return false
+	}
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	contentType := h.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, "image/") && !strings.HasPrefix(contentType, "image/svg"),
+		strings.HasPrefix(contentType, "video/"),
+		strings.HasPrefix(contentType, "audio/"),
+		strings.HasPrefix(contentType, "application/zip"),
+		strings.HasPrefix(contentType, "application/gzip"),
+		strings.HasPrefix(contentType, "application/zstd"):
+		return false
+	}
+	return true
+}
+
+// Close sends the pending body and returns the encoder to its pool
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if err := cw.decide(false); err != nil {
+			return err
+		}
+	}
+	if cw.encoder == nil {
+		return nil
+	}
+
+	err := cw.encoder.Close()
+	switch encoder := cw.encoder.(type) {
+	case *zstd.Encoder:
+		encoder.Reset(nil)
+		cw.compressor.zstdPool.Put(encoder)
+	case *gzip.Writer:
+		encoder.Reset(io.Discard)
+		cw.compressor.gzipPool.Put(encoder)
+	}
+	cw.encoder = nil
+	return err
+}
+
+// Flush sends what is written so far, compressing it if eligible, e.g. for streamed responses
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if err := cw.decide(true); err != nil {
+			return
+		}
+	}
+	if flusher, ok := cw.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets websockets and the like take over the connection
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(cw.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
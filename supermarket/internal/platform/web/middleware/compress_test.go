@@ -0,0 +1,109 @@
+package middleware_test
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"supermarket/internal/platform/web/middleware"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompressor tests the Compress middleware.
+func TestCompressor(t *testing.T) {
+	body := strings.Repeat(`{"name":"product","price":100}`, 100)
+	handler := middleware.NewCompressor(1024).Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, body[:len(body)/2])
+		io.WriteString(w, body[len(body)/2:])
+	}))
+
+	t.Run("success - gzip", func(t *testing.T) {
+		// arrange
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+		require.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+		gz, err := gzip.NewReader(rr.Body)
+		require.NoError(t, err)
+		decoded, err := io.ReadAll(gz)
+		require.NoError(t, err)
+		require.Equal(t, body, string(decoded))
+	})
+
+	t.Run("success - zstd preferred on equal quality", func(t *testing.T) {
+		// arrange
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		req.Header.Set("Accept-Encoding", "gzip, deflate, br, zstd")
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.Equal(t, "zstd", rr.Header().Get("Content-Encoding"))
+		zr, err := zstd.NewReader(rr.Body)
+		require.NoError(t, err)
+		defer zr.Close()
+		decoded, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		require.Equal(t, body, string(decoded))
+	})
+
+	t.Run("success - quality values honoured", func(t *testing.T) {
+		// arrange
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		req.Header.Set("Accept-Encoding", "zstd;q=0, *;q=0.5")
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+	})
+
+	t.Run("success - identity without Accept-Encoding", func(t *testing.T) {
+		// arrange
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.Empty(t, rr.Header().Get("Content-Encoding"))
+		require.Equal(t, body, rr.Body.String())
+	})
+
+	t.Run("success - small responses sent as is", func(t *testing.T) {
+		// arrange
+		handler := middleware.NewCompressor(1024).Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"id":1}`)
+		}))
+		req := httptest.NewRequest(http.MethodPost, "/products", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.Equal(t, http.StatusCreated, rr.Code)
+		require.Empty(t, rr.Header().Get("Content-Encoding"))
+		require.Equal(t, `{"id":1}`, rr.Body.String())
+	})
+}
@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"supermarket/internal/platform/httpcache"
+	"time"
+)
+
+// NewConditional creates a new conditional GET middleware for the routes of the resource of version,
+// sending cacheControl as Cache-Control header. An empty cacheControl sends no directive.
+func NewConditional(version *httpcache.Version, cacheControl string) *Conditional {
+	return &Conditional{
+		version:      version,
+		cacheControl: cacheControl,
+	}
+}
+
+// Conditional answers the conditional GET requests of a versioned resource.
+type Conditional struct {
+	// version is the version of the resource served by the routes
+	version *httpcache.Version
+	// cacheControl are the caching directives of the routes
+	cacheControl string
+}
+
+// Handle sets the ETag and Last-Modified headers of the current version on the successful responses, and
+// replaces them with 304 Not Modified when the If-None-Match header matches the ETag or, without If-None-Match,
+// when the resource was not modified since If-Modified-Since. Error responses are sent as is, without validators,
+// so that a missing resource never matches "*". Other methods than GET and HEAD are served as is.
+func (c *Conditional) Handle(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		etag, modified := c.version.Current()
+		handler.ServeHTTP(&conditionalWriter{ResponseWriter: w, c: c, r: r, etag: etag, modified: modified}, r)
+	})
+}
+
+// conditionalWriter applies the validators and the preconditions of a Conditional to the response once its
+// status code is known, and discards the body of the responses turned into 304 Not Modified
+type conditionalWriter struct {
+	http.ResponseWriter
+	c        *Conditional
+	r        *http.Request
+	etag     string
+	modified time.Time
+	// wroteHeader is true once the status code is sent
+	wroteHeader bool
+	// notModified is true if the response was replaced with 304 Not Modified
+	notModified bool
+}
+
+// WriteHeader sets the validators of a successful response and evaluates the preconditions of the request
+func (w *conditionalWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.wroteHeader = true
+
+	if status >= 200 && status < 300 {
+		h := w.Header()
+		h.Set("ETag", w.etag)
+		h.Set("Last-Modified", w.modified.Format(http.TimeFormat))
+		if w.c.cacheControl != "" {
+			h.Set("Cache-Control", w.c.cacheControl)
+		}
+		if notModified(w.r, w.etag, w.modified) {
+			w.notModified = true
+			h.Del("Content-Length")
+			status = http.StatusNotModified
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write sends the body, unless the response was replaced with 304 Not Modified
+func (w *conditionalWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.notModified {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher when the underlying writer does
+func (w *conditionalWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+		if !w.notModified {
+			f.Flush()
+		}
+	}
+}
+
+// Unwrap returns the underlying writer, used by http.ResponseController
+func (w *conditionalWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// notModified evaluates the preconditions of a GET request, If-None-Match taking precedence
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			// weak comparison, as for GET requests
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		since, err := http.ParseTime(ims)
+		return err == nil && !modified.After(since)
+	}
+	return false
+}
+
+// NewResponseCache creates a new middleware caching the responses of the routes of the resource of version.
+func NewResponseCache(cache *httpcache.Cache, version *httpcache.Version) *ResponseCache {
+	return &ResponseCache{
+		cache:   cache,
+		version: version,
+	}
+}
+
+// ResponseCache serves the responses of a versioned resource from a cache.
+type ResponseCache struct {
+	// cache keeps the responses, it should be cleared on every change of version
+	cache *httpcache.Cache
+	// version is part of the keys, so that the responses of older versions are never served
+	version *httpcache.Version
+}
+
+// Handle serves the GET requests from the cache, keyed by version, url and Accept header, and caches the
+// 200 OK responses. Other methods are served as is.
+func (rc *ResponseCache) Handle(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		etag, _ := rc.version.Current()
+		key := etag + " " + r.URL.RequestURI() + " " + r.Header.Get("Accept")
+		if cached, ok := rc.cache.Get(key); ok {
+			for k, v := range cached.Header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(cached.Status)
+			w.Write(cached.Body)
+			return
+		}
+
+		rw := &recordWriter{ResponseWriter: w}
+		handler.ServeHTTP(rw, r)
+		if rw.Status() == http.StatusOK {
+			rc.cache.Set(key, httpcache.Response{
+				Status: rw.Status(),
+				Header: storedHeader(w.Header()),
+				Body:   rw.body.Bytes(),
+			})
+		}
+	})
+}
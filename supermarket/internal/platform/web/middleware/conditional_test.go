@@ -0,0 +1,165 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"supermarket/internal/platform/httpcache"
+	"supermarket/internal/platform/web/middleware"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestConditional tests the Conditional middleware.
+func TestConditional(t *testing.T) {
+	version := httpcache.NewVersion()
+	handler := middleware.NewConditional(version, "no-cache").Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "products")
+	}))
+
+	t.Run("success - validators and cache control set", func(t *testing.T) {
+		// arrange
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		rr := httptest.NewRecorder()
+		etag, modified := version.Current()
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, etag, rr.Header().Get("ETag"))
+		require.Equal(t, modified.Format(http.TimeFormat), rr.Header().Get("Last-Modified"))
+		require.Equal(t, "no-cache", rr.Header().Get("Cache-Control"))
+		require.Equal(t, "products", rr.Body.String())
+	})
+
+	t.Run("success - not modified on matching If-None-Match", func(t *testing.T) {
+		// arrange
+		etag, _ := version.Current()
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		req.Header.Set("If-None-Match", `"other", `+etag)
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.Equal(t, http.StatusNotModified, rr.Code)
+		require.Empty(t, rr.Body.String())
+	})
+
+	t.Run("success - not modified since If-Modified-Since", func(t *testing.T) {
+		// arrange
+		_, modified := version.Current()
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		req.Header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.Equal(t, http.StatusNotModified, rr.Code)
+	})
+
+	t.Run("success - modified after a bump", func(t *testing.T) {
+		// arrange
+		etag, modified := version.Current()
+		version.Bump()
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		req.Header.Set("If-None-Match", etag)
+		rr := httptest.NewRecorder()
+		reqSince := httptest.NewRequest(http.MethodGet, "/products", nil)
+		reqSince.Header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+		rrSince := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(rr, req)
+		handler.ServeHTTP(rrSince, reqSince)
+
+		// assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.NotEqual(t, etag, rr.Header().Get("ETag"))
+		require.Equal(t, http.StatusOK, rrSince.Code)
+	})
+
+	t.Run("success - not modified on If-None-Match * for an existing resource", func(t *testing.T) {
+		// arrange
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		req.Header.Set("If-None-Match", "*")
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.Equal(t, http.StatusNotModified, rr.Code)
+		require.Empty(t, rr.Body.String())
+	})
+
+	t.Run("failure - error responses are sent without validators nor preconditions", func(t *testing.T) {
+		// arrange
+		missing := middleware.NewConditional(version, "no-cache").Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "product not found", http.StatusNotFound)
+		}))
+		etag, _ := version.Current()
+		req := httptest.NewRequest(http.MethodGet, "/products/99", nil)
+		req.Header.Set("If-None-Match", "*")
+		rr := httptest.NewRecorder()
+		reqETag := httptest.NewRequest(http.MethodGet, "/products/99", nil)
+		reqETag.Header.Set("If-None-Match", etag)
+		rrETag := httptest.NewRecorder()
+
+		// act
+		missing.ServeHTTP(rr, req)
+		missing.ServeHTTP(rrETag, reqETag)
+
+		// assert
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Empty(t, rr.Header().Get("ETag"))
+		require.Empty(t, rr.Header().Get("Last-Modified"))
+		require.Empty(t, rr.Header().Get("Cache-Control"))
+		require.Contains(t, rr.Body.String(), "product not found")
+		require.Equal(t, http.StatusNotFound, rrETag.Code)
+	})
+}
+
+// TestResponseCache tests the ResponseCache middleware.
+func TestResponseCache(t *testing.T) {
+	t.Run("success - served from the cache until the version changes", func(t *testing.T) {
+		// arrange
+		version := httpcache.NewVersion()
+		cache := httpcache.NewCache(10)
+		version.OnChange(cache.Clear)
+		calls := 0
+		handler := middleware.NewResponseCache(cache, version).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"calls":`+strconv.Itoa(calls)+`}`)
+		}))
+		get := func(accept string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, "/products", nil)
+			req.Header.Set("Accept", accept)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			return rr
+		}
+
+		// act
+		first := get("application/json")
+		cached := get("application/json")
+		otherFormat := get("text/csv")
+		version.Bump()
+		afterWrite := get("application/json")
+
+		// assert
+		require.Equal(t, 3, calls)
+		require.Equal(t, first.Body.String(), cached.Body.String())
+		require.Equal(t, "application/json", cached.Header().Get("Content-Type"))
+		require.Equal(t, http.StatusOK, otherFormat.Code)
+		require.Equal(t, http.StatusOK, afterWrite.Code)
+	})
+}
@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig is the configuration of the CORS middleware
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to call the api, "*" allows any origin.
+	// No origin disables CORS.
+	AllowedOrigins []string
+	// AllowedMethods are the methods allowed in cross-origin requests
+	AllowedMethods []string
+	// AllowedHeaders are the request headers allowed in cross-origin requests
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers readable by the browser
+	ExposedHeaders []string
+	// AllowCredentials allows cookies and authorization headers, it can not be used with the origin "*"
+	AllowCredentials bool
+	// MaxAge is how long the browser caches a preflight response
+	MaxAge time.Duration
+}
+
+// NewCORS creates a new CORS middleware.
+func NewCORS(cfg CORSConfig) *CORS {
+	// default values
+	if len(cfg.AllowedMethods) == 0 {
+		cfg.AllowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+
+	c := &CORS{
+		origins:          make(map[string]bool, len(cfg.AllowedOrigins)),
+		methods:          strings.Join(cfg.AllowedMethods, ", "),
+		allowedMethods:   make(map[string]bool, len(cfg.AllowedMethods)),
+		headers:          strings.Join(cfg.AllowedHeaders, ", "),
+		allowedHeaders:   make(map[string]bool, len(cfg.AllowedHeaders)),
+		exposedHeaders:   strings.Join(cfg.ExposedHeaders, ", "),
+		allowCredentials: cfg.AllowCredentials,
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			c.anyOrigin = true
+		}
+		c.origins[strings.ToLower(origin)] = true
+	}
+	for _, method := range cfg.AllowedMethods {
+		c.allowedMethods[strings.ToUpper(method)] = true
+	}
+	for _, header := range cfg.AllowedHeaders {
+		c.allowedHeaders[http.CanonicalHeaderKey(header)] = true
+	}
+	if cfg.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	}
+	return c
+}
+
+// CORS handles the cross-origin requests of browsers.
+type CORS struct {
+	origins          map[string]bool
+	anyOrigin        bool
+	methods          string
+	allowedMethods   map[string]bool
+	headers          string
+	allowedHeaders   map[string]bool
+	exposedHeaders   string
+	allowCredentials bool
+	maxAge           string
+}
+
+// Handler answers the preflight requests and sets the CORS headers of the requests of allowed origins.
+// Requests of other origins are served without CORS headers, so the browser blocks their responses.
+func (c *CORS) Handler(handler http.Handler) http.Handler {
+	if len(c.origins) == 0 {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		// responses depend on the origin, caches must not share them between origins
+		w.Header().Add("Vary", "Origin")
+		if origin == "" || !c.isAllowedOrigin(origin) {
+			if preflight {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		// - preflight
+		if preflight {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			if !c.allowedMethods[strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))] || !c.areAllowedHeaders(r.Header.Get("Access-Control-Request-Headers")) {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			c.setOrigin(w, origin)
+			w.Header().Set("Access-Control-Allow-Methods", c.methods)
+			if c.headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", c.headers)
+			}
+			if c.maxAge != "" {
+				w.Header().Set("Access-Control-Max-Age", c.maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		// - actual request
+		c.setOrigin(w, origin)
+		if c.exposedHeaders != "" {
+			w.Header().Set("Access-Control-Expose-Headers", c.exposedHeaders)
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// isAllowedOrigin returns true if origin may call the api
+func (c *CORS) isAllowedOrigin(origin string) bool {
+	return c.anyOrigin || c.origins[strings.ToLower(origin)]
+}
+
+// areAllowedHeaders returns true if every header of the comma-separated list is allowed
+func (c *CORS) areAllowedHeaders(headers string) bool {
+	for _, header := range strings.Split(headers, ",") {
+		header = strings.TrimSpace(header)
+		if header != "" && !c.allowedHeaders[http.CanonicalHeaderKey(header)] {
+			return false
+		}
+	}
+	return true
+}
+
+// setOrigin sets the allowed origin of the response, echoing the origin unless any origin is allowed
+// without credentials
+func (c *CORS) setOrigin(w http.ResponseWriter, origin string) {
+	if c.anyOrigin && !c.allowCredentials {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if c.allowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
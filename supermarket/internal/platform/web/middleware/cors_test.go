@@ -0,0 +1,85 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"supermarket/internal/platform/web/middleware"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestCORSHandler tests the CORS middleware.
+func TestCORSHandler(t *testing.T) {
+	cors := middleware.NewCORS(middleware.CORSConfig{
+		AllowedOrigins: []string{"https://shop.example.com"},
+		AllowedHeaders: []string{"Content-Type", "Token"},
+		ExposedHeaders: []string{middleware.RequestIDHeader},
+		MaxAge:         10 * time.Minute,
+	})
+	var called bool
+	handler := cors.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	t.Run("success - preflight of an allowed origin", func(t *testing.T) {
+		// arrange
+		called = false
+		req := httptest.NewRequest(http.MethodOptions, "/products", nil)
+		req.Header.Set("Origin", "https://shop.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "content-type, token")
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.False(t, called)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+		require.Equal(t, "https://shop.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+		require.Equal(t, "GET, POST, PUT, PATCH, DELETE", rr.Header().Get("Access-Control-Allow-Methods"))
+		require.Equal(t, "Content-Type, Token", rr.Header().Get("Access-Control-Allow-Headers"))
+		require.Equal(t, "600", rr.Header().Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("success - request of an allowed origin", func(t *testing.T) {
+		// arrange
+		called = false
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		req.Header.Set("Origin", "https://shop.example.com")
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.True(t, called)
+		require.Equal(t, "https://shop.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+		require.Equal(t, middleware.RequestIDHeader, rr.Header().Get("Access-Control-Expose-Headers"))
+		require.Contains(t, rr.Header().Values("Vary"), "Origin")
+	})
+
+	t.Run("error - preflight of another origin or header is not allowed", func(t *testing.T) {
+		for _, origin := range []string{"https://evil.example.com", "https://shop.example.com"} {
+			// arrange
+			called = false
+			req := httptest.NewRequest(http.MethodOptions, "/products", nil)
+			req.Header.Set("Origin", origin)
+			req.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+			if origin == "https://shop.example.com" {
+				req.Header.Set("Access-Control-Request-Headers", "X-Custom")
+			}
+			rr := httptest.NewRecorder()
+
+			// act
+			handler.ServeHTTP(rr, req)
+
+			// assert
+			require.False(t, called)
+			require.Equal(t, http.StatusNoContent, rr.Code)
+			require.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+		}
+	})
+}
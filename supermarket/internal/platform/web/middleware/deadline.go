@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// NewDeadline creates a new deadline middleware. A timeout of 0 leaves the requests without deadline,
+// as the requests to the paths of streams, e.g. of server-sent events.
+func NewDeadline(timeout time.Duration, streams ...string) *Deadline {
+	exempt := make(map[string]bool, len(streams))
+	for _, path := range streams {
+		exempt[path] = true
+	}
+	return &Deadline{
+		timeout: timeout,
+		streams: exempt,
+	}
+}
+
+// Deadline bounds the time spent handling a request.
+type Deadline struct {
+	// timeout is the maximum duration of a request
+	timeout time.Duration
+	// streams are the paths of the long-lived responses, left without deadline
+	streams map[string]bool
+}
+
+// Timeout sets a deadline on the context of the request, so the service, repository and storage
+// stop working once the client is gone or the deadline is exceeded.
+func (d *Deadline) Timeout(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.timeout <= 0 || d.streams[r.URL.Path] {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), d.timeout)
+		defer cancel()
+
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,64 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"supermarket/internal/platform/web/middleware"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestDeadlineTimeout tests the Timeout middleware.
+func TestDeadlineTimeout(t *testing.T) {
+	t.Run("success - sets the deadline of the request context", func(t *testing.T) {
+		// arrange
+		var deadline time.Time
+		var ok bool
+		handler := middleware.NewDeadline(time.Second).Timeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deadline, ok = r.Context().Deadline()
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.True(t, ok)
+		require.WithinDuration(t, time.Now().Add(time.Second), deadline, time.Second)
+	})
+
+	t.Run("success - a timeout of 0 sets no deadline", func(t *testing.T) {
+		// arrange
+		var ok bool
+		handler := middleware.NewDeadline(0).Timeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, ok = r.Context().Deadline()
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.False(t, ok)
+	})
+
+	t.Run("success - streams are left without deadline", func(t *testing.T) {
+		// arrange
+		var ok bool
+		handler := middleware.NewDeadline(time.Second, "/products/events").Timeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, ok = r.Context().Deadline()
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/products/events", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.False(t, ok)
+	})
+}
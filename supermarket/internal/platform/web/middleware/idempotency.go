@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"supermarket/internal/platform/idempotency"
+	"supermarket/internal/platform/logging"
+	"supermarket/internal/platform/web/request"
+	"supermarket/internal/platform/web/response"
+	"time"
+)
+
+const (
+	// IdempotencyKeyHeader is the header carrying the idempotency key of a request
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on the responses replayed from the store
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength is the maximum length of an idempotency key
+	maxIdempotencyKeyLength = 255
+)
+
+// NewIdempotency creates a new idempotency middleware keeping the responses in store for ttl.
+func NewIdempotency(store idempotency.Store, ttl time.Duration) *Idempotency {
+	return &Idempotency{
+		store: store,
+		ttl:   ttl,
+	}
+}
+
+// Idempotency replays the first response to the retries of a request with the same Idempotency-Key.
+type Idempotency struct {
+	// store keeps the responses by token and key
+	store idempotency.Store
+	// ttl is how long a response is kept
+	ttl time.Duration
+}
+
+// Handle stores the response of the requests with an Idempotency-Key header, keyed by api token and key,
+// and replays it to the retries with the same body. Retries with a different body get a
+// 422 Unprocessable Entity, and retries while the first request is in progress a 409 Conflict.
+// Responses 5xx are not stored, so the request can be retried. Requests without key are served as is.
+func (i *Idempotency) Handle(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			response.Error(w, http.StatusBadRequest, "idempotency key too long")
+			return
+		}
+
+		// before
+		// - fingerprint of the request, the body is restored for the handler
+		body, err := request.ReadAll(r)
+		if err != nil {
+			switch {
+			case errors.Is(err, request.ErrRequestBodyTooLarge):
+				response.Error(w, http.StatusRequestEntityTooLarge, "request body too large")
+			default:
+				response.Error(w, http.StatusBadRequest, "bad request")
+			}
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := hash(r.Method, r.URL.Path, string(body))
+
+		// - reserve the key, scoped by token so clients can not replay each other's responses
+		storeKey := hash(r.Header.Get("Token"), key)
+		record, err := i.store.Reserve(r.Context(), storeKey, fingerprint, i.ttl)
+		switch {
+		case errors.Is(err, idempotency.ErrFingerprintMismatch):
+			response.Error(w, http.StatusUnprocessableEntity, "idempotency key reused with a different request")
+			return
+		case errors.Is(err, idempotency.ErrInProgress):
+			response.Error(w, http.StatusConflict, "request with the same idempotency key in progress")
+			return
+		case err != nil:
+			logging.FromContext(r.Context()).Error("idempotency reserve", slog.Any("error", err))
+			response.Error(w, http.StatusInternalServerError, "internal server error")
+			return
+		case record != nil:
+			replay(w, record)
+			return
+		}
+
+		// call
+		rw := &recordWriter{ResponseWriter: w}
+		// the store is updated even if the client is gone, as the request was processed
+		ctx := context.WithoutCancel(r.Context())
+		stored := false
+		defer func() {
+			// the handler panicked, the key is released so the request can be retried
+			if !stored {
+				i.store.Release(ctx, storeKey)
+			}
+		}()
+		handler.ServeHTTP(rw, r)
+
+		// after
+		if rw.Status() >= http.StatusInternalServerError {
+			err = i.store.Release(ctx, storeKey)
+		} else {
+			err = i.store.Complete(ctx, storeKey, idempotency.Record{
+				Fingerprint: fingerprint,
+				Status:      rw.Status(),
+				Header:      storedHeader(w.Header()),
+				Body:        rw.body.Bytes(),
+			})
+		}
+		stored = true
+		if err != nil {
+			logging.FromContext(ctx).Error("idempotency store", slog.Any("error", err))
+		}
+	})
+}
+
+// replay writes the stored response
+func replay(w http.ResponseWriter, record *idempotency.Record) {
+	for k, v := range record.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(record.Status)
+	w.Write(record.Body)
+}
+
+// storedHeader returns the headers of the response describing its body, the others being set per request
+func storedHeader(h http.Header) http.Header {
+	stored := make(http.Header)
+	for _, k := range []string{"Content-Type", "Content-Language", "Location"} {
+		if v := h.Values(k); len(v) > 0 {
+			stored[k] = v
+		}
+	}
+	return stored
+}
+
+// hash returns the hex sha256 of the parts, separated so that they can not be shifted between each other
+func hash(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		io.WriteString(h, part)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordWriter records the status code and a copy of the body of the response
+type recordWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader records the status code
+func (w *recordWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write copies the body
+func (w *recordWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// Status returns the status code of the response
+func (w *recordWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Unwrap returns the underlying writer, used by http.ResponseController
+func (w *recordWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
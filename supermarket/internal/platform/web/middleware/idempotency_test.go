@@ -0,0 +1,112 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"supermarket/internal/platform/idempotency"
+	"supermarket/internal/platform/web/middleware"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestIdempotency tests the Idempotency middleware.
+func TestIdempotency(t *testing.T) {
+	// newHandler returns a handler creating a product per call, and the counter of calls
+	newHandler := func() (http.Handler, *int) {
+		calls := 0
+		handler := middleware.NewIdempotency(idempotency.NewMemoryStore(), time.Hour).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"call":`+strconv.Itoa(calls)+`,"body":`+string(body)+`}`)
+		}))
+		return handler, &calls
+	}
+	// newRequest returns a POST /products with an idempotency key
+	newRequest := func(token, key, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(body))
+		req.Header.Set("Token", token)
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+		return req
+	}
+
+	t.Run("success - retry replays the first response", func(t *testing.T) {
+		// arrange
+		handler, calls := newHandler()
+		first := httptest.NewRecorder()
+		retry := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(first, newRequest("token", "key-1", `{"name":"milk"}`))
+		handler.ServeHTTP(retry, newRequest("token", "key-1", `{"name":"milk"}`))
+
+		// assert
+		require.Equal(t, 1, *calls)
+		require.Equal(t, http.StatusCreated, retry.Code)
+		require.Equal(t, first.Body.String(), retry.Body.String())
+		require.Equal(t, "application/json", retry.Header().Get("Content-Type"))
+		require.Equal(t, "true", retry.Header().Get(middleware.IdempotentReplayedHeader))
+		require.Empty(t, first.Header().Get(middleware.IdempotentReplayedHeader))
+	})
+
+	t.Run("success - keys are scoped by token", func(t *testing.T) {
+		// arrange
+		handler, calls := newHandler()
+
+		// act
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest("token-a", "key-1", `{}`))
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest("token-b", "key-1", `{}`))
+
+		// assert
+		require.Equal(t, 2, *calls)
+	})
+
+	t.Run("success - requests without key are not stored", func(t *testing.T) {
+		// arrange
+		handler, calls := newHandler()
+
+		// act
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest("token", "", `{}`))
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest("token", "", `{}`))
+
+		// assert
+		require.Equal(t, 2, *calls)
+	})
+
+	t.Run("error - retry with a different body", func(t *testing.T) {
+		// arrange
+		handler, calls := newHandler()
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest("token", "key-1", `{"name":"milk"}`))
+		handler.ServeHTTP(rr, newRequest("token", "key-1", `{"name":"bread"}`))
+
+		// assert
+		require.Equal(t, 1, *calls)
+		require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		require.JSONEq(t, `{"status":"Unprocessable Entity","message":"idempotency key reused with a different request"}`, rr.Body.String())
+	})
+
+	t.Run("success - server errors are not replayed", func(t *testing.T) {
+		// arrange
+		calls := 0
+		handler := middleware.NewIdempotency(idempotency.NewMemoryStore(), time.Hour).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+
+		// act
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest("token", "key-1", `{}`))
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest("token", "key-1", `{}`))
+
+		// assert
+		require.Equal(t, 2, calls)
+	})
+}
@@ -1,38 +1,93 @@
 package middleware
 
 import (
-	"fmt"
+	"context"
+	"log/slog"
+	"math/rand"
 	"net/http"
+	"supermarket/internal/platform/logging"
+	"supermarket/internal/platform/tracing"
 	"time"
+
+	"github.com/go-chi/chi/v5"
 )
 
 // NewLogger creates a new logger.
-func NewLogger() *Logger {
-	return &Logger{}
+// sampleRate is the fraction of successful requests logged, errors are always logged.
+func NewLogger(logger *slog.Logger, sampleRate float64) *Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Logger{
+		logger:     logger,
+		sampleRate: sampleRate,
+	}
 }
 
 // Logger handles logging.
 type Logger struct {
-	// ...
+	// logger is the base logger, request-scoped loggers are derived from it
+	logger *slog.Logger
+	// sampleRate is the fraction, between 0 and 1, of requests with status < 400 that are logged
+	sampleRate float64
 }
 
 // Log logs requests.
+// It propagates or generates the X-Request-ID and puts a request-scoped logger in the context,
+// tagged with the trace id when the request is traced.
 func (l *Logger) Log(handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// before
 		// - start timer
 		start := time.Now()
+		// - request id
+		id := requestID(r)
+		w.Header().Set(RequestIDHeader, id)
+		// - request-scoped logger
+		logger := l.logger.With(slog.String("request_id", id))
+		if sc := tracing.SpanContextFromContext(r.Context()); sc.IsValid() {
+			logger = logger.With(slog.String("trace_id", sc.TraceID.String()))
+		}
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		ctx = logging.WithLogger(ctx, logger)
+		sw := newStatusWriter(w)
 
 		// call
-		handler.ServeHTTP(w, r)
+		handler.ServeHTTP(sw, r.WithContext(ctx))
 
 		// after
-		// - stop timer
-		duration := time.Since(start)
-		// - message
-		messageRequest := fmt.Sprintf("[%s] %s -size: %d", r.Method, r.URL, r.ContentLength)
-		messageResponse := fmt.Sprintf("| -time: %v", duration)
+		status := sw.Status()
+		// - sampling, errors are never dropped
+		if status < http.StatusBadRequest && (l.sampleRate <= 0 || (l.sampleRate < 1 && rand.Float64() >= l.sampleRate)) {
+			return
+		}
+		// - level
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
 		// - log
-		fmt.Println(messageRequest, messageResponse)
+		logger.LogAttrs(ctx, level, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("route", routePattern(r)),
+			slog.Int("status", status),
+			slog.Int64("bytes_in", r.ContentLength),
+			slog.Int("bytes_out", sw.bytes),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.String("user_agent", r.UserAgent()),
+		)
 	})
 }
+
+// routePattern returns the chi route pattern that matched the request, if any
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
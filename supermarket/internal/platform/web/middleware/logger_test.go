@@ -0,0 +1,66 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"supermarket/internal/platform/logging"
+	"supermarket/internal/platform/web/middleware"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoggerLog tests the Log middleware.
+func TestLoggerLog(t *testing.T) {
+	t.Run("success - logs status, size and propagated request id", func(t *testing.T) {
+		// arrange
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, nil))
+		lg := middleware.NewLogger(logger, 1)
+		handler := lg.Log(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logging.FromContext(r.Context()).Info("inside handler")
+			w.WriteHeader(http.StatusTeapot)
+			w.Write([]byte("hello"))
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		req.Header.Set(middleware.RequestIDHeader, "abc-123")
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.Equal(t, "abc-123", rr.Header().Get(middleware.RequestIDHeader))
+		lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+		require.Len(t, lines, 2)
+		var inside, request map[string]any
+		require.NoError(t, json.Unmarshal(lines[0], &inside))
+		require.NoError(t, json.Unmarshal(lines[1], &request))
+		require.Equal(t, "abc-123", inside["request_id"])
+		require.Equal(t, "WARN", request["level"])
+		require.Equal(t, float64(http.StatusTeapot), request["status"])
+		require.Equal(t, float64(5), request["bytes_out"])
+	})
+
+	t.Run("success - generates a request id and samples out successful requests", func(t *testing.T) {
+		// arrange
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, nil))
+		lg := middleware.NewLogger(logger, 0)
+		handler := lg.Log(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.NotEmpty(t, middleware.RequestIDFromContext(r.Context()))
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.Len(t, rr.Header().Get(middleware.RequestIDHeader), 32)
+		require.Empty(t, buf.String())
+	})
+}
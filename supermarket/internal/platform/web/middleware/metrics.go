@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"supermarket/internal/platform/metrics"
+	"time"
+)
+
+// NewMetrics creates a new metrics middleware registering its metrics in reg.
+func NewMetrics(reg *metrics.Registry) *Metrics {
+	return &Metrics{
+		requests: reg.NewCounter("http_requests_total",
+			"Number of http requests.", "method", "route", "status"),
+		duration: reg.NewHistogram("http_request_duration_seconds",
+			"Duration of the http requests.", nil, "method", "route", "status"),
+	}
+}
+
+// Metrics records the count and latency of requests.
+type Metrics struct {
+	requests *metrics.Counter
+	duration *metrics.Histogram
+}
+
+// Instrument records the requests labelled by method, chi route pattern and status.
+// Requests that match no route are labelled with the route "unmatched" to bound the cardinality.
+func (m *Metrics) Instrument(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// before
+		start := time.Now()
+		sw := newStatusWriter(w)
+
+		// call
+		handler.ServeHTTP(sw, r)
+
+		// after
+		route := routePattern(r)
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(sw.Status())
+		m.requests.Inc(r.Method, route, status)
+		m.duration.Observe(time.Since(start).Seconds(), r.Method, route, status)
+	})
+}
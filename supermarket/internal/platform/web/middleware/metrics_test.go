@@ -0,0 +1,73 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"supermarket/internal/platform/metrics"
+	"supermarket/internal/platform/web/middleware"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// exposition returns the metrics of reg in the text exposition format
+func exposition(t *testing.T, reg *metrics.Registry) string {
+	t.Helper()
+	var sb strings.Builder
+	_, err := reg.WriteTo(&sb)
+	require.NoError(t, err)
+	return sb.String()
+}
+
+// TestMetrics tests the Metrics middleware.
+func TestMetrics(t *testing.T) {
+	// newRouter returns a router instrumented by a Metrics registering in reg
+	newRouter := func(reg *metrics.Registry) http.Handler {
+		router := chi.NewRouter()
+		router.Use(middleware.NewMetrics(reg).Instrument)
+		router.Get("/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+			if chi.URLParam(r, "id") == "0" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte("product"))
+		})
+		return router
+	}
+
+	t.Run("success - requests labelled by route pattern and status", func(t *testing.T) {
+		// arrange
+		reg := metrics.NewRegistry()
+		router := newRouter(reg)
+
+		// act
+		for _, path := range []string{"/products/1", "/products/2", "/products/0"} {
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}
+
+		// assert
+		text := exposition(t, reg)
+		require.Contains(t, text, `http_requests_total{method="GET",route="/products/{id}",status="200"} 2`)
+		require.Contains(t, text, `http_requests_total{method="GET",route="/products/{id}",status="404"} 1`)
+		require.Contains(t, text, `http_request_duration_seconds_count{method="GET",route="/products/{id}",status="200"} 2`)
+		require.NotContains(t, text, `route="/products/1"`)
+	})
+
+	t.Run("success - requests matching no route labelled unmatched", func(t *testing.T) {
+		// arrange
+		reg := metrics.NewRegistry()
+		router := newRouter(reg)
+
+		// act
+		for _, path := range []string{"/a", "/b/c"} {
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}
+
+		// assert
+		text := exposition(t, reg)
+		require.Contains(t, text, `http_requests_total{method="GET",route="unmatched",status="404"} 2`)
+		require.NotContains(t, text, `route="/a"`)
+	})
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"supermarket/internal/platform/logging"
+	"supermarket/internal/platform/ratelimit"
+	"supermarket/internal/platform/web/response"
+	"time"
+)
+
+// KeyFunc returns the key of the bucket a request takes its token from
+type KeyFunc func(r *http.Request) string
+
+// NewRateLimiter creates a new rate limiter taking a token of limit, from the bucket of key, per request.
+// group names the routes limited, so that route groups sharing a store have their own buckets.
+func NewRateLimiter(store ratelimit.Store, group string, limit ratelimit.Limit, key KeyFunc) *RateLimiter {
+	return &RateLimiter{
+		store: store,
+		group: group,
+		limit: limit,
+		key:   key,
+	}
+}
+
+// RateLimiter limits the rate of requests of each client.
+type RateLimiter struct {
+	// store keeps the buckets, possibly shared with other route groups
+	store ratelimit.Store
+	// group prefixes the keys of the buckets
+	group string
+	// limit is the rate and burst of the route group
+	limit ratelimit.Limit
+	// key identifies the client
+	key KeyFunc
+}
+
+// Limit responds 429 Too Many Requests, with a Retry-After header, once the bucket of the client is empty.
+// The RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers are set on every response.
+// Requests are let through if the store fails, so an outage of the store does not take the api down.
+func (rl *RateLimiter) Limit(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// before
+		result, err := rl.store.Take(r.Context(), rl.group+":"+rl.key(r), rl.limit)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("rate limit", slog.Any("error", err))
+			handler.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", ceilSeconds(result.Reset))
+		if !result.Allowed {
+			w.Header().Set("Retry-After", ceilSeconds(result.RetryAfter))
+			response.Error(w, http.StatusTooManyRequests, "too many requests")
+			return
+		}
+
+		// call
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// ceilSeconds formats d as a whole number of seconds, rounded up
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// NewClientIP creates a resolver of the client ip trusting the forwarding headers set by the proxies
+// in trustedProxies, a list of ips or CIDRs.
+func NewClientIP(trustedProxies []string) (*ClientIP, error) {
+	c := &ClientIP{}
+	for _, proxy := range trustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			c.trusted = append(c.trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		c.trusted = append(c.trusted, network)
+	}
+	return c, nil
+}
+
+// ClientIP resolves the ip of the client of a request.
+type ClientIP struct {
+	// trusted are the networks of the proxies whose forwarding headers are trusted
+	trusted []*net.IPNet
+}
+
+// IP returns the ip of the client. When the peer is a trusted proxy, the X-Forwarded-For header is walked
+// from the right and the first ip that is not a trusted proxy is returned, falling back to X-Real-IP.
+// Headers sent by untrusted peers are ignored, as they can be forged.
+func (c *ClientIP) IP(r *http.Request) string {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+	if !c.isTrusted(peer) {
+		return peer
+	}
+
+	// - X-Forwarded-For: client, proxy1, proxy2
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		if !c.isTrusted(hop) {
+			return hop
+		}
+		peer = hop
+	}
+
+	// - X-Real-IP
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(ip) != nil {
+		return ip
+	}
+	return peer
+}
+
+// isTrusted returns true if ip belongs to a trusted proxy
+func (c *ClientIP) isTrusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range c.trusted {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyByIP keys the buckets by client ip.
+func KeyByIP(clientIP *ClientIP) KeyFunc {
+	return func(r *http.Request) string {
+		return "ip:" + clientIP.IP(r)
+	}
+}
+
+// KeyByToken keys the buckets by the api token of the Token header when valid returns true for it,
+// and by client ip otherwise, so that requests with made up tokens share the bucket of their ip.
+// Tokens are hashed so they are never kept by the store.
+func KeyByToken(clientIP *ClientIP, valid func(token string) bool) KeyFunc {
+	return func(r *http.Request) string {
+		if token := r.Header.Get("Token"); token != "" && valid(token) {
+			sum := sha256.Sum256([]byte(token))
+			return "token:" + hex.EncodeToString(sum[:])
+		}
+		return "ip:" + clientIP.IP(r)
+	}
+}
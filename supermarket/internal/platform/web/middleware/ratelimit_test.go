@@ -0,0 +1,107 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"supermarket/internal/platform/ratelimit"
+	"supermarket/internal/platform/web/middleware"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRateLimiterLimit tests the Limit middleware.
+func TestRateLimiterLimit(t *testing.T) {
+	t.Run("error - too many requests of the same client", func(t *testing.T) {
+		// arrange
+		clientIP, err := middleware.NewClientIP(nil)
+		require.NoError(t, err)
+		rl := middleware.NewRateLimiter(ratelimit.NewMemoryStore(), "read", ratelimit.Limit{Rate: 1, Burst: 1}, middleware.KeyByIP(clientIP))
+		handler := rl.Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		first := httptest.NewRecorder()
+		second := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(first, req)
+		handler.ServeHTTP(second, req)
+
+		// assert
+		require.Equal(t, http.StatusOK, first.Code)
+		require.Equal(t, "1", first.Header().Get("RateLimit-Limit"))
+		require.Equal(t, "0", first.Header().Get("RateLimit-Remaining"))
+		require.Equal(t, "1", first.Header().Get("RateLimit-Reset"))
+		require.Equal(t, http.StatusTooManyRequests, second.Code)
+		require.Equal(t, "1", second.Header().Get("Retry-After"))
+		require.JSONEq(t, `{"status":"Too Many Requests","message":"too many requests"}`, second.Body.String())
+	})
+
+	t.Run("success - valid tokens have their own bucket", func(t *testing.T) {
+		// arrange
+		clientIP, err := middleware.NewClientIP(nil)
+		require.NoError(t, err)
+		key := middleware.KeyByToken(clientIP, func(token string) bool { return token == "valid" })
+		rl := middleware.NewRateLimiter(ratelimit.NewMemoryStore(), "write", ratelimit.Limit{Rate: 1, Burst: 1}, key)
+		handler := rl.Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		codes := make([]int, 0, 3)
+
+		// act
+		for _, token := range []string{"made-up", "other-made-up", "valid"} {
+			req := httptest.NewRequest(http.MethodPost, "/products", nil)
+			req.RemoteAddr = "10.0.0.1:1234"
+			req.Header.Set("Token", token)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			codes = append(codes, rr.Code)
+		}
+
+		// assert
+		require.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests, http.StatusOK}, codes)
+	})
+}
+
+// TestClientIPIP tests the resolution of the client ip.
+func TestClientIPIP(t *testing.T) {
+	t.Run("success - forwarding headers of trusted proxies only", func(t *testing.T) {
+		// arrange
+		clientIP, err := middleware.NewClientIP([]string{"10.0.0.0/8", "192.168.1.1"})
+		require.NoError(t, err)
+		cases := []struct {
+			remoteAddr string
+			forwarded  string
+			realIP     string
+			expected   string
+		}{
+			{"203.0.113.9:1234", "198.51.100.1", "", "203.0.113.9"},
+			{"10.0.0.1:1234", "198.51.100.7, 198.51.100.1, 192.168.1.1", "", "198.51.100.1"},
+			{"10.0.0.1:1234", "", "198.51.100.2", "198.51.100.2"},
+			{"10.0.0.1:1234", "10.0.0.2", "", "10.0.0.2"},
+		}
+
+		for _, c := range cases {
+			req := httptest.NewRequest(http.MethodGet, "/products", nil)
+			req.RemoteAddr = c.remoteAddr
+			if c.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", c.forwarded)
+			}
+			if c.realIP != "" {
+				req.Header.Set("X-Real-IP", c.realIP)
+			}
+
+			// act
+			ip := clientIP.IP(req)
+
+			// assert
+			require.Equal(t, c.expected, ip)
+		}
+	})
+
+	t.Run("error - invalid trusted proxy", func(t *testing.T) {
+		// act
+		_, err := middleware.NewClientIP([]string{"not-an-ip"})
+
+		// assert
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,28 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"supermarket/internal/platform/web/middleware"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSecurityHeaders tests the SecurityHeaders middleware.
+func TestSecurityHeaders(t *testing.T) {
+	t.Run("success - headers set, without HSTS over plain http", func(t *testing.T) {
+		// arrange
+		handler := middleware.SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"))
+		require.Equal(t, "DENY", rr.Header().Get("X-Frame-Options"))
+		require.Empty(t, rr.Header().Get("Strict-Transport-Security"))
+	})
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"supermarket/internal/platform/tracing"
+)
+
+// Trace starts a server span per request, child of the incoming traceparent header if any.
+// The span is named after the chi route pattern once the request is routed, to bound the cardinality.
+func Trace(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// before
+		ctx := tracing.Extract(r.Context(), r.Header)
+		ctx, span := tracing.Start(ctx, r.Method, tracing.WithSpanKind(tracing.SpanKindServer),
+			tracing.WithAttributes(
+				tracing.String("http.method", r.Method),
+				tracing.String("http.target", r.URL.Path),
+			))
+		defer span.End()
+		sw := newStatusWriter(w)
+
+		// call
+		handler.ServeHTTP(sw, r.WithContext(ctx))
+
+		// after
+		route := routePattern(r)
+		if route == "" {
+			route = "unmatched"
+		}
+		status := sw.Status()
+		span.SetName(r.Method + " " + route)
+		span.SetAttributes(
+			tracing.String("http.route", route),
+			tracing.Int("http.status_code", status),
+		)
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(tracing.StatusError, strconv.Itoa(status))
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// newStatusWriter wraps w to capture the status code and the size of the response
+func newStatusWriter(w http.ResponseWriter) *statusWriter {
+	return &statusWriter{ResponseWriter: w}
+}
+
+// statusWriter is an http.ResponseWriter that records the status code and the bytes written
+type statusWriter struct {
+	http.ResponseWriter
+	// status is the status code, 0 until the header is written
+	status int
+	// bytes is the number of bytes of the body written
+	bytes int
+}
+
+// WriteHeader records the status code
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the bytes written, an implicit 200 if the header was not written
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Status returns the status code of the response
+func (w *statusWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Flush implements http.Flusher when the underlying writer does
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer, used by http.ResponseController
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -1,10 +1,14 @@
 package request
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"strings"
 )
 
 // JSON decodes json from request body to ptr
@@ -13,22 +17,203 @@ var (
 	ErrRequestContentTypeNotJSON = errors.New("request content type is not application/json")
 	// ErrRequestJSONInvalid is used when the request json is invalid.
 	ErrRequestJSONInvalid = errors.New("request json invalid")
+	// ErrRequestBodyTooLarge is used when the request body exceeds the limit of http.MaxBytesReader.
+	ErrRequestBodyTooLarge = errors.New("request body too large")
 )
 
+// DecodeError is a json body that can not be decoded to the destination.
+// It matches ErrRequestJSONInvalid with errors.Is.
+type DecodeError struct {
+	// Field is the path of the offending field, empty if unknown
+	Field string
+	// Offset is the number of bytes of the body read when the error was found, right after the offending token
+	Offset int64
+	// Reason describes the error
+	Reason string
+}
+
+// Error returns the reason, with the field and offset.
+func (e *DecodeError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("%s: field %q at offset %d: %s", ErrRequestJSONInvalid, e.Field, e.Offset, e.Reason)
+	}
+	return fmt.Sprintf("%s: at offset %d: %s", ErrRequestJSONInvalid, e.Offset, e.Reason)
+}
+
+// Is makes the error match ErrRequestJSONInvalid
+func (e *DecodeError) Is(target error) bool {
+	return target == ErrRequestJSONInvalid
+}
+
+// Option configures the decoding of a json body
+type Option func(*options)
+
+// options are the settings of the decoding
+type options struct {
+	strict   bool
+	maxBytes int64
+}
+
+// Strict rejects the bodies with fields unknown to the destination, or with anything after the json document.
+func Strict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// MaxBytes limits the size of the body to n bytes, for the routes without a body limit middleware.
+func MaxBytes(n int64) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxBytes = n
+		}
+	}
+}
+
 // JSON decodes json from request body to ptr
-func JSON(r *http.Request, ptr any) (err error) {
+func JSON(r *http.Request, ptr any, opts ...Option) (err error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	// check content type
-	if r.Header.Get("Content-Type") != "application/json" {
+	if !isJSONContentType(r.Header.Get("Content-Type")) {
 		err = ErrRequestContentTypeNotJSON
 		return
 	}
 
-	// get body
-	err = json.NewDecoder(r.Body).Decode(ptr)
+	// get body, kept to locate the unknown fields
+	body := r.Body
+	if o.maxBytes > 0 {
+		body = http.MaxBytesReader(nil, body, o.maxBytes)
+	}
+	var read bytes.Buffer
+	decoder := json.NewDecoder(io.TeeReader(body, &read))
+	if o.strict {
+		decoder.DisallowUnknownFields()
+	}
+	err = decoder.Decode(ptr)
 	if err != nil {
-		err = fmt.Errorf("%w. %v", ErrRequestJSONInvalid, err)
+		err = decodeError(decoder, read.Bytes(), err)
 		return
 	}
 
+	// strict: a single json document
+	if o.strict {
+		var extra json.RawMessage
+		offset := decoder.InputOffset()
+		if err = decoder.Decode(&extra); err != io.EOF {
+			if err != nil {
+				err = decodeError(decoder, read.Bytes(), err)
+				return
+			}
+			err = &DecodeError{Offset: offset, Reason: "body must contain a single json document"}
+			return
+		}
+		err = nil
+	}
+
 	return
 }
+
+// isJSONContentType returns true for application/json and the json based media types, e.g.
+// application/problem+json, in utf-8
+func isJSONContentType(contentType string) bool {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if mediaType != "application/json" && !(strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json")) {
+		return false
+	}
+	charset, ok := params["charset"]
+	return !ok || strings.EqualFold(charset, "utf-8")
+}
+
+// decodeError converts an error of the decoder to a DecodeError with the field and offset of the error
+func decodeError(decoder *json.Decoder, body []byte, err error) error {
+	var (
+		maxBytesErr  *http.MaxBytesError
+		syntaxErr    *json.SyntaxError
+		typeErr      *json.UnmarshalTypeError
+		unknownField = "json: unknown field "
+	)
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return fmt.Errorf("%w. %v", ErrRequestBodyTooLarge, err)
+	case errors.As(err, &syntaxErr):
+		return &DecodeError{Offset: syntaxErr.Offset, Reason: syntaxErr.Error()}
+	case errors.As(err, &typeErr):
+		return &DecodeError{Field: typeErr.Field, Offset: typeErr.Offset, Reason: fmt.Sprintf("cannot use %s as %s", typeErr.Value, typeErr.Type)}
+	case errors.Is(err, io.EOF):
+		return &DecodeError{Offset: 0, Reason: "body is empty"}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &DecodeError{Offset: decoder.InputOffset(), Reason: "unexpected end of body"}
+	case strings.HasPrefix(err.Error(), unknownField):
+		// the decoder does not export the error of DisallowUnknownFields
+		field := strings.Trim(strings.TrimPrefix(err.Error(), unknownField), `"`)
+		return &DecodeError{Field: field, Offset: keyOffset(body, field), Reason: "unknown field"}
+	}
+	return &DecodeError{Offset: decoder.InputOffset(), Reason: err.Error()}
+}
+
+// keyOffset returns the offset right after the first object key named field in body, -1 if not found
+func keyOffset(body []byte, field string) int64 {
+	// frame is an object or array being decoded, expectKey tells if an object expects a key or a value
+	type frame struct {
+		object    bool
+		expectKey bool
+	}
+	var stack []*frame
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return -1
+		}
+		var top *frame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+
+		switch {
+		case tok == json.Delim('}') || tok == json.Delim(']'):
+			stack = stack[:len(stack)-1]
+		case top != nil && top.object && top.expectKey:
+			if tok == field {
+				return decoder.InputOffset()
+			}
+			top.expectKey = false
+		default:
+			// a value: the enclosing object expects a key next
+			if top != nil && top.object {
+				top.expectKey = true
+			}
+			if tok == json.Delim('{') {
+				stack = append(stack, &frame{object: true, expectKey: true})
+			} else if tok == json.Delim('[') {
+				stack = append(stack, &frame{})
+			}
+		}
+	}
+}
+
+// ReadAll reads the whole request body
+func ReadAll(r *http.Request) (body []byte, err error) {
+	body, err = io.ReadAll(r.Body)
+	if maxBytesErr := new(http.MaxBytesError); errors.As(err, &maxBytesErr) {
+		err = fmt.Errorf("%w. %v", ErrRequestBodyTooLarge, err)
+	}
+	return
+}
+
+// Options returns the options of the decoding of the json bodies of a handler, strict if it rejects the
+// unknown fields.
+func Options(strict bool) []Option {
+	if strict {
+		return []Option{Strict()}
+	}
+	return nil
+}
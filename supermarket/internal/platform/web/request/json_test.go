@@ -0,0 +1,153 @@
+package request_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"supermarket/internal/platform/web/request"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type product struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+// newRequest returns a request with a json body of the given content type
+func newRequest(contentType, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	return req
+}
+
+// TestJSON tests the JSON function.
+func TestJSON(t *testing.T) {
+	t.Run("success - charset parameter", func(t *testing.T) {
+		// arrange
+		req := newRequest("application/json; charset=UTF-8", `{"name":"milk","price":1.5}`)
+		var p product
+
+		// act
+		err := request.JSON(req, &p)
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, product{Name: "milk", Price: 1.5}, p)
+	})
+
+	t.Run("success - unknown fields and trailing data ignored when not strict", func(t *testing.T) {
+		// arrange
+		req := newRequest("application/json", `{"name":"milk","colour":"white"} garbage`)
+		var p product
+
+		// act
+		err := request.JSON(req, &p)
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, "milk", p.Name)
+	})
+
+	t.Run("error - content type", func(t *testing.T) {
+		for _, contentType := range []string{"", "text/plain", "application/json; charset=latin1", "application/jsonp"} {
+			// arrange
+			req := newRequest(contentType, `{}`)
+			var p product
+
+			// act
+			err := request.JSON(req, &p)
+
+			// assert
+			require.ErrorIs(t, err, request.ErrRequestContentTypeNotJSON, contentType)
+		}
+	})
+
+	t.Run("error - wrong type reports field and offset", func(t *testing.T) {
+		// arrange
+		req := newRequest("application/json", `{"name":"milk","price":"cheap"}`)
+		var p product
+
+		// act
+		err := request.JSON(req, &p)
+
+		// assert
+		require.ErrorIs(t, err, request.ErrRequestJSONInvalid)
+		var decodeErr *request.DecodeError
+		require.True(t, errors.As(err, &decodeErr))
+		require.Equal(t, "price", decodeErr.Field)
+		require.Equal(t, int64(30), decodeErr.Offset)
+	})
+
+	t.Run("error - syntax reports offset", func(t *testing.T) {
+		// arrange
+		req := newRequest("application/json", `{"name":"milk",}`)
+		var p product
+
+		// act
+		err := request.JSON(req, &p)
+
+		// assert
+		var decodeErr *request.DecodeError
+		require.True(t, errors.As(err, &decodeErr))
+		require.Equal(t, int64(16), decodeErr.Offset)
+	})
+
+	t.Run("error - strict unknown field", func(t *testing.T) {
+		// arrange
+		req := newRequest("application/json", `{"name":"milk","tags":[{"colour":"white"}],"colour":"white"}`)
+		var p product
+
+		// act
+		err := request.JSON(req, &p, request.Strict())
+
+		// assert
+		var decodeErr *request.DecodeError
+		require.True(t, errors.As(err, &decodeErr))
+		require.Equal(t, "tags", decodeErr.Field)
+		require.Equal(t, int64(21), decodeErr.Offset)
+	})
+
+	t.Run("error - strict trailing data", func(t *testing.T) {
+		// arrange
+		req := newRequest("application/json", `{"name":"milk"} {"name":"bread"}`)
+		var p product
+
+		// act
+		err := request.JSON(req, &p, request.Strict())
+
+		// assert
+		var decodeErr *request.DecodeError
+		require.True(t, errors.As(err, &decodeErr))
+		require.Equal(t, "request json invalid: at offset 15: body must contain a single json document", err.Error())
+	})
+
+	t.Run("error - body too large", func(t *testing.T) {
+		// arrange
+		req := newRequest("application/json", `{"name":"`+strings.Repeat("a", 100)+`"}`)
+		var p product
+
+		// act
+		err := request.JSON(req, &p, request.MaxBytes(64))
+
+		// assert
+		require.ErrorIs(t, err, request.ErrRequestBodyTooLarge)
+	})
+
+	t.Run("success - options of a strict handler reject the unknown fields", func(t *testing.T) {
+		// arrange
+		lenient := newRequest("application/json", `{"name":"milk","color":"white"}`)
+		strict := newRequest("application/json", `{"name":"milk","color":"white"}`)
+		var p product
+
+		// act
+		errLenient := request.JSON(lenient, &p, request.Options(false)...)
+		errStrict := request.JSON(strict, &p, request.Options(true)...)
+
+		// assert
+		require.NoError(t, errLenient)
+		require.ErrorIs(t, errStrict, request.ErrRequestJSONInvalid)
+	})
+}
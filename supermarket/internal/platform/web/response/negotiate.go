@@ -0,0 +1,190 @@
+package response
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MediaTypeJSON is the default media type of the responses
+	MediaTypeJSON = "application/json"
+	// MediaTypeXML renders the response envelope as xml
+	MediaTypeXML = "application/xml"
+	// MediaTypeNDJSON renders one json document per line, one per element of a list
+	MediaTypeNDJSON = "application/x-ndjson"
+	// MediaTypeCSV renders the data as a table, for data implementing CSVMarshaler
+	MediaTypeCSV = "text/csv"
+)
+
+// ErrNotAcceptable is returned when none of the offered media types is accepted by the client.
+var ErrNotAcceptable = errors.New("response: not acceptable")
+
+// CSVMarshaler is implemented by the data that can be rendered as csv.
+type CSVMarshaler interface {
+	// MarshalCSV returns the records of the table, the first one being the header
+	MarshalCSV() [][]string
+}
+
+// Negotiate returns the offer preferred by the Accept header of the request, the first offer winning ties.
+// A request without Accept header accepts the first offer.
+func Negotiate(r *http.Request, offers ...string) (string, error) {
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		if len(offers) == 0 {
+			return "", ErrNotAcceptable
+		}
+		return offers[0], nil
+	}
+
+	// - parse the accepted ranges
+	type mediaRange struct {
+		value string
+		q     float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		value, q := parseMediaRange(part)
+		if value != "" {
+			ranges = append(ranges, mediaRange{value: value, q: q})
+		}
+	}
+
+	// - pick the offer with the highest quality, the most specific range of an offer setting its quality
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, specificity := 0.0, -1
+		for _, mr := range ranges {
+			s := matchMediaRange(mr.value, offer)
+			if s > specificity {
+				q, specificity = mr.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	if best == "" {
+		return "", ErrNotAcceptable
+	}
+	return best, nil
+}
+
+// parseMediaRange splits an element of the Accept header into its media range and q parameter
+func parseMediaRange(part string) (value string, q float64) {
+	q = 1
+	params := strings.Split(part, ";")
+	value = strings.ToLower(strings.TrimSpace(params[0]))
+	for _, param := range params[1:] {
+		k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || strings.ToLower(strings.TrimSpace(k)) != "q" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			return value, 0
+		}
+		q = parsed
+	}
+	return value, q
+}
+
+// matchMediaRange returns how specifically mediaRange matches mediaType: 2 exactly, 1 by type/*, 0 by */*,
+// and -1 if it does not match
+func matchMediaRange(mediaRange, mediaType string) int {
+	switch {
+	case mediaRange == mediaType:
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+		return 1
+	}
+	return -1
+}
+
+// Negotiated writes the response in the media type preferred by the client among json, xml, ndjson,
+// and csv when data implements CSVMarshaler. It responds 406 Not Acceptable if none is accepted.
+func Negotiated(w http.ResponseWriter, r *http.Request, statusCode int, message string, data any) {
+	offers := []string{MediaTypeJSON, MediaTypeXML, MediaTypeNDJSON}
+	marshaler, isCSV := data.(CSVMarshaler)
+	if isCSV {
+		offers = append(offers, MediaTypeCSV)
+	}
+
+	// responses depend on the Accept header
+	w.Header().Add("Vary", "Accept")
+	mediaType, err := Negotiate(r, offers...)
+	if err != nil {
+		Error(w, http.StatusNotAcceptable, "not acceptable")
+		return
+	}
+
+	switch mediaType {
+	case MediaTypeXML:
+		XML(w, statusCode, message, data)
+	case MediaTypeNDJSON:
+		NDJSON(w, statusCode, data)
+	case MediaTypeCSV:
+		CSV(w, statusCode, marshaler)
+	default:
+		JSON(w, statusCode, message, data)
+	}
+}
+
+// XML writes the response envelope as xml, the elements of a list data being item elements
+func XML(w http.ResponseWriter, statusCode int, message string, data any) {
+	w.Header().Set("Content-Type", MediaTypeXML+"; charset=utf-8")
+	w.WriteHeader(statusCode)
+	w.Write([]byte(xml.Header))
+
+	enc := xml.NewEncoder(w)
+	response := xml.StartElement{Name: xml.Name{Local: "response"}}
+	enc.EncodeToken(response)
+	enc.EncodeElement(message, xml.StartElement{Name: xml.Name{Local: "message"}})
+	if data != nil {
+		dataElement := xml.StartElement{Name: xml.Name{Local: "data"}}
+		if v := reflect.Indirect(reflect.ValueOf(data)); v.Kind() == reflect.Slice {
+			enc.EncodeToken(dataElement)
+			for i := 0; i < v.Len(); i++ {
+				enc.EncodeElement(v.Index(i).Interface(), xml.StartElement{Name: xml.Name{Local: "item"}})
+			}
+			enc.EncodeToken(dataElement.End())
+		} else {
+			enc.EncodeElement(data, dataElement)
+		}
+	}
+	enc.EncodeToken(response.End())
+	enc.Flush()
+}
+
+// NDJSON writes one json document per line, one per element of a list data or data itself otherwise
+func NDJSON(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", MediaTypeNDJSON)
+	w.WriteHeader(statusCode)
+
+	enc := json.NewEncoder(w)
+	if v := reflect.Indirect(reflect.ValueOf(data)); v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			enc.Encode(v.Index(i).Interface())
+		}
+		return
+	}
+	if data != nil {
+		enc.Encode(data)
+	}
+}
+
+// CSV writes the records of data as csv, with a header line
+func CSV(w http.ResponseWriter, statusCode int, data CSVMarshaler) {
+	w.Header().Set("Content-Type", MediaTypeCSV+"; charset=utf-8; header=present")
+	w.WriteHeader(statusCode)
+
+	cw := csv.NewWriter(w)
+	cw.WriteAll(data.MarshalCSV())
+}
@@ -0,0 +1,22 @@
+package response
+
+import (
+	"errors"
+	"net/http"
+	"supermarket/internal/platform/web/request"
+)
+
+// RequestError responds the error of reading a json body with request.JSON: 413 for a body too large, 415
+// for a content type other than json, 400 otherwise.
+func RequestError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, request.ErrRequestBodyTooLarge):
+		Error(w, http.StatusRequestEntityTooLarge, "request body too large")
+	case errors.Is(err, request.ErrRequestContentTypeNotJSON):
+		Errorw(w, http.StatusUnsupportedMediaType, err)
+	case errors.Is(err, request.ErrRequestJSONInvalid):
+		Errorw(w, http.StatusBadRequest, err)
+	default:
+		Error(w, http.StatusBadRequest, "bad request")
+	}
+}
@@ -0,0 +1,37 @@
+package response_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"supermarket/internal/platform/web/request"
+	"supermarket/internal/platform/web/response"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRequestError tests the responses of the errors of reading a json body.
+func TestRequestError(t *testing.T) {
+	t.Run("success - status by error", func(t *testing.T) {
+		// arrange
+		cases := map[error]int{
+			fmt.Errorf("%w. limit", request.ErrRequestBodyTooLarge): http.StatusRequestEntityTooLarge,
+			request.ErrRequestContentTypeNotJSON:                    http.StatusUnsupportedMediaType,
+			&request.DecodeError{Reason: "body is empty"}:           http.StatusBadRequest,
+			errors.New("connection reset"):                          http.StatusBadRequest,
+		}
+
+		for err, status := range cases {
+			rr := httptest.NewRecorder()
+
+			// act
+			response.RequestError(rr, err)
+
+			// assert
+			require.Equal(t, status, rr.Code, err.Error())
+			require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		}
+	})
+}
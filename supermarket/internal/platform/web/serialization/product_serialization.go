@@ -1,6 +1,10 @@
 package serialization
 
-import internalProduct "supermarket/internal/product"
+import (
+	"strconv"
+	"supermarket/internal/platform/web/validator"
+	internalProduct "supermarket/internal/product"
+)
 
 type Product = internalProduct.Product
 type ConsumerPriceProducts = internalProduct.ConsumerPriceProducts
@@ -14,19 +18,51 @@ type ProductRequest struct {
 	Price       float64 `json:"price"`
 }
 
+// ProductReplaceRequest is the body of a request replacing a product, the pointers telling the missing keys
+// apart from the zero values
+type ProductReplaceRequest struct {
+	Name        *string  `json:"name"`
+	Quantity    *int     `json:"quantity"`
+	CodeValue   *string  `json:"code_value"`
+	IsPublished bool     `json:"is_published"`
+	Expiration  *string  `json:"expiration"`
+	Price       *float64 `json:"price"`
+}
+
 type ProductResponse struct {
-	Id          int     `json:"id"`
-	Name        string  `json:"name"`
-	Quantity    int     `json:"quantity"`
-	CodeValue   string  `json:"code_value"`
-	IsPublished bool    `json:"is_published"`
-	Expiration  string  `json:"expiration"`
-	Price       float64 `json:"price"`
+	Id          int     `json:"id" xml:"id"`
+	Name        string  `json:"name" xml:"name"`
+	Quantity    int     `json:"quantity" xml:"quantity"`
+	CodeValue   string  `json:"code_value" xml:"code_value"`
+	IsPublished bool    `json:"is_published" xml:"is_published"`
+	Expiration  string  `json:"expiration" xml:"expiration"`
+	Price       float64 `json:"price" xml:"price"`
+}
+
+// ProductsResponse is a list of products, that can be rendered as csv
+type ProductsResponse []ProductResponse
+
+// MarshalCSV returns a header record and a record per product.
+func (p ProductsResponse) MarshalCSV() [][]string {
+	records := make([][]string, 0, len(p)+1)
+	records = append(records, []string{"id", "name", "quantity", "code_value", "is_published", "expiration", "price"})
+	for _, product := range p {
+		records = append(records, []string{
+			strconv.Itoa(product.Id),
+			product.Name,
+			strconv.Itoa(product.Quantity),
+			product.CodeValue,
+			strconv.FormatBool(product.IsPublished),
+			product.Expiration,
+			strconv.FormatFloat(product.Price, 'f', -1, 64),
+		})
+	}
+	return records
 }
 
 type ConsumerPriceProductsResponse struct {
-	ProductsResponse []ProductResponse `json:"products"`
-	TotalPrice       float64           `json:"total_price"`
+	ProductsResponse []ProductResponse `json:"products" xml:"products>product"`
+	TotalPrice       float64           `json:"total_price" xml:"total_price"`
 }
 
 func ProductRequestToProduct(productRequest ProductRequest) Product {
@@ -40,6 +76,23 @@ func ProductRequestToProduct(productRequest ProductRequest) Product {
 	}
 }
 
+// ProductReplaceRequestToProduct returns the product of productRequest, or validator.ErrKeyNotFound if one of
+// its keys but is_published is missing.
+func ProductReplaceRequestToProduct(productRequest ProductReplaceRequest) (Product, error) {
+	if productRequest.Name == nil || productRequest.Quantity == nil || productRequest.CodeValue == nil ||
+		productRequest.Expiration == nil || productRequest.Price == nil {
+		return Product{}, validator.ErrKeyNotFound
+	}
+	return Product{
+		Name:        *productRequest.Name,
+		Quantity:    *productRequest.Quantity,
+		CodeValue:   *productRequest.CodeValue,
+		IsPublished: productRequest.IsPublished,
+		Expiration:  *productRequest.Expiration,
+		Price:       *productRequest.Price,
+	}, nil
+}
+
 func ProductToProductRequest(product Product) ProductRequest {
 	return ProductRequest{
 		Name:        product.Name,
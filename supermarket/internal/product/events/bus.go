@@ -0,0 +1,224 @@
+// Package events distributes the events of the catalogue to their subscribers.
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"supermarket/internal/platform/logging"
+	internalProduct "supermarket/internal/product"
+	"sync"
+	"time"
+)
+
+type Event = internalProduct.Event
+
+var (
+	// ErrBusClosed is returned when subscribing to a closed bus, and by the subscriptions it closed.
+	ErrBusClosed = errors.New("events: bus closed")
+	// ErrSubscriptionLagged is returned by the subscriptions dropped for not keeping up with the events.
+	ErrSubscriptionLagged = errors.New("events: subscription lagged behind")
+)
+
+const (
+	// defaultLogSize is the number of events kept by the buses without size
+	defaultLogSize = 1024
+	// subscriptionBuffer is the number of events a subscription holds before being dropped
+	subscriptionBuffer = 64
+)
+
+// NewBus returns a bus keeping the last size events, for the subscribers to resume from them.
+func NewBus(size int) *Bus {
+	if size <= 0 {
+		size = defaultLogSize
+	}
+	return &Bus{
+		size:        size,
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		subscribers: make(map[*Subscription]struct{}),
+		now:         time.Now,
+	}
+}
+
+// Bus publishes the events to its subscribers, keeping the last ones in a bounded log.
+// The ids of the events are "<epoch>-<sequence>", the epoch telling the ids of a previous run of the server.
+type Bus struct {
+	// mu guards the fields below
+	mu sync.Mutex
+	// size is the maximum number of events of the log
+	size int
+	// log are the last events, oldest first
+	log []entry
+	// epoch identifies the bus among the runs of the server
+	epoch string
+	// seq is the sequence of the last event published
+	seq uint64
+	// subscribers are the open subscriptions
+	subscribers map[*Subscription]struct{}
+	// handlers are called with every event, after the lock is released and before Publish returns
+	handlers []Handler
+	// closed tells if the bus is closed
+	closed bool
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// Handler handles the events of a bus synchronously, e.g. to save them in an outbox. Its error is logged,
+// as the write of the event is done already.
+type Handler func(ctx context.Context, event Event) error
+
+// Handle registers handler, called in order with every event once its id is set, before Publish returns.
+// The handlers are called without the lock of the bus, so the events of concurrent writes may be handled
+// concurrently, and a handler may publish.
+func (b *Bus) Handle(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// entry is an event of the log with its sequence
+type entry struct {
+	seq   uint64
+	event Event
+}
+
+// Publish sets the id and time of event, appends it to the log, sends it to the subscribers and calls the
+// handlers, logging their errors. Subscribers whose buffer is full are dropped rather than blocking the publisher.
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	b.mu.Lock()
+	b.seq++
+	event.ID = b.epoch + "-" + strconv.FormatUint(b.seq, 10)
+	if event.Time.IsZero() {
+		event.Time = b.now()
+	}
+	if len(b.log) == b.size {
+		b.log = b.log[1:]
+	}
+	b.log = append(b.log, entry{seq: b.seq, event: event})
+
+	for sub := range b.subscribers {
+		select {
+		case sub.ch <- event:
+		default:
+			b.drop(sub, ErrSubscriptionLagged)
+		}
+	}
+	handlers := append([]Handler(nil), b.handlers...)
+	b.mu.Unlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			logging.FromContext(ctx).Error("event handler",
+				slog.String("event_id", event.ID),
+				slog.String("event_type", event.Type),
+				slog.Any("error", err),
+			)
+		}
+	}
+}
+
+// Subscribe returns a subscription to the events published from now on. With a lastEventID, the events
+// of the log following it are replayed first. When they are not all in the log anymore, e.g. the id is
+// too old or of a previous run, the subscription has a gap and replays the whole log.
+func (b *Bus) Subscribe(lastEventID string) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrBusClosed
+	}
+
+	sub := &Subscription{bus: b, ch: make(chan Event, subscriptionBuffer)}
+	if lastEventID != "" {
+		sub.Replay, sub.Gap = b.since(lastEventID)
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+// since returns the events of the log after lastEventID, and whether events are missing in between
+func (b *Bus) since(lastEventID string) ([]Event, bool) {
+	seq, err := b.parseID(lastEventID)
+	gap := err != nil || seq > b.seq
+	if !gap && len(b.log) > 0 {
+		gap = seq+1 < b.log[0].seq
+	}
+	if !gap && len(b.log) == 0 {
+		gap = seq < b.seq
+	}
+
+	var replay []Event
+	for _, e := range b.log {
+		if gap || e.seq > seq {
+			replay = append(replay, e.event)
+		}
+	}
+	return replay, gap
+}
+
+// parseID returns the sequence of id, an error if it is not an id of this bus
+func (b *Bus) parseID(id string) (uint64, error) {
+	epoch, seq, ok := strings.Cut(id, "-")
+	if !ok || epoch != b.epoch {
+		return 0, fmt.Errorf("events: id %q of another bus", id)
+	}
+	return strconv.ParseUint(seq, 10, 64)
+}
+
+// Close closes the subscriptions and rejects the new ones, e.g. for the streams to end before the server
+// drains. The events published afterwards are still handled.
+func (b *Bus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	for sub := range b.subscribers {
+		b.drop(sub, ErrBusClosed)
+	}
+	return nil
+}
+
+// drop closes sub with err, the lock being held
+func (b *Bus) drop(sub *Subscription, err error) {
+	delete(b.subscribers, sub)
+	sub.err = err
+	close(sub.ch)
+}
+
+// Subscription receives the events of a bus.
+type Subscription struct {
+	// Replay are the events of the log following the last event id of the subscription
+	Replay []Event
+	// Gap tells if events were missed since the last event id, e.g. for the subscriber to reload the catalogue
+	Gap bool
+
+	bus *Bus
+	ch  chan Event
+	// err is why the subscription was closed by the bus, guarded by the lock of the bus
+	err error
+}
+
+// Events returns the channel of the events published since the subscription, closed with the subscription.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Err returns why the bus closed the subscription, once the channel of the events is closed.
+func (s *Subscription) Err() error {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return s.err
+}
+
+// Close unsubscribes from the bus.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	if _, ok := s.bus.subscribers[s]; ok {
+		s.bus.drop(s, nil)
+	}
+}
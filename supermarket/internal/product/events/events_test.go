@@ -0,0 +1,248 @@
+package events_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"supermarket/internal/platform/logging"
+	internalProduct "supermarket/internal/product"
+	"supermarket/internal/product/events"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// publish publishes n events of type updated to bus, returning their ids
+func publish(bus *events.Bus, n int) []string {
+	sub, _ := bus.Subscribe("")
+	defer sub.Close()
+	ids := make([]string, n)
+	for i := range ids {
+		bus.Publish(context.Background(), internalProduct.Event{Type: internalProduct.EventProductUpdated, Product: internalProduct.Product{Id: i + 1}})
+		ids[i] = (<-sub.Events()).ID
+	}
+	return ids
+}
+
+// TestBus tests the publication and replay of the events.
+func TestBus(t *testing.T) {
+	t.Run("success - subscribers receive the events with ids and time", func(t *testing.T) {
+		// arrange
+		bus := events.NewBus(10)
+		sub, err := bus.Subscribe("")
+		require.NoError(t, err)
+		defer sub.Close()
+
+		// act
+		bus.Publish(context.Background(), internalProduct.Event{Type: internalProduct.EventProductCreated, Product: internalProduct.Product{Id: 1}})
+
+		// assert
+		event := <-sub.Events()
+		require.Equal(t, internalProduct.EventProductCreated, event.Type)
+		require.NotEmpty(t, event.ID)
+		require.False(t, event.Time.IsZero())
+		require.Empty(t, sub.Replay)
+		require.False(t, sub.Gap)
+	})
+
+	t.Run("success - resume after the last event id", func(t *testing.T) {
+		// arrange
+		bus := events.NewBus(10)
+		ids := publish(bus, 3)
+
+		// act
+		sub, err := bus.Subscribe(ids[0])
+		require.NoError(t, err)
+		defer sub.Close()
+
+		// assert
+		require.False(t, sub.Gap)
+		require.Len(t, sub.Replay, 2)
+		require.Equal(t, ids[1], sub.Replay[0].ID)
+		require.Equal(t, ids[2], sub.Replay[1].ID)
+	})
+
+	t.Run("success - gap when the events left the log", func(t *testing.T) {
+		// arrange
+		bus := events.NewBus(2)
+		ids := publish(bus, 4)
+
+		// act
+		sub, err := bus.Subscribe(ids[0])
+		require.NoError(t, err)
+		defer sub.Close()
+
+		// assert
+		require.True(t, sub.Gap)
+		require.Len(t, sub.Replay, 2)
+		require.Equal(t, ids[2], sub.Replay[0].ID)
+	})
+
+	t.Run("success - gap for an id of another run", func(t *testing.T) {
+		// arrange
+		bus := events.NewBus(10)
+		publish(bus, 1)
+
+		// act
+		sub, err := bus.Subscribe("previousrun-1")
+		require.NoError(t, err)
+		defer sub.Close()
+
+		// assert
+		require.True(t, sub.Gap)
+		require.Len(t, sub.Replay, 1)
+	})
+
+	t.Run("failure - lagging subscriber dropped", func(t *testing.T) {
+		// arrange
+		bus := events.NewBus(10)
+		sub, err := bus.Subscribe("")
+		require.NoError(t, err)
+
+		// act: more events than the buffer of the subscription, never received
+		for i := 0; i < 100; i++ {
+			bus.Publish(context.Background(), internalProduct.Event{Type: internalProduct.EventProductUpdated})
+		}
+
+		// assert
+		for range sub.Events() {
+		}
+		require.ErrorIs(t, sub.Err(), events.ErrSubscriptionLagged)
+	})
+
+	t.Run("failure - closed bus", func(t *testing.T) {
+		// arrange
+		bus := events.NewBus(10)
+		sub, err := bus.Subscribe("")
+		require.NoError(t, err)
+
+		// act
+		require.NoError(t, bus.Close(context.Background()))
+		_, errSubscribe := bus.Subscribe("")
+
+		// assert
+		_, open := <-sub.Events()
+		require.False(t, open)
+		require.ErrorIs(t, sub.Err(), events.ErrBusClosed)
+		require.ErrorIs(t, errSubscribe, events.ErrBusClosed)
+	})
+	t.Run("success - handlers called outside the lock, their errors logged", func(t *testing.T) {
+		// arrange
+		bus := events.NewBus(10)
+		var handled []string
+		bus.Handle(func(ctx context.Context, event internalProduct.Event) error {
+			handled = append(handled, event.Type)
+			if event.Type == internalProduct.EventProductDeleted {
+				// a handler may publish, e.g. a follow-up event
+				bus.Publish(ctx, internalProduct.Event{Type: internalProduct.EventProductUpdated})
+			}
+			return nil
+		})
+		bus.Handle(func(ctx context.Context, event internalProduct.Event) error {
+			return errors.New("outbox unavailable")
+		})
+		var logs bytes.Buffer
+		ctx := logging.WithLogger(context.Background(), slog.New(slog.NewTextHandler(&logs, nil)))
+
+		// act
+		bus.Publish(ctx, internalProduct.Event{Type: internalProduct.EventProductDeleted})
+
+		// assert
+		require.Equal(t, []string{internalProduct.EventProductDeleted, internalProduct.EventProductUpdated}, handled)
+		require.Equal(t, 2, strings.Count(logs.String(), "outbox unavailable"))
+		require.Contains(t, logs.String(), "event_type="+internalProduct.EventProductDeleted)
+	})
+}
+
+// TestStreamHandler tests the server-sent events stream.
+func TestStreamHandler(t *testing.T) {
+	// readEvent reads the next event of the stream, skipping the comments
+	readEvent := func(t *testing.T, r *bufio.Reader) map[string]string {
+		t.Helper()
+		fields := make(map[string]string)
+		for {
+			line, err := r.ReadString('\n')
+			require.NoError(t, err)
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" {
+				if len(fields) > 0 {
+					return fields
+				}
+				continue
+			}
+			if strings.HasPrefix(line, ":") {
+				continue
+			}
+			k, v, _ := strings.Cut(line, ": ")
+			fields[k] = v
+		}
+	}
+
+	t.Run("success - replay then live events", func(t *testing.T) {
+		// arrange
+		bus := events.NewBus(10)
+		ids := publish(bus, 2)
+		server := httptest.NewServer(events.NewStreamHandler(bus, time.Second))
+		defer server.Close()
+		defer bus.Close(context.Background())
+
+		// act
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Last-Event-ID", ids[0])
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		r := bufio.NewReader(res.Body)
+		replayed := readEvent(t, r)
+		bus.Publish(context.Background(), internalProduct.Event{Type: internalProduct.EventProductDeleted, Product: internalProduct.Product{Id: 7}})
+		live := readEvent(t, r)
+
+		// assert
+		require.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+		require.Equal(t, ids[1], replayed["id"])
+		require.Equal(t, internalProduct.EventProductUpdated, replayed["event"])
+		require.Equal(t, internalProduct.EventProductDeleted, live["event"])
+		require.Contains(t, live["data"], `"product":{"id":7,`)
+	})
+
+	t.Run("success - reset before the replay of a gap", func(t *testing.T) {
+		// arrange
+		bus := events.NewBus(10)
+		publish(bus, 1)
+		server := httptest.NewServer(events.NewStreamHandler(bus, time.Second))
+		defer server.Close()
+		defer bus.Close(context.Background())
+
+		// act
+		res, err := http.Get(server.URL + "?lastEventId=previousrun-5")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		r := bufio.NewReader(res.Body)
+		reset := readEvent(t, r)
+		replayed := readEvent(t, r)
+
+		// assert
+		require.Equal(t, events.EventReset, reset["event"])
+		require.Equal(t, internalProduct.EventProductUpdated, replayed["event"])
+	})
+
+	t.Run("failure - closed bus", func(t *testing.T) {
+		// arrange
+		bus := events.NewBus(10)
+		require.NoError(t, bus.Close(context.Background()))
+		rr := httptest.NewRecorder()
+
+		// act
+		events.NewStreamHandler(bus, time.Second).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/products/events", nil))
+
+		// assert
+		require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	})
+}
@@ -0,0 +1,111 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"supermarket/internal/platform/logging"
+	"supermarket/internal/platform/web/response"
+	"time"
+)
+
+const (
+	// EventReset is sent, before the replayed events, when events were missed since the Last-Event-ID
+	// of the request: the client reloads the catalogue rather than applying the events
+	EventReset = "reset"
+
+	// defaultHeartbeat is the interval of the heartbeats of the streams without interval
+	defaultHeartbeat = 15 * time.Second
+)
+
+// NewStreamHandler returns a StreamHandler of the events of bus, sending a heartbeat every heartbeat.
+func NewStreamHandler(bus *Bus, heartbeat time.Duration) *StreamHandler {
+	if heartbeat <= 0 {
+		heartbeat = defaultHeartbeat
+	}
+	return &StreamHandler{bus: bus, heartbeat: heartbeat}
+}
+
+// StreamHandler streams the events of a bus as server-sent events, resuming after the Last-Event-ID header
+// or the lastEventId query parameter.
+type StreamHandler struct {
+	bus *Bus
+	// heartbeat is the interval of the comments keeping the idle connections open through the proxies
+	heartbeat time.Duration
+}
+
+// ServeHTTP streams the events until the client is gone or the bus is closed.
+func (h *StreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	sub, err := h.bus.Subscribe(lastEventID)
+	if err != nil {
+		response.Error(w, http.StatusServiceUnavailable, "server shutting down")
+		return
+	}
+	defer sub.Close()
+
+	// the stream outlives the write timeout of the server
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		logging.FromContext(r.Context()).Warn("events stream deadline", slog.Any("error", err))
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// replay
+	if sub.Gap {
+		fmt.Fprintf(w, "event: %s\ndata: {}\n\n", EventReset)
+	}
+	for _, event := range sub.Replay {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	// live
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				// lagged subscribers reconnect and resume from the log
+				return
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes event as a server-sent event, its data being the event as json
+func writeEvent(w io.Writer, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
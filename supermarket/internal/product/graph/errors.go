@@ -0,0 +1,32 @@
+package graph
+
+// Codes of the errors, in the extensions of the errors of the responses
+const (
+	CodeBadUserInput    = "BAD_USER_INPUT"
+	CodeNotFound        = "NOT_FOUND"
+	CodeConflict        = "CONFLICT"
+	CodeTimeout         = "TIMEOUT"
+	CodeInternal        = "INTERNAL"
+	CodeUnauthenticated = "UNAUTHENTICATED"
+	CodeInvalidQuery    = "GRAPHQL_VALIDATION_FAILED"
+	CodeQueryTooDeep    = "QUERY_TOO_DEEP"
+	CodeQueryTooComplex = "QUERY_TOO_COMPLEX"
+)
+
+// Error is an error of a response, with a code in its extensions.
+type Error struct {
+	// Code classifies the error
+	Code string
+	// Message describes the error
+	Message string
+}
+
+// Error returns the message.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Extensions returns the code, as the graphql library expects to extend the errors of the responses.
+func (e *Error) Extensions() map[string]any {
+	return map[string]any{"code": e.Code}
+}
@@ -0,0 +1,364 @@
+package graph_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"supermarket/internal/auth"
+	internalProduct "supermarket/internal/product"
+	"supermarket/internal/product/graph"
+	"supermarket/internal/product/service"
+	"testing"
+
+	"github.com/graphql-go/graphql/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type ProductServiceMock = service.ProductServiceMock
+
+// result is a response of the handler
+type result struct {
+	Data   map[string]any `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+// newHandler returns a handler over productService, authenticating with the token "secret"
+func newHandler(t *testing.T, productService *ProductServiceMock, limits graph.Limits) *graph.Handler {
+	t.Helper()
+	h, err := graph.NewHandler(productService, auth.NewAuthTokenBasic("secret"), limits)
+	require.NoError(t, err)
+	return h
+}
+
+// post sends query with variables by POST, with token if not empty
+func post(t *testing.T, h http.Handler, token, query string, variables map[string]any) (int, result) {
+	t.Helper()
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Token", token)
+	}
+	return serve(t, h, req)
+}
+
+// serve sends req to h and decodes the result
+func serve(t *testing.T, h http.Handler, req *http.Request) (int, result) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	var res result
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res), rr.Body.String())
+	return rr.Code, res
+}
+
+// TestHandler_Queries tests the queries of the schema.
+func TestHandler_Queries(t *testing.T) {
+	products := []internalProduct.Product{
+		{Id: 3, Name: "Cheese", Quantity: 5, CodeValue: "c3", IsPublished: true, Expiration: "12/31/2030", Price: 300},
+		{Id: 1, Name: "Milk", Quantity: 10, CodeValue: "c1", IsPublished: true, Expiration: "12/31/2030", Price: 100},
+		{Id: 2, Name: "Goat cheese", Quantity: 20, CodeValue: "c2", Expiration: "12/31/2030", Price: 200},
+	}
+
+	t.Run("success - product by id", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		productService.On("GetProduct", mock.Anything, "1").Return(products[1], nil)
+		h := newHandler(t, productService, graph.Limits{})
+
+		// act
+		req := httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape("{ product(id: 1) { id name codeValue isPublished price } }"), nil)
+		code, res := serve(t, h, req)
+
+		// assert
+		require.Equal(t, http.StatusOK, code)
+		require.Empty(t, res.Errors)
+		require.Equal(t, map[string]any{
+			"product": map[string]any{"id": 1.0, "name": "Milk", "codeValue": "c1", "isPublished": true, "price": 100.0},
+		}, res.Data)
+	})
+
+	t.Run("success - missing product is null", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		productService.On("GetProduct", mock.Anything, "9").Return(internalProduct.Product{}, internalProduct.ErrProductNotFound)
+		h := newHandler(t, productService, graph.Limits{})
+
+		// act
+		code, res := post(t, h, "", "{ product(id: 9) { id } }", nil)
+
+		// assert
+		require.Equal(t, http.StatusOK, code)
+		require.Empty(t, res.Errors)
+		require.Equal(t, map[string]any{"product": nil}, res.Data)
+	})
+
+	t.Run("success - filtered page ordered by id", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		productService.On("GetProducts", mock.Anything).Return(products, nil)
+		h := newHandler(t, productService, graph.Limits{})
+
+		// act
+		query := `query($limit: Int) { products(filter: {nameContains: "CHEESE"}, limit: $limit) { items { id } totalCount hasMore } }`
+		code, res := post(t, h, "", query, map[string]any{"limit": 1})
+
+		// assert
+		require.Equal(t, http.StatusOK, code)
+		require.Empty(t, res.Errors)
+		require.Equal(t, map[string]any{
+			"products": map[string]any{"items": []any{map[string]any{"id": 2.0}}, "totalCount": 2.0, "hasMore": true},
+		}, res.Data)
+	})
+
+	t.Run("success - consumer price", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		productService.On("GetConsumerPriceProducts", mock.Anything, []string{"1", "1"}).
+			Return(internalProduct.ConsumerPriceProducts{Products: products[1:2], TotalPrice: 242}, nil)
+		h := newHandler(t, productService, graph.Limits{})
+
+		// act
+		code, res := post(t, h, "", "{ consumerPrice(ids: [1, 1]) { products { name } totalPrice } }", nil)
+
+		// assert
+		require.Equal(t, http.StatusOK, code)
+		require.Empty(t, res.Errors)
+		require.Equal(t, map[string]any{
+			"consumerPrice": map[string]any{"products": []any{map[string]any{"name": "Milk"}}, "totalPrice": 242.0},
+		}, res.Data)
+	})
+
+	t.Run("failure - domain error with code", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		productService.On("GetConsumerPriceProducts", mock.Anything, []string{"1"}).
+			Return(internalProduct.ConsumerPriceProducts{}, internalProduct.ErrInsufficientQuantity)
+		h := newHandler(t, productService, graph.Limits{})
+
+		// act
+		code, res := post(t, h, "", "{ consumerPrice(ids: [1]) { totalPrice } }", nil)
+
+		// assert
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, res.Errors, 1)
+		require.Equal(t, graph.CodeConflict, res.Errors[0].Extensions["code"])
+	})
+
+	t.Run("failure - invalid query", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		h := newHandler(t, productService, graph.Limits{})
+
+		// act
+		code, res := post(t, h, "", "{ product(id: 1) { unknown } }", nil)
+
+		// assert
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Len(t, res.Errors, 1)
+		require.Equal(t, graph.CodeInvalidQuery, res.Errors[0].Extensions["code"])
+	})
+}
+
+// TestHandler_Limits tests the depth and complexity limits.
+func TestHandler_Limits(t *testing.T) {
+	t.Run("failure - query too deep", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		h := newHandler(t, productService, graph.Limits{MaxDepth: 2})
+
+		// act
+		code, res := post(t, h, "", "{ products { items { id } } }", nil)
+
+		// assert
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Len(t, res.Errors, 1)
+		require.Equal(t, graph.CodeQueryTooDeep, res.Errors[0].Extensions["code"])
+		productService.AssertNotCalled(t, "GetProducts", mock.Anything)
+	})
+
+	t.Run("failure - query too complex, by the limit of a variable", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		h := newHandler(t, productService, graph.Limits{MaxComplexity: 100})
+
+		// act: 1 + 50 * (1 + 2)
+		query := `query($limit: Int) { products(limit: $limit) { ...page } } fragment page on ProductPage { items { id name } }`
+		code, res := post(t, h, "", query, map[string]any{"limit": 50})
+
+		// assert
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Len(t, res.Errors, 1)
+		require.Equal(t, graph.CodeQueryTooComplex, res.Errors[0].Extensions["code"])
+		require.Contains(t, res.Errors[0].Message, "151")
+	})
+
+	t.Run("success - introspection limited by its own depth, not the one of the queries", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		h := newHandler(t, productService, graph.Limits{MaxDepth: 1})
+
+		// act
+		code, res := post(t, h, "", "{ __schema { queryType { fields { name type { ofType { name } } } } } }", nil)
+		codeTools, resTools := post(t, h, "", testutil.IntrospectionQuery, nil)
+
+		// assert
+		require.Equal(t, http.StatusOK, code)
+		require.Empty(t, res.Errors)
+		require.Equal(t, http.StatusOK, codeTools)
+		require.Empty(t, resTools.Errors)
+	})
+
+	t.Run("failure - introspection too deep", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		h := newHandler(t, productService, graph.Limits{MaxIntrospectionDepth: 4})
+
+		// act
+		query := "{ product(id: 1) { __typename } __type(name: \"Product\") { fields { type { ofType { ofType { name } } } } } }"
+		code, res := post(t, h, "", query, nil)
+
+		// assert
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Len(t, res.Errors, 1)
+		require.Equal(t, graph.CodeQueryTooDeep, res.Errors[0].Extensions["code"])
+		require.Contains(t, res.Errors[0].Message, "introspection depth 6")
+		productService.AssertNotCalled(t, "GetProduct", mock.Anything, mock.Anything)
+	})
+
+	t.Run("failure - introspection counted in the complexity", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		h := newHandler(t, productService, graph.Limits{MaxComplexity: 5})
+
+		// act: 1 + (1 + (1 + 1 + (1 + 1)))
+		code, res := post(t, h, "", "{ __schema { types { name kind fields { name type { name } } } } }", nil)
+
+		// assert
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Len(t, res.Errors, 1)
+		require.Equal(t, graph.CodeQueryTooComplex, res.Errors[0].Extensions["code"])
+	})
+}
+
+// TestHandler_Mutations tests the mutations, authenticated by token.
+func TestHandler_Mutations(t *testing.T) {
+	product := internalProduct.Product{Name: "Milk", Quantity: 10, CodeValue: "c1", Expiration: "12/31/2030", Price: 100}
+	create := `mutation($input: ProductInput!) { createProduct(input: $input) { id } }`
+	input := map[string]any{"input": map[string]any{"name": "Milk", "quantity": 10, "codeValue": "c1", "expiration": "12/31/2030", "price": 100}}
+
+	t.Run("success - create product", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		created := product
+		created.Id = 1
+		productService.On("CreateProduct", mock.Anything, product).Return(created, nil)
+		h := newHandler(t, productService, graph.Limits{})
+
+		// act
+		code, res := post(t, h, "secret", create, input)
+
+		// assert
+		require.Equal(t, http.StatusOK, code)
+		require.Empty(t, res.Errors)
+		require.Equal(t, map[string]any{"createProduct": map[string]any{"id": 1.0}}, res.Data)
+		productService.AssertExpectations(t)
+	})
+
+	t.Run("success - update the fields of the patch", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		original := product
+		original.Id = 1
+		updated := original
+		updated.Price = 150
+		productService.On("GetProduct", mock.Anything, "1").Return(original, nil)
+		productService.On("UpdateProduct", mock.Anything, updated).Return(updated, nil)
+		h := newHandler(t, productService, graph.Limits{})
+
+		// act
+		code, res := post(t, h, "secret", "mutation { updateProduct(id: 1, patch: {price: 150}) { name price } }", nil)
+
+		// assert
+		require.Equal(t, http.StatusOK, code)
+		require.Empty(t, res.Errors)
+		require.Equal(t, map[string]any{"updateProduct": map[string]any{"name": "Milk", "price": 150.0}}, res.Data)
+		productService.AssertExpectations(t)
+	})
+
+	t.Run("failure - delete missing product", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		productService.On("DeleteProduct", mock.Anything, "9").Return(internalProduct.ErrProductNotFound)
+		h := newHandler(t, productService, graph.Limits{})
+
+		// act
+		code, res := post(t, h, "secret", "mutation { deleteProduct(id: 9) }", nil)
+
+		// assert
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, res.Errors, 1)
+		require.Equal(t, "product not found", res.Errors[0].Message)
+		require.Equal(t, graph.CodeNotFound, res.Errors[0].Extensions["code"])
+	})
+
+	t.Run("failure - unauthenticated", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		h := newHandler(t, productService, graph.Limits{})
+
+		// act
+		codeMissing, resMissing := post(t, h, "", create, input)
+		codeWrong, _ := post(t, h, "wrong", create, input)
+
+		// assert
+		require.Equal(t, http.StatusUnauthorized, codeMissing)
+		require.Equal(t, http.StatusUnauthorized, codeWrong)
+		require.Equal(t, graph.CodeUnauthenticated, resMissing.Errors[0].Extensions["code"])
+		productService.AssertNotCalled(t, "CreateProduct", mock.Anything, mock.Anything)
+	})
+
+	t.Run("failure - mutation by GET", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		h := newHandler(t, productService, graph.Limits{})
+
+		// act
+		req := httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape("mutation { deleteProduct(id: 1) }"), nil)
+		req.Header.Set("Token", "secret")
+		code, _ := serve(t, h, req)
+
+		// assert
+		require.Equal(t, http.StatusMethodNotAllowed, code)
+		productService.AssertNotCalled(t, "DeleteProduct", mock.Anything, mock.Anything)
+	})
+	t.Run("failure - mutation beyond the mutation limit, queries unaffected", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		productService.On("GetProduct", mock.Anything, "1").Return(internalProduct.Product{Id: 1, Name: "Milk"}, nil)
+		h := newHandler(t, productService, graph.Limits{})
+		h.MutationLimit = func(http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte(`{"message":"too many requests"}`))
+			})
+		}
+
+		// act
+		codeMutation, _ := post(t, h, "secret", create, input)
+		codeQuery, resQuery := post(t, h, "", "{ product(id: 1) { name } }", nil)
+
+		// assert
+		require.Equal(t, http.StatusTooManyRequests, codeMutation)
+		productService.AssertNotCalled(t, "CreateProduct", mock.Anything, mock.Anything)
+		require.Equal(t, http.StatusOK, codeQuery)
+		require.Empty(t, resQuery.Errors)
+	})
+}
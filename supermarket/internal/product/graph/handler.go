@@ -0,0 +1,207 @@
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"supermarket/internal/auth"
+	"supermarket/internal/platform/logging"
+	"supermarket/internal/platform/web/request"
+	"supermarket/internal/platform/web/response"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// Handler serves the GraphQL api: queries by GET or POST, mutations by POST with a valid token in
+// the Token header, as the write routes of the http api.
+type Handler struct {
+	schema graphql.Schema
+	au     auth.AuthToken
+	limits Limits
+	// MutationLimit wraps the execution of the mutations, e.g. with the rate limiter of the write routes.
+	// Nil executes them as the queries
+	MutationLimit func(http.Handler) http.Handler
+}
+
+// NewHandler returns a Handler over productService, authenticating the mutations with au.
+// The zero values of limits are replaced by the ones of DefaultLimits.
+func NewHandler(productService ProductServiceInterface, au auth.AuthToken, limits Limits) (*Handler, error) {
+	schema, err := NewSchema(productService)
+	if err != nil {
+		return nil, fmt.Errorf("graphql schema: %w", err)
+	}
+	if limits.MaxDepth <= 0 {
+		limits.MaxDepth = DefaultLimits.MaxDepth
+	}
+	if limits.MaxComplexity <= 0 {
+		limits.MaxComplexity = DefaultLimits.MaxComplexity
+	}
+	if limits.MaxIntrospectionDepth <= 0 {
+		limits.MaxIntrospectionDepth = DefaultLimits.MaxIntrospectionDepth
+	}
+	return &Handler{schema: schema, au: au, limits: limits}, nil
+}
+
+// params are the parameters of a request
+type params struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// ServeHTTP executes the operation of the request.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// request
+	var p params
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		p.Query = query.Get("query")
+		p.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &p.Variables); err != nil {
+				response.Error(w, http.StatusBadRequest, "invalid variables")
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := request.JSON(r, &p); err != nil {
+			switch {
+			case errors.Is(err, request.ErrRequestBodyTooLarge):
+				response.Error(w, http.StatusRequestEntityTooLarge, "request body too large")
+			case errors.Is(err, request.ErrRequestContentTypeNotJSON):
+				response.Errorw(w, http.StatusUnsupportedMediaType, err)
+			default:
+				response.Errorw(w, http.StatusBadRequest, err)
+			}
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		response.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if p.Query == "" {
+		response.Error(w, http.StatusBadRequest, "query is required")
+		return
+	}
+
+	// parse and validate
+	doc, err := parser.Parse(parser.ParseParams{Source: p.Query})
+	if err != nil {
+		writeResult(w, r, http.StatusBadRequest, &graphql.Result{Errors: []gqlerrors.FormattedError{withCode(gqlerrors.FormatError(err), CodeInvalidQuery)}})
+		return
+	}
+	validation := graphql.ValidateDocument(&h.schema, doc, nil)
+	if !validation.IsValid {
+		for i := range validation.Errors {
+			validation.Errors[i] = withCode(validation.Errors[i], CodeInvalidQuery)
+		}
+		writeResult(w, r, http.StatusBadRequest, &graphql.Result{Errors: validation.Errors})
+		return
+	}
+
+	// limits and authentication, for the operation to execute. If not found, the execution reports it
+	operation := findOperation(doc, p.OperationName)
+	if operation != nil {
+		if code, status, err := h.check(r, doc, operation, p.Variables); err != nil {
+			writeResult(w, r, status, &graphql.Result{Errors: []gqlerrors.FormattedError{
+				withCode(gqlerrors.FormatError(err), code),
+			}})
+			return
+		}
+	}
+
+	// execute
+	var execute http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result := graphql.Execute(graphql.ExecuteParams{
+			Schema:        h.schema,
+			AST:           doc,
+			OperationName: p.OperationName,
+			Args:          p.Variables,
+			Context:       r.Context(),
+		})
+		writeResult(w, r, http.StatusOK, result)
+	})
+	if operation != nil && operation.Operation == ast.OperationTypeMutation && h.MutationLimit != nil {
+		execute = h.MutationLimit(execute)
+	}
+	execute.ServeHTTP(w, r)
+}
+
+// check rejects the operations beyond the limits, the mutations sent by GET, and the mutations without a valid token
+func (h *Handler) check(r *http.Request, doc *ast.Document, operation *ast.OperationDefinition, variables map[string]any) (code string, status int, err error) {
+	a := newAnalysis(doc, variables)
+	if depth := a.depth(operation.SelectionSet); depth > h.limits.MaxDepth {
+		return CodeQueryTooDeep, http.StatusBadRequest,
+			fmt.Errorf("query depth %d exceeds the maximum of %d", depth, h.limits.MaxDepth)
+	}
+	if depth := a.introspectionDepth(operation.SelectionSet); depth > h.limits.MaxIntrospectionDepth {
+		return CodeQueryTooDeep, http.StatusBadRequest,
+			fmt.Errorf("introspection depth %d exceeds the maximum of %d", depth, h.limits.MaxIntrospectionDepth)
+	}
+	if complexity := a.complexity(operation.SelectionSet); complexity > h.limits.MaxComplexity {
+		return CodeQueryTooComplex, http.StatusBadRequest,
+			fmt.Errorf("query complexity %d exceeds the maximum of %d", complexity, h.limits.MaxComplexity)
+	}
+
+	if operation.Operation != ast.OperationTypeMutation {
+		return "", 0, nil
+	}
+	if r.Method != http.MethodPost {
+		return CodeInvalidQuery, http.StatusMethodNotAllowed, errors.New("mutations must be sent by POST")
+	}
+	if err := h.au.Auth(r.Header.Get("Token")); err != nil {
+		return CodeUnauthenticated, http.StatusUnauthorized, errors.New("Unauthorized")
+	}
+	return "", 0, nil
+}
+
+// findOperation returns the operation of doc named name, or its only operation when name is empty
+func findOperation(doc *ast.Document, name string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		operation, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			if found != nil {
+				return nil
+			}
+			found = operation
+			continue
+		}
+		if operation.Name != nil && operation.Name.Value == name {
+			return operation
+		}
+	}
+	return found
+}
+
+// withCode sets the code of the extensions of err
+func withCode(err gqlerrors.FormattedError, code string) gqlerrors.FormattedError {
+	if err.Extensions == nil {
+		err.Extensions = make(map[string]any)
+	}
+	err.Extensions["code"] = code
+	return err
+}
+
+// writeResult writes result as json with statusCode
+func writeResult(w http.ResponseWriter, r *http.Request, statusCode int, result *graphql.Result) {
+	bytes, err := json.Marshal(result)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("graphql result", slog.Any("error", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(bytes)
+}
@@ -0,0 +1,200 @@
+package graph
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Limits bound the queries before they are executed.
+type Limits struct {
+	// MaxDepth is the maximum nesting of the fields of an operation
+	MaxDepth int
+	// MaxComplexity is the maximum cost of an operation: a field costs 1, and the fields
+	// returning lists cost their items as many times as the items they may return
+	MaxComplexity int
+	// MaxIntrospectionDepth is the maximum nesting of the introspection fields, e.g. __schema, and of their
+	// selections. They are not counted in MaxDepth, the introspection queries of the tools being deeper
+	MaxIntrospectionDepth int
+}
+
+// DefaultLimits are the limits of the handlers without limits.
+var DefaultLimits = Limits{MaxDepth: 8, MaxComplexity: 1000, MaxIntrospectionDepth: 15}
+
+// analysis walks the selections of an operation, computing its depth and complexity
+type analysis struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+	// visiting are the fragments being walked, guarding against cycles
+	visiting map[string]bool
+}
+
+// newAnalysis returns an analysis of the operations of doc, with variables
+func newAnalysis(doc *ast.Document, variables map[string]any) *analysis {
+	a := &analysis{
+		fragments: make(map[string]*ast.FragmentDefinition),
+		variables: variables,
+		visiting:  make(map[string]bool),
+	}
+	for _, def := range doc.Definitions {
+		if fragment, ok := def.(*ast.FragmentDefinition); ok && fragment.Name != nil {
+			a.fragments[fragment.Name.Value] = fragment
+		}
+	}
+	return a
+}
+
+// depth returns the nesting of the fields of set, the introspection fields aside
+func (a *analysis) depth(set *ast.SelectionSet) int {
+	return a.nesting(set, false)
+}
+
+// introspectionDepth returns the nesting of the deepest introspection field of set and of its selections
+func (a *analysis) introspectionDepth(set *ast.SelectionSet) int {
+	if set == nil {
+		return 0
+	}
+	max := 0
+	for _, selection := range set.Selections {
+		d := 0
+		switch s := selection.(type) {
+		case *ast.Field:
+			if isIntrospection(s) {
+				d = 1 + a.nesting(s.SelectionSet, true)
+			} else {
+				d = a.introspectionDepth(s.SelectionSet)
+			}
+		case *ast.InlineFragment:
+			d = a.introspectionDepth(s.SelectionSet)
+		case *ast.FragmentSpread:
+			d = a.spread(s, a.introspectionDepth)
+		}
+		if d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+// nesting returns the nesting of the fields of set, the introspection fields aside unless introspection is true
+func (a *analysis) nesting(set *ast.SelectionSet, introspection bool) int {
+	if set == nil {
+		return 0
+	}
+	max := 0
+	for _, selection := range set.Selections {
+		d := 0
+		switch s := selection.(type) {
+		case *ast.Field:
+			if !introspection && isIntrospection(s) {
+				continue
+			}
+			d = 1 + a.nesting(s.SelectionSet, introspection)
+		case *ast.InlineFragment:
+			d = a.nesting(s.SelectionSet, introspection)
+		case *ast.FragmentSpread:
+			d = a.spread(s, func(set *ast.SelectionSet) int { return a.nesting(set, introspection) })
+		}
+		if d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+// complexity returns the cost of the fields of set, the introspection fields included
+func (a *analysis) complexity(set *ast.SelectionSet) int {
+	if set == nil {
+		return 0
+	}
+	total := 0
+	for _, selection := range set.Selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			total += 1 + a.listSize(s)*a.complexity(s.SelectionSet)
+		case *ast.InlineFragment:
+			total += a.complexity(s.SelectionSet)
+		case *ast.FragmentSpread:
+			total += a.spread(s, a.complexity)
+		}
+	}
+	return total
+}
+
+// spread applies measure to the selections of the fragment of s, 0 if unknown or already being walked
+func (a *analysis) spread(s *ast.FragmentSpread, measure func(*ast.SelectionSet) int) int {
+	if s.Name == nil {
+		return 0
+	}
+	name := s.Name.Value
+	fragment, ok := a.fragments[name]
+	if !ok || a.visiting[name] {
+		return 0
+	}
+	a.visiting[name] = true
+	defer delete(a.visiting, name)
+	return measure(fragment.SelectionSet)
+}
+
+// listSize returns the number of items a field may return, 1 for the fields not returning lists
+func (a *analysis) listSize(field *ast.Field) int {
+	switch field.Name.Value {
+	case "products":
+		if limit, ok := a.intArgument(field, "limit"); ok {
+			return max(limit, 0)
+		}
+		return defaultLimit
+	case "consumerPrice":
+		// a product per distinct id at most
+		if n, ok := a.listArgument(field, "ids"); ok {
+			return max(n, 1)
+		}
+	}
+	return 1
+}
+
+// intArgument returns the value of the Int argument name of field, either a literal or a variable
+func (a *analysis) intArgument(field *ast.Field, name string) (int, bool) {
+	switch v := argument(field, name).(type) {
+	case *ast.IntValue:
+		n, err := strconv.Atoi(v.Value)
+		return n, err == nil
+	case *ast.Variable:
+		switch n := a.variables[v.Name.Value].(type) {
+		case float64:
+			return int(n), true
+		case int:
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// listArgument returns the length of the list argument name of field, either a literal or a variable
+func (a *analysis) listArgument(field *ast.Field, name string) (int, bool) {
+	switch v := argument(field, name).(type) {
+	case *ast.ListValue:
+		return len(v.Values), true
+	case *ast.Variable:
+		if list, ok := a.variables[v.Name.Value].([]any); ok {
+			return len(list), true
+		}
+	}
+	return 0, false
+}
+
+// argument returns the value of the argument name of field, nil if not set
+func argument(field *ast.Field, name string) ast.Value {
+	for _, arg := range field.Arguments {
+		if arg.Name != nil && arg.Name.Value == name {
+			return arg.Value
+		}
+	}
+	return nil
+}
+
+// isIntrospection tells if field queries the schema, e.g. __schema or __typename
+func isIntrospection(field *ast.Field) bool {
+	return field.Name != nil && strings.HasPrefix(field.Name.Value, "__")
+}
@@ -0,0 +1,346 @@
+// Package graph serves the catalogue over GraphQL.
+package graph
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"supermarket/internal/platform/logging"
+	internalProduct "supermarket/internal/product"
+
+	"github.com/graphql-go/graphql"
+)
+
+type Product = internalProduct.Product
+
+type ProductServiceInterface = internalProduct.ProductServiceInterface
+
+const (
+	// defaultLimit is the page size of the product lists without limit
+	defaultLimit = 20
+	// maxLimit is the maximum page size of the product lists
+	maxLimit = 100
+)
+
+// NewSchema returns the GraphQL schema of the catalogue, resolved by productService.
+func NewSchema(productService ProductServiceInterface) (graphql.Schema, error) {
+	r := &resolver{service: productService}
+
+	product := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Product",
+		Description: "A product of the catalogue.",
+		Fields: graphql.Fields{
+			"id":          productField(graphql.Int, func(p Product) any { return p.Id }),
+			"name":        productField(graphql.String, func(p Product) any { return p.Name }),
+			"quantity":    productField(graphql.Int, func(p Product) any { return p.Quantity }),
+			"codeValue":   productField(graphql.String, func(p Product) any { return p.CodeValue }),
+			"isPublished": productField(graphql.Boolean, func(p Product) any { return p.IsPublished }),
+			"expiration":  productField(graphql.String, func(p Product) any { return p.Expiration }),
+			"price":       productField(graphql.Float, func(p Product) any { return p.Price }),
+		},
+	})
+	productList := graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(product)))
+
+	productPage := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "ProductPage",
+		Description: "A page of products, ordered by id.",
+		Fields: graphql.Fields{
+			"items":      &graphql.Field{Type: productList},
+			"totalCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Number of products matching the filter."},
+			"hasMore":    &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Description: "Whether products follow the page."},
+		},
+	})
+
+	consumerPrice := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "ConsumerPrice",
+		Description: "A list of products and their total price for a consumer.",
+		Fields: graphql.Fields{
+			"products":   &graphql.Field{Type: productList},
+			"totalPrice": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		},
+	})
+
+	productFilter := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "ProductFilter",
+		Description: "The conditions the listed products match, all of them.",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"priceGt":      &graphql.InputObjectFieldConfig{Type: graphql.Float},
+			"priceLt":      &graphql.InputObjectFieldConfig{Type: graphql.Float},
+			"isPublished":  &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"nameContains": &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Case insensitive."},
+		},
+	})
+
+	productInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "ProductInput",
+		Description: "The fields of a product to create. The expiration is a date mm/dd/yyyy.",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"quantity":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+			"codeValue":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"isPublished": &graphql.InputObjectFieldConfig{Type: graphql.Boolean, DefaultValue: false},
+			"expiration":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"price":       &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float)},
+		},
+	})
+
+	productPatch := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "ProductPatch",
+		Description: "The fields of a product to update, the missing ones being kept.",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"quantity":    &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"codeValue":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"isPublished": &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"expiration":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"price":       &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"product": &graphql.Field{
+				Type:        product,
+				Description: "A product by id, null if it does not exist.",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: r.product,
+			},
+			"products": &graphql.Field{
+				Type:        graphql.NewNonNull(productPage),
+				Description: "A page of the products matching filter.",
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: productFilter},
+					"limit":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultLimit, Description: "At most 100."},
+					"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: r.products,
+			},
+			"consumerPrice": &graphql.Field{
+				Type:        graphql.NewNonNull(consumerPrice),
+				Description: "The products of ids, an id repeated once per unit, and their total price.",
+				Args: graphql.FieldConfigArgument{
+					"ids": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.Int)))},
+				},
+				Resolve: r.consumerPrice,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createProduct": &graphql.Field{
+				Type: graphql.NewNonNull(product),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(productInput)},
+				},
+				Resolve: r.createProduct,
+			},
+			"updateProduct": &graphql.Field{
+				Type: graphql.NewNonNull(product),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"patch": &graphql.ArgumentConfig{Type: graphql.NewNonNull(productPatch)},
+				},
+				Resolve: r.updateProduct,
+			},
+			"deleteProduct": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: r.deleteProduct,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+// productField returns a non null field of Product read by get
+func productField(typ graphql.Output, get func(Product) any) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(typ),
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			return get(p.Source.(Product)), nil
+		},
+	}
+}
+
+// resolver resolves the fields of the schema with the service
+type resolver struct {
+	service ProductServiceInterface
+}
+
+// page is a page of products
+type page struct {
+	Items      []Product `json:"items"`
+	TotalCount int       `json:"totalCount"`
+	HasMore    bool      `json:"hasMore"`
+}
+
+// consumerPriceResult is the consumer price of a list of products
+type consumerPriceResult struct {
+	Products   []Product `json:"products"`
+	TotalPrice float64   `json:"totalPrice"`
+}
+
+func (r *resolver) product(p graphql.ResolveParams) (any, error) {
+	product, err := r.service.GetProduct(p.Context, formatID(p.Args["id"]))
+	if errors.Is(err, internalProduct.ErrProductNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, resolveError(p.Context, "get product", err)
+	}
+	return product, nil
+}
+
+func (r *resolver) products(p graphql.ResolveParams) (any, error) {
+	limit, _ := p.Args["limit"].(int)
+	offset, _ := p.Args["offset"].(int)
+	if limit < 0 || limit > maxLimit {
+		return nil, &Error{Code: CodeBadUserInput, Message: "limit must be between 0 and " + strconv.Itoa(maxLimit)}
+	}
+	if offset < 0 {
+		return nil, &Error{Code: CodeBadUserInput, Message: "offset must not be negative"}
+	}
+
+	products, err := r.service.GetProducts(p.Context)
+	if err != nil {
+		return nil, resolveError(p.Context, "get products", err)
+	}
+
+	// filter, on a copy as the service may return its own slice
+	filter, _ := p.Args["filter"].(map[string]any)
+	matching := make([]Product, 0, len(products))
+	for _, product := range products {
+		if matches(product, filter) {
+			matching = append(matching, product)
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool { return matching[i].Id < matching[j].Id })
+
+	// paginate
+	result := page{Items: []Product{}, TotalCount: len(matching)}
+	if offset < len(matching) {
+		end := min(offset+limit, len(matching))
+		result.Items = matching[offset:end]
+		result.HasMore = end < len(matching)
+	}
+	return result, nil
+}
+
+// matches tells if product matches every condition of filter
+func matches(product Product, filter map[string]any) bool {
+	if v, ok := filter["priceGt"].(float64); ok && product.Price <= v {
+		return false
+	}
+	if v, ok := filter["priceLt"].(float64); ok && product.Price >= v {
+		return false
+	}
+	if v, ok := filter["isPublished"].(bool); ok && product.IsPublished != v {
+		return false
+	}
+	if v, ok := filter["nameContains"].(string); ok && !strings.Contains(strings.ToLower(product.Name), strings.ToLower(v)) {
+		return false
+	}
+	return true
+}
+
+func (r *resolver) consumerPrice(p graphql.ResolveParams) (any, error) {
+	args, _ := p.Args["ids"].([]any)
+	ids := make([]string, len(args))
+	for i, id := range args {
+		ids[i] = formatID(id)
+	}
+	consumerPrice, err := r.service.GetConsumerPriceProducts(p.Context, ids)
+	if err != nil {
+		return nil, resolveError(p.Context, "get consumer price", err)
+	}
+	return consumerPriceResult{Products: consumerPrice.Products, TotalPrice: consumerPrice.TotalPrice}, nil
+}
+
+func (r *resolver) createProduct(p graphql.ResolveParams) (any, error) {
+	var product Product
+	applyFields(&product, p.Args["input"].(map[string]any))
+	product, err := r.service.CreateProduct(p.Context, product)
+	if err != nil {
+		return nil, resolveError(p.Context, "create product", err)
+	}
+	return product, nil
+}
+
+func (r *resolver) updateProduct(p graphql.ResolveParams) (any, error) {
+	// find original product to patch
+	product, err := r.service.GetProduct(p.Context, formatID(p.Args["id"]))
+	if err != nil {
+		return nil, resolveError(p.Context, "update product", err)
+	}
+	applyFields(&product, p.Args["patch"].(map[string]any))
+	product, err = r.service.UpdateProduct(p.Context, product)
+	if err != nil {
+		return nil, resolveError(p.Context, "update product", err)
+	}
+	return product, nil
+}
+
+func (r *resolver) deleteProduct(p graphql.ResolveParams) (any, error) {
+	if err := r.service.DeleteProduct(p.Context, formatID(p.Args["id"])); err != nil {
+		return nil, resolveError(p.Context, "delete product", err)
+	}
+	return true, nil
+}
+
+// applyFields sets the fields of product present in fields, an input object
+func applyFields(product *Product, fields map[string]any) {
+	if v, ok := fields["name"].(string); ok {
+		product.Name = v
+	}
+	if v, ok := fields["quantity"].(int); ok {
+		product.Quantity = v
+	}
+	if v, ok := fields["codeValue"].(string); ok {
+		product.CodeValue = v
+	}
+	if v, ok := fields["isPublished"].(bool); ok {
+		product.IsPublished = v
+	}
+	if v, ok := fields["expiration"].(string); ok {
+		product.Expiration = v
+	}
+	if v, ok := fields["price"].(float64); ok {
+		product.Price = v
+	}
+}
+
+// formatID formats an Int argument as the service expects the ids
+func formatID(id any) string {
+	v, _ := id.(int)
+	return strconv.Itoa(v)
+}
+
+// resolveError maps the errors of the service to an Error with a code, logging the unexpected ones as op
+func resolveError(ctx context.Context, op string, err error) error {
+	switch {
+	case errors.Is(err, internalProduct.ErrInvalidID),
+		errors.Is(err, internalProduct.ErrInvalidPriceGt),
+		errors.Is(err, internalProduct.ErrInvalidProduct):
+		return &Error{Code: CodeBadUserInput, Message: err.Error()}
+	case errors.Is(err, internalProduct.ErrProductNotFound):
+		return &Error{Code: CodeNotFound, Message: err.Error()}
+	case errors.Is(err, internalProduct.ErrDuplicateCodeValue),
+		errors.Is(err, internalProduct.ErrInsufficientQuantity):
+		return &Error{Code: CodeConflict, Message: err.Error()}
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return &Error{Code: CodeTimeout, Message: "request timeout"}
+	}
+	logging.FromContext(ctx).Error(op, slog.Any("error", err))
+	return &Error{Code: CodeInternal, Message: "internal server error"}
+}
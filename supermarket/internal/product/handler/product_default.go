@@ -1,16 +1,16 @@
 package handler
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
-	"io"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
+	"supermarket/internal/platform/logging"
 	"supermarket/internal/platform/web/request"
 	"supermarket/internal/platform/web/response"
 	"supermarket/internal/platform/web/serialization"
-	"supermarket/internal/platform/web/validator"
 	internalProduct "supermarket/internal/product"
 
 	"github.com/go-chi/chi/v5"
@@ -22,6 +22,25 @@ type ProductServiceInterface = internalProduct.ProductServiceInterface
 
 type ProductHandler struct {
 	ProductService ProductServiceInterface
+	// Categories filters GET /products by the category query parameters, ignored if nil
+	Categories CategoryFilter
+	// Stores prices GET /products/consumer_price at the store of the store query parameter, rejected if nil
+	Stores StoreConsumerPrice
+	// StrictJSON rejects the json bodies with unknown fields or trailing data
+	StrictJSON bool
+}
+
+// CategoryFilter returns the products of categories.
+type CategoryFilter interface {
+	// ProductIDs returns the ids of the products of the categories or their descendants
+	ProductIDs(ctx context.Context, categoryIDs []int) (map[int]bool, error)
+}
+
+// StoreConsumerPrice returns the consumer price of products at a store.
+type StoreConsumerPrice interface {
+	// GetConsumerPriceProducts returns the products of ids and their total price, checked against the stock of
+	// the store
+	GetConsumerPriceProducts(ctx context.Context, storeID int, ids []string) (internalProduct.ConsumerPriceProducts, error)
 }
 
 // NewProductHandler returns a new ProductHandler.
@@ -36,28 +55,47 @@ func (h *ProductHandler) GetPingHandler(w http.ResponseWriter, r *http.Request)
 	response.Text(w, http.StatusOK, "pong")
 }
 
-// GetProductsHandler returns the products from the repository.
+// GetProductsHandler returns the products from the repository, of the categories of the category query
+// parameters if any, repeated or comma separated.
 func (h *ProductHandler) GetProductsHandler(w http.ResponseWriter, r *http.Request) {
-	products, err := h.ProductService.GetProducts()
+	categoryIDs, err := categoryParams(r)
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, "internal server error")
+		response.Error(w, http.StatusBadRequest, "invalid category")
+		return
+	}
+
+	products, err := h.ProductService.GetProducts(r.Context())
+	if err == nil && len(categoryIDs) > 0 && h.Categories != nil {
+		products, err = h.filterByCategories(r.Context(), products, categoryIDs)
+	}
+	if err != nil {
+		switch {
+		case isContextError(err):
+			response.Error(w, http.StatusGatewayTimeout, "request timeout")
+		default:
+			logging.FromContext(r.Context()).Error("get products", slog.Any("error", err))
+			response.Error(w, http.StatusInternalServerError, "internal server error")
+		}
 		return
 	}
 	// serialize products to ProductResponseJSON
-	productsResponse := serialization.ProductsToProductsResponse(products)
-	response.JSON(w, http.StatusOK, "products fetched successfully", productsResponse)
+	productsResponse := serialization.ProductsResponse(serialization.ProductsToProductsResponse(products))
+	response.Negotiated(w, r, http.StatusOK, "products fetched successfully", productsResponse)
 }
 
 // GetProductHandler returns a product from the repository by id.
 func (h *ProductHandler) GetProductHandler(w http.ResponseWriter, r *http.Request) {
-	product, err := h.ProductService.GetProduct(chi.URLParam(r, "id"))
+	product, err := h.ProductService.GetProduct(r.Context(), chi.URLParam(r, "id"))
 	if err != nil {
 		switch {
 		case errors.Is(err, internalProduct.ErrInvalidID):
 			response.Errorw(w, http.StatusBadRequest, err)
 		case errors.Is(err, internalProduct.ErrProductNotFound):
 			response.Errorw(w, http.StatusNotFound, err)
+		case isContextError(err):
+			response.Error(w, http.StatusGatewayTimeout, "request timeout")
 		default:
+			logging.FromContext(r.Context()).Error("get product", slog.Any("error", err))
 			response.Error(w, http.StatusInternalServerError, "internal server error")
 		}
 		return
@@ -70,29 +108,32 @@ func (h *ProductHandler) GetProductHandler(w http.ResponseWriter, r *http.Reques
 
 // SearchProductsHandler returns the products from the repository that have a price greater than priceGt.
 func (h *ProductHandler) SearchProductsByPriceHandler(w http.ResponseWriter, r *http.Request) {
-	products, err := h.ProductService.SearchProductsByPrice(r.URL.Query().Get("priceGt"))
+	products, err := h.ProductService.SearchProductsByPrice(r.Context(), r.URL.Query().Get("priceGt"))
 	if err != nil {
-		switch err {
-		case internalProduct.ErrInvalidPriceGt:
+		switch {
+		case errors.Is(err, internalProduct.ErrInvalidPriceGt):
 			response.Errorw(w, http.StatusBadRequest, err)
+		case isContextError(err):
+			response.Error(w, http.StatusGatewayTimeout, "request timeout")
 		default:
+			logging.FromContext(r.Context()).Error("search products by price", slog.Any("error", err))
 			response.Error(w, http.StatusInternalServerError, "internal server error")
 		}
 		return
 	}
 
 	// serialize products to ProductResponseJSON
-	productsResponse := serialization.ProductsToProductsResponse(products)
-	response.JSON(w, http.StatusOK, "products fetched successfully", productsResponse)
+	productsResponse := serialization.ProductsResponse(serialization.ProductsToProductsResponse(products))
+	response.Negotiated(w, r, http.StatusOK, "products fetched successfully", productsResponse)
 }
 
 // CreateProductHandler adds a product to the repository.
 func (h *ProductHandler) CreateProductHandler(w http.ResponseWriter, r *http.Request) {
 	// read product from request
 	var productRequest serialization.ProductRequest
-	err := request.JSON(r, &productRequest)
+	err := request.JSON(r, &productRequest, request.Options(h.StrictJSON)...)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, "bad request")
+		response.RequestError(w, err)
 		return
 	}
 
@@ -100,14 +141,17 @@ func (h *ProductHandler) CreateProductHandler(w http.ResponseWriter, r *http.Req
 	product := serialization.ProductRequestToProduct(productRequest)
 
 	// create product
-	product, err = h.ProductService.CreateProduct(product)
+	product, err = h.ProductService.CreateProduct(r.Context(), product)
 	if err != nil {
 		switch {
 		case errors.Is(err, internalProduct.ErrInvalidProduct):
 			response.Errorw(w, http.StatusBadRequest, err)
 		case errors.Is(err, internalProduct.ErrDuplicateCodeValue):
 			response.Errorw(w, http.StatusConflict, err)
+		case isContextError(err):
+			response.Error(w, http.StatusGatewayTimeout, "request timeout")
 		default:
+			logging.FromContext(r.Context()).Error("create product", slog.Any("error", err))
 			response.Error(w, http.StatusInternalServerError, "internal server error")
 		}
 		return
@@ -127,48 +171,34 @@ func (h *ProductHandler) UpdateOrCreateProductHandler(w http.ResponseWriter, r *
 		return
 	}
 
-	// get body to []byte
-	body, err := io.ReadAll(r.Body)
+	// read product from request, every key but is_published being required
+	var productRequest serialization.ProductReplaceRequest
+	err = request.JSON(r, &productRequest, request.Options(h.StrictJSON)...)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, "bad request")
+		response.RequestError(w, err)
 		return
 	}
 
-	// create a map to validate the body
-	var bodyMap map[string]any
-	err = json.Unmarshal(body, &bodyMap)
-	if err != nil {
-		response.Error(w, http.StatusBadRequest, "bad request")
-		return
-	}
-
-	err = validator.ValidateRequiredKeys(bodyMap, "name", "quantity", "code_value", "expiration", "price")
+	// deserialize productRequest to Product
+	product, err := serialization.ProductReplaceRequestToProduct(productRequest)
 	if err != nil {
 		response.Errorw(w, http.StatusBadRequest, err)
 		return
 	}
-
-	// read product from bytes
-	var productRequest serialization.ProductRequest
-	err = json.Unmarshal(body, &productRequest)
-	if err != nil {
-		response.Error(w, http.StatusBadRequest, "bad request")
-		return
-	}
-
-	// deserialize productRequest to Product
-	product := serialization.ProductRequestToProduct(productRequest)
 	product.Id = id
 
 	// update or create product
-	product, err = h.ProductService.UpdateOrCreateProduct(product)
+	product, err = h.ProductService.UpdateOrCreateProduct(r.Context(), product)
 	if err != nil {
 		switch {
 		case errors.Is(err, internalProduct.ErrInvalidProduct):
 			response.Errorw(w, http.StatusBadRequest, err)
 		case errors.Is(err, internalProduct.ErrDuplicateCodeValue):
 			response.Errorw(w, http.StatusConflict, err)
+		case isContextError(err):
+			response.Error(w, http.StatusGatewayTimeout, "request timeout")
 		default:
+			logging.FromContext(r.Context()).Error("update or create product", slog.Any("error", err))
 			response.Error(w, http.StatusInternalServerError, "internal server error")
 		}
 		return
@@ -190,14 +220,17 @@ func (h *ProductHandler) UpdateProductHandler(w http.ResponseWriter, r *http.Req
 
 	// find original product to patch
 	var originalProduct Product
-	originalProduct, err = h.ProductService.GetProduct(chi.URLParam(r, "id"))
+	originalProduct, err = h.ProductService.GetProduct(r.Context(), chi.URLParam(r, "id"))
 	if err != nil {
 		switch {
 		case errors.Is(err, internalProduct.ErrInvalidID):
 			response.Errorw(w, http.StatusBadRequest, err)
 		case errors.Is(err, internalProduct.ErrProductNotFound):
 			response.Errorw(w, http.StatusNotFound, err)
+		case isContextError(err):
+			response.Error(w, http.StatusGatewayTimeout, "request timeout")
 		default:
+			logging.FromContext(r.Context()).Error("update product", slog.Any("error", err))
 			response.Error(w, http.StatusInternalServerError, "internal server error")
 		}
 		return
@@ -207,9 +240,9 @@ func (h *ProductHandler) UpdateProductHandler(w http.ResponseWriter, r *http.Req
 	updateProductRequest := serialization.ProductToProductRequest(originalProduct)
 
 	// read productRequest from request into updateProductRequest
-	err = request.JSON(r, &updateProductRequest)
+	err = request.JSON(r, &updateProductRequest, request.Options(h.StrictJSON)...)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, "bad request")
+		response.RequestError(w, err)
 		return
 	}
 
@@ -218,14 +251,17 @@ func (h *ProductHandler) UpdateProductHandler(w http.ResponseWriter, r *http.Req
 	updateProduct.Id = id
 
 	// update product
-	updateProduct, err = h.ProductService.UpdateProduct(updateProduct)
+	updateProduct, err = h.ProductService.UpdateProduct(r.Context(), updateProduct)
 	if err != nil {
 		switch {
 		case errors.Is(err, internalProduct.ErrInvalidProduct):
 			response.Errorw(w, http.StatusBadRequest, err)
 		case errors.Is(err, internalProduct.ErrDuplicateCodeValue):
 			response.Errorw(w, http.StatusConflict, err)
+		case isContextError(err):
+			response.Error(w, http.StatusGatewayTimeout, "request timeout")
 		default:
+			logging.FromContext(r.Context()).Error("update product", slog.Any("error", err))
 			response.Error(w, http.StatusInternalServerError, "internal server error")
 		}
 		return
@@ -239,14 +275,17 @@ func (h *ProductHandler) UpdateProductHandler(w http.ResponseWriter, r *http.Req
 // DeleteProductHandler deletes a product from the repository by id.
 func (h *ProductHandler) DeleteProductHandler(w http.ResponseWriter, r *http.Request) {
 	// delete product
-	err := h.ProductService.DeleteProduct(chi.URLParam(r, "id"))
+	err := h.ProductService.DeleteProduct(r.Context(), chi.URLParam(r, "id"))
 	if err != nil {
 		switch {
 		case errors.Is(err, internalProduct.ErrInvalidID):
 			response.Errorw(w, http.StatusBadRequest, err)
 		case errors.Is(err, internalProduct.ErrProductNotFound):
 			response.Errorw(w, http.StatusNotFound, err)
+		case isContextError(err):
+			response.Error(w, http.StatusGatewayTimeout, "request timeout")
 		default:
+			logging.FromContext(r.Context()).Error("delete product", slog.Any("error", err))
 			response.Error(w, http.StatusInternalServerError, "internal server error")
 		}
 		return
@@ -255,8 +294,15 @@ func (h *ProductHandler) DeleteProductHandler(w http.ResponseWriter, r *http.Req
 	response.Text(w, http.StatusOK, "product deleted successfully")
 }
 
-// GetConsumerPriceProductsHandler returns a list of products indicated by ids and the total adjusted price
+// GetConsumerPriceProductsHandler returns a list of products indicated by ids and the total adjusted price,
+// checked against the stock of the store of the store query parameter if any
 func (h *ProductHandler) GetConsumerPriceHandler(w http.ResponseWriter, r *http.Request) {
+	storeParam := r.URL.Query().Get("store")
+	storeID, err := strconv.Atoi(storeParam)
+	if storeParam != "" && (err != nil || h.Stores == nil) {
+		response.Error(w, http.StatusBadRequest, "invalid store")
+		return
+	}
 	// read list of ids from query params
 	listParam := r.URL.Query().Get("list")
 	// remove brackets
@@ -265,16 +311,24 @@ func (h *ProductHandler) GetConsumerPriceHandler(w http.ResponseWriter, r *http.
 	ids := strings.Split(listParam, ",")
 
 	// get consumer price products
-	consumerPriceProducts, err := h.ProductService.GetConsumerPriceProducts(ids)
+	var consumerPriceProducts internalProduct.ConsumerPriceProducts
+	if storeParam != "" {
+		consumerPriceProducts, err = h.Stores.GetConsumerPriceProducts(r.Context(), storeID, ids)
+	} else {
+		consumerPriceProducts, err = h.ProductService.GetConsumerPriceProducts(r.Context(), ids)
+	}
 	if err != nil {
 		switch {
 		case errors.Is(err, internalProduct.ErrInvalidID):
 			response.Errorw(w, http.StatusBadRequest, err)
-		case errors.Is(err, internalProduct.ErrProductNotFound):
+		case errors.Is(err, internalProduct.ErrProductNotFound), errors.Is(err, internalProduct.ErrStoreNotFound):
 			response.Errorw(w, http.StatusNotFound, err)
 		case errors.Is(err, internalProduct.ErrInsufficientQuantity):
 			response.Errorw(w, http.StatusConflict, err)
+		case isContextError(err):
+			response.Error(w, http.StatusGatewayTimeout, "request timeout")
 		default:
+			logging.FromContext(r.Context()).Error("get consumer price", slog.Any("error", err))
 			response.Error(w, http.StatusInternalServerError, "internal server error")
 		}
 		return
@@ -284,3 +338,38 @@ func (h *ProductHandler) GetConsumerPriceHandler(w http.ResponseWriter, r *http.
 	consumerPriceProductsResponse := serialization.ConsumerPriceProductsToConsumerPriceProductsResponse(consumerPriceProducts)
 	response.JSON(w, http.StatusOK, "consumer price products fetched successfully", consumerPriceProductsResponse)
 }
+
+// filterByCategories returns the products of the categories or their descendants
+func (h *ProductHandler) filterByCategories(ctx context.Context, products []Product, categoryIDs []int) ([]Product, error) {
+	productIDs, err := h.Categories.ProductIDs(ctx, categoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	filtered := []Product{}
+	for _, product := range products {
+		if productIDs[product.Id] {
+			filtered = append(filtered, product)
+		}
+	}
+	return filtered, nil
+}
+
+// categoryParams returns the ids of the category query parameters
+func categoryParams(r *http.Request) ([]int, error) {
+	var ids []int
+	for _, param := range r.URL.Query()["category"] {
+		for _, value := range strings.Split(param, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// isContextError returns true if err is due to the request being cancelled or exceeding its deadline.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
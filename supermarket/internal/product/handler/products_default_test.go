@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -69,7 +70,7 @@ func TestGetProducts(t *testing.T) {
         }`
 
 		// create a mock of GetProducts method
-		productService.On("GetProducts").Return(products, nil)
+		productService.On("GetProducts", mock.Anything).Return(products, nil)
 		// create a new ProductHandler
 		productHandler := handler.NewProductHandler(productService)
 		// create a new request and recorder
@@ -85,7 +86,118 @@ func TestGetProducts(t *testing.T) {
 		// assert
 		require.Equal(t, http.StatusOK, rr.Code)
 		require.JSONEq(t, expectedResponse, rr.Body.String())
-		productService.AssertCalled(t, "GetProducts")
+		productService.AssertCalled(t, "GetProducts", mock.Anything)
+	})
+
+	t.Run("error - request deadline exceeded", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		productService.On("GetProducts", mock.Anything).Return([]internalProduct.Product(nil), context.DeadlineExceeded)
+		productHandler := handler.NewProductHandler(productService)
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(productHandler.GetProductsHandler)
+		expectedResponse := `{"status": "Gateway Timeout", "message": "request timeout"}`
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.Equal(t, http.StatusGatewayTimeout, rr.Code)
+		require.JSONEq(t, expectedResponse, rr.Body.String())
+	})
+
+	t.Run("success - get products as csv", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		products := []internalProduct.Product{
+			{Id: 1, Name: "product 1", Quantity: 10, CodeValue: "code 1", IsPublished: true, Expiration: "2021-12-31", Price: 100.5},
+		}
+		productService.On("GetProducts", mock.Anything).Return(products, nil)
+		productHandler := handler.NewProductHandler(productService)
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		req.Header.Set("Accept", "text/csv")
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(productHandler.GetProductsHandler)
+		expectedResponse := "id,name,quantity,code_value,is_published,expiration,price\n" +
+			"1,product 1,10,code 1,true,2021-12-31,100.5\n"
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "text/csv; charset=utf-8; header=present", rr.Header().Get("Content-Type"))
+		require.Equal(t, expectedResponse, rr.Body.String())
+	})
+
+	t.Run("success - get products as ndjson", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		products := []internalProduct.Product{
+			{Id: 1, Name: "product 1", Quantity: 10, CodeValue: "code 1", IsPublished: true, Expiration: "2021-12-31", Price: 100},
+			{Id: 2, Name: "product 2", Quantity: 20, CodeValue: "code 2", IsPublished: false, Expiration: "2021-12-31", Price: 200},
+		}
+		productService.On("GetProducts", mock.Anything).Return(products, nil)
+		productHandler := handler.NewProductHandler(productService)
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		req.Header.Set("Accept", "application/x-ndjson")
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(productHandler.GetProductsHandler)
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "application/x-ndjson", rr.Header().Get("Content-Type"))
+		lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+		require.Len(t, lines, 2)
+		require.JSONEq(t, `{"id":2,"name":"product 2","quantity":20,"code_value":"code 2","is_published":false,"expiration":"2021-12-31","price":200}`, lines[1])
+	})
+
+	t.Run("success - get products as xml", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		products := []internalProduct.Product{
+			{Id: 1, Name: "product 1", Quantity: 10, CodeValue: "code 1", IsPublished: true, Expiration: "2021-12-31", Price: 100},
+		}
+		productService.On("GetProducts", mock.Anything).Return(products, nil)
+		productHandler := handler.NewProductHandler(productService)
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		req.Header.Set("Accept", "application/xml")
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(productHandler.GetProductsHandler)
+		expectedResponse := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+			`<response><message>products fetched successfully</message><data><item><id>1</id><name>product 1</name>` +
+			`<quantity>10</quantity><code_value>code 1</code_value><is_published>true</is_published>` +
+			`<expiration>2021-12-31</expiration><price>100</price></item></data></response>`
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, expectedResponse, rr.Body.String())
+	})
+
+	t.Run("error - not acceptable", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		productService.On("GetProducts", mock.Anything).Return([]internalProduct.Product{}, nil)
+		productHandler := handler.NewProductHandler(productService)
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		req.Header.Set("Accept", "application/pdf")
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(productHandler.GetProductsHandler)
+		expectedResponse := `{"status": "Not Acceptable", "message": "not acceptable"}`
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.Equal(t, http.StatusNotAcceptable, rr.Code)
+		require.JSONEq(t, expectedResponse, rr.Body.String())
 	})
 }
 
@@ -120,7 +232,7 @@ func TestGetProduct(t *testing.T) {
 		}`
 
 		// create a mock of GetProduct method
-		productService.On("GetProduct", "1").Return(product, nil)
+		productService.On("GetProduct", mock.Anything, "1").Return(product, nil)
 
 		// create a new ProductHandler
 		productHandler := handler.NewProductHandler(productService)
@@ -146,7 +258,7 @@ func TestGetProduct(t *testing.T) {
 		// assert
 		require.Equal(t, http.StatusOK, rr.Code)
 		require.JSONEq(t, expectedResponse, rr.Body.String())
-		productService.AssertCalled(t, "GetProduct", "1")
+		productService.AssertCalled(t, "GetProduct", mock.Anything, "1")
 	})
 	t.Run("fail - get product invalid id", func(t *testing.T) {
 		// arrange
@@ -154,7 +266,7 @@ func TestGetProduct(t *testing.T) {
 		expectedResponse := `{"message":"invalid id", "status":"Bad Request"}`
 		// create a mock of ProductServiceInterface
 		productService := new(ProductServiceMock)
-		productService.On("GetProduct", "bad id").Return(internalProduct.Product{}, internalProduct.ErrInvalidID)
+		productService.On("GetProduct", mock.Anything, "bad id").Return(internalProduct.Product{}, internalProduct.ErrInvalidID)
 
 		// create a new ProductHandler
 		productHandler := handler.NewProductHandler(productService)
@@ -187,7 +299,7 @@ func TestGetProduct(t *testing.T) {
 		expectedResponse := `{"message":"product not found", "status":"Not Found"}`
 		// create a mock of ProductServiceInterface
 		productService := new(ProductServiceMock)
-		productService.On("GetProduct", "1").Return(internalProduct.Product{}, internalProduct.ErrProductNotFound)
+		productService.On("GetProduct", mock.Anything, "1").Return(internalProduct.Product{}, internalProduct.ErrProductNotFound)
 
 		// create a new ProductHandler
 		productHandler := handler.NewProductHandler(productService)
@@ -256,7 +368,7 @@ func TestCreateProduct(t *testing.T) {
 		}`
 
 		// create a mock of CreateProduct method
-		productService.On("CreateProduct", product).Return(product, nil)
+		productService.On("CreateProduct", mock.Anything, product).Return(product, nil)
 
 		// create a new ProductHandler
 		productHandler := handler.NewProductHandler(productService)
@@ -275,12 +387,12 @@ func TestCreateProduct(t *testing.T) {
 		// assert
 		require.Equal(t, http.StatusCreated, rr.Code)
 		require.JSONEq(t, expectedResponse, rr.Body.String())
-		productService.AssertCalled(t, "CreateProduct", product)
+		productService.AssertCalled(t, "CreateProduct", mock.Anything, product)
 	})
 	t.Run("fail - create product bad request", func(t *testing.T) {
 		// arrange
 		// expected response
-		expectedResponse := `{"message":"bad request", "status":"Bad Request"}`
+		expectedResponse := `{"message":"request json invalid: at offset 1: invalid character 'a' looking for beginning of value", "status":"Bad Request"}`
 		// create a mock of ProductServiceInterface
 		productService := new(ProductServiceMock)
 		// body
@@ -300,6 +412,43 @@ func TestCreateProduct(t *testing.T) {
 		require.Equal(t, http.StatusBadRequest, rr.Code)
 		require.JSONEq(t, expectedResponse, rr.Body.String())
 	})
+	t.Run("fail - create product unknown field in strict mode", func(t *testing.T) {
+		// arrange
+		expectedResponse := `{"message":"request json invalid: field \"colour\" at offset 28: unknown field", "status":"Bad Request"}`
+		productService := new(ProductServiceMock)
+		body := `{"name":"product 1","colour":"red","quantity":10}`
+		productHandler := handler.NewProductHandler(productService)
+		productHandler.StrictJSON = true
+		req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(productHandler.CreateProductHandler)
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.JSONEq(t, expectedResponse, rr.Body.String())
+		productService.AssertNotCalled(t, "CreateProduct", mock.Anything, mock.Anything)
+	})
+	t.Run("fail - create product unsupported media type", func(t *testing.T) {
+		// arrange
+		expectedResponse := `{"message":"request content type is not application/json", "status":"Unsupported Media Type"}`
+		productService := new(ProductServiceMock)
+		productHandler := handler.NewProductHandler(productService)
+		req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "text/plain")
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(productHandler.CreateProductHandler)
+
+		// act
+		handler.ServeHTTP(rr, req)
+
+		// assert
+		require.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+		require.JSONEq(t, expectedResponse, rr.Body.String())
+	})
 	t.Run("fail - create product invalid product", func(t *testing.T) {
 		// arrange
 		// expected response
@@ -325,7 +474,7 @@ func TestCreateProduct(t *testing.T) {
 			"price": 100.0
 		}`
 		// create a mock of CreateProduct method
-		productService.On("CreateProduct", product).Return(product, internalProduct.ErrInvalidProduct)
+		productService.On("CreateProduct", mock.Anything, product).Return(product, internalProduct.ErrInvalidProduct)
 		// create a new ProductHandler
 		productHandler := handler.NewProductHandler(productService)
 		// create a new request and recorder
@@ -366,7 +515,7 @@ func TestCreateProduct(t *testing.T) {
 			"price": 100.0
 		}`
 		// create a mock of CreateProduct method
-		productService.On("CreateProduct", product).Return(product, internalProduct.ErrDuplicateCodeValue)
+		productService.On("CreateProduct", mock.Anything, product).Return(product, internalProduct.ErrDuplicateCodeValue)
 		// create a new ProductHandler
 		productHandler := handler.NewProductHandler(productService)
 		// create a new request and recorder
@@ -384,6 +533,90 @@ func TestCreateProduct(t *testing.T) {
 	})
 }
 
+// TestUpdateOrCreateProduct tests the UpdateOrCreateProductHandler method.
+func TestUpdateOrCreateProduct(t *testing.T) {
+	// newRequest returns a request replacing the product 1 with body
+	newRequest := func(body string, contentType string) *http.Request {
+		req := httptest.NewRequest(http.MethodPut, "/products/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("id", "1")
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+	}
+
+	t.Run("success - update or create product", func(t *testing.T) {
+		// arrange
+		expectedResponse := `{"message":"product updated or created successfully", "data":{"id":1, "name":"product 1", "quantity":10, "code_value":"code 1", "is_published":false, "expiration":"12/31/2022", "price":100}}`
+		product := internalProduct.Product{
+			Id:          1,
+			Name:        "product 1",
+			Quantity:    10,
+			CodeValue:   "code 1",
+			IsPublished: false,
+			Expiration:  "12/31/2022",
+			Price:       100,
+		}
+		body := `{"name":"product 1","quantity":10,"code_value":"code 1","expiration":"12/31/2022","price":100.0}`
+		productService := new(ProductServiceMock)
+		productService.On("UpdateOrCreateProduct", mock.Anything, product).Return(product, nil)
+		productHandler := handler.NewProductHandler(productService)
+		rr := httptest.NewRecorder()
+
+		// act
+		http.HandlerFunc(productHandler.UpdateOrCreateProductHandler).ServeHTTP(rr, newRequest(body, "application/json"))
+
+		// assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, expectedResponse, rr.Body.String())
+	})
+	t.Run("fail - update or create product with a missing key", func(t *testing.T) {
+		// arrange
+		expectedResponse := `{"message":"key not found", "status":"Bad Request"}`
+		body := `{"name":"product 1","quantity":10,"code_value":"code 1","expiration":"12/31/2022"}`
+		productService := new(ProductServiceMock)
+		productHandler := handler.NewProductHandler(productService)
+		rr := httptest.NewRecorder()
+
+		// act
+		http.HandlerFunc(productHandler.UpdateOrCreateProductHandler).ServeHTTP(rr, newRequest(body, "application/json"))
+
+		// assert
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.JSONEq(t, expectedResponse, rr.Body.String())
+		productService.AssertNotCalled(t, "UpdateOrCreateProduct", mock.Anything, mock.Anything)
+	})
+	t.Run("fail - update or create product with an unknown field in strict mode", func(t *testing.T) {
+		// arrange
+		body := `{"name":"product 1","colour":"red"}`
+		productService := new(ProductServiceMock)
+		productHandler := handler.NewProductHandler(productService)
+		productHandler.StrictJSON = true
+		rr := httptest.NewRecorder()
+
+		// act
+		http.HandlerFunc(productHandler.UpdateOrCreateProductHandler).ServeHTTP(rr, newRequest(body, "application/json"))
+
+		// assert
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), `unknown field`)
+		productService.AssertNotCalled(t, "UpdateOrCreateProduct", mock.Anything, mock.Anything)
+	})
+	t.Run("fail - update or create product unsupported media type", func(t *testing.T) {
+		// arrange
+		expectedResponse := `{"message":"request content type is not application/json", "status":"Unsupported Media Type"}`
+		productService := new(ProductServiceMock)
+		productHandler := handler.NewProductHandler(productService)
+		rr := httptest.NewRecorder()
+
+		// act
+		http.HandlerFunc(productHandler.UpdateOrCreateProductHandler).ServeHTTP(rr, newRequest(`{}`, "text/plain"))
+
+		// assert
+		require.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+		require.JSONEq(t, expectedResponse, rr.Body.String())
+	})
+}
+
 func TestDeleteProduct(t *testing.T) {
 	t.Run("success - delete product", func(t *testing.T) {
 		// arrange
@@ -393,7 +626,7 @@ func TestDeleteProduct(t *testing.T) {
 		expectedResponse := "product deleted successfully"
 
 		// create a mock of DeleteProduct method
-		productService.On("DeleteProduct", "1").Return(nil)
+		productService.On("DeleteProduct", mock.Anything, "1").Return(nil)
 
 		// create a new ProductHandler
 		productHandler := handler.NewProductHandler(productService)
@@ -419,7 +652,7 @@ func TestDeleteProduct(t *testing.T) {
 		// assert
 		require.Equal(t, http.StatusOK, rr.Code)
 		require.Equal(t, expectedResponse, rr.Body.String())
-		productService.AssertCalled(t, "DeleteProduct", "1")
+		productService.AssertCalled(t, "DeleteProduct", mock.Anything, "1")
 	})
 	t.Run("fail - delete product not found", func(t *testing.T) {
 		// arrange
@@ -430,7 +663,7 @@ func TestDeleteProduct(t *testing.T) {
 		expectedResponse := `{"message":"product not found", "status":"Not Found"}`
 
 		// create a mock of DeleteProduct method
-		productService.On("DeleteProduct", "1").Return(internalProduct.ErrProductNotFound)
+		productService.On("DeleteProduct", mock.Anything, "1").Return(internalProduct.ErrProductNotFound)
 
 		// create a new ProductHandler
 		productHandler := handler.NewProductHandler(productService)
@@ -461,7 +694,7 @@ func TestDeleteProduct(t *testing.T) {
 		// arrange
 		// create a mock of ProductServiceInterface
 		productService := new(ProductServiceMock)
-		productService.On("DeleteProduct", "1").Return(internalProduct.ErrInvalidID)
+		productService.On("DeleteProduct", mock.Anything, "1").Return(internalProduct.ErrInvalidID)
 
 		// create the expected response
 		expectedResponse := `{"message":"invalid id", "status":"Bad Request"}`
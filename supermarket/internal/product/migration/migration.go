@@ -0,0 +1,173 @@
+// Package migration copies the catalogue between product storages, e.g. from a json file to another backend.
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	internalProduct "supermarket/internal/product"
+)
+
+type Product = internalProduct.Product
+
+// ErrVerification is returned when the destination does not hold the products of the source after a migration.
+var ErrVerification = errors.New("migration: verification failed")
+
+// Options are the options of a migration.
+type Options struct {
+	// DryRun computes the diff without writing the destination
+	DryRun bool
+	// Prune removes the products of the destination missing in the source, kept by default
+	Prune bool
+}
+
+// Change is a product of the destination replaced by the one of the source.
+type Change struct {
+	Before Product `json:"before"`
+	After  Product `json:"after"`
+}
+
+// Diff is the difference between the products of a source and a destination.
+type Diff struct {
+	// Added are the products of the source missing in the destination
+	Added []Product `json:"added"`
+	// Updated are the products of the destination different in the source
+	Updated []Change `json:"updated"`
+	// Extra are the products of the destination missing in the source
+	Extra []Product `json:"extra"`
+	// Unchanged is the number of products equal in both
+	Unchanged int `json:"unchanged"`
+}
+
+// Compare returns the diff of the products of destination to the ones of source, by id.
+func Compare(source, destination map[int]Product) Diff {
+	diff := Diff{Added: []Product{}, Updated: []Change{}, Extra: []Product{}}
+	for _, id := range sortedIDs(source) {
+		after := source[id]
+		before, ok := destination[id]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, after)
+		case before != after:
+			diff.Updated = append(diff.Updated, Change{Before: before, After: after})
+		default:
+			diff.Unchanged++
+		}
+	}
+	for _, id := range sortedIDs(destination) {
+		if _, ok := source[id]; !ok {
+			diff.Extra = append(diff.Extra, destination[id])
+		}
+	}
+	return diff
+}
+
+// Checksum returns the hex sha256 of the products in the order of their ids, equal for equal catalogues
+// whatever the storage.
+func Checksum(products map[int]Product) string {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	for _, id := range sortedIDs(products) {
+		// the encoding of a product never fails
+		enc.Encode(products[id])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Report is the outcome of a migration.
+type Report struct {
+	Diff
+	// Source and Destination are the numbers of products of the source and of the destination after the
+	// migration, or that it would hold on a dry run
+	Source      int `json:"source"`
+	Destination int `json:"destination"`
+	// Checksum is the checksum of the products of the source
+	Checksum string `json:"checksum"`
+	// Pruned tells if the extra products are removed from the destination
+	Pruned bool `json:"pruned"`
+	// Written tells if the destination was saved, not when it already held the products or on a dry run
+	Written bool `json:"written"`
+}
+
+// Migrate copies the products of source to destination, replacing the ones with the same id. The storages
+// load and save the catalogue whole. A destination without products, e.g. a json file to create, is empty.
+//
+// Migrate is idempotent: the destination is not written when it already holds the products, so it can run
+// again after a failure. The destination is then loaded again to verify its count of products and the
+// checksum of the ones of the source, ErrVerification being returned on a mismatch.
+func Migrate(ctx context.Context, source, destination internalProduct.ProductStorageInterface, options Options) (Report, error) {
+	sourceProducts, err := source.LoadProducts(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("load source: %w", err)
+	}
+	destinationProducts, err := destination.LoadProducts(ctx)
+	if errors.Is(err, internalProduct.ErrFileNotFound) {
+		destinationProducts, err = map[int]Product{}, nil
+	}
+	if err != nil {
+		return Report{}, fmt.Errorf("load destination: %w", err)
+	}
+
+	diff := Compare(sourceProducts, destinationProducts)
+	// the products of the destination after the migration
+	migrated := make(map[int]Product, len(sourceProducts)+len(diff.Extra))
+	if !options.Prune {
+		for _, product := range diff.Extra {
+			migrated[product.Id] = product
+		}
+	}
+	for id, product := range sourceProducts {
+		migrated[id] = product
+	}
+	report := Report{
+		Diff:        diff,
+		Source:      len(sourceProducts),
+		Destination: len(migrated),
+		Checksum:    Checksum(sourceProducts),
+		Pruned:      options.Prune && len(diff.Extra) > 0,
+	}
+
+	if options.DryRun {
+		return report, nil
+	}
+	if len(diff.Added) > 0 || len(diff.Updated) > 0 || report.Pruned {
+		if err := destination.SaveProducts(ctx, migrated); err != nil {
+			return report, fmt.Errorf("save destination: %w", err)
+		}
+		report.Written = true
+	}
+	return report, verify(ctx, destination, sourceProducts, len(migrated), report.Checksum)
+}
+
+// verify checks that destination holds count products, the ones of source having checksum
+func verify(ctx context.Context, destination internalProduct.ProductStorageInterface, source map[int]Product, count int, checksum string) error {
+	saved, err := destination.LoadProducts(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: load destination: %v", ErrVerification, err)
+	}
+	if len(saved) != count {
+		return fmt.Errorf("%w: %d products saved, %d expected", ErrVerification, len(saved), count)
+	}
+	migrated := make(map[int]Product, len(source))
+	for id := range source {
+		migrated[id] = saved[id]
+	}
+	if got := Checksum(migrated); got != checksum {
+		return fmt.Errorf("%w: checksum %s, %s expected", ErrVerification, got, checksum)
+	}
+	return nil
+}
+
+// sortedIDs returns the ids of products in increasing order
+func sortedIDs(products map[int]Product) []int {
+	ids := make([]int, 0, len(products))
+	for id := range products {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
@@ -0,0 +1,127 @@
+package migration_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	internalProduct "supermarket/internal/product"
+	"supermarket/internal/product/migration"
+	"supermarket/internal/product/storage"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// lossyStorage is a storage losing the product of id 1 when saving
+type lossyStorage struct {
+	*storage.ProductStorageMemory
+}
+
+func (s lossyStorage) SaveProducts(ctx context.Context, products map[int]internalProduct.Product) error {
+	delete(products, 1)
+	return s.ProductStorageMemory.SaveProducts(ctx, products)
+}
+
+// jsonStorage writes products to a json file, returning its storage
+func jsonStorage(t *testing.T, products string) *storage.ProductStorage {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "products.json")
+	require.NoError(t, os.WriteFile(path, []byte(products), 0644))
+	return storage.NewProductStorage(path)
+}
+
+const products = `[
+	{"id":1,"name":"Milk","quantity":10,"code_value":"M1","is_published":true,"expiration":"01/02/2030","price":1.5},
+	{"id":2,"name":"Bread","quantity":5,"code_value":"B1","is_published":false,"expiration":"12/31/2030","price":2}
+]`
+
+// TestMigrate tests the migration of the products between storages.
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success - json file to memory, idempotently", func(t *testing.T) {
+		// arrange
+		source := jsonStorage(t, products)
+		destination := storage.NewProductStorageMemory(nil)
+
+		// act
+		first, errFirst := migration.Migrate(ctx, source, destination, migration.Options{})
+		second, errSecond := migration.Migrate(ctx, source, destination, migration.Options{})
+
+		// assert
+		require.NoError(t, errFirst)
+		require.True(t, first.Written)
+		require.Len(t, first.Added, 2)
+		require.Equal(t, 2, first.Destination)
+		require.NoError(t, errSecond)
+		require.False(t, second.Written)
+		require.Equal(t, 2, second.Unchanged)
+		require.Equal(t, first.Checksum, second.Checksum)
+		migrated, _ := destination.LoadProducts(ctx)
+		require.Equal(t, first.Checksum, migration.Checksum(migrated))
+	})
+
+	t.Run("success - dry run diff, the destination unchanged", func(t *testing.T) {
+		// arrange
+		source := jsonStorage(t, products)
+		destination := storage.NewProductStorageMemory(map[int]internalProduct.Product{
+			2: {Id: 2, Name: "Bread", Quantity: 1, CodeValue: "B1", Expiration: "12/31/2030", Price: 2},
+			3: {Id: 3, Name: "Eggs", Quantity: 12, CodeValue: "E1", Expiration: "12/31/2030", Price: 3},
+		})
+
+		// act
+		report, err := migration.Migrate(ctx, source, destination, migration.Options{DryRun: true})
+
+		// assert
+		require.NoError(t, err)
+		require.False(t, report.Written)
+		require.Equal(t, "Milk", report.Added[0].Name)
+		require.Equal(t, 1, report.Updated[0].Before.Quantity)
+		require.Equal(t, 5, report.Updated[0].After.Quantity)
+		require.Equal(t, "Eggs", report.Extra[0].Name)
+		require.Equal(t, 3, report.Destination)
+		unchanged, _ := destination.LoadProducts(ctx)
+		require.Equal(t, 1, unchanged[2].Quantity)
+	})
+
+	t.Run("success - prune the extra products to a new json file", func(t *testing.T) {
+		// arrange
+		source := storage.NewProductStorageMemory(map[int]internalProduct.Product{1: {Id: 1, Name: "Milk"}})
+		destination := storage.NewProductStorage(filepath.Join(t.TempDir(), "products.json"))
+		_, err := migration.Migrate(ctx, storage.NewProductStorageMemory(map[int]internalProduct.Product{7: {Id: 7}}), destination, migration.Options{})
+		require.NoError(t, err)
+
+		// act
+		report, err := migration.Migrate(ctx, source, destination, migration.Options{Prune: true})
+
+		// assert
+		require.NoError(t, err)
+		require.True(t, report.Pruned)
+		require.Equal(t, 1, report.Destination)
+		migrated, _ := destination.LoadProducts(ctx)
+		require.Len(t, migrated, 1)
+	})
+
+	t.Run("failure - destination losing products", func(t *testing.T) {
+		// arrange
+		source := jsonStorage(t, products)
+		destination := lossyStorage{storage.NewProductStorageMemory(nil)}
+
+		// act
+		_, err := migration.Migrate(ctx, source, destination, migration.Options{})
+
+		// assert
+		require.ErrorIs(t, err, migration.ErrVerification)
+	})
+
+	t.Run("error - invalid source", func(t *testing.T) {
+		// arrange
+		source := jsonStorage(t, "{")
+
+		// act
+		_, err := migration.Migrate(ctx, source, storage.NewProductStorageMemory(nil), migration.Options{})
+
+		// assert
+		require.ErrorIs(t, err, internalProduct.ErrInvalidFile)
+	})
+}
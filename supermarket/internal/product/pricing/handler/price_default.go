@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"supermarket/internal/platform/logging"
+	"supermarket/internal/platform/web/request"
+	"supermarket/internal/platform/web/response"
+	internalProduct "supermarket/internal/product"
+	"supermarket/internal/product/pricing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type PriceHandler struct {
+	Prices         *pricing.Prices
+	ProductService internalProduct.ProductServiceInterface
+	// StrictJSON rejects the json bodies with unknown fields or trailing data
+	StrictJSON bool
+}
+
+// NewPriceHandler returns a new PriceHandler.
+func NewPriceHandler(prices *pricing.Prices, productService internalProduct.ProductServiceInterface) *PriceHandler {
+	return &PriceHandler{
+		Prices:         prices,
+		ProductService: productService,
+	}
+}
+
+// PriceRequestJSON is the body of a request scheduling a price
+type PriceRequestJSON struct {
+	Price       float64   `json:"price"`
+	EffectiveAt time.Time `json:"effective_at"`
+}
+
+// PriceHistoryResponseJSON is the price of a product and its entries
+type PriceHistoryResponseJSON struct {
+	ProductID int `json:"product_id"`
+	// Price is the price effective at the time of the request
+	Price float64 `json:"price"`
+	// Entries are sorted by effective_at, the scheduled ones last
+	Entries []pricing.Entry `json:"entries"`
+}
+
+// GetPricesHandler returns the effective price of a product, its changes and its scheduled prices.
+func (h *PriceHandler) GetPricesHandler(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.product(w, r, "get prices")
+	if !ok {
+		return
+	}
+
+	entries, err := h.Prices.History(r.Context(), product.Id)
+	if err != nil {
+		priceError(w, r, "get prices", err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "prices fetched successfully", PriceHistoryResponseJSON{
+		ProductID: product.Id,
+		Price:     product.Price,
+		Entries:   entries,
+	})
+}
+
+// SchedulePriceHandler schedules a price of a product, taking effect at a future time.
+func (h *PriceHandler) SchedulePriceHandler(w http.ResponseWriter, r *http.Request) {
+	var priceRequest PriceRequestJSON
+	if err := request.JSON(r, &priceRequest, request.Options(h.StrictJSON)...); err != nil {
+		response.RequestError(w, err)
+		return
+	}
+	product, ok := h.product(w, r, "schedule price")
+	if !ok {
+		return
+	}
+
+	entry, err := h.Prices.Schedule(r.Context(), product.Id, priceRequest.Price, priceRequest.EffectiveAt)
+	if err != nil {
+		priceError(w, r, "schedule price", err)
+		return
+	}
+	response.JSON(w, http.StatusCreated, "price scheduled successfully", entry)
+}
+
+// CancelPriceHandler cancels a scheduled price of a product, before it takes effect.
+func (h *PriceHandler) CancelPriceHandler(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.product(w, r, "cancel price")
+	if !ok {
+		return
+	}
+
+	if err := h.Prices.Cancel(r.Context(), product.Id, chi.URLParam(r, "entryId")); err != nil {
+		priceError(w, r, "cancel price", err)
+		return
+	}
+	response.Text(w, http.StatusOK, "scheduled price cancelled successfully")
+}
+
+// product returns the product of the id of the url, responding the error if it is not found
+func (h *PriceHandler) product(w http.ResponseWriter, r *http.Request, op string) (internalProduct.Product, bool) {
+	product, err := h.ProductService.GetProduct(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		priceError(w, r, op, err)
+		return internalProduct.Product{}, false
+	}
+	return product, true
+}
+
+// priceError responds the error of the prices or the product, logging the unexpected ones
+func priceError(w http.ResponseWriter, r *http.Request, op string, err error) {
+	switch {
+	case errors.Is(err, internalProduct.ErrInvalidID):
+		response.Errorw(w, http.StatusBadRequest, err)
+	case errors.Is(err, internalProduct.ErrProductNotFound), errors.Is(err, pricing.ErrEntryNotFound):
+		response.Errorw(w, http.StatusNotFound, err)
+	case errors.Is(err, pricing.ErrEntryEffective):
+		response.Errorw(w, http.StatusConflict, err)
+	case errors.Is(err, pricing.ErrInvalidPrice), errors.Is(err, pricing.ErrInvalidEffectiveAt):
+		response.Errorw(w, http.StatusUnprocessableEntity, err)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		response.Error(w, http.StatusGatewayTimeout, "request timeout")
+	default:
+		logging.FromContext(r.Context()).Error(op, slog.Any("error", err))
+		response.Error(w, http.StatusInternalServerError, "internal server error")
+	}
+}
@@ -0,0 +1,313 @@
+// Package pricing keeps the history of the prices of the products, and the prices scheduled to take
+// effect in the future, writing them to the products once they do.
+package pricing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"supermarket/internal/auth"
+	"supermarket/internal/platform/logging"
+	"supermarket/internal/platform/random"
+	internalProduct "supermarket/internal/product"
+	"sync"
+	"time"
+)
+
+type Product = internalProduct.Product
+
+var (
+	// ErrEntryNotFound is returned when a price entry does not exist.
+	ErrEntryNotFound = errors.New("pricing: price entry not found")
+	// ErrEntryEffective is returned when cancelling a price entry which already took effect.
+	ErrEntryEffective = errors.New("pricing: price entry already effective")
+	// ErrInvalidPrice is returned for a price which is not positive.
+	ErrInvalidPrice = errors.New("pricing: invalid price")
+	// ErrInvalidEffectiveAt is returned when scheduling a price which does not take effect in the future.
+	ErrInvalidEffectiveAt = errors.New("pricing: invalid effective_at")
+)
+
+// Entry is a price of a product, from the time it takes effect until the next entry of the product.
+type Entry struct {
+	// ID identifies the entry
+	ID string `json:"id"`
+	// ProductID is the id of the product
+	ProductID int `json:"product_id"`
+	// Price is the price of the product from EffectiveAt
+	Price float64 `json:"price"`
+	// PreviousPrice is the price replaced by a change of the product, 0 for the scheduled prices
+	PreviousPrice float64 `json:"previous_price,omitempty"`
+	// EffectiveAt is when the price takes effect, RecordedAt for the changes of the product
+	EffectiveAt time.Time `json:"effective_at"`
+	// RecordedAt is when the entry was recorded
+	RecordedAt time.Time `json:"recorded_at"`
+	// Actor is who recorded the entry, empty if unknown
+	Actor string `json:"actor"`
+	// AppliedAt is when a scheduled price was written to the product, nil until then
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// Store keeps the price entries. Implementations must be safe for concurrent use.
+type Store interface {
+	// Add adds an entry
+	Add(ctx context.Context, entry Entry) error
+	// Delete removes an entry of a product, ErrEntryNotFound if it does not exist
+	Delete(ctx context.Context, productID int, id string) error
+	// Update replaces the entry of a product with the id of entry, ErrEntryNotFound if it does not exist
+	Update(ctx context.Context, entry Entry) error
+	// History returns the entries of a product, sorted by EffectiveAt then RecordedAt
+	History(ctx context.Context, productID int) ([]Entry, error)
+	// Entries returns the entries by product, each sorted as by History
+	Entries(ctx context.Context) (map[int][]Entry, error)
+	// Renumber moves the entries of the products to their new ids, ids mapping the old ids to the new ones
+	Renumber(ctx context.Context, ids map[int]int) error
+}
+
+// NewPrices returns the Prices kept in store, arming the timer of the prices scheduled in it.
+func NewPrices(ctx context.Context, store Store) (*Prices, error) {
+	p := &Prices{store: store}
+	if err := p.arm(ctx); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Prices records the changes of the prices, schedules the future ones, and writes them to the products once
+// they take effect.
+type Prices struct {
+	// RetryDelay is the delay before writing again the scheduled prices whose write failed, a minute if 0
+	RetryDelay time.Duration
+
+	store Store
+	// mu guards the timer, the writer and the callbacks
+	mu sync.Mutex
+	// write writes the price of a product, nil until WriteThrough
+	write func(ctx context.Context, id int, price float64) error
+	// timer fires when the next scheduled price takes effect, nil if none or closed
+	timer  *time.Timer
+	closed bool
+	// onEffective are called when a scheduled price takes effect
+	onEffective []func()
+}
+
+// WriteThrough sets write as the writer of the scheduled prices taking effect, e.g. an update of the product
+// service so the change is saved, recorded and published as any other, then writes the ones already effective.
+// Each scheduled price is written once: a restore of the products keeps its prices, and a scheduled price is
+// skipped when the price of the product was changed after it took effect.
+func (p *Prices) WriteThrough(ctx context.Context, write func(ctx context.Context, id int, price float64) error) error {
+	p.mu.Lock()
+	p.write = write
+	p.mu.Unlock()
+	// the prices failing to be written are retried by the timer
+	err := p.writeEffective(ctx)
+	return errors.Join(err, p.arm(ctx))
+}
+
+// OnEffective registers fn to be called when a scheduled price takes effect, e.g. to invalidate the
+// caches of the catalogue.
+func (p *Prices) OnEffective(fn func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onEffective = append(p.onEffective, fn)
+}
+
+// RecordPrice records the change of the price of the product id, made by the actor of ctx.
+func (p *Prices) RecordPrice(ctx context.Context, id int, previous, price float64) error {
+	now := time.Now().UTC()
+	return p.store.Add(ctx, Entry{
+		ID:            random.ID(),
+		ProductID:     id,
+		Price:         price,
+		PreviousPrice: previous,
+		EffectiveAt:   now,
+		RecordedAt:    now,
+		Actor:         auth.ActorFromContext(ctx),
+	})
+}
+
+// Schedule records price as the price of the product id from effectiveAt, which must be in the future.
+func (p *Prices) Schedule(ctx context.Context, id int, price float64, effectiveAt time.Time) (Entry, error) {
+	if price <= 0 {
+		return Entry{}, fmt.Errorf("%w: %v must be positive", ErrInvalidPrice, price)
+	}
+	if effectiveAt.IsZero() {
+		return Entry{}, fmt.Errorf("%w: required", ErrInvalidEffectiveAt)
+	}
+	now := time.Now().UTC()
+	if !effectiveAt.After(now) {
+		return Entry{}, fmt.Errorf("%w: %s must be in the future", ErrInvalidEffectiveAt, effectiveAt.Format(time.RFC3339))
+	}
+
+	entry := Entry{
+		ID:          random.ID(),
+		ProductID:   id,
+		Price:       price,
+		EffectiveAt: effectiveAt.UTC(),
+		RecordedAt:  now,
+		Actor:       auth.ActorFromContext(ctx),
+	}
+	if err := p.store.Add(ctx, entry); err != nil {
+		return Entry{}, err
+	}
+	return entry, p.arm(ctx)
+}
+
+// Cancel removes the scheduled price entryID of the product id, unless it already took effect.
+func (p *Prices) Cancel(ctx context.Context, id int, entryID string) error {
+	history, err := p.store.History(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, entry := range history {
+		if entry.ID != entryID {
+			continue
+		}
+		if !entry.EffectiveAt.After(time.Now()) {
+			return ErrEntryEffective
+		}
+		if err := p.store.Delete(ctx, id, entryID); err != nil {
+			return err
+		}
+		return p.arm(ctx)
+	}
+	return ErrEntryNotFound
+}
+
+// History returns the entries of the product id, the scheduled ones last.
+func (p *Prices) History(ctx context.Context, id int) ([]Entry, error) {
+	return p.store.History(ctx, id)
+}
+
+// writeEffective writes the last scheduled price of each product which took effect and was not written
+// yet, unless the price of the product was changed since, then marks them written. A product deleted
+// since has no price to write. The prices failing to be written are left unwritten, to be retried.
+func (p *Prices) writeEffective(ctx context.Context) error {
+	p.mu.Lock()
+	write := p.write
+	p.mu.Unlock()
+	if write == nil {
+		return nil
+	}
+	entries, err := p.store.Entries(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	var errs []error
+	for id, history := range entries {
+		var effective []Entry
+		changed := false
+		for _, entry := range history {
+			switch {
+			case !scheduled(entry):
+				// the history is sorted, so a change of the product following the scheduled prices replaces them
+				changed = len(effective) > 0
+			case entry.AppliedAt == nil && !entry.EffectiveAt.After(now):
+				effective = append(effective, entry)
+				changed = false
+			}
+		}
+		if len(effective) == 0 {
+			continue
+		}
+		if last := effective[len(effective)-1]; !changed {
+			err := write(auth.WithActor(ctx, last.Actor), id, last.Price)
+			if err != nil && !errors.Is(err, internalProduct.ErrProductNotFound) {
+				errs = append(errs, fmt.Errorf("pricing: write the price %s of product %d: %w", last.ID, id, err))
+				continue
+			}
+		}
+		for _, entry := range effective {
+			entry.AppliedAt = &now
+			if err := p.store.Update(ctx, entry); err != nil {
+				errs = append(errs, fmt.Errorf("pricing: mark the price %s of product %d written: %w", entry.ID, id, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Renumber moves the price history of the products to their new ids, ids mapping the old ids to the new
+// ones. The products missing from ids keep their id.
+func (p *Prices) Renumber(ctx context.Context, ids map[int]int) error {
+	if err := p.store.Renumber(ctx, ids); err != nil {
+		return fmt.Errorf("pricing: renumber the products: %w", err)
+	}
+	return nil
+}
+
+// Close stops the timer of the scheduled prices.
+func (p *Prices) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+}
+
+// arm sets the timer to the next scheduled price, or to the retry of the effective ones not written yet
+func (p *Prices) arm(ctx context.Context) error {
+	entries, err := p.store.Entries(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	retry := p.RetryDelay
+	if retry == 0 {
+		retry = time.Minute
+	}
+	var next time.Time
+	for _, history := range entries {
+		for _, entry := range history {
+			at := entry.EffectiveAt
+			switch {
+			case at.After(now):
+			case scheduled(entry) && entry.AppliedAt == nil:
+				at = now.Add(retry)
+			default:
+				continue
+			}
+			if next.IsZero() || at.Before(next) {
+				next = at
+			}
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	if !next.IsZero() && !p.closed {
+		p.timer = time.AfterFunc(next.Sub(now), p.fire)
+	}
+	return nil
+}
+
+// fire writes the scheduled prices taking effect and calls the OnEffective functions, then sets the timer
+// to the next scheduled price
+func (p *Prices) fire() {
+	ctx := context.Background()
+	if err := p.writeEffective(ctx); err != nil {
+		logging.FromContext(ctx).Error("write scheduled prices", slog.Any("error", err))
+	}
+
+	p.mu.Lock()
+	onEffective := p.onEffective
+	p.mu.Unlock()
+	for _, fn := range onEffective {
+		fn()
+	}
+	// without the entries, the timer is set again by the next schedule
+	p.arm(context.Background())
+}
+
+// scheduled tells if entry was scheduled, rather than recorded by a change of the product
+func scheduled(entry Entry) bool {
+	return entry.EffectiveAt.After(entry.RecordedAt)
+}
@@ -0,0 +1,209 @@
+package pricing_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"supermarket/internal/auth"
+	"supermarket/internal/product/pricing"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestPrices tests the recording, the scheduling and the writing of the prices.
+func TestPrices(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success - a scheduled price taking effect is written once", func(t *testing.T) {
+		// arrange
+		prices, err := pricing.NewPrices(ctx, pricing.NewMemoryStore())
+		require.NoError(t, err)
+		defer prices.Close()
+		written := make(chan float64, 2)
+		require.NoError(t, prices.WriteThrough(ctx, func(ctx context.Context, id int, price float64) error {
+			require.Equal(t, 1, id)
+			require.Equal(t, "marketing", auth.ActorFromContext(ctx))
+			written <- price
+			return nil
+		}))
+		require.NoError(t, prices.RecordPrice(ctx, 1, 1.5, 2))
+		scheduled, err := prices.Schedule(auth.WithActor(ctx, "marketing"), 1, 3, time.Now().Add(50*time.Millisecond))
+		require.NoError(t, err)
+
+		// act
+		var price float64
+		select {
+		case price = <-written:
+		case <-time.After(5 * time.Second):
+			t.Fatal("scheduled price never written")
+		}
+		errAgain := prices.WriteThrough(ctx, func(ctx context.Context, id int, price float64) error {
+			written <- price
+			return nil
+		})
+
+		// assert
+		require.Equal(t, 3.0, price)
+		require.NoError(t, errAgain)
+		require.Empty(t, written)
+		history, err := prices.History(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		require.Equal(t, scheduled.ID, history[1].ID)
+		require.NotNil(t, history[1].AppliedAt)
+	})
+
+	t.Run("success - a scheduled price failing to be written is retried", func(t *testing.T) {
+		// arrange
+		prices, err := pricing.NewPrices(ctx, pricing.NewMemoryStore())
+		require.NoError(t, err)
+		defer prices.Close()
+		prices.RetryDelay = 20 * time.Millisecond
+		attempts := make(chan int, 2)
+		failed := false
+		require.NoError(t, prices.WriteThrough(ctx, func(ctx context.Context, id int, price float64) error {
+			attempts <- id
+			if !failed {
+				failed = true
+				return errors.New("disk full")
+			}
+			return nil
+		}))
+		_, err = prices.Schedule(ctx, 1, 3, time.Now().Add(20*time.Millisecond))
+		require.NoError(t, err)
+
+		// act
+		for range 2 {
+			select {
+			case <-attempts:
+			case <-time.After(5 * time.Second):
+				t.Fatal("scheduled price never retried")
+			}
+		}
+
+		// assert
+		require.Eventually(t, func() bool {
+			history, err := prices.History(ctx, 1)
+			return err == nil && history[0].AppliedAt != nil
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("success - the prices effective before the writer are written, unless changed since", func(t *testing.T) {
+		// arrange
+		prices, err := pricing.NewPrices(ctx, pricing.NewMemoryStore())
+		require.NoError(t, err)
+		defer prices.Close()
+		_, err = prices.Schedule(ctx, 1, 3, time.Now().Add(20*time.Millisecond))
+		require.NoError(t, err)
+		_, err = prices.Schedule(ctx, 2, 4, time.Now().Add(20*time.Millisecond))
+		require.NoError(t, err)
+		time.Sleep(50 * time.Millisecond)
+		require.NoError(t, prices.RecordPrice(ctx, 2, 1, 5))
+
+		// act
+		written := map[int]float64{}
+		err = prices.WriteThrough(ctx, func(ctx context.Context, id int, price float64) error {
+			written[id] = price
+			return nil
+		})
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, map[int]float64{1: 3}, written)
+		history, err := prices.History(ctx, 2)
+		require.NoError(t, err)
+		require.NotNil(t, history[0].AppliedAt)
+	})
+
+	t.Run("success - a scheduled price taking effect calls the OnEffective functions", func(t *testing.T) {
+		// arrange
+		prices, err := pricing.NewPrices(ctx, pricing.NewMemoryStore())
+		require.NoError(t, err)
+		defer prices.Close()
+		effective := make(chan struct{}, 1)
+		prices.OnEffective(func() { effective <- struct{}{} })
+
+		// act
+		_, err = prices.Schedule(ctx, 1, 3, time.Now().Add(50*time.Millisecond))
+
+		// assert
+		require.NoError(t, err)
+		select {
+		case <-effective:
+		case <-time.After(5 * time.Second):
+			t.Fatal("scheduled price never took effect")
+		}
+	})
+
+	t.Run("success - the file store keeps the entries and the timer across restarts", func(t *testing.T) {
+		// arrange
+		filename := filepath.Join(t.TempDir(), "prices.json")
+		store, err := pricing.NewFileStore(filename)
+		require.NoError(t, err)
+		prices, err := pricing.NewPrices(ctx, store)
+		require.NoError(t, err)
+		_, err = prices.Schedule(ctx, 1, 3, time.Now().Add(100*time.Millisecond))
+		require.NoError(t, err)
+		prices.Close()
+
+		// act
+		reopened, err := pricing.NewFileStore(filename)
+		require.NoError(t, err)
+		restarted, err := pricing.NewPrices(ctx, reopened)
+		require.NoError(t, err)
+		defer restarted.Close()
+		effective := make(chan struct{}, 1)
+		restarted.OnEffective(func() { effective <- struct{}{} })
+
+		// assert
+		history, err := restarted.History(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		select {
+		case <-effective:
+		case <-time.After(5 * time.Second):
+			t.Fatal("scheduled price never took effect")
+		}
+	})
+
+	t.Run("failure - cancel a price already effective", func(t *testing.T) {
+		// arrange
+		prices, err := pricing.NewPrices(ctx, pricing.NewMemoryStore())
+		require.NoError(t, err)
+		defer prices.Close()
+		require.NoError(t, prices.RecordPrice(ctx, 1, 0, 1.5))
+		scheduled, err := prices.Schedule(ctx, 1, 3, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		history, err := prices.History(ctx, 1)
+		require.NoError(t, err)
+
+		// act
+		errEffective := prices.Cancel(ctx, 1, history[0].ID)
+		errCancel := prices.Cancel(ctx, 1, scheduled.ID)
+		errNotFound := prices.Cancel(ctx, 1, scheduled.ID)
+
+		// assert
+		require.ErrorIs(t, errEffective, pricing.ErrEntryEffective)
+		require.NoError(t, errCancel)
+		require.ErrorIs(t, errNotFound, pricing.ErrEntryNotFound)
+	})
+
+	t.Run("error - invalid scheduled price", func(t *testing.T) {
+		// arrange
+		prices, err := pricing.NewPrices(ctx, pricing.NewMemoryStore())
+		require.NoError(t, err)
+		defer prices.Close()
+
+		// act
+		_, errPrice := prices.Schedule(ctx, 1, 0, time.Now().Add(time.Hour))
+		_, errPast := prices.Schedule(ctx, 1, 3, time.Now().Add(-time.Hour))
+		_, errMissing := prices.Schedule(ctx, 1, 3, time.Time{})
+
+		// assert
+		require.ErrorIs(t, errPrice, pricing.ErrInvalidPrice)
+		require.ErrorIs(t, errPast, pricing.ErrInvalidEffectiveAt)
+		require.ErrorIs(t, errMissing, pricing.ErrInvalidEffectiveAt)
+	})
+}
@@ -0,0 +1,275 @@
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// NewMemoryStore creates a Store keeping the price entries in memory.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[int][]Entry),
+	}
+}
+
+// MemoryStore is a Store local to the process
+type MemoryStore struct {
+	mu sync.RWMutex
+	// entries are the entries by product, each sorted as by History
+	entries map[int][]Entry
+}
+
+// Add adds an entry.
+func (s *MemoryStore) Add(ctx context.Context, entry Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.add(entry)
+	return nil
+}
+
+// add implements Add, with the lock held
+func (s *MemoryStore) add(entry Entry) {
+	history := append(s.entries[entry.ProductID], entry)
+	sortHistory(history)
+	s.entries[entry.ProductID] = history
+}
+
+// Delete removes an entry of a product.
+func (s *MemoryStore) Delete(ctx context.Context, productID int, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.delete(productID, id)
+	return err
+}
+
+// delete implements Delete, with the lock held, returning the removed entry
+func (s *MemoryStore) delete(productID int, id string) (Entry, error) {
+	history := s.entries[productID]
+	for i, entry := range history {
+		if entry.ID != id {
+			continue
+		}
+		history = append(history[:i:i], history[i+1:]...)
+		if len(history) == 0 {
+			delete(s.entries, productID)
+		} else {
+			s.entries[productID] = history
+		}
+		return entry, nil
+	}
+	return Entry{}, ErrEntryNotFound
+}
+
+// Update replaces the entry of a product with the id of entry.
+func (s *MemoryStore) Update(ctx context.Context, entry Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.update(entry)
+	return err
+}
+
+// update implements Update, with the lock held, returning the replaced entry
+func (s *MemoryStore) update(entry Entry) (Entry, error) {
+	history := s.entries[entry.ProductID]
+	for i, previous := range history {
+		if previous.ID == entry.ID {
+			history[i] = entry
+			sortHistory(history)
+			return previous, nil
+		}
+	}
+	return Entry{}, ErrEntryNotFound
+}
+
+// History returns a copy of the entries of a product.
+func (s *MemoryStore) History(ctx context.Context, productID int) ([]Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Entry{}, s.entries[productID]...), nil
+}
+
+// Entries returns a copy of the entries by product.
+func (s *MemoryStore) Entries(ctx context.Context) (map[int][]Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries := make(map[int][]Entry, len(s.entries))
+	for productID, history := range s.entries {
+		entries[productID] = append([]Entry{}, history...)
+	}
+	return entries, nil
+}
+
+// Renumber moves the entries of the products to their new ids.
+func (s *MemoryStore) Renumber(ctx context.Context, ids map[int]int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = renumber(s.entries, ids)
+	return nil
+}
+
+// NewFileStore creates a Store persisting the price entries to a json file. The content of the file is
+// loaded if it exists.
+func NewFileStore(filename string) (*FileStore, error) {
+	s := &FileStore{MemoryStore: NewMemoryStore(), filename: filename}
+
+	data, err := os.ReadFile(filename)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return s, nil
+	case err != nil:
+		return nil, fmt.Errorf("pricing: read %s: %w", filename, err)
+	}
+	var entries map[int][]Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("pricing: decode %s: %w", filename, err)
+	}
+	for _, history := range entries {
+		for _, entry := range history {
+			s.add(entry)
+		}
+	}
+	return s, nil
+}
+
+// FileStore is a MemoryStore saved to a json file on every change
+type FileStore struct {
+	*MemoryStore
+	filename string
+}
+
+// Add adds an entry, unless it can not be saved.
+func (s *FileStore) Add(ctx context.Context, entry Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.add(entry)
+	if err := s.save(); err != nil {
+		s.delete(entry.ProductID, entry.ID)
+		return err
+	}
+	return nil
+}
+
+// Delete removes an entry of a product, unless it can not be saved.
+func (s *FileStore) Delete(ctx context.Context, productID int, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, err := s.delete(productID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.save(); err != nil {
+		s.add(entry)
+		return err
+	}
+	return nil
+}
+
+// Update replaces the entry of a product with the id of entry, unless it can not be saved.
+func (s *FileStore) Update(ctx context.Context, entry Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, err := s.update(entry)
+	if err != nil {
+		return err
+	}
+	if err := s.save(); err != nil {
+		s.update(previous)
+		return err
+	}
+	return nil
+}
+
+// Renumber moves the entries of the products to their new ids, unless they can not be saved.
+func (s *FileStore) Renumber(ctx context.Context, ids map[int]int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := s.entries
+	s.entries = renumber(entries, ids)
+	if err := s.save(); err != nil {
+		s.entries = entries
+		return err
+	}
+	return nil
+}
+
+// save writes the entries to a temporary file renamed over the file, so a crash never leaves it truncated
+func (s *FileStore) save() error {
+	data, err := json.Marshal(s.entries)
+	if err != nil {
+		return fmt.Errorf("pricing: encode: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(s.filename), filepath.Base(s.filename)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("pricing: save: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("pricing: save: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("pricing: save: %w", err)
+	}
+	if err := os.Rename(file.Name(), s.filename); err != nil {
+		return fmt.Errorf("pricing: save: %w", err)
+	}
+	return nil
+}
+
+// renumber returns entries with the product ids of ids changed to their new ids
+func renumber(entries map[int][]Entry, ids map[int]int) map[int][]Entry {
+	renumbered := make(map[int][]Entry, len(entries))
+	for productID, history := range entries {
+		if id, ok := ids[productID]; ok {
+			productID = id
+		}
+		moved := make([]Entry, len(history))
+		for i, entry := range history {
+			entry.ProductID = productID
+			moved[i] = entry
+		}
+		renumbered[productID] = moved
+	}
+	return renumbered
+}
+
+// sortHistory sorts the entries of a product by EffectiveAt then RecordedAt
+func sortHistory(history []Entry) {
+	sort.SliceStable(history, func(i, j int) bool {
+		if !history[i].EffectiveAt.Equal(history[j].EffectiveAt) {
+			return history[i].EffectiveAt.Before(history[j].EffectiveAt)
+		}
+		return history[i].RecordedAt.Before(history[j].RecordedAt)
+	})
+}
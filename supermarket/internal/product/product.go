@@ -1,5 +1,14 @@
 package product
 
+import "time"
+
+const (
+	// ExpirationLayout is the layout of Product.Expiration, MM/DD/YYYY
+	ExpirationLayout = "01/02/2006"
+	// LegacyExpirationLayout is the DD/MM/YYYY layout of the expirations of the shipped catalogue
+	LegacyExpirationLayout = "02/01/2006"
+)
+
 type Product struct {
 	Id          int     `json:"id"`
 	Name        string  `json:"name"`
@@ -9,3 +18,17 @@ type Product struct {
 	Expiration  string  `json:"expiration"`
 	Price       float64 `json:"price"`
 }
+
+// IsExpired returns true if the expiration date of the product is before now. The expirations invalid as
+// MM/DD/YYYY are read as DD/MM/YYYY, the dates valid in both layouts being read as MM/DD/YYYY.
+// Products with an expiration invalid in both layouts are not considered expired.
+func (p Product) IsExpired(now time.Time) bool {
+	expiration, err := time.Parse(ExpirationLayout, p.Expiration)
+	if err != nil {
+		expiration, err = time.Parse(LegacyExpirationLayout, p.Expiration)
+	}
+	if err != nil {
+		return false
+	}
+	return expiration.Before(now)
+}
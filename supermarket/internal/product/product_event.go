@@ -0,0 +1,38 @@
+package product
+
+import (
+	"context"
+	"time"
+)
+
+// Types of the events of the catalogue
+const (
+	// EventProductCreated is published when a product is created
+	EventProductCreated = "product.created"
+	// EventProductUpdated is published when a product is updated
+	EventProductUpdated = "product.updated"
+	// EventProductDeleted is published when a product is deleted
+	EventProductDeleted = "product.deleted"
+	// EventStockChanged is published, after EventProductUpdated, when the quantity of a product changes
+	EventStockChanged = "product.stock_changed"
+)
+
+// Event is a change of the catalogue.
+type Event struct {
+	// ID identifies the event, set by the publisher
+	ID string `json:"id"`
+	// Type is one of the Event* constants
+	Type string `json:"type"`
+	// Time is when the change happened
+	Time time.Time `json:"time"`
+	// Product is the product after the change, or before it when deleted
+	Product Product `json:"product"`
+	// PreviousQuantity is the quantity before the change, for EventStockChanged
+	PreviousQuantity *int `json:"previous_quantity,omitempty"`
+}
+
+// EventPublisher publishes the events of the catalogue.
+type EventPublisher interface {
+	// Publish publishes event, setting its id. It does not block on the subscribers.
+	Publish(ctx context.Context, event Event)
+}
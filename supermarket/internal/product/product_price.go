@@ -0,0 +1,10 @@
+package product
+
+import "context"
+
+// PriceRecorder records the changes of the prices of the products.
+type PriceRecorder interface {
+	// RecordPrice records the change of the price of the product id from previous, 0 for a new product,
+	// to price, effective from now on
+	RecordPrice(ctx context.Context, id int, previous, price float64) error
+}
@@ -1,6 +1,9 @@
 package product
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 
 var (
 	ErrLoadProducts = errors.New("failed to load products")
@@ -8,12 +11,18 @@ var (
 )
 
 type ProductRepositoryInterface interface {
-	Get() ([]Product, error)
-	GetById(id int) (Product, error)
-	SearchByPrice(priceGt float64) ([]Product, error)
-	Save(product Product) (Product, error)
-	SaveOrUpdate(product Product) (Product, error)
-	Update(product Product) (Product, error)
-	Delete(id int) error
-	GetConsumerPriceProducts(ids []string) (ConsumerPriceProducts, error)
+	Get(ctx context.Context) ([]Product, error)
+	GetById(ctx context.Context, id int) (Product, error)
+	SearchByPrice(ctx context.Context, priceGt float64) ([]Product, error)
+	Save(ctx context.Context, product Product) (Product, error)
+	// SaveOrUpdate returns the saved product and the one it replaced, zero if it was created
+	SaveOrUpdate(ctx context.Context, product Product) (saved Product, previous Product, err error)
+	// Update returns the updated product and the one it replaced
+	Update(ctx context.Context, product Product) (updated Product, previous Product, err error)
+	// Change replaces the product id with the result of change, applied to it in the same write, and
+	// returns the changed product and the one it replaced
+	Change(ctx context.Context, id int, change func(product Product) Product) (changed Product, previous Product, err error)
+	// Delete returns the deleted product
+	Delete(ctx context.Context, id int) (Product, error)
+	GetConsumerPriceProducts(ctx context.Context, ids []string) (ConsumerPriceProducts, error)
 }
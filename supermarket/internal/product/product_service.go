@@ -1,6 +1,9 @@
 package product
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 
 var (
 	ErrInvalidID            = errors.New("invalid id")
@@ -9,6 +12,7 @@ var (
 	ErrInvalidProduct       = errors.New("invalid product parameters")
 	ErrDuplicateCodeValue   = errors.New("duplicated code value")
 	ErrInsufficientQuantity = errors.New("insufficient quantity of product")
+	ErrStoreNotFound        = errors.New("store not found")
 )
 
 type ConsumerPriceProducts struct {
@@ -16,13 +20,36 @@ type ConsumerPriceProducts struct {
 	TotalPrice float64   `json:"total_price"`
 }
 
+// NewConsumerPriceProducts returns the products and their total price, taxed by 21% below 10 products,
+// 17% up to 20 products and 15% beyond.
+func NewConsumerPriceProducts(products []Product) ConsumerPriceProducts {
+	consumerProducts := ConsumerPriceProducts{
+		Products:   products,
+		TotalPrice: 0,
+	}
+	for _, product := range products {
+		consumerProducts.TotalPrice += product.Price
+	}
+
+	totalProducts := len(consumerProducts.Products)
+	switch {
+	case totalProducts < 10:
+		consumerProducts.TotalPrice *= 1.21
+	case totalProducts <= 20:
+		consumerProducts.TotalPrice *= 1.17
+	default:
+		consumerProducts.TotalPrice *= 1.15
+	}
+	return consumerProducts
+}
+
 type ProductServiceInterface interface {
-	GetProducts() ([]Product, error)
-	GetProduct(id string) (Product, error)
-	SearchProductsByPrice(priceGt string) ([]Product, error)
-	CreateProduct(product Product) (Product, error)
-	UpdateOrCreateProduct(product Product) (Product, error)
-	UpdateProduct(product Product) (Product, error)
-	DeleteProduct(id string) error
-	GetConsumerPriceProducts(ids []string) (ConsumerPriceProducts, error)
+	GetProducts(ctx context.Context) ([]Product, error)
+	GetProduct(ctx context.Context, id string) (Product, error)
+	SearchProductsByPrice(ctx context.Context, priceGt string) ([]Product, error)
+	CreateProduct(ctx context.Context, product Product) (Product, error)
+	UpdateOrCreateProduct(ctx context.Context, product Product) (Product, error)
+	UpdateProduct(ctx context.Context, product Product) (Product, error)
+	DeleteProduct(ctx context.Context, id string) error
+	GetConsumerPriceProducts(ctx context.Context, ids []string) (ConsumerPriceProducts, error)
 }
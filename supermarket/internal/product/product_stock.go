@@ -0,0 +1,9 @@
+package product
+
+import "context"
+
+// StockCounter counts the stock of the products kept by the stores.
+type StockCounter interface {
+	// CountStock returns the stock of the product id summed over the stores, ok false if no store keeps it
+	CountStock(ctx context.Context, id int) (quantity int, ok bool, err error)
+}
@@ -1,6 +1,7 @@
 package product
 
 import (
+	"context"
 	"errors"
 )
 
@@ -11,6 +12,6 @@ var (
 )
 
 type ProductStorageInterface interface {
-	LoadProducts() (map[int]Product, error)
-	SaveProducts(products map[int]Product) error
+	LoadProducts(ctx context.Context) (map[int]Product, error)
+	SaveProducts(ctx context.Context, products map[int]Product) error
 }
@@ -0,0 +1,51 @@
+package product_test
+
+import (
+	internalProduct "supermarket/internal/product"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestProductIsExpired tests the expiration of the products in both layouts.
+func TestProductIsExpired(t *testing.T) {
+	now := time.Date(2030, time.March, 15, 0, 0, 0, 0, time.UTC)
+
+	t.Run("success - expirations as MM/DD/YYYY", func(t *testing.T) {
+		// arrange
+		past := internalProduct.Product{Expiration: "03/14/2030"}
+		future := internalProduct.Product{Expiration: "03/16/2030"}
+
+		// act
+		pastExpired, futureExpired := past.IsExpired(now), future.IsExpired(now)
+
+		// assert
+		require.True(t, pastExpired)
+		require.False(t, futureExpired)
+	})
+
+	t.Run("success - expirations as DD/MM/YYYY", func(t *testing.T) {
+		// arrange
+		past := internalProduct.Product{Expiration: "14/03/2030"}
+		future := internalProduct.Product{Expiration: "16/03/2030"}
+
+		// act
+		pastExpired, futureExpired := past.IsExpired(now), future.IsExpired(now)
+
+		// assert
+		require.True(t, pastExpired)
+		require.False(t, futureExpired)
+	})
+
+	t.Run("failure - invalid expiration not expired", func(t *testing.T) {
+		// arrange
+		product := internalProduct.Product{Expiration: "2030-03-14"}
+
+		// act
+		expired := product.IsExpired(now)
+
+		// assert
+		require.False(t, expired)
+	})
+}
@@ -1,8 +1,12 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"strconv"
+	"supermarket/internal/platform/tracing"
 	internalProduct "supermarket/internal/product"
+	"sync"
 )
 
 type Product = internalProduct.Product
@@ -11,19 +15,27 @@ type ProductRepository struct {
 	Storage  internalProduct.ProductStorageInterface
 	Products map[int]Product
 	LastId   int
+	// Mu is held by the operations, which load the products into Products, so a write saves what it loaded and
+	// concurrent writes are not lost. The other writers of Storage, e.g. the restores of the snapshots, share it.
+	Mu *sync.Mutex
 }
 
 // NewProductRepository creates a new ProductRepository.
 func NewProductRepository(storage internalProduct.ProductStorageInterface) *ProductRepository {
 	return &ProductRepository{
 		Storage: storage,
+		Mu:      &sync.Mutex{},
 	}
 }
 
 // LoadProducts loads products from storage to the repository.
-func (pr *ProductRepository) LoadProducts() error {
-	products, err := pr.Storage.LoadProducts()
-	if err != nil {
+func (pr *ProductRepository) LoadProducts(ctx context.Context) error {
+	products, err := pr.Storage.LoadProducts(ctx)
+	switch {
+	case errors.Is(err, internalProduct.ErrFileNotFound):
+		// the catalogue is empty until its first save creates the file
+		products = make(map[int]Product)
+	case err != nil:
 		return err
 	}
 	pr.Products = products
@@ -40,13 +52,28 @@ func (pr *ProductRepository) LoadProducts() error {
 }
 
 // SaveProducts saves products from the repository to storage.
-func (pr *ProductRepository) SaveProducts() error {
-	return pr.Storage.SaveProducts(pr.Products)
+func (pr *ProductRepository) SaveProducts(ctx context.Context) error {
+	return pr.Storage.SaveProducts(ctx, pr.Products)
 }
 
 // Get returns all products from the repository.
-func (pr *ProductRepository) Get() ([]Product, error) {
-	err := pr.LoadProducts()
+func (pr *ProductRepository) Get(ctx context.Context) ([]Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductRepository.Get")
+	defer span.End()
+
+	pr.Mu.Lock()
+	defer pr.Mu.Unlock()
+	products, err := pr.get(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return products, nil
+}
+
+// get implements Get, with the lock held
+func (pr *ProductRepository) get(ctx context.Context) ([]Product, error) {
+	err := pr.LoadProducts(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -58,8 +85,25 @@ func (pr *ProductRepository) Get() ([]Product, error) {
 }
 
 // GetById returns a product from the repository by id.
-func (pr *ProductRepository) GetById(id int) (Product, error) {
-	pr.LoadProducts()
+func (pr *ProductRepository) GetById(ctx context.Context, id int) (Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductRepository.GetById", tracing.WithAttributes(tracing.Int("product.id", id)))
+	defer span.End()
+
+	pr.Mu.Lock()
+	defer pr.Mu.Unlock()
+	product, err := pr.getById(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		return Product{}, err
+	}
+	return product, nil
+}
+
+// getById implements GetById, with the lock held
+func (pr *ProductRepository) getById(ctx context.Context, id int) (Product, error) {
+	if err := pr.LoadProducts(ctx); err != nil {
+		return Product{}, err
+	}
 	product, ok := pr.Products[id]
 	if !ok {
 		return Product{}, internalProduct.ErrProductNotFound
@@ -68,10 +112,13 @@ func (pr *ProductRepository) GetById(id int) (Product, error) {
 }
 
 // SearchByPrice returns the products from the repository that have a price greater than priceGt.
-func (pr *ProductRepository) SearchByPrice(priceGt float64) ([]Product, error) {
-	pr.LoadProducts()
-	products, err := pr.Get()
+func (pr *ProductRepository) SearchByPrice(ctx context.Context, priceGt float64) ([]Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductRepository.SearchByPrice", tracing.WithAttributes(tracing.Float64("price_gt", priceGt)))
+	defer span.End()
+
+	products, err := pr.Get(ctx)
 	if err != nil {
+		span.RecordError(err)
 		return nil, err
 	}
 	var filteredProducts []Product
@@ -84,98 +131,183 @@ func (pr *ProductRepository) SearchByPrice(priceGt float64) ([]Product, error) {
 }
 
 // Save adds a product to the repository.
-func (pr *ProductRepository) Save(product Product) (Product, error) {
-	pr.LoadProducts()
+func (pr *ProductRepository) Save(ctx context.Context, product Product) (Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductRepository.Save")
+	defer span.End()
+
+	pr.Mu.Lock()
+	defer pr.Mu.Unlock()
+	if err := pr.LoadProducts(ctx); err != nil {
+		span.RecordError(err)
+		return Product{}, err
+	}
+	product = pr.save(product)
+	span.SetAttributes(tracing.Int("product.id", product.Id))
+	if err := pr.SaveProducts(ctx); err != nil {
+		span.RecordError(err)
+		return Product{}, err
+	}
+	return product, nil
+}
+
+// save adds product to the loaded products with the next id, with the lock held
+func (pr *ProductRepository) save(product Product) Product {
 	pr.LastId++
 	product.Id = pr.LastId
 	pr.Products[product.Id] = product
-	pr.SaveProducts()
-	return product, nil
+	return product
 }
 
-// SaveOrUpdate updates a product in the repository or creates it if it doesn't exist.
-func (pr *ProductRepository) SaveOrUpdate(product Product) (Product, error) {
-	pr.LoadProducts()
-	_, ok := pr.Products[product.Id]
-	if !ok {
-		return pr.Save(product)
+// SaveOrUpdate updates a product in the repository or creates it if it doesn't exist. The replaced product is
+// returned, zero if the product was created.
+func (pr *ProductRepository) SaveOrUpdate(ctx context.Context, product Product) (Product, Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductRepository.SaveOrUpdate", tracing.WithAttributes(tracing.Int("product.id", product.Id)))
+	defer span.End()
+
+	pr.Mu.Lock()
+	defer pr.Mu.Unlock()
+	if err := pr.LoadProducts(ctx); err != nil {
+		span.RecordError(err)
+		return Product{}, Product{}, err
 	}
-	pr.Products[product.Id] = product
-	pr.SaveProducts()
-	return product, nil
+	previous, ok := pr.Products[product.Id]
+	if ok {
+		pr.Products[product.Id] = product
+	} else {
+		product = pr.save(product)
+	}
+	if err := pr.SaveProducts(ctx); err != nil {
+		span.RecordError(err)
+		return Product{}, Product{}, err
+	}
+	return product, previous, nil
 }
 
-// Update updates a product in the repository.
-func (pr *ProductRepository) Update(product Product) (Product, error) {
-	pr.LoadProducts()
-	_, ok := pr.Products[product.Id]
+// Update updates a product in the repository, returning the replaced product.
+func (pr *ProductRepository) Update(ctx context.Context, product Product) (Product, Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductRepository.Update", tracing.WithAttributes(tracing.Int("product.id", product.Id)))
+	defer span.End()
+
+	pr.Mu.Lock()
+	defer pr.Mu.Unlock()
+	updated, previous, err := pr.change(ctx, product.Id, func(Product) Product { return product })
+	if err != nil {
+		span.RecordError(err)
+		return Product{}, Product{}, err
+	}
+	return updated, previous, nil
+}
+
+// Change replaces a product of the repository with the result of change applied to it, returning the changed
+// and the replaced products. An unchanged product is not saved.
+func (pr *ProductRepository) Change(ctx context.Context, id int, change func(product Product) Product) (Product, Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductRepository.Change", tracing.WithAttributes(tracing.Int("product.id", id)))
+	defer span.End()
+
+	pr.Mu.Lock()
+	defer pr.Mu.Unlock()
+	changed, previous, err := pr.change(ctx, id, change)
+	if err != nil {
+		span.RecordError(err)
+		return Product{}, Product{}, err
+	}
+	return changed, previous, nil
+}
+
+// change implements Change, with the lock held
+func (pr *ProductRepository) change(ctx context.Context, id int, change func(product Product) Product) (Product, Product, error) {
+	if err := pr.LoadProducts(ctx); err != nil {
+		return Product{}, Product{}, err
+	}
+	previous, ok := pr.Products[id]
 	if !ok {
-		return Product{}, internalProduct.ErrProductNotFound
+		return Product{}, Product{}, internalProduct.ErrProductNotFound
 	}
-	pr.Products[product.Id] = product
-	pr.SaveProducts()
-	return product, nil
+	product := change(previous)
+	product.Id = id
+	if product == previous {
+		return product, previous, nil
+	}
+	pr.Products[id] = product
+	if err := pr.SaveProducts(ctx); err != nil {
+		return Product{}, Product{}, err
+	}
+	return product, previous, nil
 }
 
-// Delete deletes a product from the repository by id.
-func (pr *ProductRepository) Delete(id int) error {
-	pr.LoadProducts()
-	_, ok := pr.Products[id]
+// Delete deletes a product from the repository by id, returning it.
+func (pr *ProductRepository) Delete(ctx context.Context, id int) (Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductRepository.Delete", tracing.WithAttributes(tracing.Int("product.id", id)))
+	defer span.End()
+
+	pr.Mu.Lock()
+	defer pr.Mu.Unlock()
+	if err := pr.LoadProducts(ctx); err != nil {
+		span.RecordError(err)
+		return Product{}, err
+	}
+	product, ok := pr.Products[id]
 	if !ok {
-		return internalProduct.ErrProductNotFound
+		span.RecordError(internalProduct.ErrProductNotFound)
+		return Product{}, internalProduct.ErrProductNotFound
 	}
 	delete(pr.Products, id)
-	pr.SaveProducts()
-	return nil
+	if err := pr.SaveProducts(ctx); err != nil {
+		span.RecordError(err)
+		return Product{}, err
+	}
+	return product, nil
 }
 
 // GetConsumerPriceProducts receives a list of ids and returns those products and the total price.
-func (pr *ProductRepository) GetConsumerPriceProducts(ids []string) (internalProduct.ConsumerPriceProducts, error) {
+func (pr *ProductRepository) GetConsumerPriceProducts(ctx context.Context, ids []string) (internalProduct.ConsumerPriceProducts, error) {
+	ctx, span := tracing.Start(ctx, "ProductRepository.GetConsumerPriceProducts", tracing.WithAttributes(tracing.Int("ids.count", len(ids))))
+	defer span.End()
+
 	consumerProducts := internalProduct.ConsumerPriceProducts{
 		Products:   []Product{},
 		TotalPrice: 0,
 	}
+	selected := []Product{}
 
+	pr.Mu.Lock()
+	defer pr.Mu.Unlock()
 	if ids[0] == "" {
-		products, err := pr.Get()
+		products, err := pr.get(ctx)
 		if err != nil {
+			span.RecordError(err)
 			return consumerProducts, err
 		}
-		for _, product := range products {
-			consumerProducts.TotalPrice += product.Price
-			consumerProducts.Products = append(consumerProducts.Products, product)
-		}
+		selected = append(selected, products...)
 	} else {
 		quantityMap := make(map[string]int)
 		for _, id := range ids {
+			// stop as soon as the client is gone or the deadline is exceeded
+			if err := ctx.Err(); err != nil {
+				span.RecordError(err)
+				return consumerProducts, err
+			}
+
 			productId, err := strconv.Atoi(id)
 			if err != nil {
+				span.RecordError(internalProduct.ErrInvalidID)
 				return consumerProducts, internalProduct.ErrInvalidID
 			}
 
-			product, err := pr.GetById(productId)
+			product, err := pr.getById(ctx, productId)
 			if err != nil {
-				return consumerProducts, internalProduct.ErrProductNotFound
+				span.RecordError(err)
+				return consumerProducts, err
 			}
 
 			quantityMap[id]++
 			if quantityMap[id] > product.Quantity {
+				span.RecordError(internalProduct.ErrInsufficientQuantity)
 				return consumerProducts, internalProduct.ErrInsufficientQuantity
 			}
-			consumerProducts.TotalPrice += product.Price
-			consumerProducts.Products = append(consumerProducts.Products, product)
+			selected = append(selected, product)
 		}
 	}
 
-	totalProducts := len(consumerProducts.Products)
-	switch {
-	case totalProducts < 10:
-		consumerProducts.TotalPrice *= 1.21
-	case totalProducts <= 20:
-		consumerProducts.TotalPrice *= 1.17
-	default:
-		consumerProducts.TotalPrice *= 1.15
-	}
-
-	return consumerProducts, nil
+	return internalProduct.NewConsumerPriceProducts(selected), nil
 }
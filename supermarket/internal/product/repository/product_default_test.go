@@ -0,0 +1,163 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	internalProduct "supermarket/internal/product"
+	"supermarket/internal/product/repository"
+	"supermarket/internal/product/storage"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// failingStorage is a storage failing its loads with errLoad and its saves with errSave, when not nil
+type failingStorage struct {
+	internalProduct.ProductStorageInterface
+	errLoad error
+	errSave error
+}
+
+func (s *failingStorage) LoadProducts(ctx context.Context) (map[int]internalProduct.Product, error) {
+	if s.errLoad != nil {
+		return nil, s.errLoad
+	}
+	return s.ProductStorageInterface.LoadProducts(ctx)
+}
+
+func (s *failingStorage) SaveProducts(ctx context.Context, products map[int]internalProduct.Product) error {
+	if s.errSave != nil {
+		return s.errSave
+	}
+	return s.ProductStorageInterface.SaveProducts(ctx, products)
+}
+
+// TestProductRepository tests the errors of the storage through the repository.
+func TestProductRepository(t *testing.T) {
+	ctx := context.Background()
+	milk := internalProduct.Product{Id: 1, Name: "Milk", Quantity: 10, CodeValue: "M1", Expiration: "01/02/2030", Price: 1.5}
+
+	t.Run("success - a missing file is an empty catalogue", func(t *testing.T) {
+		// arrange
+		st := &failingStorage{ProductStorageInterface: storage.NewProductStorageMemory(nil), errLoad: internalProduct.ErrFileNotFound}
+		rp := repository.NewProductRepository(st)
+
+		// act
+		products, errGet := rp.Get(ctx)
+		st.errLoad = nil
+		saved, errSave := rp.Save(ctx, milk)
+
+		// assert
+		require.NoError(t, errGet)
+		require.Empty(t, products)
+		require.NoError(t, errSave)
+		require.Equal(t, 1, saved.Id)
+	})
+
+	t.Run("error - the writes return the errors of the save", func(t *testing.T) {
+		// arrange
+		errSave := errors.New("disk full")
+		st := &failingStorage{ProductStorageInterface: storage.NewProductStorageMemory(map[int]internalProduct.Product{1: milk}), errSave: errSave}
+		rp := repository.NewProductRepository(st)
+
+		cheaper := milk
+		cheaper.Price = 1.2
+
+		// act
+		_, errCreate := rp.Save(ctx, milk)
+		_, _, errUpdate := rp.Update(ctx, cheaper)
+		_, _, errUpsert := rp.SaveOrUpdate(ctx, cheaper)
+		_, errDelete := rp.Delete(ctx, milk.Id)
+
+		// assert
+		require.ErrorIs(t, errCreate, errSave)
+		require.ErrorIs(t, errUpdate, errSave)
+		require.ErrorIs(t, errUpsert, errSave)
+		require.ErrorIs(t, errDelete, errSave)
+	})
+
+	t.Run("error - the reads and the writes return the errors of the load", func(t *testing.T) {
+		// arrange
+		st := &failingStorage{ProductStorageInterface: storage.NewProductStorageMemory(map[int]internalProduct.Product{1: milk}), errLoad: internalProduct.ErrInvalidFile}
+		rp := repository.NewProductRepository(st)
+
+		// act
+		_, errGet := rp.GetById(ctx, milk.Id)
+		_, errSearch := rp.SearchByPrice(ctx, 0)
+		_, errPrice := rp.GetConsumerPriceProducts(ctx, []string{"1"})
+		_, errCreate := rp.Save(ctx, milk)
+		_, errDelete := rp.Delete(ctx, milk.Id)
+
+		// assert
+		require.ErrorIs(t, errGet, internalProduct.ErrInvalidFile)
+		require.ErrorIs(t, errSearch, internalProduct.ErrInvalidFile)
+		require.ErrorIs(t, errPrice, internalProduct.ErrInvalidFile)
+		require.ErrorIs(t, errCreate, internalProduct.ErrInvalidFile)
+		require.ErrorIs(t, errDelete, internalProduct.ErrInvalidFile)
+	})
+
+	t.Run("success - concurrent writes are all saved", func(t *testing.T) {
+		// arrange
+		st := storage.NewProductStorage(filepath.Join(t.TempDir(), "products.json"))
+		require.NoError(t, st.SaveProducts(ctx, map[int]internalProduct.Product{1: milk}))
+		rp := repository.NewProductRepository(st)
+		var wg sync.WaitGroup
+
+		// act
+		for range 100 {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				_, err := rp.Save(ctx, milk)
+				require.NoError(t, err)
+			}()
+			go func() {
+				defer wg.Done()
+				_, err := rp.GetById(ctx, 1)
+				require.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		// assert
+		products, err := st.LoadProducts(ctx)
+		require.NoError(t, err)
+		require.Len(t, products, 101)
+	})
+
+	t.Run("success - the writes return the replaced product, concurrent changes all applied", func(t *testing.T) {
+		// arrange
+		st := storage.NewProductStorage(filepath.Join(t.TempDir(), "products.json"))
+		require.NoError(t, st.SaveProducts(ctx, map[int]internalProduct.Product{1: milk}))
+		rp := repository.NewProductRepository(st)
+		cheaper := milk
+		cheaper.Price = 1.2
+		var wg sync.WaitGroup
+
+		// act
+		updated, previous, err := rp.Update(ctx, cheaper)
+		require.NoError(t, err)
+		for range 100 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _, err := rp.Change(ctx, 1, func(product internalProduct.Product) internalProduct.Product {
+					product.Quantity++
+					return product
+				})
+				require.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		deleted, errDelete := rp.Delete(ctx, 1)
+
+		// assert
+		require.Equal(t, cheaper, updated)
+		require.Equal(t, milk, previous)
+		require.NoError(t, errDelete)
+		require.Equal(t, milk.Quantity+100, deleted.Quantity)
+		require.Equal(t, 1.2, deleted.Price)
+	})
+}
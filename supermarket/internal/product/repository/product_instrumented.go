@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"supermarket/internal/platform/metrics"
+	internalProduct "supermarket/internal/product"
+	"time"
+)
+
+// NewProductRepositoryInstrumented wraps repository to record the duration and errors of its operations.
+func NewProductRepositoryInstrumented(repository internalProduct.ProductRepositoryInterface, reg *metrics.Registry) *ProductRepositoryInstrumented {
+	return &ProductRepositoryInstrumented{
+		repository: repository,
+		duration: reg.NewHistogram("supermarket_repository_operation_duration_seconds",
+			"Duration of the repository operations.", nil, "operation"),
+		errors: reg.NewCounter("supermarket_repository_operation_errors_total",
+			"Number of repository operations that returned an error.", "operation"),
+	}
+}
+
+// ProductRepositoryInstrumented is a ProductRepositoryInterface that records metrics
+type ProductRepositoryInstrumented struct {
+	repository internalProduct.ProductRepositoryInterface
+
+	duration *metrics.Histogram
+	errors   *metrics.Counter
+}
+
+// observe records the duration and the error of an operation
+func (pr *ProductRepositoryInstrumented) observe(operation string, start time.Time, err error) {
+	pr.duration.Observe(time.Since(start).Seconds(), operation)
+	if err != nil {
+		pr.errors.Inc(operation)
+	}
+}
+
+// Get returns all products from the wrapped repository.
+func (pr *ProductRepositoryInstrumented) Get(ctx context.Context) ([]Product, error) {
+	start := time.Now()
+	products, err := pr.repository.Get(ctx)
+	pr.observe("get", start, err)
+	return products, err
+}
+
+// GetById returns a product from the wrapped repository by id.
+func (pr *ProductRepositoryInstrumented) GetById(ctx context.Context, id int) (Product, error) {
+	start := time.Now()
+	product, err := pr.repository.GetById(ctx, id)
+	pr.observe("get_by_id", start, err)
+	return product, err
+}
+
+// SearchByPrice returns the products from the wrapped repository that have a price greater than priceGt.
+func (pr *ProductRepositoryInstrumented) SearchByPrice(ctx context.Context, priceGt float64) ([]Product, error) {
+	start := time.Now()
+	products, err := pr.repository.SearchByPrice(ctx, priceGt)
+	pr.observe("search_by_price", start, err)
+	return products, err
+}
+
+// Save adds a product to the wrapped repository.
+func (pr *ProductRepositoryInstrumented) Save(ctx context.Context, product Product) (Product, error) {
+	start := time.Now()
+	product, err := pr.repository.Save(ctx, product)
+	pr.observe("save", start, err)
+	return product, err
+}
+
+// SaveOrUpdate updates a product in the wrapped repository or creates it if it doesn't exist.
+func (pr *ProductRepositoryInstrumented) SaveOrUpdate(ctx context.Context, product Product) (Product, Product, error) {
+	start := time.Now()
+	product, previous, err := pr.repository.SaveOrUpdate(ctx, product)
+	pr.observe("save_or_update", start, err)
+	return product, previous, err
+}
+
+// Update updates a product in the wrapped repository.
+func (pr *ProductRepositoryInstrumented) Update(ctx context.Context, product Product) (Product, Product, error) {
+	start := time.Now()
+	product, previous, err := pr.repository.Update(ctx, product)
+	pr.observe("update", start, err)
+	return product, previous, err
+}
+
+// Change changes a product of the wrapped repository.
+func (pr *ProductRepositoryInstrumented) Change(ctx context.Context, id int, change func(product Product) Product) (Product, Product, error) {
+	start := time.Now()
+	product, previous, err := pr.repository.Change(ctx, id, change)
+	pr.observe("change", start, err)
+	return product, previous, err
+}
+
+// Delete deletes a product from the wrapped repository by id.
+func (pr *ProductRepositoryInstrumented) Delete(ctx context.Context, id int) (Product, error) {
+	start := time.Now()
+	product, err := pr.repository.Delete(ctx, id)
+	pr.observe("delete", start, err)
+	return product, err
+}
+
+// GetConsumerPriceProducts returns the products of ids and their total price from the wrapped repository.
+func (pr *ProductRepositoryInstrumented) GetConsumerPriceProducts(ctx context.Context, ids []string) (internalProduct.ConsumerPriceProducts, error) {
+	start := time.Now()
+	consumerProducts, err := pr.repository.GetConsumerPriceProducts(ctx, ids)
+	pr.observe("get_consumer_price_products", start, err)
+	return consumerProducts, err
+}
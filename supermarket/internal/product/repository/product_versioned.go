@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"supermarket/internal/platform/httpcache"
+	internalProduct "supermarket/internal/product"
+)
+
+// NewProductRepositoryVersioned wraps repository to bump version on every write.
+func NewProductRepositoryVersioned(repository internalProduct.ProductRepositoryInterface, version *httpcache.Version) *ProductRepositoryVersioned {
+	return &ProductRepositoryVersioned{
+		repository: repository,
+		version:    version,
+	}
+}
+
+// ProductRepositoryVersioned is a ProductRepositoryInterface that counts the changes of the catalogue.
+// A failed write may still have changed the products, so every write bumps the version.
+type ProductRepositoryVersioned struct {
+	repository internalProduct.ProductRepositoryInterface
+	version    *httpcache.Version
+}
+
+// Get returns all products from the wrapped repository.
+func (pr *ProductRepositoryVersioned) Get(ctx context.Context) ([]Product, error) {
+	return pr.repository.Get(ctx)
+}
+
+// GetById returns a product from the wrapped repository by id.
+func (pr *ProductRepositoryVersioned) GetById(ctx context.Context, id int) (Product, error) {
+	return pr.repository.GetById(ctx, id)
+}
+
+// SearchByPrice returns the products from the wrapped repository that have a price greater than priceGt.
+func (pr *ProductRepositoryVersioned) SearchByPrice(ctx context.Context, priceGt float64) ([]Product, error) {
+	return pr.repository.SearchByPrice(ctx, priceGt)
+}
+
+// Save adds a product to the wrapped repository.
+func (pr *ProductRepositoryVersioned) Save(ctx context.Context, product Product) (Product, error) {
+	defer pr.version.Bump()
+	return pr.repository.Save(ctx, product)
+}
+
+// SaveOrUpdate adds or updates a product in the wrapped repository.
+func (pr *ProductRepositoryVersioned) SaveOrUpdate(ctx context.Context, product Product) (Product, Product, error) {
+	defer pr.version.Bump()
+	return pr.repository.SaveOrUpdate(ctx, product)
+}
+
+// Update updates a product in the wrapped repository.
+func (pr *ProductRepositoryVersioned) Update(ctx context.Context, product Product) (Product, Product, error) {
+	defer pr.version.Bump()
+	return pr.repository.Update(ctx, product)
+}
+
+// Change changes a product of the wrapped repository.
+func (pr *ProductRepositoryVersioned) Change(ctx context.Context, id int, change func(product Product) Product) (Product, Product, error) {
+	defer pr.version.Bump()
+	return pr.repository.Change(ctx, id, change)
+}
+
+// Delete deletes a product from the wrapped repository by id.
+func (pr *ProductRepositoryVersioned) Delete(ctx context.Context, id int) (Product, error) {
+	defer pr.version.Bump()
+	return pr.repository.Delete(ctx, id)
+}
+
+// GetConsumerPriceProducts returns the products of ids and their total price from the wrapped repository.
+func (pr *ProductRepositoryVersioned) GetConsumerPriceProducts(ctx context.Context, ids []string) (internalProduct.ConsumerPriceProducts, error) {
+	return pr.repository.GetConsumerPriceProducts(ctx, ids)
+}
@@ -0,0 +1,67 @@
+package rpc
+
+import (
+	"context"
+	"supermarket/internal/auth"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TokenMetadata is the metadata key carrying the api token, as the Token header of the http api
+const TokenMetadata = "token"
+
+// writeMethods are the methods that require a valid token, as the write routes of the http api
+var writeMethods = map[string]bool{
+	ProductService_CreateProduct_FullMethodName:         true,
+	ProductService_UpdateOrCreateProduct_FullMethodName: true,
+	ProductService_UpdateProduct_FullMethodName:         true,
+	ProductService_DeleteProduct_FullMethodName:         true,
+}
+
+// NewAuthenticator creates an Authenticator to handle authentication via interceptors
+func NewAuthenticator(au auth.AuthToken) *Authenticator {
+	return &Authenticator{
+		au: au,
+	}
+}
+
+// Authenticator authenticates the calls to the write methods.
+type Authenticator struct {
+	// au is the authenticator service.
+	au auth.AuthToken
+}
+
+// Unary is a unary interceptor rejecting the calls to the write methods without a valid token.
+func (a *Authenticator) Unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := a.authenticate(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// Stream is a stream interceptor rejecting the calls to the write methods without a valid token.
+func (a *Authenticator) Stream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.authenticate(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// authenticate validates the token of the metadata of ctx, for the write methods
+func (a *Authenticator) authenticate(ctx context.Context, fullMethod string) error {
+	if !writeMethods[fullMethod] {
+		return nil
+	}
+
+	var token string
+	if values := metadata.ValueFromIncomingContext(ctx, TokenMetadata); len(values) > 0 {
+		token = values[0]
+	}
+	if err := a.au.Auth(token); err != nil {
+		return status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+	return nil
+}
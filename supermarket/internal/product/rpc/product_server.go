@@ -0,0 +1,217 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"supermarket/internal/platform/logging"
+	internalProduct "supermarket/internal/product"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type ProductServiceInterface = internalProduct.ProductServiceInterface
+
+// NewProductServer returns a new ProductServer.
+func NewProductServer(productService ProductServiceInterface) *ProductServer {
+	return &ProductServer{
+		ProductService: productService,
+	}
+}
+
+// ProductServer implements the ProductService of api/product.proto over a ProductServiceInterface,
+// the same the http handlers use.
+type ProductServer struct {
+	UnimplementedProductServiceServer
+	ProductService ProductServiceInterface
+}
+
+// Register registers the service on s.
+func (p *ProductServer) Register(s grpc.ServiceRegistrar) {
+	RegisterProductServiceServer(s, p)
+}
+
+// ListProducts streams the products of the catalogue.
+func (p *ProductServer) ListProducts(req *ListProductsRequest, stream ProductService_ListProductsServer) error {
+	products, err := p.ProductService.GetProducts(stream.Context())
+	if err != nil {
+		return statusError(stream.Context(), "list products", err)
+	}
+	return sendProducts(stream, products)
+}
+
+// GetProduct returns a product by id.
+func (p *ProductServer) GetProduct(ctx context.Context, req *GetProductRequest) (*Product, error) {
+	product, err := p.ProductService.GetProduct(ctx, formatID(req.Id))
+	if err != nil {
+		return nil, statusError(ctx, "get product", err)
+	}
+	return toProduct(product), nil
+}
+
+// SearchProductsByPrice streams the products with a price greater than the price of req.
+func (p *ProductServer) SearchProductsByPrice(req *SearchProductsByPriceRequest, stream ProductService_SearchProductsByPriceServer) error {
+	priceGt := strconv.FormatFloat(req.PriceGt, 'f', -1, 64)
+	products, err := p.ProductService.SearchProductsByPrice(stream.Context(), priceGt)
+	if err != nil {
+		return statusError(stream.Context(), "search products by price", err)
+	}
+	return sendProducts(stream, products)
+}
+
+// CreateProduct creates a product, returned with its id.
+func (p *ProductServer) CreateProduct(ctx context.Context, req *CreateProductRequest) (*Product, error) {
+	product, err := p.ProductService.CreateProduct(ctx, fromFields(req.Product))
+	if err != nil {
+		return nil, statusError(ctx, "create product", err)
+	}
+	return toProduct(product), nil
+}
+
+// UpdateOrCreateProduct replaces a product, creating it if it does not exist.
+func (p *ProductServer) UpdateOrCreateProduct(ctx context.Context, req *UpdateOrCreateProductRequest) (*Product, error) {
+	product := fromFields(req.Product)
+	product.Id = int(req.Id)
+	product, err := p.ProductService.UpdateOrCreateProduct(ctx, product)
+	if err != nil {
+		return nil, statusError(ctx, "update or create product", err)
+	}
+	return toProduct(product), nil
+}
+
+// UpdateProduct updates the fields of a product named in the update mask, all of them if empty.
+func (p *ProductServer) UpdateProduct(ctx context.Context, req *UpdateProductRequest) (*Product, error) {
+	// find original product to patch
+	product, err := p.ProductService.GetProduct(ctx, formatID(req.Id))
+	if err != nil {
+		return nil, statusError(ctx, "update product", err)
+	}
+
+	// apply the fields of the mask
+	fields := req.Product
+	if fields == nil {
+		fields = &ProductFields{}
+	}
+	mask := req.UpdateMask
+	if len(mask) == 0 {
+		mask = []string{"name", "quantity", "code_value", "is_published", "expiration", "price"}
+	}
+	for _, path := range mask {
+		switch path {
+		case "name":
+			product.Name = fields.Name
+		case "quantity":
+			product.Quantity = int(fields.Quantity)
+		case "code_value":
+			product.CodeValue = fields.CodeValue
+		case "is_published":
+			product.IsPublished = fields.IsPublished
+		case "expiration":
+			product.Expiration = fields.Expiration
+		case "price":
+			product.Price = fields.Price
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "unknown field %q in update mask", path)
+		}
+	}
+
+	product, err = p.ProductService.UpdateProduct(ctx, product)
+	if err != nil {
+		return nil, statusError(ctx, "update product", err)
+	}
+	return toProduct(product), nil
+}
+
+// DeleteProduct deletes a product by id.
+func (p *ProductServer) DeleteProduct(ctx context.Context, req *DeleteProductRequest) (*DeleteProductResponse, error) {
+	if err := p.ProductService.DeleteProduct(ctx, formatID(req.Id)); err != nil {
+		return nil, statusError(ctx, "delete product", err)
+	}
+	return &DeleteProductResponse{}, nil
+}
+
+// GetConsumerPrice returns the products of the ids of req and their total price.
+func (p *ProductServer) GetConsumerPrice(ctx context.Context, req *GetConsumerPriceRequest) (*ConsumerPrice, error) {
+	ids := make([]string, len(req.Ids))
+	for i, id := range req.Ids {
+		ids[i] = formatID(id)
+	}
+	consumerPrice, err := p.ProductService.GetConsumerPriceProducts(ctx, ids)
+	if err != nil {
+		return nil, statusError(ctx, "get consumer price", err)
+	}
+
+	response := &ConsumerPrice{TotalPrice: consumerPrice.TotalPrice}
+	for _, product := range consumerPrice.Products {
+		response.Products = append(response.Products, toProduct(product))
+	}
+	return response, nil
+}
+
+// sendProducts streams products, stopping if the client is gone
+func sendProducts(stream grpc.ServerStreamingServer[Product], products []internalProduct.Product) error {
+	for _, product := range products {
+		if err := stream.Send(toProduct(product)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statusError maps the errors of the service to a grpc status, logging the unexpected ones as op
+func statusError(ctx context.Context, op string, err error) error {
+	switch {
+	case errors.Is(err, internalProduct.ErrInvalidID),
+		errors.Is(err, internalProduct.ErrInvalidPriceGt),
+		errors.Is(err, internalProduct.ErrInvalidProduct):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, internalProduct.ErrProductNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, internalProduct.ErrDuplicateCodeValue):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, internalProduct.ErrInsufficientQuantity):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request canceled")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "request timeout")
+	}
+	logging.FromContext(ctx).Error(op, slog.Any("error", err))
+	return status.Error(codes.Internal, "internal server error")
+}
+
+// formatID formats an id as the service expects it
+func formatID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// toProduct converts a product of the service to a message
+func toProduct(product internalProduct.Product) *Product {
+	return &Product{
+		Id:          int64(product.Id),
+		Name:        product.Name,
+		Quantity:    int64(product.Quantity),
+		CodeValue:   product.CodeValue,
+		IsPublished: product.IsPublished,
+		Expiration:  product.Expiration,
+		Price:       product.Price,
+	}
+}
+
+// fromFields converts the fields of a message to a product of the service, without id
+func fromFields(fields *ProductFields) internalProduct.Product {
+	if fields == nil {
+		return internalProduct.Product{}
+	}
+	return internalProduct.Product{
+		Name:        fields.Name,
+		Quantity:    int(fields.Quantity),
+		CodeValue:   fields.CodeValue,
+		IsPublished: fields.IsPublished,
+		Expiration:  fields.Expiration,
+		Price:       fields.Price,
+	}
+}
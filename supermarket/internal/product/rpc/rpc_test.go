@@ -0,0 +1,211 @@
+package rpc_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"supermarket/internal/auth"
+	internalProduct "supermarket/internal/product"
+	"supermarket/internal/product/rpc"
+	"supermarket/internal/product/service"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+type ProductServiceMock = service.ProductServiceMock
+
+// newClient serves productService in memory, authenticating with token, and returns a client of it
+func newClient(t *testing.T, productService *ProductServiceMock, token string) rpc.ProductServiceClient {
+	t.Helper()
+	ln := bufconn.Listen(1 << 20)
+	server := rpc.NewServer(productService, auth.NewAuthTokenBasic(token), 0)
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return rpc.NewProductServiceClient(conn)
+}
+
+// requireProtoEqual asserts that the messages are equal, as the generated messages cannot be compared with ==
+func requireProtoEqual(t *testing.T, expected, actual proto.Message) {
+	t.Helper()
+	require.True(t, proto.Equal(expected, actual), "expected %v, got %v", expected, actual)
+}
+
+// TestProductServer_Reads tests the read methods of the ProductService.
+func TestProductServer_Reads(t *testing.T) {
+	products := []internalProduct.Product{
+		{Id: 1, Name: "product 1", Quantity: 10, CodeValue: "code 1", IsPublished: true, Expiration: "12/31/2030", Price: 100},
+		{Id: 2, Name: "product 2", Quantity: 20, CodeValue: "code 2", Expiration: "12/31/2030", Price: 200.5},
+	}
+
+	t.Run("success - list products streamed", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		productService.On("GetProducts", mock.Anything).Return(products, nil)
+		client := newClient(t, productService, "secret")
+
+		// act
+		stream, err := client.ListProducts(context.Background(), &rpc.ListProductsRequest{})
+		require.NoError(t, err)
+		var received []*rpc.Product
+		for {
+			product, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			received = append(received, product)
+		}
+
+		// assert
+		require.Len(t, received, 2)
+		requireProtoEqual(t, &rpc.Product{Id: 1, Name: "product 1", Quantity: 10, CodeValue: "code 1", IsPublished: true, Expiration: "12/31/2030", Price: 100}, received[0])
+		requireProtoEqual(t, &rpc.Product{Id: 2, Name: "product 2", Quantity: 20, CodeValue: "code 2", Expiration: "12/31/2030", Price: 200.5}, received[1])
+		productService.AssertExpectations(t)
+	})
+
+	t.Run("success - get product", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		productService.On("GetProduct", mock.Anything, "2").Return(products[1], nil)
+		client := newClient(t, productService, "secret")
+
+		// act
+		product, err := client.GetProduct(context.Background(), &rpc.GetProductRequest{Id: 2})
+
+		// assert
+		require.NoError(t, err)
+		requireProtoEqual(t, &rpc.Product{Id: 2, Name: "product 2", Quantity: 20, CodeValue: "code 2", Expiration: "12/31/2030", Price: 200.5}, product)
+	})
+
+	t.Run("success - consumer price", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		productService.On("GetConsumerPriceProducts", mock.Anything, []string{"1", "1"}).
+			Return(internalProduct.ConsumerPriceProducts{Products: products[:1], TotalPrice: 242}, nil)
+		client := newClient(t, productService, "secret")
+
+		// act
+		consumerPrice, err := client.GetConsumerPrice(context.Background(), &rpc.GetConsumerPriceRequest{Ids: []int64{1, 1}})
+
+		// assert
+		require.NoError(t, err)
+		require.Len(t, consumerPrice.Products, 1)
+		require.Equal(t, 242.0, consumerPrice.TotalPrice)
+	})
+
+	t.Run("failure - domain errors mapped to codes", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		productService.On("GetProduct", mock.Anything, "3").Return(internalProduct.Product{}, internalProduct.ErrProductNotFound)
+		productService.On("GetProduct", mock.Anything, "-1").Return(internalProduct.Product{}, internalProduct.ErrInvalidID)
+		productService.On("GetConsumerPriceProducts", mock.Anything, []string{"1"}).
+			Return(internalProduct.ConsumerPriceProducts{}, internalProduct.ErrInsufficientQuantity)
+		client := newClient(t, productService, "secret")
+
+		// act
+		_, errNotFound := client.GetProduct(context.Background(), &rpc.GetProductRequest{Id: 3})
+		_, errInvalid := client.GetProduct(context.Background(), &rpc.GetProductRequest{Id: -1})
+		_, errQuantity := client.GetConsumerPrice(context.Background(), &rpc.GetConsumerPriceRequest{Ids: []int64{1}})
+
+		// assert
+		require.Equal(t, codes.NotFound, status.Code(errNotFound))
+		require.Equal(t, "product not found", status.Convert(errNotFound).Message())
+		require.Equal(t, codes.InvalidArgument, status.Code(errInvalid))
+		require.Equal(t, codes.FailedPrecondition, status.Code(errQuantity))
+	})
+}
+
+// TestProductServer_Writes tests the write methods of the ProductService.
+func TestProductServer_Writes(t *testing.T) {
+	withToken := func(token string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), rpc.TokenMetadata, token)
+	}
+	fields := &rpc.ProductFields{Name: "product 1", Quantity: 10, CodeValue: "code 1", Expiration: "12/31/2030", Price: 100}
+	product := internalProduct.Product{Name: "product 1", Quantity: 10, CodeValue: "code 1", Expiration: "12/31/2030", Price: 100}
+
+	t.Run("success - create product", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		created := product
+		created.Id = 1
+		productService.On("CreateProduct", mock.Anything, product).Return(created, nil)
+		client := newClient(t, productService, "secret")
+
+		// act
+		res, err := client.CreateProduct(withToken("secret"), &rpc.CreateProductRequest{Product: fields})
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, int64(1), res.Id)
+		productService.AssertExpectations(t)
+	})
+
+	t.Run("success - update the fields of the mask", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		original := product
+		original.Id = 1
+		updated := original
+		updated.Price = 150
+		productService.On("GetProduct", mock.Anything, "1").Return(original, nil)
+		productService.On("UpdateProduct", mock.Anything, updated).Return(updated, nil)
+		client := newClient(t, productService, "secret")
+
+		// act
+		res, err := client.UpdateProduct(withToken("secret"), &rpc.UpdateProductRequest{
+			Id:         1,
+			Product:    &rpc.ProductFields{Name: "ignored", Price: 150},
+			UpdateMask: []string{"price"},
+		})
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, "product 1", res.Name)
+		require.Equal(t, 150.0, res.Price)
+		productService.AssertExpectations(t)
+	})
+
+	t.Run("failure - duplicated code value", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		productService.On("CreateProduct", mock.Anything, product).Return(internalProduct.Product{}, internalProduct.ErrDuplicateCodeValue)
+		client := newClient(t, productService, "secret")
+
+		// act
+		_, err := client.CreateProduct(withToken("secret"), &rpc.CreateProductRequest{Product: fields})
+
+		// assert
+		require.Equal(t, codes.AlreadyExists, status.Code(err))
+	})
+
+	t.Run("failure - unauthenticated", func(t *testing.T) {
+		// arrange
+		productService := new(ProductServiceMock)
+		client := newClient(t, productService, "secret")
+
+		// act
+		_, errMissing := client.DeleteProduct(context.Background(), &rpc.DeleteProductRequest{Id: 1})
+		_, errWrong := client.DeleteProduct(withToken("wrong"), &rpc.DeleteProductRequest{Id: 1})
+
+		// assert
+		require.Equal(t, codes.Unauthenticated, status.Code(errMissing))
+		require.Equal(t, codes.Unauthenticated, status.Code(errWrong))
+		productService.AssertNotCalled(t, "DeleteProduct", mock.Anything, mock.Anything)
+	})
+}
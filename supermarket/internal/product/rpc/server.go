@@ -0,0 +1,96 @@
+package rpc
+
+import (
+	"context"
+	"log/slog"
+	"supermarket/internal/auth"
+	"supermarket/internal/platform/logging"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NewServer creates a grpc server of the ProductService over productService, authenticating the
+// write methods with au. Calls are logged, and have a deadline of requestTimeout if not 0.
+func NewServer(productService ProductServiceInterface, au auth.AuthToken, requestTimeout time.Duration, opts ...grpc.ServerOption) *grpc.Server {
+	authenticator := NewAuthenticator(au)
+	opts = append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(logUnary, deadlineUnary(requestTimeout), authenticator.Unary),
+		grpc.ChainStreamInterceptor(logStream, deadlineStream(requestTimeout), authenticator.Stream),
+	}, opts...)
+
+	s := grpc.NewServer(opts...)
+	NewProductServer(productService).Register(s)
+	return s
+}
+
+// logUnary logs the unary calls, with the logger of the context
+func logUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	res, err := handler(ctx, req)
+	logCall(ctx, info.FullMethod, start, err)
+	return res, err
+}
+
+// logStream logs the streaming calls, with the logger of the context
+func logStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, stream)
+	logCall(stream.Context(), info.FullMethod, start, err)
+	return err
+}
+
+// logCall logs a call at a level by its status code: error for the server errors, warn for the client ones
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK:
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable, codes.Unimplemented:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+	logging.FromContext(ctx).Log(ctx, level, "rpc",
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("duration", time.Since(start)),
+	)
+}
+
+// deadlineUnary sets the deadline of the unary calls to timeout, unless the client set a shorter one
+func deadlineUnary(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if timeout <= 0 {
+			return handler(ctx, req)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// deadlineStream sets the deadline of the streaming calls to timeout, unless the client set a shorter one
+func deadlineStream(timeout time.Duration) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if timeout <= 0 {
+			return handler(srv, stream)
+		}
+		ctx, cancel := context.WithTimeout(stream.Context(), timeout)
+		defer cancel()
+		return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// contextStream is a stream with another context
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context of the stream
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -1,8 +1,13 @@
 package service
 
 import (
+	"context"
+	"log/slog"
 	"strconv"
+	"supermarket/internal/platform/logging"
+	"supermarket/internal/platform/tracing"
 	internalProduct "supermarket/internal/product"
+	"sync"
 	"time"
 )
 
@@ -11,6 +16,16 @@ type ProductRepositoryInterface = internalProduct.ProductRepositoryInterface
 
 type ProductService struct {
 	ProductRepository ProductRepositoryInterface
+	// Events receives the events of the successful writes, none are published if nil
+	Events internalProduct.EventPublisher
+	// Prices records the price changes of the successful writes, none are recorded if nil
+	Prices internalProduct.PriceRecorder
+	// Stock counts the stock of the products kept by the stores, their quantity being that stock, if not nil
+	Stock internalProduct.StockCounter
+
+	// stockMu is held by the updates counting the stock and by SetQuantity, so the quantity written by a
+	// change of the stock is not overwritten with the count of an update started before it
+	stockMu sync.Mutex
 }
 
 // NewProductService creates a new ProductService.
@@ -21,24 +36,34 @@ func NewProductService(productRepository ProductRepositoryInterface) *ProductSer
 }
 
 // GetProducts returns the products from the repository.
-func (ps *ProductService) GetProducts() ([]Product, error) {
-	products, err := ps.ProductRepository.Get()
+func (ps *ProductService) GetProducts(ctx context.Context) ([]Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetProducts")
+	defer span.End()
+
+	products, err := ps.ProductRepository.Get(ctx)
 	if err != nil {
+		span.RecordError(err)
 		return nil, err
 	}
 	return products, nil
 }
 
 // GetProduct returns a product from the repository by id.
-func (ps *ProductService) GetProduct(id string) (Product, error) {
+func (ps *ProductService) GetProduct(ctx context.Context, id string) (Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetProduct", tracing.WithAttributes(tracing.String("product.id", id)))
+	defer span.End()
+
 	productId, err := strconv.Atoi(id)
 	if err != nil {
+		span.RecordError(internalProduct.ErrInvalidID)
 		return Product{}, internalProduct.ErrInvalidID
 	}
 
-	product, err := ps.ProductRepository.GetById(productId)
+	product, err := ps.ProductRepository.GetById(ctx, productId)
 	if err != nil {
-		return Product{}, internalProduct.ErrProductNotFound
+		err = orContextErr(ctx, internalProduct.ErrProductNotFound)
+		span.RecordError(err)
+		return Product{}, err
 	}
 
 	return product, nil
@@ -46,104 +71,226 @@ func (ps *ProductService) GetProduct(id string) (Product, error) {
 }
 
 // SearchProducts returns the products from the repository that have a price greater than priceGt.
-func (ps *ProductService) SearchProductsByPrice(priceGt string) ([]Product, error) {
+func (ps *ProductService) SearchProductsByPrice(ctx context.Context, priceGt string) ([]Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.SearchProductsByPrice", tracing.WithAttributes(tracing.String("price_gt", priceGt)))
+	defer span.End()
+
 	price, err := strconv.ParseFloat(priceGt, 64)
 	if err != nil {
+		span.RecordError(internalProduct.ErrInvalidPriceGt)
 		return nil, internalProduct.ErrInvalidPriceGt
 	}
 
-	products, err := ps.ProductRepository.SearchByPrice(price)
+	products, err := ps.ProductRepository.SearchByPrice(ctx, price)
 	if err != nil {
-		return nil, internalProduct.ErrProductNotFound
+		err = orContextErr(ctx, internalProduct.ErrProductNotFound)
+		span.RecordError(err)
+		return nil, err
 	}
 
 	return products, nil
 }
 
 // CreateProduct adds a product to the repository.
-func (ps *ProductService) CreateProduct(product Product) (Product, error) {
+func (ps *ProductService) CreateProduct(ctx context.Context, product Product) (Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.CreateProduct")
+	defer span.End()
+
 	// validate product
-	err := ps.ValidateProduct(product, false)
+	err := ps.ValidateProduct(ctx, product, false)
 	if err != nil {
+		span.RecordError(err)
 		return product, err
 	}
 
 	// add product to repository
-	product, err = ps.ProductRepository.Save(product)
+	product, err = ps.ProductRepository.Save(ctx, product)
 	if err != nil {
+		span.RecordError(err)
 		return product, err
 	}
 
+	ps.publish(ctx, internalProduct.EventProductCreated, product, nil)
+	ps.recordPrice(ctx, product.Id, 0, product.Price)
 	return product, nil
 }
 
 // UpdateOrCreateProduct updates a product in the repository or creates it if it doesn't exist.
-func (ps *ProductService) UpdateOrCreateProduct(product Product) (Product, error) {
+func (ps *ProductService) UpdateOrCreateProduct(ctx context.Context, product Product) (Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.UpdateOrCreateProduct")
+	defer span.End()
+
 	// validate product
-	err := ps.ValidateProduct(product, true)
+	err := ps.ValidateProduct(ctx, product, true)
 	if err != nil {
+		span.RecordError(err)
 		return product, err
 	}
 
-	// update product in repository
-	product, err = ps.ProductRepository.SaveOrUpdate(product)
+	// the quantity of a product kept by the stores is their stock
+	ps.stockMu.Lock()
+	defer ps.stockMu.Unlock()
+	product, err = ps.countStock(ctx, product)
 	if err != nil {
+		span.RecordError(err)
 		return product, err
 	}
 
+	// update product in repository, which returns the previous one for the events and the price history
+	product, previous, err := ps.ProductRepository.SaveOrUpdate(ctx, product)
+	if err != nil {
+		span.RecordError(err)
+		return product, err
+	}
+
+	if previous.Id == 0 {
+		ps.publish(ctx, internalProduct.EventProductCreated, product, nil)
+		ps.recordPrice(ctx, product.Id, 0, product.Price)
+	} else {
+		ps.publishUpdate(ctx, previous, product)
+		ps.recordPriceChange(ctx, previous, product)
+	}
 	return product, nil
 }
 
 // UpdateProduct updates a product in the repository.
-func (ps *ProductService) UpdateProduct(product Product) (Product, error) {
+func (ps *ProductService) UpdateProduct(ctx context.Context, product Product) (Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.UpdateProduct")
+	defer span.End()
+
 	// validate product
-	err := ps.ValidateProduct(product, true)
+	err := ps.ValidateProduct(ctx, product, true)
+	if err != nil {
+		span.RecordError(err)
+		return product, err
+	}
+
+	// the quantity of a product kept by the stores is their stock
+	ps.stockMu.Lock()
+	defer ps.stockMu.Unlock()
+	product, err = ps.countStock(ctx, product)
 	if err != nil {
+		span.RecordError(err)
 		return product, err
 	}
 
-	// update product in repository
-	product, err = ps.ProductRepository.Update(product)
+	// update product in repository, which returns the previous one for the events and the price history
+	product, previous, err := ps.ProductRepository.Update(ctx, product)
 	if err != nil {
+		span.RecordError(err)
 		return product, err
 	}
 
+	ps.publishUpdate(ctx, previous, product)
+	ps.recordPriceChange(ctx, previous, product)
+	return product, nil
+}
+
+// SetQuantity sets the quantity of the product id, e.g. to its stock at the stores, leaving its other fields
+// as they are when written. Unlike the updates, the quantity may be 0 and the product is not validated.
+func (ps *ProductService) SetQuantity(ctx context.Context, id int, quantity int) (Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.SetQuantity", tracing.WithAttributes(tracing.Int("product.id", id)))
+	defer span.End()
+
+	if quantity < 0 {
+		span.RecordError(internalProduct.ErrInvalidProduct)
+		return Product{}, internalProduct.ErrInvalidProduct
+	}
+
+	ps.stockMu.Lock()
+	defer ps.stockMu.Unlock()
+	product, err := ps.change(ctx, id, func(product Product) Product {
+		product.Quantity = quantity
+		return product
+	})
+	if err != nil {
+		span.RecordError(err)
+		return Product{}, err
+	}
+	return product, nil
+}
+
+// SetPrice sets the price of the product id, e.g. to a scheduled price taking effect, leaving its other
+// fields as they are when written. Unlike the updates, the product is not validated, so the products
+// invalid since their creation, e.g. with an expiration as DD/MM/YYYY, still have their price changed.
+func (ps *ProductService) SetPrice(ctx context.Context, id int, price float64) (Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.SetPrice", tracing.WithAttributes(tracing.Int("product.id", id)))
+	defer span.End()
+
+	if price <= 0 {
+		span.RecordError(internalProduct.ErrInvalidProduct)
+		return Product{}, internalProduct.ErrInvalidProduct
+	}
+
+	product, err := ps.change(ctx, id, func(product Product) Product {
+		product.Price = price
+		return product
+	})
+	if err != nil {
+		span.RecordError(err)
+		return Product{}, err
+	}
+	return product, nil
+}
+
+// change applies change to the product id in a single write of the repository, publishing the update and
+// recording the price change if the product changed
+func (ps *ProductService) change(ctx context.Context, id int, change func(product Product) Product) (Product, error) {
+	product, previous, err := ps.ProductRepository.Change(ctx, id, change)
+	if err != nil {
+		return Product{}, orContextErr(ctx, err)
+	}
+	if product != previous {
+		ps.publishUpdate(ctx, previous, product)
+		ps.recordPriceChange(ctx, previous, product)
+	}
 	return product, nil
 }
 
 // DeleteProduct deletes a product from the repository by id.
-func (ps *ProductService) DeleteProduct(id string) error {
+func (ps *ProductService) DeleteProduct(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "ProductService.DeleteProduct", tracing.WithAttributes(tracing.String("product.id", id)))
+	defer span.End()
+
 	productId, err := strconv.Atoi(id)
 	if err != nil {
+		span.RecordError(internalProduct.ErrInvalidID)
 		return internalProduct.ErrInvalidID
 	}
 
-	err = ps.ProductRepository.Delete(productId)
+	previous, err := ps.ProductRepository.Delete(ctx, productId)
 	if err != nil {
-		return internalProduct.ErrProductNotFound
+		err = orContextErr(ctx, internalProduct.ErrProductNotFound)
+		span.RecordError(err)
+		return err
 	}
 
+	ps.publish(ctx, internalProduct.EventProductDeleted, previous, nil)
 	return nil
 }
 
 // GetConsumerPriceProducts receives a list of ids and returns those products and the total price.
-func (ps *ProductService) GetConsumerPriceProducts(ids []string) (internalProduct.ConsumerPriceProducts, error) {
-	consumerProducts, err := ps.ProductRepository.GetConsumerPriceProducts(ids)
+func (ps *ProductService) GetConsumerPriceProducts(ctx context.Context, ids []string) (internalProduct.ConsumerPriceProducts, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetConsumerPriceProducts", tracing.WithAttributes(tracing.Int("ids.count", len(ids))))
+	defer span.End()
+
+	consumerProducts, err := ps.ProductRepository.GetConsumerPriceProducts(ctx, ids)
 	if err != nil {
+		span.RecordError(err)
 		return consumerProducts, err
 	}
 	return consumerProducts, nil
 }
 
 // ValidateProduct validates the product parameters.
-func (ps *ProductService) ValidateProduct(product Product, isUpdate bool) error {
+func (ps *ProductService) ValidateProduct(ctx context.Context, product Product, isUpdate bool) error {
 	// no value can be empty. Except is_published, where empty means false
 	if product.Name == "" || product.Quantity == 0 || product.CodeValue == "" || product.Expiration == "" || product.Price == 0 {
 		return internalProduct.ErrInvalidProduct
 	}
 
 	// check if CodeValue already exists and ids are different
-	products, err := ps.ProductRepository.Get()
+	products, err := ps.ProductRepository.Get(ctx)
 	if err != nil {
 		return err
 	}
@@ -158,10 +305,68 @@ func (ps *ProductService) ValidateProduct(product Product, isUpdate bool) error
 	}
 
 	// product.Expiration must be in MM/DD/YYYY format
-	_, err = time.Parse("01/02/2006", product.Expiration)
+	_, err = time.Parse(internalProduct.ExpirationLayout, product.Expiration)
 	if err != nil {
 		return internalProduct.ErrInvalidProduct
 	}
 
 	return nil
 }
+
+// countStock returns product with its quantity set to its stock if the stores keep it, with stockMu held
+func (ps *ProductService) countStock(ctx context.Context, product Product) (Product, error) {
+	if ps.Stock == nil {
+		return product, nil
+	}
+	quantity, ok, err := ps.Stock.CountStock(ctx, product.Id)
+	if err != nil {
+		return product, err
+	}
+	if ok {
+		product.Quantity = quantity
+	}
+	return product, nil
+}
+
+// publishUpdate publishes the update of previous to product, and the change of its stock if any
+func (ps *ProductService) publishUpdate(ctx context.Context, previous, product Product) {
+	ps.publish(ctx, internalProduct.EventProductUpdated, product, nil)
+	if previous.Quantity != product.Quantity {
+		ps.publish(ctx, internalProduct.EventStockChanged, product, &previous.Quantity)
+	}
+}
+
+// publish publishes an event of type about product, if the service has a publisher
+func (ps *ProductService) publish(ctx context.Context, eventType string, product Product, previousQuantity *int) {
+	if ps.Events == nil {
+		return
+	}
+	ps.Events.Publish(ctx, internalProduct.Event{Type: eventType, Product: product, PreviousQuantity: previousQuantity})
+}
+
+// recordPriceChange records the price of product if it changed from previous
+func (ps *ProductService) recordPriceChange(ctx context.Context, previous, product Product) {
+	if previous.Price != product.Price {
+		ps.recordPrice(ctx, product.Id, previous.Price, product.Price)
+	}
+}
+
+// recordPrice records the price of the product id, if the service has a recorder. The write already
+// succeeded, so a failure is logged rather than returned.
+func (ps *ProductService) recordPrice(ctx context.Context, id int, previous, price float64) {
+	if ps.Prices == nil {
+		return
+	}
+	if err := ps.Prices.RecordPrice(ctx, id, previous, price); err != nil {
+		logging.FromContext(ctx).Error("record price", slog.Int("product.id", id), slog.Any("error", err))
+	}
+}
+
+// orContextErr returns the error of ctx if it is done, so a cancellation or an exceeded deadline
+// is not reported as a domain error
+func orContextErr(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
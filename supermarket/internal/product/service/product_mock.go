@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	internalProduct "supermarket/internal/product"
 
 	"github.com/stretchr/testify/mock"
@@ -10,42 +11,42 @@ type ProductServiceMock struct {
 	mock.Mock
 }
 
-func (m *ProductServiceMock) GetProducts() ([]internalProduct.Product, error) {
-	args := m.Called()
+func (m *ProductServiceMock) GetProducts(ctx context.Context) ([]internalProduct.Product, error) {
+	args := m.Called(ctx)
 	return args.Get(0).([]internalProduct.Product), args.Error(1)
 }
 
-func (m *ProductServiceMock) CreateProduct(p internalProduct.Product) (internalProduct.Product, error) {
-	args := m.Called(p)
+func (m *ProductServiceMock) CreateProduct(ctx context.Context, p internalProduct.Product) (internalProduct.Product, error) {
+	args := m.Called(ctx, p)
 	return args.Get(0).(internalProduct.Product), args.Error(1)
 }
 
-func (m *ProductServiceMock) GetProduct(id string) (internalProduct.Product, error) {
-	args := m.Called(id)
+func (m *ProductServiceMock) GetProduct(ctx context.Context, id string) (internalProduct.Product, error) {
+	args := m.Called(ctx, id)
 	return args.Get(0).(internalProduct.Product), args.Error(1)
 }
 
-func (m *ProductServiceMock) SearchProductsByPrice(priceGt string) ([]internalProduct.Product, error) {
-	args := m.Called(priceGt)
+func (m *ProductServiceMock) SearchProductsByPrice(ctx context.Context, priceGt string) ([]internalProduct.Product, error) {
+	args := m.Called(ctx, priceGt)
 	return args.Get(0).([]internalProduct.Product), args.Error(1)
 }
 
-func (m *ProductServiceMock) UpdateOrCreateProduct(p internalProduct.Product) (internalProduct.Product, error) {
-	args := m.Called(p)
+func (m *ProductServiceMock) UpdateOrCreateProduct(ctx context.Context, p internalProduct.Product) (internalProduct.Product, error) {
+	args := m.Called(ctx, p)
 	return args.Get(0).(internalProduct.Product), args.Error(1)
 }
 
-func (m *ProductServiceMock) UpdateProduct(p internalProduct.Product) (internalProduct.Product, error) {
-	args := m.Called(p)
+func (m *ProductServiceMock) UpdateProduct(ctx context.Context, p internalProduct.Product) (internalProduct.Product, error) {
+	args := m.Called(ctx, p)
 	return args.Get(0).(internalProduct.Product), args.Error(1)
 }
 
-func (m *ProductServiceMock) DeleteProduct(id string) error {
-	args := m.Called(id)
+func (m *ProductServiceMock) DeleteProduct(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
-func (m *ProductServiceMock) GetConsumerPriceProducts(ids []string) (internalProduct.ConsumerPriceProducts, error) {
-	args := m.Called(ids)
+func (m *ProductServiceMock) GetConsumerPriceProducts(ctx context.Context, ids []string) (internalProduct.ConsumerPriceProducts, error) {
+	args := m.Called(ctx, ids)
 	return args.Get(0).(internalProduct.ConsumerPriceProducts), args.Error(1)
 }
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"supermarket/internal/platform/logging"
+	"supermarket/internal/platform/web/request"
+	"supermarket/internal/platform/web/response"
+	"supermarket/internal/product/snapshot"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type SnapshotHandler struct {
+	Manager *snapshot.Manager
+	// StrictJSON rejects the json bodies with unknown fields or trailing data
+	StrictJSON bool
+}
+
+// NewSnapshotHandler returns a new SnapshotHandler.
+func NewSnapshotHandler(manager *snapshot.Manager) *SnapshotHandler {
+	return &SnapshotHandler{
+		Manager: manager,
+	}
+}
+
+// SnapshotRequestJSON is the body of a request creating a snapshot
+type SnapshotRequestJSON struct {
+	Name string `json:"name"`
+}
+
+// CreateSnapshotHandler copies the catalogue to a snapshot, named after the time if the body has no name.
+func (h *SnapshotHandler) CreateSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	var snapshotRequest SnapshotRequestJSON
+	if r.ContentLength != 0 {
+		if err := request.JSON(r, &snapshotRequest, request.Options(h.StrictJSON)...); err != nil {
+			response.RequestError(w, err)
+			return
+		}
+	}
+
+	s, err := h.Manager.Create(r.Context(), snapshotRequest.Name)
+	if err != nil {
+		snapshotError(w, r, "create snapshot", err)
+		return
+	}
+	response.JSON(w, http.StatusCreated, "snapshot created successfully", s)
+}
+
+// GetSnapshotsHandler returns the snapshots, the newest first.
+func (h *SnapshotHandler) GetSnapshotsHandler(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := h.Manager.List(r.Context())
+	if err != nil {
+		snapshotError(w, r, "get snapshots", err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "snapshots fetched successfully", snapshots)
+}
+
+// DownloadSnapshotHandler responds a snapshot with its products as a json file.
+func (h *SnapshotHandler) DownloadSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	s, products, err := h.Manager.Load(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		snapshotError(w, r, "download snapshot", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+s.Name+`.json"`)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(snapshot.NewFile(s, products))
+}
+
+// RestoreSnapshotHandler replaces the catalogue with the products of a snapshot.
+func (h *SnapshotHandler) RestoreSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	s, err := h.Manager.Restore(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		snapshotError(w, r, "restore snapshot", err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "snapshot restored successfully", s)
+}
+
+// snapshotError responds the error of the manager, logging the unexpected ones
+func snapshotError(w http.ResponseWriter, r *http.Request, op string, err error) {
+	switch {
+	case errors.Is(err, snapshot.ErrSnapshotNotFound):
+		response.Errorw(w, http.StatusNotFound, err)
+	case errors.Is(err, snapshot.ErrSnapshotExists):
+		response.Errorw(w, http.StatusConflict, err)
+	case errors.Is(err, snapshot.ErrInvalidName):
+		response.Errorw(w, http.StatusUnprocessableEntity, err)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		response.Error(w, http.StatusGatewayTimeout, "request timeout")
+	default:
+		logging.FromContext(r.Context()).Error(op, slog.Any("error", err))
+		response.Error(w, http.StatusInternalServerError, "internal server error")
+	}
+}
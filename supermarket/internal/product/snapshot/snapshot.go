@@ -0,0 +1,197 @@
+// Package snapshot keeps named copies of the catalogue, to restore it to a point in time.
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	internalProduct "supermarket/internal/product"
+	"supermarket/internal/product/migration"
+	"sync"
+	"time"
+)
+
+type Product = internalProduct.Product
+
+var (
+	// ErrSnapshotNotFound is returned when a snapshot does not exist.
+	ErrSnapshotNotFound = errors.New("snapshot: not found")
+	// ErrSnapshotExists is returned when creating a snapshot with the name of another.
+	ErrSnapshotExists = errors.New("snapshot: already exists")
+	// ErrInvalidName is returned for a name which is not 1 to 64 letters, digits, dots, dashes or underscores.
+	ErrInvalidName = errors.New("snapshot: invalid name")
+	// ErrCorrupted is returned when the products of a snapshot do not match its checksum.
+	ErrCorrupted = errors.New("snapshot: corrupted")
+)
+
+// validName are the valid names, safe as file names
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// Snapshot describes a copy of the catalogue.
+type Snapshot struct {
+	// Name identifies the snapshot
+	Name string `json:"name"`
+	// CreatedAt is when the catalogue was copied
+	CreatedAt time.Time `json:"created_at"`
+	// Count is the number of products
+	Count int `json:"count"`
+	// Checksum is the checksum of the products, as computed by migration.Checksum
+	Checksum string `json:"checksum"`
+}
+
+// Store keeps the snapshots and their products. Implementations must be safe for concurrent use.
+type Store interface {
+	// Save adds a snapshot of products, ErrSnapshotExists if its name is taken
+	Save(ctx context.Context, snapshot Snapshot, products map[int]Product) error
+	// List returns the snapshots, the newest first
+	List(ctx context.Context) ([]Snapshot, error)
+	// Load returns a snapshot and its products, ErrSnapshotNotFound if it does not exist
+	Load(ctx context.Context, name string) (Snapshot, map[int]Product, error)
+	// Delete removes a snapshot, ErrSnapshotNotFound if it does not exist
+	Delete(ctx context.Context, name string) error
+}
+
+// Retention bounds the snapshots kept, the oldest being deleted after every creation.
+type Retention struct {
+	// MaxCount is the number of snapshots kept, unbounded if 0
+	MaxCount int
+	// MaxAge is the age of the snapshots kept, unbounded if 0
+	MaxAge time.Duration
+}
+
+// NewManager returns a Manager of the snapshots of storage, kept in store.
+func NewManager(storage internalProduct.ProductStorageInterface, store Store, retention Retention) *Manager {
+	return &Manager{
+		storage:   storage,
+		store:     store,
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+// Manager creates the snapshots of the catalogue of a storage, and restores them.
+type Manager struct {
+	// WriteLock, if not nil, is held by the restores, e.g. the lock of the repository of the storage so a
+	// restore is not overwritten by a write loaded before it
+	WriteLock sync.Locker
+
+	storage   internalProduct.ProductStorageInterface
+	store     Store
+	retention Retention
+	// mu serializes the creations and restores, so a restore is not copied half done
+	mu sync.Mutex
+	// onRestore are called after every restore
+	onRestore []func()
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// OnRestore registers fn to be called after every restore, e.g. to invalidate the caches of the catalogue.
+func (m *Manager) OnRestore(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onRestore = append(m.onRestore, fn)
+}
+
+// Create copies the catalogue to a snapshot named name, the time of the creation if empty, then deletes
+// the snapshots out of the retention.
+func (m *Manager) Create(ctx context.Context, name string) (Snapshot, error) {
+	now := m.now().UTC()
+	if name == "" {
+		name = now.Format("20060102T150405.000Z")
+	}
+	if !validName.MatchString(name) {
+		return Snapshot{}, fmt.Errorf("%w: %q must be 1 to 64 letters, digits, dots, dashes or underscores", ErrInvalidName, name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	products, err := m.storage.LoadProducts(ctx)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	snapshot := Snapshot{
+		Name:      name,
+		CreatedAt: now,
+		Count:     len(products),
+		Checksum:  migration.Checksum(products),
+	}
+	if err := m.store.Save(ctx, snapshot, products); err != nil {
+		return Snapshot{}, err
+	}
+	return snapshot, m.prune(ctx, name)
+}
+
+// prune deletes the snapshots out of the retention, but the one named keep
+func (m *Manager) prune(ctx context.Context, keep string) error {
+	if m.retention == (Retention{}) {
+		return nil
+	}
+	// the snapshots are listed the newest first
+	snapshots, err := m.store.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	kept := 0
+	for _, snapshot := range snapshots {
+		if snapshot.Name == keep {
+			kept++
+			continue
+		}
+		expired := m.retention.MaxAge > 0 && m.now().Sub(snapshot.CreatedAt) > m.retention.MaxAge
+		if expired || (m.retention.MaxCount > 0 && kept >= m.retention.MaxCount) {
+			if err := m.store.Delete(ctx, snapshot.Name); err != nil && !errors.Is(err, ErrSnapshotNotFound) {
+				return err
+			}
+			continue
+		}
+		kept++
+	}
+	return nil
+}
+
+// List returns the snapshots, the newest first.
+func (m *Manager) List(ctx context.Context) ([]Snapshot, error) {
+	return m.store.List(ctx)
+}
+
+// Load returns a snapshot and its products.
+func (m *Manager) Load(ctx context.Context, name string) (Snapshot, map[int]Product, error) {
+	return m.store.Load(ctx, name)
+}
+
+// Restore replaces the catalogue with the products of a snapshot, in a single save of the storage, once
+// their checksum is verified. The OnRestore functions are called after the save.
+func (m *Manager) Restore(ctx context.Context, name string) (Snapshot, error) {
+	snapshot, products, err := m.store.Load(ctx, name)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if migration.Checksum(products) != snapshot.Checksum || len(products) != snapshot.Count {
+		return Snapshot{}, fmt.Errorf("%w: %s", ErrCorrupted, name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// a failed save may still have changed the catalogue, so the caches are invalidated anyway
+	err = m.save(ctx, products)
+	for _, fn := range m.onRestore {
+		fn()
+	}
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return snapshot, nil
+}
+
+// save saves products to the storage with WriteLock held, released before the OnRestore functions are called
+// so they may write the catalogue
+func (m *Manager) save(ctx context.Context, products map[int]Product) error {
+	if m.WriteLock != nil {
+		m.WriteLock.Lock()
+		defer m.WriteLock.Unlock()
+	}
+	return m.storage.SaveProducts(ctx, products)
+}
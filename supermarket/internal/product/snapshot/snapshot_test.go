@@ -0,0 +1,156 @@
+package snapshot_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	internalProduct "supermarket/internal/product"
+	"supermarket/internal/product/repository"
+	"supermarket/internal/product/snapshot"
+	"supermarket/internal/product/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// names returns the names of the snapshots of manager
+func names(t *testing.T, manager *snapshot.Manager) []string {
+	t.Helper()
+	snapshots, err := manager.List(context.Background())
+	require.NoError(t, err)
+	var names []string
+	for _, s := range snapshots {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// TestManager tests the creation, retention and restore of the snapshots.
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	milk := internalProduct.Product{Id: 1, Name: "Milk", Quantity: 10, CodeValue: "M1", Expiration: "01/02/2030", Price: 1.5}
+
+	t.Run("success - restore the catalogue of a snapshot", func(t *testing.T) {
+		// arrange
+		st := storage.NewProductStorageMemory(map[int]internalProduct.Product{1: milk})
+		manager := snapshot.NewManager(st, snapshot.NewMemoryStore(), snapshot.Retention{})
+		restored := 0
+		manager.OnRestore(func() { restored++ })
+		created, err := manager.Create(ctx, "")
+		require.NoError(t, err)
+		require.NoError(t, st.SaveProducts(ctx, map[int]internalProduct.Product{}))
+
+		// act
+		s, err := manager.Restore(ctx, created.Name)
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, 1, s.Count)
+		require.Equal(t, 1, restored)
+		products, _ := st.LoadProducts(ctx)
+		require.Equal(t, milk, products[1])
+	})
+
+	t.Run("success - a restore waits for the write holding the lock of the repository", func(t *testing.T) {
+		// arrange
+		st := storage.NewProductStorageMemory(map[int]internalProduct.Product{1: milk})
+		rp := repository.NewProductRepository(st)
+		manager := snapshot.NewManager(st, snapshot.NewMemoryStore(), snapshot.Retention{})
+		manager.WriteLock = rp.Mu
+		created, err := manager.Create(ctx, "")
+		require.NoError(t, err)
+		rp.Mu.Lock()
+		done := make(chan error)
+
+		// act
+		go func() {
+			_, err := manager.Restore(ctx, created.Name)
+			done <- err
+		}()
+		select {
+		case <-done:
+			t.Fatal("restore did not wait for the lock")
+		case <-time.After(50 * time.Millisecond):
+		}
+		require.NoError(t, st.SaveProducts(ctx, map[int]internalProduct.Product{}))
+		rp.Mu.Unlock()
+
+		// assert
+		require.NoError(t, <-done)
+		products, _ := st.LoadProducts(ctx)
+		require.Equal(t, milk, products[1])
+	})
+
+	t.Run("success - the oldest snapshots out of the retention deleted", func(t *testing.T) {
+		// arrange
+		st := storage.NewProductStorageMemory(nil)
+		manager := snapshot.NewManager(st, snapshot.NewMemoryStore(), snapshot.Retention{MaxCount: 2})
+
+		// act
+		for _, name := range []string{"a", "b", "c"} {
+			_, err := manager.Create(ctx, name)
+			require.NoError(t, err)
+		}
+
+		// assert
+		require.Equal(t, []string{"c", "b"}, names(t, manager))
+	})
+
+	t.Run("success - snapshots of a directory survive a restart", func(t *testing.T) {
+		// arrange
+		dir := t.TempDir()
+		st := storage.NewProductStorageMemory(map[int]internalProduct.Product{1: milk})
+		store, err := snapshot.NewDirStore(dir)
+		require.NoError(t, err)
+		_, err = snapshot.NewManager(st, store, snapshot.Retention{}).Create(ctx, "v1")
+		require.NoError(t, err)
+
+		// act
+		reopened, err := snapshot.NewDirStore(dir)
+		require.NoError(t, err)
+		s, products, err := reopened.Load(ctx, "v1")
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, "v1", s.Name)
+		require.Equal(t, milk, products[1])
+	})
+
+	t.Run("failure - corrupted snapshot not restored", func(t *testing.T) {
+		// arrange
+		dir := t.TempDir()
+		st := storage.NewProductStorageMemory(map[int]internalProduct.Product{1: milk})
+		store, err := snapshot.NewDirStore(dir)
+		require.NoError(t, err)
+		manager := snapshot.NewManager(st, store, snapshot.Retention{})
+		_, err = manager.Create(ctx, "v1")
+		require.NoError(t, err)
+		data, err := os.ReadFile(filepath.Join(dir, "v1.json"))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "v1.json"), []byte(string(data[:len(data)-3])+"]}"), 0644))
+
+		// act
+		_, err = manager.Restore(ctx, "v1")
+
+		// assert
+		require.ErrorIs(t, err, snapshot.ErrCorrupted)
+	})
+
+	t.Run("error - invalid or taken name", func(t *testing.T) {
+		// arrange
+		manager := snapshot.NewManager(storage.NewProductStorageMemory(nil), snapshot.NewMemoryStore(), snapshot.Retention{})
+		_, err := manager.Create(ctx, "v1")
+		require.NoError(t, err)
+
+		// act
+		_, errInvalid := manager.Create(ctx, "../v2")
+		_, errTaken := manager.Create(ctx, "v1")
+		_, errNotFound := manager.Restore(ctx, "v2")
+
+		// assert
+		require.ErrorIs(t, errInvalid, snapshot.ErrInvalidName)
+		require.ErrorIs(t, errTaken, snapshot.ErrSnapshotExists)
+		require.ErrorIs(t, errNotFound, snapshot.ErrSnapshotNotFound)
+	})
+}
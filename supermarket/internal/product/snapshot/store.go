@@ -0,0 +1,252 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// NewMemoryStore creates a Store keeping the snapshots in memory.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		snapshots: make(map[string]memorySnapshot),
+	}
+}
+
+// MemoryStore is a Store keeping the snapshots in memory
+type MemoryStore struct {
+	mu        sync.RWMutex
+	snapshots map[string]memorySnapshot
+}
+
+// memorySnapshot is a snapshot and its products
+type memorySnapshot struct {
+	snapshot Snapshot
+	products map[int]Product
+}
+
+// Save adds a snapshot of a copy of products.
+func (s *MemoryStore) Save(ctx context.Context, snapshot Snapshot, products map[int]Product) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.snapshots[snapshot.Name]; ok {
+		return ErrSnapshotExists
+	}
+	s.snapshots[snapshot.Name] = memorySnapshot{snapshot: snapshot, products: copyProducts(products)}
+	return nil
+}
+
+// List returns the snapshots, the newest first.
+func (s *MemoryStore) List(ctx context.Context) ([]Snapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshots := make([]Snapshot, 0, len(s.snapshots))
+	for _, m := range s.snapshots {
+		snapshots = append(snapshots, m.snapshot)
+	}
+	sortNewestFirst(snapshots)
+	return snapshots, nil
+}
+
+// Load returns a snapshot and a copy of its products.
+func (s *MemoryStore) Load(ctx context.Context, name string) (Snapshot, map[int]Product, error) {
+	if err := ctx.Err(); err != nil {
+		return Snapshot{}, nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	m, ok := s.snapshots[name]
+	if !ok {
+		return Snapshot{}, nil, ErrSnapshotNotFound
+	}
+	return m.snapshot, copyProducts(m.products), nil
+}
+
+// Delete removes a snapshot.
+func (s *MemoryStore) Delete(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.snapshots[name]; !ok {
+		return ErrSnapshotNotFound
+	}
+	delete(s.snapshots, name)
+	return nil
+}
+
+// NewDirStore creates a Store keeping the snapshots as json files of a directory, created if missing.
+func NewDirStore(dir string) (*DirStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	return &DirStore{dir: dir}, nil
+}
+
+// DirStore is a Store keeping every snapshot in a json file "<name>.json" of a directory, a File
+type DirStore struct {
+	dir string
+	// mu serializes the writes, so two snapshots of the same name do not overwrite each other
+	mu sync.Mutex
+}
+
+// File is the content of a snapshot file, also the body of its download.
+type File struct {
+	Snapshot
+	// Products are the products of the snapshot, by id
+	Products []Product `json:"products"`
+}
+
+// NewFile returns the file of snapshot, its products sorted by id.
+func NewFile(snapshot Snapshot, products map[int]Product) File {
+	file := File{Snapshot: snapshot, Products: make([]Product, 0, len(products))}
+	for _, product := range products {
+		file.Products = append(file.Products, product)
+	}
+	sort.Slice(file.Products, func(i, j int) bool { return file.Products[i].Id < file.Products[j].Id })
+	return file
+}
+
+// Save writes a snapshot file, to a temporary file renamed once complete.
+func (s *DirStore) Save(ctx context.Context, snapshot Snapshot, products map[int]Product) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	filename := s.filename(snapshot.Name)
+	if _, err := os.Stat(filename); err == nil {
+		return ErrSnapshotExists
+	}
+	data, err := json.Marshal(NewFile(snapshot, products))
+	if err != nil {
+		return fmt.Errorf("snapshot: encode: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, snapshot.Name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if _, err := tmp.Write(data); err != nil {
+		return fmt.Errorf("snapshot: write %s: %w", filename, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("snapshot: write %s: %w", filename, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("snapshot: write %s: %w", filename, err)
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("snapshot: write %s: %w", filename, err)
+	}
+	return nil
+}
+
+// List reads the snapshot files, the newest first.
+func (s *DirStore) List(ctx context.Context) ([]Snapshot, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	snapshots := []Snapshot{}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		file, err := s.read(name)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, file.Snapshot)
+	}
+	sortNewestFirst(snapshots)
+	return snapshots, nil
+}
+
+// Load reads a snapshot file.
+func (s *DirStore) Load(ctx context.Context, name string) (Snapshot, map[int]Product, error) {
+	if err := ctx.Err(); err != nil {
+		return Snapshot{}, nil, err
+	}
+	file, err := s.read(name)
+	if err != nil {
+		return Snapshot{}, nil, err
+	}
+	products := make(map[int]Product, len(file.Products))
+	for _, product := range file.Products {
+		products[product.Id] = product
+	}
+	return file.Snapshot, products, nil
+}
+
+// Delete removes a snapshot file.
+func (s *DirStore) Delete(ctx context.Context, name string) error {
+	if !validName.MatchString(name) {
+		return ErrSnapshotNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := os.Remove(s.filename(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrSnapshotNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	return nil
+}
+
+// read decodes the snapshot file of name
+func (s *DirStore) read(name string) (File, error) {
+	if !validName.MatchString(name) {
+		return File{}, ErrSnapshotNotFound
+	}
+	data, err := os.ReadFile(s.filename(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return File{}, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return File{}, fmt.Errorf("snapshot: %w", err)
+	}
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return File{}, fmt.Errorf("%w: %s: %v", ErrCorrupted, name, err)
+	}
+	return file, nil
+}
+
+// filename returns the path of the file of the snapshot name
+func (s *DirStore) filename(name string) string {
+	return filepath.Join(s.dir, name+".json")
+}
+
+// copyProducts returns a copy of products
+func copyProducts(products map[int]Product) map[int]Product {
+	copied := make(map[int]Product, len(products))
+	for id, product := range products {
+		copied[id] = product
+	}
+	return copied
+}
+
+// sortNewestFirst sorts snapshots by creation, the newest first
+func sortNewestFirst(snapshots []Snapshot) {
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt) })
+}
@@ -1,15 +1,21 @@
 package storage
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
 	internalProduct "supermarket/internal/product"
+	"sync"
 )
 
 type Product = internalProduct.Product
 
 type ProductStorage struct {
 	filename string
+	// mu serializes writes so a flush waits for any pending write to complete
+	mu sync.Mutex
 }
 
 func NewProductStorage(filename string) *ProductStorage {
@@ -19,7 +25,11 @@ func NewProductStorage(filename string) *ProductStorage {
 }
 
 // LoadProducts loads the products from a JSON file into the repository.
-func (ps *ProductStorage) LoadProducts() (map[int]Product, error) {
+func (ps *ProductStorage) LoadProducts(ctx context.Context) (map[int]Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	file, err := os.Open(ps.filename)
 	if err != nil {
 		return nil, internalProduct.ErrFileNotFound
@@ -41,12 +51,25 @@ func (ps *ProductStorage) LoadProducts() (map[int]Product, error) {
 }
 
 // SaveProducts saves the products from the repository to a JSON file.
-func (ps *ProductStorage) SaveProducts(products map[int]Product) error {
-	file, err := os.Create(ps.filename)
+// The file is written to a temporary file first and renamed, so a crash never leaves it truncated.
+func (ps *ProductStorage) SaveProducts(ctx context.Context, products map[int]Product) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	// the write itself is not interrupted, so the file is never left half written
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(ps.filename), filepath.Base(ps.filename)+".*.tmp")
 	if err != nil {
 		return internalProduct.ErrSaveProducts
 	}
+	defer os.Remove(file.Name())
 	defer file.Close()
+	if err = file.Chmod(0644); err != nil {
+		return internalProduct.ErrSaveProducts
+	}
 
 	// Convert map to slice
 	var productsSlice []Product
@@ -58,6 +81,47 @@ func (ps *ProductStorage) SaveProducts(products map[int]Product) error {
 	if err != nil {
 		return internalProduct.ErrSaveProducts
 	}
+	if err = file.Sync(); err != nil {
+		return internalProduct.ErrSaveProducts
+	}
+	if err = file.Close(); err != nil {
+		return internalProduct.ErrSaveProducts
+	}
+	if err = os.Rename(file.Name(), ps.filename); err != nil {
+		return internalProduct.ErrSaveProducts
+	}
 
 	return nil
 }
+
+// Flush waits for any pending write to reach the file. It is meant to be called on shutdown.
+func (ps *ProductStorage) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		ps.mu.Lock()
+		defer ps.mu.Unlock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", internalProduct.ErrSaveProducts, ctx.Err())
+	}
+}
+
+// HealthCheck checks that the JSON file can be read and decoded.
+func (ps *ProductStorage) HealthCheck(ctx context.Context) error {
+	_, err := ps.LoadProducts(ctx)
+	return err
+}
+
+// WritableCheck checks that the JSON file can be opened for writing, without modifying it.
+func (ps *ProductStorage) WritableCheck(ctx context.Context) error {
+	file, err := os.OpenFile(ps.filename, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("%w: %v", internalProduct.ErrSaveProducts, err)
+	}
+	return file.Close()
+}
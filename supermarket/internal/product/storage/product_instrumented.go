@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"supermarket/internal/platform/metrics"
+	"supermarket/internal/platform/tracing"
+	internalProduct "supermarket/internal/product"
+	"time"
+)
+
+// NewProductStorageInstrumented wraps storage to record the duration and errors of its operations
+// and the business gauges of the catalogue it loads and saves.
+func NewProductStorageInstrumented(storage internalProduct.ProductStorageInterface, reg *metrics.Registry) *ProductStorageInstrumented {
+	return &ProductStorageInstrumented{
+		storage: storage,
+		duration: reg.NewHistogram("supermarket_storage_operation_duration_seconds",
+			"Duration of the storage operations.", nil, "operation"),
+		errors: reg.NewCounter("supermarket_storage_operation_errors_total",
+			"Number of failed storage operations.", "operation"),
+		products: reg.NewGauge("supermarket_products",
+			"Number of products in the catalogue."),
+		inventoryValue: reg.NewGauge("supermarket_inventory_value",
+			"Total value of the inventory, the sum of price times quantity."),
+		expired: reg.NewGauge("supermarket_products_expired",
+			"Number of products past their expiration date."),
+	}
+}
+
+// ProductStorageInstrumented is a ProductStorageInterface that records metrics and traces its operations,
+// whatever the backend
+type ProductStorageInstrumented struct {
+	storage internalProduct.ProductStorageInterface
+
+	duration       *metrics.Histogram
+	errors         *metrics.Counter
+	products       *metrics.Gauge
+	inventoryValue *metrics.Gauge
+	expired        *metrics.Gauge
+}
+
+// LoadProducts loads the products from the wrapped storage.
+func (ps *ProductStorageInstrumented) LoadProducts(ctx context.Context) (map[int]Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductStorage.LoadProducts")
+	defer span.End()
+
+	start := time.Now()
+	products, err := ps.storage.LoadProducts(ctx)
+	ps.observe("load", start, err)
+	if err != nil {
+		span.RecordError(err)
+	} else {
+		span.SetAttributes(tracing.Int("products.count", len(products)))
+		ps.updateGauges(products)
+	}
+	return products, err
+}
+
+// SaveProducts saves the products to the wrapped storage.
+func (ps *ProductStorageInstrumented) SaveProducts(ctx context.Context, products map[int]Product) error {
+	ctx, span := tracing.Start(ctx, "ProductStorage.SaveProducts", tracing.WithAttributes(tracing.Int("products.count", len(products))))
+	defer span.End()
+
+	start := time.Now()
+	err := ps.storage.SaveProducts(ctx, products)
+	ps.observe("save", start, err)
+	if err != nil {
+		span.RecordError(err)
+	} else {
+		ps.updateGauges(products)
+	}
+	return err
+}
+
+// observe records the duration and the error of an operation
+func (ps *ProductStorageInstrumented) observe(operation string, start time.Time, err error) {
+	ps.duration.Observe(time.Since(start).Seconds(), operation)
+	if err != nil {
+		ps.errors.Inc(operation)
+	}
+}
+
+// updateGauges sets the business gauges from the whole catalogue
+func (ps *ProductStorageInstrumented) updateGauges(products map[int]Product) {
+	now := time.Now()
+	var value float64
+	var expired int
+	for _, product := range products {
+		value += product.Price * float64(product.Quantity)
+		if product.IsExpired(now) {
+			expired++
+		}
+	}
+	ps.products.Set(float64(len(products)))
+	ps.inventoryValue.Set(value)
+	ps.expired.Set(float64(expired))
+}
@@ -0,0 +1,80 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"supermarket/internal/platform/metrics"
+	internalProduct "supermarket/internal/product"
+	"supermarket/internal/product/storage"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// failing is a storage failing every operation
+type failing struct{}
+
+func (failing) LoadProducts(ctx context.Context) (map[int]internalProduct.Product, error) {
+	return nil, errors.New("disk failure")
+}
+
+func (failing) SaveProducts(ctx context.Context, products map[int]internalProduct.Product) error {
+	return errors.New("disk failure")
+}
+
+// TestProductStorageInstrumented tests the metrics of the storage operations and of the catalogue.
+func TestProductStorageInstrumented(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success - durations and gauges of the catalogue", func(t *testing.T) {
+		// arrange
+		reg := metrics.NewRegistry()
+		st := storage.NewProductStorageInstrumented(storage.NewProductStorageMemory(nil), reg)
+		products := map[int]internalProduct.Product{
+			1: {Id: 1, Name: "Milk", Quantity: 10, CodeValue: "M1", Expiration: "01/02/2000", Price: 1.5},
+			2: {Id: 2, Name: "Water", Quantity: 4, CodeValue: "W1", Expiration: "14/10/2021", Price: 2},
+			3: {Id: 3, Name: "Bread", Quantity: 2, CodeValue: "B1", Expiration: "01/02/2999", Price: 3},
+		}
+
+		// act
+		errSave := st.SaveProducts(ctx, products)
+		_, errLoad := st.LoadProducts(ctx)
+
+		// assert
+		require.NoError(t, errSave)
+		require.NoError(t, errLoad)
+		var sb strings.Builder
+		_, err := reg.WriteTo(&sb)
+		require.NoError(t, err)
+		text := sb.String()
+		require.Contains(t, text, `supermarket_storage_operation_duration_seconds_count{operation="save"} 1`)
+		require.Contains(t, text, `supermarket_storage_operation_duration_seconds_count{operation="load"} 1`)
+		require.Contains(t, text, "supermarket_products 3\n")
+		require.Contains(t, text, "supermarket_inventory_value 29\n")
+		require.Contains(t, text, "supermarket_products_expired 2\n")
+		require.NotContains(t, text, "supermarket_storage_operation_errors_total{")
+	})
+
+	t.Run("failure - errors counted by operation, gauges kept", func(t *testing.T) {
+		// arrange
+		reg := metrics.NewRegistry()
+		st := storage.NewProductStorageInstrumented(failing{}, reg)
+
+		// act
+		_, errLoad := st.LoadProducts(ctx)
+		errSave := st.SaveProducts(ctx, map[int]internalProduct.Product{1: {Id: 1}})
+
+		// assert
+		require.Error(t, errLoad)
+		require.Error(t, errSave)
+		var sb strings.Builder
+		_, err := reg.WriteTo(&sb)
+		require.NoError(t, err)
+		text := sb.String()
+		require.Contains(t, text, `supermarket_storage_operation_errors_total{operation="load"} 1`)
+		require.Contains(t, text, `supermarket_storage_operation_errors_total{operation="save"} 1`)
+		require.Contains(t, text, `supermarket_storage_operation_duration_seconds_count{operation="save"} 1`)
+		require.NotContains(t, text, "supermarket_products 1")
+	})
+}
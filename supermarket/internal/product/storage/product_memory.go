@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"sync"
+)
+
+// ProductStorageMemory is a ProductStorageInterface that keeps the products in memory.
+type ProductStorageMemory struct {
+	mu       sync.RWMutex
+	products map[int]Product
+}
+
+// NewProductStorageMemory creates a new ProductStorageMemory holding a copy of seed.
+func NewProductStorageMemory(seed map[int]Product) *ProductStorageMemory {
+	products := make(map[int]Product, len(seed))
+	for id, product := range seed {
+		products[id] = product
+	}
+	return &ProductStorageMemory{
+		products: products,
+	}
+}
+
+// LoadProducts returns a copy of the products in memory.
+func (ps *ProductStorageMemory) LoadProducts(ctx context.Context) (map[int]Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	products := make(map[int]Product, len(ps.products))
+	for id, product := range ps.products {
+		products[id] = product
+	}
+	return products, nil
+}
+
+// SaveProducts replaces the products in memory with a copy of products.
+func (ps *ProductStorageMemory) SaveProducts(ctx context.Context, products map[int]Product) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.products = make(map[int]Product, len(products))
+	for id, product := range products {
+		ps.products[id] = product
+	}
+	return nil
+}
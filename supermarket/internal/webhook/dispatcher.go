@@ -0,0 +1,271 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"supermarket/internal/platform/random"
+	"sync"
+	"time"
+)
+
+const (
+	// batchSize is the number of due deliveries attempted at once
+	batchSize = 100
+	// workers is the number of deliveries attempted concurrently
+	workers = 4
+	// maxResponseBytes is the part of the responses read, for the connections to be reused
+	maxResponseBytes = 64 << 10
+)
+
+// Config is the configuration of a Dispatcher. The zero values are replaced by the defaults.
+type Config struct {
+	// MaxAttempts is the number of attempts of a delivery before it is dead, 8 by default
+	MaxAttempts int
+	// MinBackoff is the delay before the second attempt, doubled for every attempt up to MaxBackoff.
+	// 10 seconds and 1 hour by default.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Timeout bounds an attempt, 10 seconds by default
+	Timeout time.Duration
+	// PollInterval is the interval the outbox is checked for due deliveries, 1 second by default
+	PollInterval time.Duration
+	// Client sends the deliveries, http.DefaultClient by default
+	Client *http.Client
+}
+
+// NewDispatcher returns a Dispatcher of the deliveries of store.
+func NewDispatcher(store Store, config Config) *Dispatcher {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 8
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = 10 * time.Second
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = max(time.Hour, config.MinBackoff)
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	return &Dispatcher{
+		store:  store,
+		config: config,
+		wake:   make(chan struct{}, 1),
+		now:    time.Now,
+	}
+}
+
+// Dispatcher enqueues the events of the catalogue in the outbox of a store, as a delivery per
+// subscription receiving them, and delivers them. Deliveries are at least once: an attempt interrupted
+// by a shutdown is made again, receivers ignore the ids of HeaderID they already processed.
+type Dispatcher struct {
+	store  Store
+	config Config
+	// wake signals the enqueued deliveries to Run
+	wake chan struct{}
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// Publish enqueues a delivery of event to each subscription receiving its type. It is a handler of
+// the event bus, called before the write of the event returns, so the deliveries are saved even if
+// the server stops right after.
+func (d *Dispatcher) Publish(ctx context.Context, event Event) error {
+	if err := d.enqueue(ctx, event); err != nil {
+		return fmt.Errorf("webhook: enqueue: %w", err)
+	}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// enqueue implements Publish
+func (d *Dispatcher) enqueue(ctx context.Context, event Event) error {
+	subscriptions, err := d.store.Subscriptions(ctx)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	now := d.now()
+	var deliveries []Delivery
+	for _, subscription := range subscriptions {
+		if !subscription.Matches(event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, Delivery{
+			ID:             random.ID(),
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        payload,
+			Status:         StatusPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return d.store.Enqueue(ctx, deliveries)
+}
+
+// Run delivers the due deliveries until ctx is done, when events are enqueued and every poll interval.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := d.DeliverDue(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("webhook deliveries", slog.Any("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// DeliverDue attempts the deliveries due now, until none is left or ctx is done.
+func (d *Dispatcher) DeliverDue(ctx context.Context) error {
+	for ctx.Err() == nil {
+		due, err := d.store.Due(ctx, d.now(), batchSize)
+		if err != nil {
+			return err
+		}
+
+		sem := make(chan struct{}, workers)
+		var wg sync.WaitGroup
+		for _, delivery := range due {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(delivery Delivery) {
+				defer func() { <-sem; wg.Done() }()
+				d.attempt(ctx, delivery)
+			}(delivery)
+		}
+		wg.Wait()
+
+		if len(due) < batchSize {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+// attempt sends delivery and records the outcome
+func (d *Dispatcher) attempt(ctx context.Context, delivery Delivery) {
+	subscription, err := d.store.Subscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		// the subscription was deleted with its deliveries
+		return
+	}
+
+	statusCode, err := d.send(ctx, subscription, delivery)
+	if ctx.Err() != nil {
+		// interrupted by the shutdown, attempted again on the next start
+		return
+	}
+
+	now := d.now()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.UpdatedAt = now
+	switch {
+	case err == nil:
+		delivery.Status = StatusDelivered
+		delivery.LastError = ""
+	case delivery.Attempts >= d.config.MaxAttempts:
+		delivery.Status = StatusDead
+		delivery.LastError = err.Error()
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+	}
+	if err := d.store.UpdateDelivery(ctx, delivery); err != nil && err != ErrDeliveryNotFound {
+		slog.Error("webhook delivery update", slog.String("delivery_id", delivery.ID), slog.Any("error", err))
+	}
+}
+
+// send posts the payload of delivery, signed, to the url of subscription. The status code of the
+// response is returned, with an error unless it is a 2xx.
+func (d *Dispatcher) send(ctx context.Context, subscription Subscription, delivery Delivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.config.Timeout)
+	defer cancel()
+
+	timestamp := d.now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "supermarket-webhooks")
+	req.Header.Set(HeaderID, delivery.ID)
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, timestamp, delivery.Payload))
+
+	res, err := d.config.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, maxResponseBytes))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// backoff returns the delay after the attempt number attempts, doubling from the minimum backoff
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.MinBackoff
+	for i := 1; i < attempts && delay < d.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.config.MaxBackoff)
+}
+
+// Redeliver makes a dead delivery pending again, due now, with a new budget of attempts.
+func (d *Dispatcher) Redeliver(ctx context.Context, id string) (Delivery, error) {
+	delivery, err := d.store.Delivery(ctx, id)
+	if err != nil {
+		return Delivery{}, err
+	}
+	if delivery.Status != StatusDead {
+		return delivery, ErrDeliveryNotDead
+	}
+
+	now := d.now()
+	delivery.Status = StatusPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = now
+	delivery.UpdatedAt = now
+	if err := d.store.UpdateDelivery(ctx, delivery); err != nil {
+		return Delivery{}, err
+	}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return delivery, nil
+}
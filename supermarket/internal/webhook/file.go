@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// NewFileStore creates a Store persisting the subscriptions and deliveries to a json file, so that the
+// pending deliveries survive restarts, the finished deliveries within retention. The content of the file
+// is loaded if it exists.
+func NewFileStore(filename string, retention Retention) (*FileStore, error) {
+	s := &FileStore{MemoryStore: NewMemoryStore(retention), filename: filename}
+
+	data, err := os.ReadFile(filename)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return s, nil
+	case err != nil:
+		return nil, fmt.Errorf("webhook: read %s: %w", filename, err)
+	}
+	if err := json.Unmarshal(data, &s.data); err != nil {
+		return nil, fmt.Errorf("webhook: decode %s: %w", filename, err)
+	}
+	if s.data.Subscriptions == nil {
+		s.data.Subscriptions = make(map[string]Subscription)
+	}
+	if s.data.Deliveries == nil {
+		s.data.Deliveries = make(map[string]Delivery)
+	}
+	// the deliveries out of the retention are not saved again, with the next change
+	s.prune()
+	return s, nil
+}
+
+// FileStore is a MemoryStore saved to a json file on every change, the finished deliveries out of the
+// retention being pruned before, so that the file does not grow with every event
+type FileStore struct {
+	*MemoryStore
+	filename string
+}
+
+// CreateSubscription adds a subscription.
+func (s *FileStore) CreateSubscription(ctx context.Context, subscription Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Subscriptions[subscription.ID] = subscription
+	if err := s.save(); err != nil {
+		delete(s.data.Subscriptions, subscription.ID)
+		return err
+	}
+	return nil
+}
+
+// DeleteSubscription removes a subscription and its deliveries.
+func (s *FileStore) DeleteSubscription(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.deleteSubscription(id); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+// Enqueue adds deliveries, none of them if they can not be saved.
+func (s *FileStore) Enqueue(ctx context.Context, deliveries []Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, delivery := range deliveries {
+		s.data.Deliveries[delivery.ID] = delivery
+	}
+	if err := s.save(); err != nil {
+		for _, delivery := range deliveries {
+			delete(s.data.Deliveries, delivery.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+// UpdateDelivery replaces a delivery.
+func (s *FileStore) UpdateDelivery(ctx context.Context, delivery Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.updateDelivery(delivery); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+// save prunes the deliveries out of the retention, then writes the data to a temporary file renamed over the
+// file, so a crash never leaves it truncated
+func (s *FileStore) save() error {
+	s.prune()
+	data, err := json.Marshal(s.data)
+	if err != nil {
+		return fmt.Errorf("webhook: encode: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(s.filename), filepath.Base(s.filename)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("webhook: save: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("webhook: save: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("webhook: save: %w", err)
+	}
+	if err := os.Rename(file.Name(), s.filename); err != nil {
+		return fmt.Errorf("webhook: save: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"supermarket/internal/platform/logging"
+	"supermarket/internal/platform/web/request"
+	"supermarket/internal/platform/web/response"
+	"supermarket/internal/webhook"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type WebhookHandler struct {
+	Store      webhook.Store
+	Dispatcher *webhook.Dispatcher
+	// StrictJSON rejects the json bodies with unknown fields or trailing data
+	StrictJSON bool
+}
+
+// NewWebhookHandler returns a new WebhookHandler.
+func NewWebhookHandler(store webhook.Store, dispatcher *webhook.Dispatcher) *WebhookHandler {
+	return &WebhookHandler{
+		Store:      store,
+		Dispatcher: dispatcher,
+	}
+}
+
+// SubscriptionRequestJSON is the body of a request creating a subscription
+type SubscriptionRequestJSON struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+// SubscriptionResponseJSON is a subscription, its secret only being responded on creation
+type SubscriptionResponseJSON struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// subscriptionResponse returns subscription without its secret
+func subscriptionResponse(subscription webhook.Subscription) SubscriptionResponseJSON {
+	return SubscriptionResponseJSON{
+		ID:        subscription.ID,
+		URL:       subscription.URL,
+		Events:    subscription.Events,
+		CreatedAt: subscription.CreatedAt,
+	}
+}
+
+// CreateSubscriptionHandler creates a subscription, responding its secret, generated if not set.
+func (h *WebhookHandler) CreateSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	var subscriptionRequest SubscriptionRequestJSON
+	if err := request.JSON(r, &subscriptionRequest, request.Options(h.StrictJSON)...); err != nil {
+		response.RequestError(w, err)
+		return
+	}
+
+	subscription, err := webhook.NewSubscription(subscriptionRequest.URL, subscriptionRequest.Events, subscriptionRequest.Secret)
+	if err != nil {
+		response.Errorw(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if err := h.Store.CreateSubscription(r.Context(), subscription); err != nil {
+		serverError(w, r, "create webhook subscription", err)
+		return
+	}
+
+	subscriptionResponse := subscriptionResponse(subscription)
+	subscriptionResponse.Secret = subscription.Secret
+	response.JSON(w, http.StatusCreated, "webhook subscription created successfully", subscriptionResponse)
+}
+
+// GetSubscriptionsHandler returns the subscriptions.
+func (h *WebhookHandler) GetSubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := h.Store.Subscriptions(r.Context())
+	if err != nil {
+		serverError(w, r, "get webhook subscriptions", err)
+		return
+	}
+
+	subscriptionsResponse := make([]SubscriptionResponseJSON, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		subscriptionsResponse = append(subscriptionsResponse, subscriptionResponse(subscription))
+	}
+	response.JSON(w, http.StatusOK, "webhook subscriptions fetched successfully", subscriptionsResponse)
+}
+
+// GetSubscriptionHandler returns a subscription by id.
+func (h *WebhookHandler) GetSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	subscription, err := h.Store.Subscription(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		storeError(w, r, "get webhook subscription", err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "webhook subscription fetched successfully", subscriptionResponse(subscription))
+}
+
+// DeleteSubscriptionHandler deletes a subscription and its deliveries, pending ones included.
+func (h *WebhookHandler) DeleteSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	if err := h.Store.DeleteSubscription(r.Context(), chi.URLParam(r, "id")); err != nil {
+		storeError(w, r, "delete webhook subscription", err)
+		return
+	}
+	response.Text(w, http.StatusOK, "webhook subscription deleted successfully")
+}
+
+// GetDeliveriesHandler returns the delivery log of a subscription, the newest first.
+func (h *WebhookHandler) GetDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := h.Store.Deliveries(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		storeError(w, r, "get webhook deliveries", err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "webhook deliveries fetched successfully", deliveries)
+}
+
+// RedeliverHandler attempts a dead delivery again.
+func (h *WebhookHandler) RedeliverHandler(w http.ResponseWriter, r *http.Request) {
+	delivery, err := h.Store.Delivery(r.Context(), chi.URLParam(r, "deliveryId"))
+	if err == nil && delivery.SubscriptionID != chi.URLParam(r, "id") {
+		err = webhook.ErrDeliveryNotFound
+	}
+	if err == nil {
+		delivery, err = h.Dispatcher.Redeliver(r.Context(), delivery.ID)
+	}
+	if err != nil {
+		storeError(w, r, "redeliver webhook delivery", err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "webhook delivery scheduled successfully", delivery)
+}
+
+// storeError responds the error of the store
+func storeError(w http.ResponseWriter, r *http.Request, op string, err error) {
+	switch {
+	case errors.Is(err, webhook.ErrSubscriptionNotFound), errors.Is(err, webhook.ErrDeliveryNotFound):
+		response.Errorw(w, http.StatusNotFound, err)
+	case errors.Is(err, webhook.ErrDeliveryNotDead):
+		response.Errorw(w, http.StatusConflict, err)
+	default:
+		serverError(w, r, op, err)
+	}
+}
+
+// serverError responds a timeout or an internal error, logging the latter
+func serverError(w http.ResponseWriter, r *http.Request, op string, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		response.Error(w, http.StatusGatewayTimeout, "request timeout")
+		return
+	}
+	logging.FromContext(r.Context()).Error(op, slog.Any("error", err))
+	response.Error(w, http.StatusInternalServerError, "internal server error")
+}
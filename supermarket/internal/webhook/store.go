@@ -0,0 +1,213 @@
+package webhook
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Retention bounds the delivered and dead deliveries kept per subscription in the delivery log, the oldest
+// being removed on every change of the store. The pending deliveries are always kept.
+type Retention struct {
+	// MaxCount is the number of finished deliveries kept per subscription, unbounded if 0
+	MaxCount int
+	// MaxAge is the age of the finished deliveries kept, since their last attempt, unbounded if 0
+	MaxAge time.Duration
+}
+
+// NewMemoryStore creates a Store keeping the subscriptions and deliveries in memory, the finished deliveries
+// within retention.
+func NewMemoryStore(retention Retention) *MemoryStore {
+	return &MemoryStore{
+		data: storeData{
+			Subscriptions: make(map[string]Subscription),
+			Deliveries:    make(map[string]Delivery),
+		},
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+// MemoryStore is a Store local to the process
+type MemoryStore struct {
+	mu        sync.Mutex
+	data      storeData
+	retention Retention
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// storeData is the content of a store
+type storeData struct {
+	Subscriptions map[string]Subscription `json:"subscriptions"`
+	Deliveries    map[string]Delivery     `json:"deliveries"`
+}
+
+// CreateSubscription adds a subscription.
+func (s *MemoryStore) CreateSubscription(ctx context.Context, subscription Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Subscriptions[subscription.ID] = subscription
+	return nil
+}
+
+// Subscriptions returns the subscriptions, oldest first.
+func (s *MemoryStore) Subscriptions(ctx context.Context) ([]Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subscriptions := make([]Subscription, 0, len(s.data.Subscriptions))
+	for _, subscription := range s.data.Subscriptions {
+		subscriptions = append(subscriptions, subscription)
+	}
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+	})
+	return subscriptions, nil
+}
+
+// Subscription returns a subscription by id.
+func (s *MemoryStore) Subscription(ctx context.Context, id string) (Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subscription, ok := s.data.Subscriptions[id]
+	if !ok {
+		return Subscription{}, ErrSubscriptionNotFound
+	}
+	return subscription, nil
+}
+
+// DeleteSubscription removes a subscription and its deliveries.
+func (s *MemoryStore) DeleteSubscription(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deleteSubscription(id)
+}
+
+// deleteSubscription implements DeleteSubscription, with the lock held
+func (s *MemoryStore) deleteSubscription(id string) error {
+	if _, ok := s.data.Subscriptions[id]; !ok {
+		return ErrSubscriptionNotFound
+	}
+	delete(s.data.Subscriptions, id)
+	for deliveryID, delivery := range s.data.Deliveries {
+		if delivery.SubscriptionID == id {
+			delete(s.data.Deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+// Enqueue adds deliveries.
+func (s *MemoryStore) Enqueue(ctx context.Context, deliveries []Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, delivery := range deliveries {
+		s.data.Deliveries[delivery.ID] = delivery
+	}
+	s.prune()
+	return nil
+}
+
+// Due returns at most limit pending deliveries due at now, the oldest first.
+func (s *MemoryStore) Due(ctx context.Context, now time.Time, limit int) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []Delivery
+	for _, delivery := range s.data.Deliveries {
+		if delivery.Status == StatusPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	sortOldestFirst(due)
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// UpdateDelivery replaces a delivery.
+func (s *MemoryStore) UpdateDelivery(ctx context.Context, delivery Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.updateDelivery(delivery); err != nil {
+		return err
+	}
+	s.prune()
+	return nil
+}
+
+// updateDelivery implements UpdateDelivery, with the lock held
+func (s *MemoryStore) updateDelivery(delivery Delivery) error {
+	if _, ok := s.data.Deliveries[delivery.ID]; !ok {
+		return ErrDeliveryNotFound
+	}
+	s.data.Deliveries[delivery.ID] = delivery
+	return nil
+}
+
+// prune removes the finished deliveries out of the retention, with the lock held
+func (s *MemoryStore) prune() {
+	if s.retention == (Retention{}) {
+		return
+	}
+
+	finished := make(map[string][]Delivery)
+	for _, d := range s.data.Deliveries {
+		if d.Status != StatusPending {
+			finished[d.SubscriptionID] = append(finished[d.SubscriptionID], d)
+		}
+	}
+	now := s.now()
+	for _, deliveries := range finished {
+		sortOldestFirst(deliveries)
+		for i, d := range deliveries {
+			tooMany := s.retention.MaxCount > 0 && i < len(deliveries)-s.retention.MaxCount
+			tooOld := s.retention.MaxAge > 0 && now.Sub(d.UpdatedAt) > s.retention.MaxAge
+			if tooMany || tooOld {
+				delete(s.data.Deliveries, d.ID)
+			}
+		}
+	}
+}
+
+// Delivery returns a delivery by id.
+func (s *MemoryStore) Delivery(ctx context.Context, id string) (Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delivery, ok := s.data.Deliveries[id]
+	if !ok {
+		return Delivery{}, ErrDeliveryNotFound
+	}
+	return delivery, nil
+}
+
+// Deliveries returns the deliveries of a subscription, the newest first.
+func (s *MemoryStore) Deliveries(ctx context.Context, subscriptionID string) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.Subscriptions[subscriptionID]; !ok {
+		return nil, ErrSubscriptionNotFound
+	}
+	deliveries := []Delivery{}
+	for _, delivery := range s.data.Deliveries {
+		if delivery.SubscriptionID == subscriptionID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sortOldestFirst(deliveries)
+	for i, j := 0, len(deliveries)-1; i < j; i, j = i+1, j-1 {
+		deliveries[i], deliveries[j] = deliveries[j], deliveries[i]
+	}
+	return deliveries, nil
+}
+
+// sortOldestFirst sorts deliveries by creation, then id for the deliveries of the same event
+func sortOldestFirst(deliveries []Delivery) {
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].ID < deliveries[j].ID
+	})
+}
@@ -0,0 +1,188 @@
+// Package webhook notifies the subscribed urls of the events of the catalogue.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"supermarket/internal/platform/random"
+	internalProduct "supermarket/internal/product"
+	"time"
+)
+
+type Event = internalProduct.Event
+
+var (
+	// ErrSubscriptionNotFound is returned when a subscription does not exist.
+	ErrSubscriptionNotFound = errors.New("webhook: subscription not found")
+	// ErrDeliveryNotFound is returned when a delivery does not exist.
+	ErrDeliveryNotFound = errors.New("webhook: delivery not found")
+	// ErrInvalidSubscription is returned for a subscription with an invalid url or unknown event types.
+	ErrInvalidSubscription = errors.New("webhook: invalid subscription")
+	// ErrDeliveryNotDead is returned when redelivering a delivery which is not dead.
+	ErrDeliveryNotDead = errors.New("webhook: delivery not dead")
+)
+
+// Status of the deliveries
+const (
+	// StatusPending is a delivery waiting for its next attempt
+	StatusPending = "pending"
+	// StatusDelivered is a delivery acknowledged by the receiver with a 2xx response
+	StatusDelivered = "delivered"
+	// StatusDead is a delivery given up after the maximum number of attempts
+	StatusDead = "dead"
+)
+
+// Headers of the deliveries
+const (
+	// HeaderID is the id of the delivery, the same for all its attempts, for the receivers to ignore duplicates
+	HeaderID = "X-Webhook-Id"
+	// HeaderEvent is the type of the event delivered
+	HeaderEvent = "X-Webhook-Event"
+	// HeaderTimestamp is the unix time of the attempt, signed with the body
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature is "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>" keyed by the secret
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// eventTypes are the types of the events a subscription can receive
+var eventTypes = map[string]bool{
+	internalProduct.EventProductCreated: true,
+	internalProduct.EventProductUpdated: true,
+	internalProduct.EventProductDeleted: true,
+	internalProduct.EventStockChanged:   true,
+}
+
+// Subscription is a url notified of the events of some types.
+type Subscription struct {
+	// ID identifies the subscription
+	ID string `json:"id"`
+	// URL receives the deliveries by POST
+	URL string `json:"url"`
+	// Events are the types of the events delivered, all of them if empty
+	Events []string `json:"events"`
+	// Secret keys the signature of the deliveries
+	Secret string `json:"secret"`
+	// CreatedAt is when the subscription was created
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewSubscription returns a validated subscription of url to the events of types eventTypes, all of them
+// if empty, signed with secret. A random secret is generated if empty.
+func NewSubscription(url string, eventTypes []string, secret string) (Subscription, error) {
+	if secret == "" {
+		secret = NewSecret()
+	}
+	subscription := Subscription{
+		ID:        random.ID(),
+		URL:       url,
+		Events:    eventTypes,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	}
+	if subscription.Events == nil {
+		subscription.Events = []string{}
+	}
+	return subscription, subscription.Validate()
+}
+
+// Validate checks the url and the event types of the subscription.
+func (s Subscription) Validate() error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https url", ErrInvalidSubscription)
+	}
+	for _, eventType := range s.Events {
+		if !eventTypes[eventType] {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidSubscription, eventType)
+		}
+	}
+	return nil
+}
+
+// Matches tells if the subscription receives the events of type eventType.
+func (s Subscription) Matches(eventType string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, t := range s.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery is an event to deliver to a subscription, and the outcome of its attempts.
+type Delivery struct {
+	// ID identifies the delivery
+	ID string `json:"id"`
+	// SubscriptionID is the subscription the event is delivered to
+	SubscriptionID string `json:"subscription_id"`
+	// EventID and EventType are the id and type of the event
+	EventID   string `json:"event_id"`
+	EventType string `json:"event_type"`
+	// Payload is the body of the deliveries, the event as json
+	Payload json.RawMessage `json:"payload"`
+	// Status is one of the Status* constants
+	Status string `json:"status"`
+	// Attempts is the number of attempts made
+	Attempts int `json:"attempts"`
+	// NextAttemptAt is when the next attempt is due, for the pending deliveries
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	// LastStatusCode is the status code of the last response, 0 if none
+	LastStatusCode int `json:"last_status_code,omitempty"`
+	// LastError describes why the last attempt failed
+	LastError string `json:"last_error,omitempty"`
+	// CreatedAt is when the event was enqueued, and UpdatedAt when the delivery last changed
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Store keeps the subscriptions and their deliveries, the outbox of the pending ones.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// CreateSubscription adds a subscription
+	CreateSubscription(ctx context.Context, subscription Subscription) error
+	// Subscriptions returns the subscriptions, oldest first
+	Subscriptions(ctx context.Context) ([]Subscription, error)
+	// Subscription returns a subscription by id, ErrSubscriptionNotFound if it does not exist
+	Subscription(ctx context.Context, id string) (Subscription, error)
+	// DeleteSubscription removes a subscription and its deliveries
+	DeleteSubscription(ctx context.Context, id string) error
+
+	// Enqueue adds deliveries, atomically
+	Enqueue(ctx context.Context, deliveries []Delivery) error
+	// Due returns at most limit pending deliveries due at now, the oldest first
+	Due(ctx context.Context, now time.Time, limit int) ([]Delivery, error)
+	// UpdateDelivery replaces a delivery, ErrDeliveryNotFound if it does not exist
+	UpdateDelivery(ctx context.Context, delivery Delivery) error
+	// Delivery returns a delivery by id, ErrDeliveryNotFound if it does not exist
+	Delivery(ctx context.Context, id string) (Delivery, error)
+	// Deliveries returns the deliveries of a subscription, the newest first
+	Deliveries(ctx context.Context, subscriptionID string) ([]Delivery, error)
+}
+
+// Sign returns the signature of body sent at timestamp, the value of HeaderSignature.
+// Receivers compare it with hmac.Equal to the one they compute with the secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret returns a random secret, for the subscriptions created without one.
+func NewSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
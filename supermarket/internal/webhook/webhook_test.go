@@ -0,0 +1,247 @@
+package webhook_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	internalProduct "supermarket/internal/product"
+	"supermarket/internal/product/events"
+	"supermarket/internal/webhook"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// receiver is a local webhook receiver responding the status codes of statuses in turn, then 200
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+// newReceiver starts a receiver
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	t.Helper()
+	rc := &receiver{statuses: statuses}
+	rc.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rc.mu.Lock()
+		defer rc.mu.Unlock()
+		rc.requests = append(rc.requests, r)
+		rc.bodies = append(rc.bodies, body)
+		status := http.StatusOK
+		if len(rc.statuses) > 0 {
+			status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(rc.Close)
+	return rc
+}
+
+// received returns the number of requests received
+func (rc *receiver) received() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+// subscribe creates a subscription of url to eventTypes in store
+func subscribe(t *testing.T, store webhook.Store, url string, eventTypes ...string) webhook.Subscription {
+	t.Helper()
+	subscription, err := webhook.NewSubscription(url, eventTypes, "secret")
+	require.NoError(t, err)
+	require.NoError(t, store.CreateSubscription(context.Background(), subscription))
+	return subscription
+}
+
+// publish publishes an event of type eventType through a bus handled by dispatcher
+func publish(dispatcher *webhook.Dispatcher, eventType string) {
+	bus := events.NewBus(10)
+	bus.Handle(dispatcher.Publish)
+	bus.Publish(context.Background(), internalProduct.Event{Type: eventType, Product: internalProduct.Product{Id: 1, Name: "Milk"}})
+}
+
+// TestDispatcher tests the deliveries of the events to a local receiver.
+func TestDispatcher(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success - signed delivery to the matching subscriptions", func(t *testing.T) {
+		// arrange
+		rc := newReceiver(t)
+		other := newReceiver(t)
+		store := webhook.NewMemoryStore(webhook.Retention{})
+		subscription := subscribe(t, store, rc.URL, internalProduct.EventStockChanged)
+		subscribe(t, store, other.URL, internalProduct.EventProductDeleted)
+		dispatcher := webhook.NewDispatcher(store, webhook.Config{})
+
+		// act
+		publish(dispatcher, internalProduct.EventStockChanged)
+		err := dispatcher.DeliverDue(ctx)
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, 1, rc.received())
+		require.Equal(t, 0, other.received())
+		req, body := rc.requests[0], rc.bodies[0]
+		require.Equal(t, internalProduct.EventStockChanged, req.Header.Get(webhook.HeaderEvent))
+		timestamp, err := strconv.ParseInt(req.Header.Get(webhook.HeaderTimestamp), 10, 64)
+		require.NoError(t, err)
+		require.True(t, hmac.Equal([]byte(webhook.Sign("secret", timestamp, body)), []byte(req.Header.Get(webhook.HeaderSignature))))
+		require.Contains(t, string(body), `"name":"Milk"`)
+
+		deliveries, err := store.Deliveries(ctx, subscription.ID)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		require.Equal(t, webhook.StatusDelivered, deliveries[0].Status)
+		require.Equal(t, req.Header.Get(webhook.HeaderID), deliveries[0].ID)
+	})
+
+	t.Run("success - retried with backoff until delivered", func(t *testing.T) {
+		// arrange
+		rc := newReceiver(t, http.StatusInternalServerError)
+		store := webhook.NewMemoryStore(webhook.Retention{})
+		subscription := subscribe(t, store, rc.URL)
+		dispatcher := webhook.NewDispatcher(store, webhook.Config{MinBackoff: 50 * time.Millisecond})
+
+		// act
+		publish(dispatcher, internalProduct.EventProductCreated)
+		require.NoError(t, dispatcher.DeliverDue(ctx))
+		failed, _ := store.Deliveries(ctx, subscription.ID)
+		// not due before the backoff
+		require.NoError(t, dispatcher.DeliverDue(ctx))
+		received := rc.received()
+		time.Sleep(60 * time.Millisecond)
+		require.NoError(t, dispatcher.DeliverDue(ctx))
+		delivered, _ := store.Deliveries(ctx, subscription.ID)
+
+		// assert
+		require.Equal(t, webhook.StatusPending, failed[0].Status)
+		require.Equal(t, http.StatusInternalServerError, failed[0].LastStatusCode)
+		require.Equal(t, "unexpected status 500", failed[0].LastError)
+		require.Equal(t, 1, received)
+		require.Equal(t, 2, rc.received())
+		require.Equal(t, webhook.StatusDelivered, delivered[0].Status)
+		require.Equal(t, 2, delivered[0].Attempts)
+		require.Equal(t, rc.requests[0].Header.Get(webhook.HeaderID), rc.requests[1].Header.Get(webhook.HeaderID))
+	})
+
+	t.Run("failure - dead-lettered after the maximum attempts, then redelivered", func(t *testing.T) {
+		// arrange
+		rc := newReceiver(t, http.StatusBadGateway, http.StatusBadGateway)
+		store := webhook.NewMemoryStore(webhook.Retention{})
+		subscription := subscribe(t, store, rc.URL)
+		dispatcher := webhook.NewDispatcher(store, webhook.Config{MaxAttempts: 2, MinBackoff: time.Millisecond})
+
+		// act
+		publish(dispatcher, internalProduct.EventProductCreated)
+		require.NoError(t, dispatcher.DeliverDue(ctx))
+		time.Sleep(5 * time.Millisecond)
+		require.NoError(t, dispatcher.DeliverDue(ctx))
+		dead, _ := store.Deliveries(ctx, subscription.ID)
+		redelivered, err := dispatcher.Redeliver(ctx, dead[0].ID)
+		require.NoError(t, err)
+		require.NoError(t, dispatcher.DeliverDue(ctx))
+		delivered, _ := store.Deliveries(ctx, subscription.ID)
+
+		// assert
+		require.Equal(t, webhook.StatusDead, dead[0].Status)
+		require.Equal(t, 2, dead[0].Attempts)
+		require.Equal(t, webhook.StatusPending, redelivered.Status)
+		require.Equal(t, webhook.StatusDelivered, delivered[0].Status)
+		require.Equal(t, 3, rc.received())
+	})
+
+	t.Run("success - outbox of a file store survives a restart", func(t *testing.T) {
+		// arrange
+		rc := newReceiver(t)
+		filename := filepath.Join(t.TempDir(), "webhooks.json")
+		store, err := webhook.NewFileStore(filename, webhook.Retention{})
+		require.NoError(t, err)
+		subscription := subscribe(t, store, rc.URL)
+		// enqueued, the server stopping before the delivery
+		publish(webhook.NewDispatcher(store, webhook.Config{}), internalProduct.EventProductDeleted)
+
+		// act
+		restarted, err := webhook.NewFileStore(filename, webhook.Retention{})
+		require.NoError(t, err)
+		err = webhook.NewDispatcher(restarted, webhook.Config{}).DeliverDue(ctx)
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, 1, rc.received())
+		deliveries, err := restarted.Deliveries(ctx, subscription.ID)
+		require.NoError(t, err)
+		require.Equal(t, webhook.StatusDelivered, deliveries[0].Status)
+	})
+}
+
+// TestStoreRetention tests the pruning of the finished deliveries out of the retention.
+func TestStoreRetention(t *testing.T) {
+	t.Run("success - the oldest and the expired finished deliveries are pruned on save", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		filename := filepath.Join(t.TempDir(), "webhooks.json")
+		store, err := webhook.NewFileStore(filename, webhook.Retention{MaxCount: 2, MaxAge: time.Hour})
+		require.NoError(t, err)
+		subscription := subscribe(t, store, "https://erp.example.com/hooks")
+		now := time.Now()
+		delivery := func(id, status string, age time.Duration) webhook.Delivery {
+			return webhook.Delivery{ID: id, SubscriptionID: subscription.ID, Status: status, CreatedAt: now.Add(-age), UpdatedAt: now.Add(-age)}
+		}
+
+		// act
+		err = store.Enqueue(ctx, []webhook.Delivery{
+			delivery("pending", webhook.StatusPending, 3*time.Hour),
+			delivery("expired", webhook.StatusDead, 2*time.Hour),
+			delivery("oldest", webhook.StatusDelivered, 30*time.Minute),
+			delivery("older", webhook.StatusDelivered, 20*time.Minute),
+			delivery("newest", webhook.StatusDead, 10*time.Minute),
+		})
+		require.NoError(t, err)
+		restarted, errRestart := webhook.NewFileStore(filename, webhook.Retention{})
+
+		// assert
+		require.NoError(t, errRestart)
+		for _, s := range []webhook.Store{store, restarted} {
+			deliveries, err := s.Deliveries(ctx, subscription.ID)
+			require.NoError(t, err)
+			ids := make([]string, len(deliveries))
+			for i, d := range deliveries {
+				ids[i] = d.ID
+			}
+			require.Equal(t, []string{"newest", "older", "pending"}, ids)
+		}
+	})
+}
+
+// TestNewSubscription tests the validation of the subscriptions.
+func TestNewSubscription(t *testing.T) {
+	t.Run("success - secret generated", func(t *testing.T) {
+		// act
+		subscription, err := webhook.NewSubscription("https://erp.example.com/hooks", nil, "")
+
+		// assert
+		require.NoError(t, err)
+		require.Len(t, subscription.Secret, 64)
+		require.True(t, subscription.Matches(internalProduct.EventProductCreated))
+	})
+
+	t.Run("failure - invalid url or event type", func(t *testing.T) {
+		// act
+		_, errURL := webhook.NewSubscription("ftp://erp.example.com", nil, "")
+		_, errType := webhook.NewSubscription("https://erp.example.com", []string{"product.sold"}, "")
+
+		// assert
+		require.ErrorIs(t, errURL, webhook.ErrInvalidSubscription)
+		require.ErrorIs(t, errType, webhook.ErrInvalidSubscription)
+	})
+}